@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// auditFileName 本地审计日志文件，位于用户主目录的.deploy目录下，每行一条JSON记录
+const auditFileName = "audit.jsonl"
+
+// AuditConfig 审计日志配置，本地审计日志始终记录
+type AuditConfig struct {
+	Ledger bool `yaml:"ledger,omitempty"` // 同时把审计记录发布到共享台账
+}
+
+// auditEntry 一条审计记录：谁（本地用户和主机）、做了什么（项目、环境、参数）、结果
+// 每条记录包含上一条记录的哈希，形成哈希链，修改或删除中间的记录都能被verify发现
+type auditEntry struct {
+	Time      time.Time         `json:"time"`
+	Action    string            `json:"action"`
+	User      string            `json:"user"`
+	Hostname  string            `json:"hostname"`
+	Deployer  string            `json:"deployer,omitempty"` // git配置的用户名
+	Project   string            `json:"project,omitempty"`
+	Env       string            `json:"env,omitempty"`
+	Params    map[string]string `json:"params,omitempty"`
+	Result    string            `json:"result"`
+	Error     string            `json:"error,omitempty"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+	Signer    string            `json:"signer,omitempty"`    // 签名人，配置了ledger.signing时记录
+	Signature string            `json:"signature,omitempty"` // 签名人对hash的SSH签名
+}
+
+// auditPublisher 支持接收审计记录的台账后端
+type auditPublisher interface {
+	publishAudit(ctx context.Context, entry auditEntry) error
+}
+
+// newAuditEntry 生成审计记录，填充操作者身份
+func newAuditEntry(action, project, env string, params map[string]string, result, errMessage string) auditEntry {
+	entry := auditEntry{
+		Time:     time.Now(),
+		Action:   action,
+		Deployer: currentDeployer(),
+		Project:  project,
+		Env:      env,
+		Params:   maskSecretMap(params),
+		Result:   result,
+		Error:    maskSecrets(errMessage),
+	}
+	entry.User = currentOperator()
+	entry.Hostname, _ = os.Hostname()
+	return entry
+}
+
+// computeHash 计算记录的哈希：上一条记录的哈希加上本记录（不含hash字段）的JSON
+func (e auditEntry) computeHash() (string, error) {
+	e.Hash, e.Signature = "", ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(e.PrevHash), data...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// recordAudit 追加本地审计记录，配置了audit.ledger时同时发布到共享台账，失败只打印警告
+func recordAudit(ctx context.Context, config *Config, entry auditEntry) {
+	var signing *SigningConfig
+	if config != nil && config.Ledger != nil {
+		signing = config.Ledger.Signing
+	}
+	entry, err := appendAuditEntry(entry, signing)
+	if err != nil {
+		slog.Warn(msg("failed to write audit log: %v", err))
+		return
+	}
+	if config == nil || config.Audit == nil || !config.Audit.Ledger {
+		return
+	}
+	for _, backend := range config.Ledger.backends() {
+		publisher, ok := backend.(auditPublisher)
+		if !ok {
+			continue
+		}
+		if err := publisher.publishAudit(ctx, entry); err != nil {
+			slog.Warn(msg("failed to ship audit entry to ledger: %v", err))
+		}
+	}
+}
+
+// auditLogPath 本地审计日志路径
+func auditLogPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", auditFileName), nil
+}
+
+// appendAuditEntry 读取最后一条记录的哈希，链接后追加写入，配置了签名时对哈希签名，返回带哈希的记录
+func appendAuditEntry(entry auditEntry, signing *SigningConfig) (auditEntry, error) {
+	path, err := auditLogPath()
+	if err != nil {
+		return entry, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return entry, err
+	}
+	entries, err := readAuditLog(path)
+	if err != nil {
+		return entry, err
+	}
+	if len(entries) > 0 {
+		entry.PrevHash = entries[len(entries)-1].Hash
+	}
+	if signing != nil {
+		entry.Signer = signing.principal()
+	}
+	entry.Hash, err = entry.computeHash()
+	if err != nil {
+		return entry, err
+	}
+	if err := signing.signAuditEntry(&entry); err != nil {
+		slog.Warn(msg("failed to sign audit entry: %v", err))
+		entry.Signer = ""
+		if entry.Hash, err = entry.computeHash(); err != nil {
+			return entry, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return entry, err
+	}
+	defer file.Close()
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return entry, err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return entry, err
+}
+
+// readAuditLog 读取全部审计记录，文件不存在时返回空
+func readAuditLog(path string) ([]auditEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []auditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var entry auditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errorf("%s line %d: invalid entry: %v", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// verifyAuditChain 校验哈希链，返回第一条被篡改的记录序号（从1开始）
+func verifyAuditChain(entries []auditEntry) (int, error) {
+	prevHash := ""
+	for i, entry := range entries {
+		if entry.PrevHash != prevHash {
+			return i + 1, errorf("entry %d does not link to the previous entry, entries were removed or reordered", i+1)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return i + 1, err
+		}
+		if hash != entry.Hash {
+			return i + 1, errorf("entry %d was modified, hash mismatch", i+1)
+		}
+		prevHash = entry.Hash
+	}
+	return 0, nil
+}
+
+// verifyAuditSignatures 配置了ledger.signing.allowed_signers时校验审计记录的签名
+func verifyAuditSignatures(entries []auditEntry) error {
+	config, err := loadDefaultConfig()
+	if err != nil || config.Ledger == nil || config.Ledger.Signing == nil || config.Ledger.Signing.AllowedSigners == "" {
+		return nil
+	}
+	signed := 0
+	for i, entry := range entries {
+		result, err := config.Ledger.Signing.verify(entry.Signer, entry.Signature, []byte(entry.Hash))
+		if err != nil {
+			return err
+		}
+		switch result {
+		case signatureInvalid:
+			return errorf("entry %d has an invalid signature for %s", i+1, entry.Signer)
+		case signatureValid:
+			signed++
+		}
+	}
+	fmt.Print(msg("%d of %d entries have valid signatures\n", signed, len(entries)))
+	return nil
+}
+
+// runAuditCommand deploy audit子命令：查看本地审计日志，deploy audit verify校验是否被篡改
+func runAuditCommand(args []string) error {
+	verify := len(args) > 0 && args[0] == "verify"
+	if verify {
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet("audit", flag.ExitOnError)
+	limit := flags.Int("limit", 20, "maximum number of entries to show")
+	flags.Parse(args)
+
+	path, err := auditLogPath()
+	if err != nil {
+		return err
+	}
+	entries, err := readAuditLog(path)
+	if err != nil {
+		return err
+	}
+
+	if verify {
+		if _, err := verifyAuditChain(entries); err != nil {
+			return errorf("audit log %s failed verification: %v", path, err)
+		}
+		fmt.Print(msg("Audit log %s is intact (%d entries)\n", path, len(entries)))
+		return verifyAuditSignatures(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println(tr("No audit entries found"))
+		return nil
+	}
+	if *limit > 0 && len(entries) > *limit {
+		entries = entries[len(entries)-*limit:]
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TIME\tUSER\tACTION\tPROJECT\tENV\tRESULT\tERROR")
+	for _, entry := range entries {
+		fmt.Fprintf(writer, "%s\t%s@%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTime(entry.Time), entry.User, entry.Hostname, entry.Action,
+			entry.Project, entry.Env, strings.ToUpper(entry.Result), entry.Error)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bndr/gojenkins"
+)
+
+// 出现认证失败（HTTP 401）的服务
+const (
+	authServiceJenkins    = "jenkins"
+	authServiceKubernetes = "kubernetes"
+)
+
+// authFailures 本次运行中返回过401的服务，失败时据此给出重新登录的提示，而不是笼统的错误
+var authFailures sync.Map
+
+// authWatchTransport 记录返回401的响应，凭证过期或被吊销时服务端返回401
+type authWatchTransport struct {
+	service string
+	base    http.RoundTripper
+}
+
+func (t authWatchTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if reached, ok := request.Context().Value(transportReachedKey{}).(*atomic.Bool); ok {
+		reached.Store(true)
+	}
+	response, err := t.base.RoundTrip(request)
+	if err == nil && response.StatusCode == http.StatusUnauthorized {
+		authFailures.Store(t.service, request.URL.Host)
+	}
+	return response, err
+}
+
+// jenkinsHTTPClient 调用Jenkins使用的HTTP客户端，记录认证失败，单个请求超过http.jenkins_timeout时失败
+func jenkinsHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: authWatchTransport{service: authServiceJenkins, base: http.DefaultTransport},
+		Timeout:   jenkinsTimeout,
+	}
+}
+
+// wrapK8sAuthWatch 包装Kubernetes客户端的transport，记录认证失败
+func wrapK8sAuthWatch(base http.RoundTripper) http.RoundTripper {
+	return authWatchTransport{service: authServiceKubernetes, base: base}
+}
+
+// errCredentialPlugin kubeconfig的exec凭证插件没有返回凭证，请求没有发送到API server
+var errCredentialPlugin = errors.New("kubeconfig credential plugin failed")
+
+// transportReachedKey 请求context中记录请求是否到达wrapK8sAuthWatch的key
+type transportReachedKey struct{}
+
+// withCredentialPluginCheck 返回用于Kubernetes请求的context和错误转换函数：client-go的exec凭证插件包装在
+// wrapK8sAuthWatch之外，插件失败时请求不会到达它，此时返回的错误包装errCredentialPlugin
+func withCredentialPluginCheck(ctx context.Context) (context.Context, func(error) error) {
+	reached := new(atomic.Bool)
+	return context.WithValue(ctx, transportReachedKey{}, reached), func(err error) error {
+		if err == nil || reached.Load() {
+			return err
+		}
+		return errorf("%w: %w", errCredentialPlugin, err)
+	}
+}
+
+// authFailed 该服务是否返回过401
+func authFailed(service string) bool {
+	_, ok := authFailures.Load(service)
+	return ok
+}
+
+// authFailureHints 针对返回过401的服务，提示如何重新认证
+func authFailureHints() []string {
+	var hints []string
+	if host, ok := authFailures.Load(authServiceJenkins); ok {
+		hints = append(hints, msg("Jenkins (%s) rejected the credentials (HTTP 401): the API token has probably expired or been revoked, run `deploy login` to store a new one", host))
+	}
+	if host, ok := authFailures.Load(authServiceKubernetes); ok {
+		hints = append(hints, msg("Kubernetes API server (%s) rejected the credentials (HTTP 401): refresh your kubeconfig credentials (e.g. `aws sso login`, `gcloud auth login`, `az login`, or download a new kubeconfig)", host))
+	}
+	return hints
+}
+
+// reauthJenkins Jenkins拒绝了钥匙串中的凭证时，在终端中询问是否立即重新登录，登录成功后返回新的连接
+// 凭证来自配置文件或插件时重新登录不会生效，不询问
+func reauthJenkins(ctx context.Context, config *Config) (*gojenkins.Jenkins, bool) {
+	if !authFailed(authServiceJenkins) || config.APIToken != "" {
+		return nil, false
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return nil, false
+	}
+	fmt.Print(tr("Jenkins rejected the saved credentials, they may have expired. Run deploy login now? [y/N] "))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+		return nil, false
+	}
+	if err := runLoginCommand(nil); err != nil {
+		fmt.Fprintln(os.Stderr, tr("Error:"), localize(err))
+		return nil, false
+	}
+	username, token, err := jenkinsCredentials(ctx, config)
+	if err != nil {
+		return nil, false
+	}
+	authFailures.Delete(authServiceJenkins)
+	jenkins := gojenkins.CreateJenkins(jenkinsHTTPClient(), config.JenkinsURL, username, token)
+	if _, err := jenkins.Init(ctx); err != nil {
+		return nil, false
+	}
+	return jenkins, true
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// deploymentBaseline 构建前记录的部署状态：用于识别新pod的revision和旧pod、汇总中的旧镜像以及配置哈希
+type deploymentBaseline struct {
+	Revision string
+	PodUIDs  map[string]bool
+	State    *deploymentState
+	Config   *configSnapshot // 只在配置了config_check时记录
+}
+
+// collectBaseline 同时获取部署及其pod、config_check中的每个ConfigMap和Secret，部署只获取一次，
+// revision、旧pod、汇总用的镜像和配置注解都由它得出，任一目标失败时取消其余请求并返回第一个错误
+func collectBaseline(ctx context.Context, k8s K8sConfig, target, configPath string) (*deploymentBaseline, error) {
+	startTime := time.Now()
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		baseline   deploymentBaseline
+		annotation string // 配置校验和注解，来自k8s.deployment，与监控的目标（如蓝绿的某个颜色）不同时单独获取
+	)
+	group, ctx := errgroup.WithContext(ctx)
+	group.Go(func() error {
+		deployment, err := getDeployment(ctx, clientset, k8s.Namespace, target)
+		if err != nil {
+			return errorf("Failed to get current deployment status: %v", errorf("failed to get deployment: %v", err))
+		}
+		baseline.Revision = getDeploymentRevision(deployment)
+		if baseline.Revision == "" {
+			return errorf("Failed to get current deployment status: %v", errorf("unable to determine deployment revision"))
+		}
+		podList, err := getDeploymentPods(ctx, clientset, k8s.Namespace, deployment)
+		if err != nil {
+			return errorf("Failed to get current deployment status: %v", errorf("failed to get initial pods: %v", err))
+		}
+		baseline.PodUIDs = podUIDs(podList)
+		baseline.State = newDeploymentState(deployment, podList)
+		if k8s.ConfigCheck != nil && k8s.Deployment == target {
+			annotation = deployment.Spec.Template.Annotations[k8s.ConfigCheck.annotation()]
+		}
+		return nil
+	})
+
+	// 仅发布配置的环境，构建前记录每个配置对象的内容哈希
+	var (
+		mu     sync.Mutex
+		hashes = make(map[string]string)
+	)
+	if k8s.ConfigCheck != nil {
+		if k8s.Deployment != target {
+			group.Go(func() error {
+				deployment, err := getDeployment(ctx, clientset, k8s.Namespace, k8s.Deployment)
+				if err != nil {
+					return errorf("Failed to snapshot config: %v", errorf("failed to get deployment: %v", err))
+				}
+				annotation = deployment.Spec.Template.Annotations[k8s.ConfigCheck.annotation()]
+				return nil
+			})
+		}
+		for _, name := range k8s.ConfigCheck.ConfigMaps {
+			group.Go(func() error {
+				hash, err := configMapHash(ctx, clientset, k8s.Namespace, name)
+				if err != nil {
+					return errorf("Failed to snapshot config: %v", err)
+				}
+				mu.Lock()
+				hashes["configmap/"+name] = hash
+				mu.Unlock()
+				return nil
+			})
+		}
+		for _, name := range k8s.ConfigCheck.Secrets {
+			group.Go(func() error {
+				hash, err := secretHash(ctx, clientset, k8s.Namespace, name)
+				if err != nil {
+					return errorf("Failed to snapshot config: %v", err)
+				}
+				mu.Lock()
+				hashes["secret/"+name] = hash
+				mu.Unlock()
+				return nil
+			})
+		}
+	}
+	if err := group.Wait(); err != nil {
+		return nil, err
+	}
+	if k8s.ConfigCheck != nil {
+		baseline.Config = &configSnapshot{Hashes: hashes, Annotation: annotation}
+	}
+	slog.Debug(fmt.Sprintf("Collected the baseline state of %s/%s in %v", k8s.Namespace, target, time.Since(startTime).Round(time.Millisecond)))
+	return &baseline, nil
+}
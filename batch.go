@@ -0,0 +1,332 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// batchManifest deploy batch的清单文件
+type batchManifest struct {
+	Concurrency int          `yaml:"concurrency,omitempty"` // 同时部署的数量，默认1
+	Dir         string       `yaml:"dir,omitempty"`         // 项目目录的根目录，每个项目默认在<dir>/<项目名>下部署，相对路径相对于清单文件，默认清单文件所在目录
+	Deploys     []batchEntry `yaml:"deploys"`
+}
+
+// batchEntry 清单中的一次部署
+type batchEntry struct {
+	Name      string   `yaml:"name,omitempty"` // 用于depends_on引用，默认<项目>/<环境>
+	Project   string   `yaml:"project"`
+	Env       string   `yaml:"env"`
+	Dir       string   `yaml:"dir,omitempty"`        // 项目目录，默认<dir>/<项目名>
+	DependsOn []string `yaml:"depends_on,omitempty"` // 这些部署成功后才开始，任一失败则跳过
+}
+
+// 批量部署中各项的状态
+const (
+	batchPending = "pending"
+	batchRunning = "running"
+	batchSkipped = "skipped"
+)
+
+// batchResult 一项部署的结果
+type batchResult struct {
+	Name       string    `json:"name"`
+	Project    string    `json:"project"`
+	Env        string    `json:"env"`
+	Result     string    `json:"result"` // success、failure、skipped
+	ExitCode   int       `json:"exit_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	LogFile    string    `json:"log_file,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// batchProgressInterval 打印整体进度的间隔
+const batchProgressInterval = 30 * time.Second
+
+// runBatchCommand deploy batch子命令：按清单并发部署多个项目环境，按依赖排序，输出汇总报告
+func runBatchCommand(args []string) error {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	manifestPath := flags.String("f", "", "path to the batch manifest (required)")
+	concurrency := flags.Int("concurrency", 0, "maximum number of concurrent deploys, overrides the manifest")
+	output := flags.String("output", "text", "format of the final report: text or json")
+	flags.Parse(args)
+	if *manifestPath == "" {
+		return errorf("usage: deploy batch -f manifest.yaml [--concurrency N] [--output json]")
+	}
+
+	manifest, entries, err := loadBatchManifest(*manifestPath)
+	if err != nil {
+		return err
+	}
+	if *concurrency > 0 {
+		manifest.Concurrency = *concurrency
+	}
+	if manifest.Concurrency <= 0 {
+		manifest.Concurrency = 1
+	}
+
+	executable, logDir, err := prepareBatch()
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(msg("Deploying %d entries with concurrency %d, logs in %s\n", len(entries), manifest.Concurrency, logDir))
+	results := runBatch(entries, manifest.Concurrency, executable, logDir)
+
+	failed := 0
+	for _, result := range results {
+		if result.Result != stageSuccess {
+			failed++
+		}
+	}
+	if *output == "json" {
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printBatchReport(results)
+	}
+	if failed > 0 {
+		return errorf("%d of %d deploys did not succeed", failed, len(results))
+	}
+	return nil
+}
+
+// prepareBatch 返回执行部署的程序路径，并创建本次批量部署的日志目录
+func prepareBatch() (string, string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", "", errorf("failed to locate deploy executable: %v", err)
+	}
+	logDir, err := expandHomePath(filepath.Join("~/.deploy/batch", time.Now().Format("20060102-150405")))
+	if err != nil {
+		return "", "", err
+	}
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return "", "", errorf("failed to create log dir: %v", err)
+	}
+	return executable, logDir, nil
+}
+
+// loadBatchManifest 读取清单，补全默认值，校验依赖并按拓扑顺序返回
+func loadBatchManifest(path string) (*batchManifest, []batchEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, errorf("failed to read manifest: %v", err)
+	}
+	var manifest batchManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, errorf("failed to parse manifest: %v", err)
+	}
+	if len(manifest.Deploys) == 0 {
+		return nil, nil, errorf("manifest %s has no deploys", path)
+	}
+
+	baseDir := filepath.Dir(path)
+	if manifest.Dir != "" {
+		baseDir, err = expandHomePath(manifest.Dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !filepath.IsAbs(baseDir) {
+			baseDir = filepath.Join(filepath.Dir(path), baseDir)
+		}
+	}
+
+	byName := make(map[string]*batchEntry)
+	for i := range manifest.Deploys {
+		entry := &manifest.Deploys[i]
+		if entry.Project == "" || entry.Env == "" {
+			return nil, nil, errorf("deploy %d: project and env are required", i+1)
+		}
+		if entry.Name == "" {
+			entry.Name = entry.Project + "/" + entry.Env
+		}
+		if byName[entry.Name] != nil {
+			return nil, nil, errorf("duplicate deploy name %q, set a unique name", entry.Name)
+		}
+		if entry.Dir == "" {
+			entry.Dir = filepath.Join(baseDir, entry.Project)
+		} else if !filepath.IsAbs(entry.Dir) {
+			entry.Dir = filepath.Join(filepath.Dir(path), entry.Dir)
+		}
+		byName[entry.Name] = entry
+	}
+
+	// 深度优先排序，同时检查未知依赖和循环依赖
+	var ordered []batchEntry
+	state := make(map[string]int) // 1访问中，2已完成
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		entry := byName[name]
+		if entry == nil {
+			return errorf("%s depends on unknown deploy %q", chain[len(chain)-1], name)
+		}
+		switch state[name] {
+		case 1:
+			return errorf("dependency cycle: %s -> %s", strings.Join(chain, " -> "), name)
+		case 2:
+			return nil
+		}
+		state[name] = 1
+		for _, dep := range entry.DependsOn {
+			if err := visit(dep, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		ordered = append(ordered, *entry)
+		return nil
+	}
+	for _, entry := range manifest.Deploys {
+		if err := visit(entry.Name, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return &manifest, ordered, nil
+}
+
+// runBatch 按依赖和并发限制执行部署，entries需要已按拓扑排序
+func runBatch(entries []batchEntry, concurrency int, executable, logDir string) []batchResult {
+	results := make(map[string]*batchResult)
+	for _, entry := range entries {
+		results[entry.Name] = &batchResult{Name: entry.Name, Project: entry.Project, Env: entry.Env, Result: batchPending}
+	}
+
+	done := make(chan batchResult)
+	ticker := time.NewTicker(batchProgressInterval)
+	defer ticker.Stop()
+	running := 0
+	for {
+		for _, entry := range entries {
+			result := results[entry.Name]
+			if result.Result != batchPending {
+				continue
+			}
+			ready := true
+			for _, dep := range entry.DependsOn {
+				switch results[dep].Result {
+				case stageSuccess:
+				case stageFailure, batchSkipped:
+					result.Result = batchSkipped
+					result.Error = fmt.Sprintf("dependency %s did not succeed", dep)
+					slog.Warn(msg("[%s] skipped: %s", entry.Name, result.Error))
+				default:
+					ready = false
+				}
+			}
+			if result.Result != batchPending || !ready || running >= concurrency {
+				continue
+			}
+			result.Result = batchRunning
+			result.StartedAt = time.Now()
+			result.LogFile = filepath.Join(logDir, strings.ReplaceAll(entry.Name, "/", "_")+".log")
+			running++
+			slog.Info(msg("[%s] started", entry.Name))
+			go func(entry batchEntry, result batchResult) {
+				done <- runBatchEntry(entry, result, executable)
+			}(entry, *result)
+		}
+		if running == 0 {
+			break
+		}
+
+		select {
+		case result := <-done:
+			running--
+			*results[result.Name] = result
+			duration := result.FinishedAt.Sub(result.StartedAt).Round(time.Second)
+			if result.Result == stageSuccess {
+				slog.Info(msg("[%s] succeeded in %v", result.Name, duration))
+			} else {
+				slog.Error(msg("[%s] failed after %v: %s (see %s)", result.Name, duration, result.Error, result.LogFile))
+			}
+		case <-ticker.C:
+			printBatchProgress(entries, results)
+		}
+	}
+
+	var ordered []batchResult
+	for _, entry := range entries {
+		ordered = append(ordered, *results[entry.Name])
+	}
+	return ordered
+}
+
+// runBatchEntry 在项目目录以子进程执行一次部署，输出写入日志文件
+func runBatchEntry(entry batchEntry, result batchResult, executable string) batchResult {
+	fail := func(err error) batchResult {
+		result.Result, result.Error, result.FinishedAt = stageFailure, err.Error(), time.Now()
+		return result
+	}
+
+	logFile, err := os.Create(result.LogFile)
+	if err != nil {
+		return fail(errorf("failed to create log file: %v", err))
+	}
+	defer logFile.Close()
+
+	if projectNameOfDir(entry.Dir) != entry.Project {
+		return fail(errorf("project dir %s is not recognized as %s, it must be named %s or match the project's path", entry.Dir, entry.Project, entry.Project))
+	}
+	cmd := exec.Command(executable, entry.Env, "--no-desktop-notify")
+	cmd.Dir = entry.Dir
+	cmd.Stdout, cmd.Stderr = logFile, logFile
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			result.ExitCode = exitErr.ExitCode()
+			return fail(errorf("exit code %d", result.ExitCode))
+		}
+		return fail(err)
+	}
+	result.Result, result.FinishedAt = stageSuccess, time.Now()
+	return result
+}
+
+// printBatchProgress 打印整体进度
+func printBatchProgress(entries []batchEntry, results map[string]*batchResult) {
+	counts := make(map[string]int)
+	var running []string
+	for _, entry := range entries {
+		result := results[entry.Name]
+		counts[result.Result]++
+		if result.Result == batchRunning {
+			running = append(running, fmt.Sprintf("%s %v", entry.Name, time.Since(result.StartedAt).Round(time.Second)))
+		}
+	}
+	slog.Info(msg("Progress: %d succeeded, %d failed, %d skipped, %d pending, %d running (%s)",
+		counts[stageSuccess], counts[stageFailure], counts[batchSkipped], counts[batchPending], counts[batchRunning], strings.Join(running, ", ")))
+}
+
+// printBatchReport 输出汇总报告
+func printBatchReport(results []batchResult) {
+	fmt.Println()
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tPROJECT\tENV\tRESULT\tDURATION\tDETAILS")
+	for _, result := range results {
+		duration := "-"
+		if !result.StartedAt.IsZero() {
+			duration = result.FinishedAt.Sub(result.StartedAt).Round(time.Second).String()
+		}
+		details := result.Error
+		if result.Result == stageFailure {
+			details += ", log: " + result.LogFile
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n", result.Name, result.Project, result.Env,
+			strings.ToUpper(result.Result), duration, details)
+	}
+	writer.Flush()
+}
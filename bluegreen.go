@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// BlueGreenConfig 蓝绿部署配置：两个部署通过Service选择器切换流量
+type BlueGreenConfig struct {
+	Service       string `yaml:"service"`
+	SelectorLabel string `yaml:"selector_label,omitempty"` // Service选择器中区分颜色的标签，默认color
+	Blue          string `yaml:"blue"`                     // blue颜色对应的部署名称
+	Green         string `yaml:"green"`                    // green颜色对应的部署名称
+}
+
+// blueGreenTarget 本次蓝绿部署的目标
+type blueGreenTarget struct {
+	ActiveColor      string
+	ActiveDeployment string
+	IdleColor        string
+	IdleDeployment   string
+}
+
+func (c BlueGreenConfig) selectorLabel() string {
+	if c.SelectorLabel == "" {
+		return "color"
+	}
+	return c.SelectorLabel
+}
+
+// resolveBlueGreenTarget 根据Service当前选择器确定正在接收流量的颜色和空闲颜色
+func resolveBlueGreenTarget(ctx context.Context, namespace string, blueGreen BlueGreenConfig, configPath string) (*blueGreenTarget, error) {
+	if blueGreen.Service == "" || blueGreen.Blue == "" || blueGreen.Green == "" {
+		return nil, errorf("blue/green configuration incomplete: service=%s, blue=%s, green=%s",
+			blueGreen.Service, blueGreen.Blue, blueGreen.Green)
+	}
+
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	service, err := clientset.CoreV1().Services(namespace).Get(ctx, blueGreen.Service, metav1.GetOptions{})
+	if err != nil {
+		return nil, errorf("failed to get service %s: %v", blueGreen.Service, err)
+	}
+
+	activeColor := service.Spec.Selector[blueGreen.selectorLabel()]
+	switch activeColor {
+	case "blue":
+		return &blueGreenTarget{
+			ActiveColor: "blue", ActiveDeployment: blueGreen.Blue,
+			IdleColor: "green", IdleDeployment: blueGreen.Green,
+		}, nil
+	case "green":
+		return &blueGreenTarget{
+			ActiveColor: "green", ActiveDeployment: blueGreen.Green,
+			IdleColor: "blue", IdleDeployment: blueGreen.Blue,
+		}, nil
+	}
+	return nil, errorf("service %s selector label %s=%q is neither blue nor green",
+		blueGreen.Service, blueGreen.selectorLabel(), activeColor)
+}
+
+// switchBlueGreenTraffic 修改Service选择器，将流量切换到指定颜色
+func switchBlueGreenTraffic(ctx context.Context, namespace string, blueGreen BlueGreenConfig, configPath, color string) error {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"selector": map[string]string{blueGreen.selectorLabel(): color},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.CoreV1().Services(namespace).Patch(ctx, blueGreen.Service, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errorf("failed to patch service %s selector: %v", blueGreen.Service, err)
+	}
+
+	slog.Info(msg("Service %s now routes traffic to %s", blueGreen.Service, color))
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// BuildLogsConfig 每次部署的Jenkins构建日志保存在~/.deploy/logs/<项目>/<环境>/<构建号>.log，终端滚动缓冲区清空后仍可以查看
+type BuildLogsConfig struct {
+	Disabled bool   `yaml:"disabled,omitempty"` // 不保存构建日志
+	Keep     int    `yaml:"keep,omitempty"`     // 每个环境保留的日志数量，超出时删除最旧的，默认50
+	MaxAge   string `yaml:"max_age,omitempty"`  // 删除超过该时长的日志，如720h，默认不按时间删除
+}
+
+// defaultBuildLogKeep 每个环境默认保留的构建日志数量
+const defaultBuildLogKeep = 50
+
+// buildLogRetention 构建日志的保存和保留设置，由配置文件中的build_logs设置
+var buildLogRetention = struct {
+	disabled bool
+	keep     int
+	maxAge   time.Duration
+}{keep: defaultBuildLogKeep}
+
+// setBuildLogRetention 按配置设置构建日志的保留数量和时长
+func setBuildLogRetention(config *BuildLogsConfig) error {
+	if config == nil {
+		return nil
+	}
+	buildLogRetention.disabled = config.Disabled
+	if config.Keep > 0 {
+		buildLogRetention.keep = config.Keep
+	}
+	if config.MaxAge != "" {
+		maxAge, err := time.ParseDuration(config.MaxAge)
+		if err != nil || maxAge <= 0 {
+			return errorf("invalid build_logs.max_age %q, expected a duration such as 720h", config.MaxAge)
+		}
+		buildLogRetention.maxAge = maxAge
+	}
+	return nil
+}
+
+// buildLogDir 项目环境的构建日志目录
+func buildLogDir(project, env string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", "logs", project, env), nil
+}
+
+// saveBuildLog 保存构建的完整控制台输出（屏蔽密钥后），记录到汇总中并按保留设置清理旧日志，失败只警告
+func saveBuildLog(ctx context.Context, build *gojenkins.Build, output string, summary *deploySummary) {
+	if buildLogRetention.disabled || summary == nil || summary.Project == "" {
+		return
+	}
+	if output == "" {
+		output = build.GetConsoleOutput(context.WithoutCancel(ctx))
+	}
+	dir, err := buildLogDir(summary.Project, summary.Env)
+	if err == nil {
+		err = os.MkdirAll(dir, 0700)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%d.log", build.GetBuildNumber()))
+	if err == nil {
+		err = os.WriteFile(path, []byte(maskSecrets(output)), 0600)
+	}
+	if err != nil {
+		slog.Warn(msg("failed to save the log of build #%d: %v", build.GetBuildNumber(), err))
+		return
+	}
+	summary.BuildLog = path
+	slog.Info(msg("Log of build #%d saved to %s", build.GetBuildNumber(), path))
+	pruneBuildLogs(dir)
+}
+
+// pruneBuildLogs 删除超过保留数量或时长的构建日志
+func pruneBuildLogs(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		return
+	}
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var logs []logFile
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			logs = append(logs, logFile{file, info.ModTime()})
+		}
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].modTime.After(logs[j].modTime) })
+	for i, log := range logs {
+		expired := buildLogRetention.maxAge > 0 && time.Since(log.modTime) > buildLogRetention.maxAge
+		if i < buildLogRetention.keep && !expired {
+			continue
+		}
+		if err := os.Remove(log.path); err != nil {
+			slog.Debug(fmt.Sprintf("failed to remove old build log %s: %v", log.path, err))
+		}
+	}
+}
+
+// buildLogPath 部署记录对应的本机构建日志路径，日志已被清理或在其他机器上部署时返回-
+func buildLogPath(record deployRecord) string {
+	path := record.BuildLog
+	if path == "" && record.BuildNumber > 0 {
+		if dir, err := buildLogDir(record.Project, record.Env); err == nil {
+			path = filepath.Join(dir, fmt.Sprintf("%d.log", record.BuildNumber))
+		}
+	}
+	if path == "" {
+		return "-"
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "-"
+	}
+	return path
+}
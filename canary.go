@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CanaryConfig 金丝雀发布配置：构建先发布到金丝雀部署，观察通过后再推广到正式部署
+type CanaryConfig struct {
+	Deployment  string           `yaml:"deployment"`             // 金丝雀部署名称，与正式部署共用Service选择器
+	Percent     int              `yaml:"percent,omitempty"`      // 金丝雀副本数占正式部署副本数的百分比，默认10
+	BakeTime    string           `yaml:"bake_time,omitempty"`    // 观察时长，默认5m
+	MaxRestarts int32            `yaml:"max_restarts,omitempty"` // 观察期间允许的容器重启次数
+	Prometheus  *PrometheusCheck `yaml:"prometheus,omitempty"`   // 可选的Prometheus错误指标检查
+}
+
+// PrometheusCheck 观察期间执行的Prometheus查询，结果超过阈值视为失败
+type PrometheusCheck struct {
+	URL       string  `yaml:"url"`
+	Query     string  `yaml:"query"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+// runCanaryRollout 扩容金丝雀部署并观察，通过后将镜像推广到正式部署，失败时将金丝雀缩容为0
+func runCanaryRollout(ctx context.Context, k8s K8sConfig, configPath string, initialRevision string, initialPodUIDs map[string]bool, summary *deploySummary) error {
+	canary := k8s.Canary
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	stable, err := getDeployment(ctx, clientset, k8s.Namespace, k8s.Deployment)
+	if err != nil {
+		return errorf("failed to get deployment: %v", err)
+	}
+	stableReplicas := 1
+	if stable.Spec.Replicas != nil {
+		stableReplicas = int(*stable.Spec.Replicas)
+	}
+
+	percent := canary.Percent
+	if percent <= 0 {
+		percent = 10
+	}
+	canaryReplicas := int(math.Ceil(float64(stableReplicas) * float64(percent) / 100))
+	if canaryReplicas < 1 {
+		canaryReplicas = 1
+	}
+
+	slog.Info(msg("Canary: scaling %s to %d replicas (%d%% of %d)", canary.Deployment, canaryReplicas, percent, stableReplicas))
+	if err := scaleDeployment(ctx, clientset, k8s.Namespace, canary.Deployment, canaryReplicas); err != nil {
+		return err
+	}
+
+	// 监控金丝雀部署的滚动
+	canaryK8s := k8s
+	canaryK8s.Deployment = canary.Deployment
+	canaryK8s.ZeroReplicas = "wait"
+	if err := monitorPodRollout(ctx, canaryK8s, configPath, initialRevision, initialPodUIDs, summary); err != nil {
+		rollbackCanary(ctx, clientset, k8s.Namespace, canary.Deployment)
+		return errorf("canary rollout failed: %w", err)
+	}
+
+	bakeStart := time.Now()
+	if err := bakeCanary(ctx, clientset, k8s.Namespace, canary); err != nil {
+		rollbackCanary(ctx, clientset, k8s.Namespace, canary.Deployment)
+		return errorf("canary bake failed: %w", err)
+	}
+	summary.addPhase("canary bake", bakeStart)
+
+	// 推广：将金丝雀的镜像应用到正式部署
+	stableRevision, stablePodUIDs, err := getCurrentDeploymentStatus(ctx, k8s.Namespace, k8s.Deployment, configPath)
+	if err != nil {
+		return errorf("failed to get deployment status before promotion: %v", err)
+	}
+	if err := promoteCanaryImages(ctx, clientset, k8s.Namespace, canary.Deployment, k8s.Deployment); err != nil {
+		rollbackCanary(ctx, clientset, k8s.Namespace, canary.Deployment)
+		return err
+	}
+	if err := monitorPodRollout(ctx, k8s, configPath, stableRevision, stablePodUIDs, summary); err != nil {
+		return errorf("full rollout after canary failed: %w", err)
+	}
+
+	// 正式部署完成后回收金丝雀
+	slog.Info(msg("Canary: full rollout completed, scaling %s back to 0", canary.Deployment))
+	return scaleDeployment(ctx, clientset, k8s.Namespace, canary.Deployment, 0)
+}
+
+// bakeCanary 在观察期内定期检查金丝雀pod的重启次数和Prometheus指标
+func bakeCanary(ctx context.Context, clientset *kubernetes.Clientset, namespace string, canary *CanaryConfig) error {
+	bakeTime := 5 * time.Minute
+	if canary.BakeTime != "" {
+		var err error
+		bakeTime, err = time.ParseDuration(canary.BakeTime)
+		if err != nil {
+			return errorf("invalid canary bake_time %q: %v", canary.BakeTime, err)
+		}
+	}
+
+	slog.Info(msg("Canary: baking for %v (max restarts=%d)", bakeTime, canary.MaxRestarts))
+
+	deadline := time.Now().Add(bakeTime)
+	for time.Now().Before(deadline) {
+		if err := sleepContext(ctx, 15*time.Second); err != nil {
+			return err
+		}
+
+		deployment, err := getDeployment(ctx, clientset, namespace, canary.Deployment)
+		if err != nil {
+			return errorf("failed to get canary deployment: %v", err)
+		}
+		podList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
+		if err != nil {
+			return errorf("failed to get canary pods: %v", err)
+		}
+
+		var restarts int32
+		for _, pod := range podList.Items {
+			for _, containerStatus := range pod.Status.ContainerStatuses {
+				restarts += containerStatus.RestartCount
+			}
+		}
+		if restarts > canary.MaxRestarts {
+			return errorf("canary pods restarted %d times, more than the allowed %d", restarts, canary.MaxRestarts)
+		}
+
+		if canary.Prometheus != nil {
+			value, err := queryPrometheus(ctx, canary.Prometheus.URL, canary.Prometheus.Query)
+			if err != nil {
+				return errorf("prometheus query failed: %v", err)
+			}
+			if value > canary.Prometheus.Threshold {
+				return errorf("prometheus query returned %v, above threshold %v", value, canary.Prometheus.Threshold)
+			}
+		}
+
+		slog.Info(msg("Canary: healthy, %d restarts, %v remaining", restarts, time.Until(deadline).Round(time.Second)))
+	}
+	return nil
+}
+
+// promoteCanaryImages 将金丝雀部署中各容器的镜像同步到正式部署
+func promoteCanaryImages(ctx context.Context, clientset *kubernetes.Clientset, namespace, canaryName, stableName string) error {
+	canary, err := getDeployment(ctx, clientset, namespace, canaryName)
+	if err != nil {
+		return errorf("failed to get canary deployment: %v", err)
+	}
+
+	var containers []map[string]string
+	for _, container := range canary.Spec.Template.Spec.Containers {
+		containers = append(containers, map[string]string{"name": container.Name, "image": container.Image})
+		slog.Info(msg("Canary: promoting container %s image %s to %s", container.Name, container.Image, stableName))
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{"containers": containers},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, stableName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errorf("failed to promote canary images to %s: %v", stableName, err)
+	}
+	return nil
+}
+
+// rollbackCanary 将金丝雀部署缩容为0，正式部署保持不变
+func rollbackCanary(ctx context.Context, clientset *kubernetes.Clientset, namespace, canaryName string) {
+	// 部署被中断时同样回收金丝雀
+	ctx = context.WithoutCancel(ctx)
+	slog.Info(msg("Canary: rolling back, scaling %s to 0", canaryName))
+	if err := scaleDeployment(ctx, clientset, namespace, canaryName, 0); err != nil {
+		slog.Error(msg("Canary: rollback failed: %v", err))
+	}
+}
+
+// scaleDeployment 修改部署的副本数
+func scaleDeployment(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string, replicas int) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	_, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, name, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return errorf("failed to scale deployment %s to %d: %v", name, replicas, err)
+	}
+	return nil
+}
+
+// queryPrometheus 执行Prometheus即时查询，返回所有结果值之和
+func queryPrometheus(ctx context.Context, prometheusURL, query string) (float64, error) {
+	endpoint := prometheusURL + "/api/v1/query?query=" + url.QueryEscape(query)
+	ctx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Value []interface{} `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, errorf("failed to decode prometheus response: %v", err)
+	}
+	if result.Status != "success" {
+		return 0, errorf("prometheus returned %s: %s", result.Status, result.Error)
+	}
+
+	var sum float64
+	for _, sample := range result.Data.Result {
+		if len(sample.Value) != 2 {
+			continue
+		}
+		raw, ok := sample.Value[1].(string)
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, errorf("invalid prometheus sample value %q", raw)
+		}
+		sum += value
+	}
+	return sum, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// changelogDisplayLimit 终端和通知中最多显示的提交数
+const changelogDisplayLimit = 20
+
+// deployChangelog 上次成功部署到当前提交之间的提交，每行为"短sha 标题 (作者)"，path不为空时只统计该目录（monorepo中的项目）
+// 同时返回上次部署的提交，无法确定或本地仓库中没有该提交时为空
+func deployChangelog(ctx context.Context, config *Config, project, env, commit, path string) ([]string, string) {
+	if commit == "" {
+		return nil, ""
+	}
+	previous, err := latestSuccessfulDeploy(ctx, config, project, env)
+	if err != nil || previous == nil || previous.Commit == "" || previous.Commit == commit {
+		return nil, ""
+	}
+	// 上次部署的提交不在本地仓库中（如未fetch）时无法生成
+	if err := exec.Command("git", "cat-file", "-e", previous.Commit+"^{commit}").Run(); err != nil {
+		slog.Debug(fmt.Sprintf("Previously deployed commit %s not found locally, skipping changelog", shortCommit(previous.Commit)))
+		return nil, ""
+	}
+	args := []string{"log", "--no-merges", "--format=%h %s (%an)", previous.Commit + ".." + commit}
+	if path != "" {
+		args = append(args, "--", ":(top)"+strings.Trim(path, "/"))
+	}
+	out := gitOutput(args...)
+	if out == "" {
+		return nil, previous.Commit
+	}
+	return strings.Split(out, "\n"), previous.Commit
+}
+
+// truncateChangelog 只保留前changelogDisplayLimit条，其余合并为一行说明
+func truncateChangelog(changelog []string) []string {
+	if len(changelog) <= changelogDisplayLimit {
+		return changelog
+	}
+	return append(changelog[:changelogDisplayLimit:changelogDisplayLimit], fmt.Sprintf("... and %d more", len(changelog)-changelogDisplayLimit))
+}
+
+// printChangelog 部署前在终端输出变更列表
+func printChangelog(env string, changelog []string) {
+	if len(changelog) == 0 {
+		return
+	}
+	slog.Info(msg("%d commit(s) since the last deploy to %s:", len(changelog), env))
+	for _, line := range truncateChangelog(changelog) {
+		fmt.Fprintf(rawOutput, "  %s\n", line)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigCheck 仅发布配置的环境：校验ConfigMap/Secret内容发生变化，且部署已重启加载新配置
+type ConfigCheck struct {
+	ConfigMaps         []string `yaml:"configmaps,omitempty"`
+	Secrets            []string `yaml:"secrets,omitempty"`
+	ChecksumAnnotation string   `yaml:"checksum_annotation,omitempty"` // pod模板上记录配置校验和的注解，默认使用kubectl rollout restart的restartedAt注解
+}
+
+// configSnapshot 构建前记录的配置内容哈希和pod模板注解
+type configSnapshot struct {
+	Hashes     map[string]string
+	Annotation string
+}
+
+func (c ConfigCheck) annotation() string {
+	if c.ChecksumAnnotation == "" {
+		return "kubectl.kubernetes.io/restartedAt"
+	}
+	return c.ChecksumAnnotation
+}
+
+// takeConfigSnapshot 计算配置的内容哈希并记录部署当前的校验和注解
+func takeConfigSnapshot(ctx context.Context, k8s K8sConfig, configPath string) (*configSnapshot, error) {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &configSnapshot{Hashes: make(map[string]string)}
+	for _, name := range k8s.ConfigCheck.ConfigMaps {
+		if snapshot.Hashes["configmap/"+name], err = configMapHash(ctx, clientset, k8s.Namespace, name); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range k8s.ConfigCheck.Secrets {
+		if snapshot.Hashes["secret/"+name], err = secretHash(ctx, clientset, k8s.Namespace, name); err != nil {
+			return nil, err
+		}
+	}
+
+	deployment, err := getDeployment(ctx, clientset, k8s.Namespace, k8s.Deployment)
+	if err != nil {
+		return nil, errorf("failed to get deployment: %v", err)
+	}
+	snapshot.Annotation = deployment.Spec.Template.Annotations[k8s.ConfigCheck.annotation()]
+	return snapshot, nil
+}
+
+// configMapHash ConfigMap内容（包括binaryData）的哈希
+func configMapHash(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", errorf("failed to get configmap %s: %v", name, err)
+	}
+	data := make(map[string][]byte)
+	for key, value := range configMap.Data {
+		data[key] = []byte(value)
+	}
+	for key, value := range configMap.BinaryData {
+		data[key] = value
+	}
+	return hashConfigData(data), nil
+}
+
+// secretHash Secret内容的哈希
+func secretHash(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string) (string, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", errorf("failed to get secret %s: %v", name, err)
+	}
+	return hashConfigData(secret.Data), nil
+}
+
+// verifyConfigRollout 对比构建前后的配置哈希和注解，返回部署是否需要滚动
+func verifyConfigRollout(ctx context.Context, k8s K8sConfig, configPath string, before *configSnapshot) (bool, error) {
+	after, err := takeConfigSnapshot(ctx, k8s, configPath)
+	if err != nil {
+		return false, err
+	}
+
+	var changed []string
+	for name, hash := range after.Hashes {
+		if before.Hashes[name] != hash {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	annotationChanged := before.Annotation != after.Annotation
+
+	for _, name := range changed {
+		slog.Info(msg("Config %s changed (sha256 %s -> %s)", name, shortHash(before.Hashes[name]), shortHash(after.Hashes[name])))
+	}
+
+	switch {
+	case len(changed) > 0 && !annotationChanged:
+		return false, errorf("config changed but deployment %s was not restarted (annotation %s unchanged), pods are still running the old config",
+			k8s.Deployment, k8s.ConfigCheck.annotation())
+	case len(changed) == 0 && !annotationChanged:
+		slog.Info(tr("No ConfigMap/Secret content changed and deployment was not restarted, nothing to roll out"))
+		return false, nil
+	case len(changed) == 0:
+		slog.Warn(tr("deployment was restarted but no ConfigMap/Secret content changed"))
+	}
+
+	slog.Info(msg("Deployment picked up new %s: %s", k8s.ConfigCheck.annotation(), after.Annotation))
+	return true, nil
+}
+
+// hashConfigData 按key排序后计算配置内容的sha256
+func hashConfigData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		hash.Write([]byte(key))
+		hash.Write([]byte{0})
+		hash.Write(data[key])
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	if hash == "" {
+		return "none"
+	}
+	return hash
+}
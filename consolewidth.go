@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"strconv"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// 终端中长行的截断，--log-file中始终保留完整内容
+var (
+	consoleWidth = flag.Int("console-width", 0, "truncate build log lines in the terminal to this many columns, 0 to use the terminal width (no truncation when not a terminal)")
+	wideOutput   = flag.Bool("wide", false, "do not truncate long build log lines in the terminal")
+)
+
+// consoleLineWidth 终端中构建日志每行的最大宽度，0表示不截断：
+// 指定了--wide或输出JSON时不截断，--console-width优先，其次是终端宽度和COLUMNS环境变量，输出不是终端时不截断
+func consoleLineWidth() int {
+	if *wideOutput || *outputFormat == "json" || *logFormat == "json" {
+		return 0
+	}
+	if *consoleWidth > 0 {
+		return *consoleWidth
+	}
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	if width, _, err := term.GetSize(fd); err == nil && width > 0 {
+		return width
+	}
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && width > 0 {
+		return width
+	}
+	return 0
+}
+
+// truncatingWriter 将超过width的行截断并以…结尾，构建日志分多次写入时按行累计宽度
+type truncatingWriter struct {
+	w       io.Writer
+	width   int
+	column  int    // 当前行已写入的列数
+	last    []byte // 当前行第width列的字符，行在此结束时原样输出，否则替换为…
+	partial []byte // 上次写入末尾不完整的UTF-8字符
+}
+
+func (t *truncatingWriter) Write(p []byte) (int, error) {
+	data := append(t.partial, p...)
+	t.partial = nil
+	var out bytes.Buffer
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 && !utf8.FullRune(data) {
+			t.partial = append([]byte(nil), data...)
+			break
+		}
+		switch {
+		case r == '\n' || r == '\r':
+			out.Write(t.last)
+			out.WriteRune(r)
+			t.column, t.last = 0, nil
+		case t.column < t.width-1:
+			out.Write(data[:size])
+			t.column++
+		case t.column == t.width-1:
+			t.last = append([]byte(nil), data[:size]...)
+			t.column++
+		case t.column == t.width:
+			out.WriteString("…")
+			t.column, t.last = t.column+1, nil
+		}
+		data = data[size:]
+	}
+	if _, err := t.w.Write(out.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+)
+
+// credentialsParameterType Jenkins任务中Credentials参数的类型，值为凭据ID
+const credentialsParameterType = "CredentialsParameterDefinition"
+
+// jobCredentialParams 任务中定义为Credentials参数且本次构建提供了值的参数名
+func jobCredentialParams(job *gojenkins.Job, params map[string]string) []string {
+	var names []string
+	for _, property := range job.Raw.Property {
+		for _, definition := range property.ParameterDefinitions {
+			if definition.Type == credentialsParameterType && params[definition.Name] != "" {
+				names = append(names, definition.Name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkJenkinsCredential 检查凭据ID存在于任务可见的凭据存储中：任务所在的各级文件夹、系统和当前用户的全局域，没有查看权限时跳过检查
+func checkJenkinsCredential(ctx context.Context, jenkins *gojenkins.Jenkins, job *gojenkins.Job, name, id string) error {
+	credential := "/domain/_/credential/" + url.PathEscape(id)
+	var stores []string
+	parts := strings.Split(strings.Trim(job.Base, "/"), "/")
+	for i := 2; i < len(parts); i += 2 {
+		stores = append(stores, "/"+strings.Join(parts[:i], "/")+"/credentials/store/folder")
+	}
+	stores = append(stores, "/credentials/store/system")
+	if jenkins.Requester.BasicAuth != nil && jenkins.Requester.BasicAuth.Username != "" {
+		stores = append(stores, "/user/"+url.PathEscape(jenkins.Requester.BasicAuth.Username)+"/credentials/store/user")
+	}
+
+	checked := 0
+	for _, store := range stores {
+		var found struct {
+			ID string `json:"id"`
+		}
+		response, err := jenkins.Requester.GetJSON(ctx, store+credential, &found, nil)
+		if err != nil {
+			return errorf("failed to look up credential %s for param %s: %v", id, name, err)
+		}
+		switch {
+		case response.StatusCode == http.StatusOK:
+			slog.Debug(fmt.Sprintf("Credential %s for param %s found in %s", id, name, store))
+			return nil
+		case response.StatusCode == http.StatusForbidden || response.StatusCode == http.StatusUnauthorized:
+			continue
+		}
+		checked++
+	}
+	if checked == 0 {
+		slog.Warn(msg("Cannot verify credential %s for param %s: no permission to view the Jenkins credential stores", id, name))
+		return nil
+	}
+	return errorf("credential %q for param %s does not exist in Jenkins (checked the global domain of the job's folders, the system store and the user store)", id, name)
+}
+
+// invokeWithCredentials 以/build的json表单触发构建，Credentials参数按{name, value}提交而不是作为字符串参数，返回队列ID
+func invokeWithCredentials(ctx context.Context, job *gojenkins.Job, params map[string]string) (int64, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parameters := make([]map[string]string, 0, len(names))
+	for _, name := range names {
+		parameters = append(parameters, map[string]string{"name": name, "value": params[name]})
+	}
+	payload, err := json.Marshal(map[string]any{"parameter": parameters})
+	if err != nil {
+		return 0, err
+	}
+	return postJenkinsBuild(ctx, job, "/build", url.Values{"json": {string(payload)}})
+}
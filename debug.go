@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// offerDebugContainer 询问是否为崩溃的pod挂载临时调试容器，确认后创建并attach到该容器
+func offerDebugContainer(ctx context.Context, clientset *kubernetes.Clientset, k8s K8sConfig, configPath string, pods []*corev1.Pod) {
+	// 只有交互式终端才能attach
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		slog.Info(tr("--debug-on-failure requires an interactive terminal, skipping debug container"))
+		return
+	}
+
+	for _, pod := range pods {
+		target := getCrashingContainer(pod)
+		if target == "" {
+			continue
+		}
+
+		fmt.Print(msg("Attach an ephemeral debug container to pod %s (target container %s)? [y/N] ", pod.Name, target))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			continue
+		}
+
+		if err := attachDebugContainer(ctx, clientset, k8s, configPath, pod.Name, target); err != nil {
+			slog.Warn(msg("Failed to debug pod %s: %v", pod.Name, err))
+		}
+		return
+	}
+}
+
+// getCrashingContainer 返回pod中处于CrashLoopBackOff或被OOMKilled的容器名称
+func getCrashingContainer(pod *corev1.Pod) string {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == "CrashLoopBackOff" {
+			return containerStatus.Name
+		}
+		if isContainerOOMKilled(containerStatus) {
+			return containerStatus.Name
+		}
+	}
+	return ""
+}
+
+// attachDebugContainer 创建共享目标容器进程空间的临时容器，运行后通过kubectl attach进入
+func attachDebugContainer(ctx context.Context, clientset *kubernetes.Clientset, k8s K8sConfig, configPath, podName, target string) error {
+	image := k8s.DebugImage
+	if image == "" {
+		image = "busybox:stable"
+	}
+
+	pod, err := clientset.CoreV1().Pods(k8s.Namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return errorf("failed to get pod: %v", err)
+	}
+
+	name := fmt.Sprintf("debugger-%d", time.Now().Unix()%100000)
+	pod.Spec.EphemeralContainers = append(pod.Spec.EphemeralContainers, corev1.EphemeralContainer{
+		EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+			Name:                     name,
+			Image:                    image,
+			Stdin:                    true,
+			TTY:                      true,
+			TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		},
+		TargetContainerName: target,
+	})
+	if _, err := clientset.CoreV1().Pods(k8s.Namespace).UpdateEphemeralContainers(ctx, podName, pod, metav1.UpdateOptions{}); err != nil {
+		return errorf("failed to add ephemeral container (requires Kubernetes 1.25+ and pods/ephemeralcontainers permission): %v", err)
+	}
+	slog.Info(msg("Started debug container %s (%s) in pod %s, waiting for it to run...", name, image, podName))
+
+	// 等待临时容器启动
+	running := false
+	for i := 0; i < 30 && !running; i++ {
+		time.Sleep(2 * time.Second)
+		pod, err = clientset.CoreV1().Pods(k8s.Namespace).Get(ctx, podName, metav1.GetOptions{})
+		if err != nil {
+			return errorf("failed to get pod: %v", err)
+		}
+		for _, status := range pod.Status.EphemeralContainerStatuses {
+			if status.Name == name && status.State.Running != nil {
+				running = true
+			}
+		}
+	}
+	if !running {
+		return errorf("debug container %s did not start within 60 seconds", name)
+	}
+
+	args := append([]string{"attach", "-it", podName, "-c", name, "-n", k8s.Namespace}, impersonationArgs()...)
+	if configPath != "" && configPath != inClusterConfigPath {
+		kubeconfig, err := expandHomePath(configPath)
+		if err != nil {
+			return err
+		}
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		slog.Warn(msg("kubectl not found in PATH, attach manually: kubectl %s", strings.Join(args, " ")))
+		return nil
+	}
+
+	cmd := exec.Command("kubectl", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// verifyTrafficReadiness 滚动完成后检查Service的EndpointSlice包含新pod，以及Ingress可以正常响应
+func verifyTrafficReadiness(ctx context.Context, k8s K8sConfig, configPath string, initialPodUIDs map[string]bool) error {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	if k8s.Service != "" {
+		if err := waitForServiceEndpoints(ctx, clientset, k8s.Namespace, k8s.Deployment, k8s.Service, initialPodUIDs); err != nil {
+			return err
+		}
+	}
+	if k8s.Ingress != "" {
+		if err := checkIngress(ctx, clientset, k8s.Namespace, k8s.Ingress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitForServiceEndpoints 等待所有就绪的新pod出现在Service的EndpointSlice中
+func waitForServiceEndpoints(ctx context.Context, clientset *kubernetes.Clientset, namespace, deploymentName, serviceName string, initialPodUIDs map[string]bool) error {
+	// EndpointSlice的更新有延迟，最多等待60秒
+	var missing []string
+	for attempt := 0; attempt < 12; attempt++ {
+		if attempt > 0 {
+			if err := sleepContext(ctx, 5*time.Second); err != nil {
+				return err
+			}
+		}
+
+		deployment, err := getDeployment(ctx, clientset, namespace, deploymentName)
+		if err != nil {
+			return errorf("failed to get deployment: %v", err)
+		}
+		podList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
+		if err != nil {
+			return errorf("failed to get pods: %v", err)
+		}
+		newPods, _ := categorizePodsByUID(podList, initialPodUIDs)
+
+		readyEndpoints, err := getReadyEndpointPods(ctx, clientset, namespace, serviceName)
+		if err != nil {
+			return err
+		}
+
+		missing = nil
+		for _, pod := range newPods {
+			if isPodReadyAndHealthy(pod) && !readyEndpoints[pod.Name] {
+				missing = append(missing, pod.Name)
+			}
+		}
+		if len(missing) == 0 {
+			slog.Info(msg("Service %s endpoints include all %d new pods", serviceName, len(newPods)))
+			return nil
+		}
+	}
+
+	return errorf("pods are ready but not receiving traffic: service %s endpoints are missing %s (check the service selector and ports)",
+		serviceName, strings.Join(missing, ", "))
+}
+
+// getReadyEndpointPods 返回Service的EndpointSlice中处于就绪状态的pod名称
+func getReadyEndpointPods(ctx context.Context, clientset *kubernetes.Clientset, namespace, serviceName string) (map[string]bool, error) {
+	slices, err := retryK8sCall(ctx, "list endpoint slices", func() (*discoveryv1.EndpointSliceList, error) {
+		return clientset.DiscoveryV1().EndpointSlices(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: discoveryv1.LabelServiceName + "=" + serviceName,
+		})
+	})
+	if err != nil {
+		return nil, errorf("failed to list endpoint slices for service %s: %v", serviceName, err)
+	}
+
+	ready := make(map[string]bool)
+	for _, slice := range slices.Items {
+		for _, endpoint := range slice.Endpoints {
+			if endpoint.TargetRef == nil || endpoint.TargetRef.Kind != "Pod" {
+				continue
+			}
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				ready[endpoint.TargetRef.Name] = true
+			}
+		}
+	}
+	return ready, nil
+}
+
+// checkIngress 检查Ingress已分配负载均衡地址，并且第一个host可以正常响应
+func checkIngress(ctx context.Context, clientset *kubernetes.Clientset, namespace, ingressName string) error {
+	ingress, err := clientset.NetworkingV1().Ingresses(namespace).Get(ctx, ingressName, metav1.GetOptions{})
+	if err != nil {
+		return errorf("failed to get ingress %s: %v", ingressName, err)
+	}
+
+	if len(ingress.Status.LoadBalancer.Ingress) == 0 {
+		return errorf("ingress %s has no load balancer address yet", ingressName)
+	}
+	address := ingress.Status.LoadBalancer.Ingress[0].Hostname
+	if address == "" {
+		address = ingress.Status.LoadBalancer.Ingress[0].IP
+	}
+
+	host := ""
+	if len(ingress.Spec.Rules) > 0 {
+		host = ingress.Spec.Rules[0].Host
+	}
+	if host == "" || strings.HasPrefix(host, "*") {
+		host = address
+	}
+	scheme := "http"
+	if len(ingress.Spec.TLS) > 0 {
+		scheme = "https"
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, scheme+"://"+host+"/", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return errorf("ingress %s (%s) is not answering: %v", ingressName, address, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return errorf("ingress %s answered with status %d", ingressName, resp.StatusCode)
+	}
+
+	slog.Info(msg("Ingress %s (%s) answered with status %d", ingressName, address, resp.StatusCode))
+	return nil
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// eventsFile 部署事件以JSON Lines写入该文件，deploy serve通过它获取子进程的事件
+var eventsFile = flag.String("events-file", "", "also write deploy events (phase changes, log messages, build output, pod updates, result) as JSON lines to this file")
+
+// 部署事件的类型
+const (
+	eventPhase  = "phase"  // 进入新的等待阶段，如排队、构建、滚动
+	eventLog    = "log"    // 一条info及以上级别的日志
+	eventOutput = "output" // 一段原样输出的内容，如Jenkins构建日志
+	eventPods   = "pods"   // 滚动监控的一次检查中新旧pod的状态
+	eventFinish = "finish" // 部署结束，附带汇总
+)
+
+// runEvent 部署过程中的事件，TUI、服务模式、机器人等订阅同一事件流而不是解析终端输出
+type runEvent struct {
+	Time    time.Time      `json:"time"`
+	Type    string         `json:"type"`
+	Phase   string         `json:"phase,omitempty"`
+	Level   string         `json:"level,omitempty"`
+	Text    string         `json:"text,omitempty"`
+	Pods    []podState     `json:"pods,omitempty"`
+	Result  string         `json:"result,omitempty"`
+	Summary *deploySummary `json:"summary,omitempty"`
+}
+
+// podState pods事件中单个pod的状态，与pod状态表格的列一致
+type podState struct {
+	Name     string    `json:"name"`
+	Old      bool      `json:"old,omitempty"`
+	Phase    string    `json:"phase"`
+	Ready    int       `json:"ready"`
+	Total    int       `json:"total"`
+	Restarts int32     `json:"restarts"`
+	Node     string    `json:"node,omitempty"`
+	Created  time.Time `json:"created"`
+}
+
+// eventBus 进程内的事件总线，订阅者按发布顺序同步收到事件
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[int]func(runEvent)
+	nextID      int
+}
+
+// events 当前进程的事件总线
+var events eventBus
+
+// subscribeEvents 注册事件回调，返回取消订阅的函数，回调中不能再发布事件
+func subscribeEvents(handler func(runEvent)) func() {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if events.subscribers == nil {
+		events.subscribers = make(map[int]func(runEvent))
+	}
+	id := events.nextID
+	events.nextID++
+	events.subscribers[id] = handler
+	return func() {
+		events.mu.Lock()
+		defer events.mu.Unlock()
+		delete(events.subscribers, id)
+	}
+}
+
+// subscribeEventChannel 以channel订阅事件，channel已满时丢弃事件而不阻塞部署，返回取消订阅的函数
+func subscribeEventChannel(size int) (<-chan runEvent, func()) {
+	ch := make(chan runEvent, size)
+	var once sync.Once
+	unsubscribe := subscribeEvents(func(event runEvent) {
+		select {
+		case ch <- event:
+		default:
+		}
+	})
+	return ch, func() {
+		once.Do(func() {
+			unsubscribe()
+			close(ch)
+		})
+	}
+}
+
+// hasEventSubscribers 是否有订阅者，没有时不构造事件
+func hasEventSubscribers() bool {
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	return len(events.subscribers) > 0
+}
+
+// publishEvent 把事件发送给所有订阅者
+func publishEvent(event runEvent) {
+	events.mu.Lock()
+	handlers := make([]func(runEvent), 0, len(events.subscribers))
+	for _, handler := range events.subscribers {
+		handlers = append(handlers, handler)
+	}
+	events.mu.Unlock()
+	if len(handlers) == 0 {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// publishPods 发布一次滚动检查中新旧pod的状态
+func publishPods(newPods, oldPods []*corev1.Pod) {
+	if !hasEventSubscribers() {
+		return
+	}
+	var pods []podState
+	for _, group := range []struct {
+		pods []*corev1.Pod
+		old  bool
+	}{{newPods, false}, {oldPods, true}} {
+		for _, pod := range group.pods {
+			state := podState{Name: pod.Name, Old: group.old, Phase: podDisplayPhase(pod), Total: len(pod.Spec.Containers),
+				Node: pod.Spec.NodeName, Created: pod.CreationTimestamp.Time}
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Ready {
+					state.Ready++
+				}
+				state.Restarts += status.RestartCount
+			}
+			pods = append(pods, state)
+		}
+	}
+	publishEvent(runEvent{Type: eventPods, Pods: pods})
+}
+
+// openEventsFile 指定了--events-file时订阅事件并逐行写入，返回关闭文件的函数
+func openEventsFile() (func(), error) {
+	if *eventsFile == "" {
+		return func() {}, nil
+	}
+	path, err := expandHomePath(*eventsFile)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errorf("failed to open events file: %v", err)
+	}
+	var mu sync.Mutex
+	encoder := json.NewEncoder(file)
+	unsubscribe := subscribeEvents(func(event runEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		encoder.Encode(event)
+	})
+	return func() {
+		unsubscribe()
+		file.Close()
+	}, nil
+}
+
+// eventWriter 把原样输出的内容作为output事件发布，写在maskingWriter之后，事件中的密钥同样被屏蔽
+type eventWriter struct{}
+
+func (eventWriter) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		publishEvent(runEvent{Type: eventOutput, Text: string(p)})
+	}
+	return len(p), nil
+}
+
+// eventHandler 把info及以上级别的日志作为log事件发布
+type eventHandler struct{}
+
+func (eventHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo && hasEventSubscribers()
+}
+
+func (eventHandler) Handle(_ context.Context, r slog.Record) error {
+	publishEvent(runEvent{Type: eventLog, Level: r.Level.String(), Text: maskSecrets(r.Message)})
+	return nil
+}
+
+func (h eventHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h eventHandler) WithGroup(string) slog.Handler { return h }
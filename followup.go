@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// abortBuildOnFailure 滚动在构建结束前开始（env.monitor_after）时，部署失败后中止仍在运行的构建，覆盖env.abort_build_on_failure
+var abortBuildOnFailure = flag.Bool("abort-build-on-failure", false, "abort the Jenkins build if the deploy fails while its follow-up stages are still running (requires monitor_after)")
+
+// rolloutMarker 构建日志中出现该内容时开始监控滚动，构建之后的阶段与监控同时进行，为空时等待构建结束
+var rolloutMarker string
+
+// followUpBuild 开始监控滚动后仍在运行的构建（如部署后验证阶段），部署成功前等待其结束
+type followUpBuild struct {
+	build   *gojenkins.Build
+	started time.Time
+	done    chan struct{}
+}
+
+// followUp 当前仍在后台跟踪的构建，没有时为nil
+var followUp *followUpBuild
+
+// startFollowUpBuild 在后台继续跟踪构建直到结束
+func startFollowUpBuild(ctx context.Context, build *gojenkins.Build, started time.Time) *followUpBuild {
+	f := &followUpBuild{build: build, started: started, done: make(chan struct{})}
+	go func() {
+		defer close(f.done)
+		for build.IsRunning(ctx) {
+			if sleepContext(ctx, 2*time.Second) != nil {
+				return
+			}
+		}
+	}()
+	return f
+}
+
+// running 构建是否仍在运行
+func (f *followUpBuild) running() bool {
+	select {
+	case <-f.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// wait 等待构建的其余阶段结束，构建失败时返回错误
+func (f *followUpBuild) wait(ctx context.Context, summary *deploySummary) error {
+	if f.running() {
+		setHeartbeatPhase("waiting for the remaining stages of Jenkins build #%d", f.build.GetBuildNumber())
+		slog.Info(msg("Rollout finished, waiting for the remaining stages of Jenkins build #%d...", f.build.GetBuildNumber()))
+	}
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if _, err := f.build.Poll(ctx); err != nil {
+		return errorf("failed to poll build: %v", err)
+	}
+	buildDuration := time.Since(f.started)
+	summary.addPhaseDuration("jenkins build", buildDuration)
+	saveBuildLog(ctx, f.build, "", summary)
+	if !f.build.IsGood(ctx) {
+		return errorf("Jenkins build #%d failed after the rollout: %s (%s)", f.build.GetBuildNumber(), f.build.GetResult(), f.build.GetUrl())
+	}
+	slog.Info(msg("Build #%d succeeded, build execution: %v", f.build.GetBuildNumber(), buildDuration.Round(time.Second)))
+	return nil
+}
+
+// abortOnFailure 部署失败时处理仍在运行的构建：abort为true时中止，否则提示构建仍在运行
+func (f *followUpBuild) abortOnFailure(ctx context.Context, abort bool) {
+	if !f.running() {
+		return
+	}
+	number := f.build.GetBuildNumber()
+	if !abort {
+		slog.Warn(msg("Jenkins build #%d is still running its follow-up stages after the deploy failed, use --abort-build-on-failure or abort_build_on_failure: true to abort it: %s", number, f.build.GetUrl()))
+		return
+	}
+	if _, err := f.build.Stop(context.WithoutCancel(ctx)); err != nil {
+		slog.Warn(msg("failed to abort Jenkins build #%d: %v", number, err))
+		return
+	}
+	slog.Warn(msg("Aborted Jenkins build #%d because the deploy failed while its follow-up stages were running: %s", number, f.build.GetUrl()))
+}
+
+// rolloutMarkerFound 构建日志中是否已出现开始监控滚动的标记
+func rolloutMarkerFound(logs string) bool {
+	return rolloutMarker != "" && strings.Contains(logs, rolloutMarker)
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// requireClean 工作区有未提交的修改或本地分支领先远程时中止部署，而不只是警告
+var requireClean = flag.Bool("require-clean", false, "fail instead of warning when the working tree has uncommitted changes or the branch has unpushed commits")
+
+// branchOverride 显式指定$branch的值，用于detached HEAD等无法从git读取分支的场景
+var branchOverride = flag.String("branch", "", "branch to use for $branch, overrides the branch detected from git (useful in detached HEAD checkouts)")
+
+// ciBranchEnvVars 常见CI提供当前分支的环境变量，按顺序查找
+var ciBranchEnvVars = []string{
+	"GITHUB_HEAD_REF",    // GitHub Actions pull request的源分支
+	"GITHUB_REF_NAME",    // GitHub Actions
+	"CI_COMMIT_REF_NAME", // GitLab CI
+	"BRANCH_NAME",        // Jenkins多分支流水线
+	"GIT_BRANCH",         // Jenkins git插件，形如origin/main
+	"BUILDKITE_BRANCH",
+	"CIRCLE_BRANCH",
+	"BITBUCKET_BRANCH",
+	"TRAVIS_BRANCH",
+}
+
+// deployBranchName 要部署的分支及其来源：--branch参数、git当前分支、rebase中的分支、CI环境变量，都没有时使用提交sha
+func deployBranchName() (string, string) {
+	if *branchOverride != "" {
+		return *branchOverride, "--branch"
+	}
+	if branch := currentBranchName(); branch != "" && branch != "HEAD" {
+		return branch, "git"
+	}
+	// rebase过程中HEAD处于detached状态，原分支记录在rebase目录中
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		if path := gitOutput("rev-parse", "--git-path", dir+"/head-name"); path != "" {
+			if data, err := os.ReadFile(path); err == nil {
+				return strings.TrimPrefix(strings.TrimSpace(string(data)), "refs/heads/"), "rebase in progress"
+			}
+		}
+	}
+	for _, name := range ciBranchEnvVars {
+		if value := os.Getenv(name); value != "" {
+			if name == "GIT_BRANCH" {
+				if _, branch, ok := strings.Cut(value, "/"); ok {
+					value = branch
+				}
+			}
+			return value, "$" + name
+		}
+	}
+	if sha := gitOutput("rev-parse", "HEAD"); sha != "" {
+		return sha, "commit sha"
+	}
+	return "", ""
+}
+
+// gitPlaceholders 参数中可以使用的git占位符，值在使用时读取当前目录的git仓库
+var gitPlaceholders = map[string]func() string{
+	"$branch":        getBranchName,
+	"$sha":           func() string { return gitOutput("rev-parse", "HEAD") },
+	"$short_sha":     func() string { return gitOutput("rev-parse", "--short", "HEAD") },
+	"$tag":           func() string { return gitOutput("describe", "--tags", "--abbrev=0") },
+	"$remote_branch": remoteBranchName,
+}
+
+// usesGitPlaceholders 构建参数是否引用了当前git仓库的信息
+func usesGitPlaceholders(env Env) bool {
+	for _, param := range env.Params {
+		if _, ok := gitPlaceholders[param.Value]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteBranchName 当前分支跟踪的远程分支在远程仓库中的名称，如origin/release/1.2对应release/1.2
+func remoteBranchName() string {
+	branch := currentBranchName()
+	if branch == "" || branch == "HEAD" {
+		return ""
+	}
+	return strings.TrimPrefix(gitOutput("config", "branch."+branch+".merge"), "refs/heads/")
+}
+
+// checkRemoteBranches 检查传给Jenkins的分支参数（$branch、$remote_branch）在远程仓库中存在，
+// 未推送的分支会在Jenkins checkout时失败，提前给出明确的错误；无法访问远程仓库时只警告
+func checkRemoteBranches(env Env, params map[string]string) error {
+	checked := make(map[string]bool)
+	for _, param := range env.Params {
+		if param.Value != "$branch" && param.Value != "$remote_branch" {
+			continue
+		}
+		branch := params[param.Name]
+		if branch == "" || checked[branch] {
+			continue
+		}
+		checked[branch] = true
+		// detached HEAD时$branch回退为提交sha，不是分支名
+		if _, source := deployBranchName(); param.Value == "$branch" && source == "commit sha" {
+			continue
+		}
+
+		remote := "origin"
+		if current := currentBranchName(); current != "" && current != "HEAD" {
+			if name := gitOutput("config", "branch."+current+".remote"); name != "" && name != "." {
+				remote = name
+			}
+		}
+		err := exec.Command("git", "ls-remote", "--exit-code", "--heads", remote, "refs/heads/"+branch).Run()
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 2 {
+			return errorf("branch %s does not exist on %s, push it first (git push -u %s %s)", branch, remote, remote, branch)
+		}
+		if err != nil {
+			slog.Warn(msg("failed to check branch %s on %s: %v", branch, remote, err))
+		}
+	}
+	return nil
+}
+
+// checkWorkingTree 检查未提交的修改和未推送的提交，Jenkins构建的是远程分支，与本地内容不一致时提示
+func checkWorkingTree() error {
+	if gitOutput("rev-parse", "--is-inside-work-tree") != "true" {
+		return nil
+	}
+
+	var problems []error
+	if status := gitOutput("status", "--porcelain"); status != "" {
+		problems = append(problems, errorf("%d uncommitted change(s) in the working tree", len(strings.Split(status, "\n"))))
+	}
+	upstream := gitOutput("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if upstream == "" {
+		if branch := currentBranchName(); branch != "" && branch != "HEAD" {
+			problems = append(problems, errorf("branch %s has no upstream, Jenkins may not be able to build it", branch))
+		}
+	} else if ahead, _ := strconv.Atoi(gitOutput("rev-list", "--count", upstream+"..HEAD")); ahead > 0 {
+		problems = append(problems, errorf("%d commit(s) not pushed to %s", ahead, upstream))
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	err := errorf("%v: Jenkins builds the remote branch, not your local checkout", joinErrors(problems, ", "))
+	if *requireClean {
+		return err
+	}
+	slog.Warn(localize(err))
+	return nil
+}
+
+// checkBranchPolicy 检查要部署的分支是否在环境的allowed_branches中，--force时需要输入环境名确认
+func checkBranchPolicy(env Env, params map[string]string) error {
+	if len(env.AllowedBranches) == 0 {
+		return nil
+	}
+	branch, source := deployBranchName()
+	if source != "commit sha" && env.allowsBranch(branch) {
+		// pre-deploy插件可以修改参数，$branch/$remote_branch参数中的分支同样需要符合规则
+		branch = disallowedBranchParam(env, params)
+		if branch == "" {
+			return nil
+		}
+	}
+
+	reason := msg("branch %s is not allowed to deploy to %s (allowed: %s)", branch, env.Name, strings.Join(env.AllowedBranches, ", "))
+	if source == "commit sha" {
+		reason = msg("no branch detected (detached HEAD at %s), %s only allows: %s", shortCommit(branch), env.Name, strings.Join(env.AllowedBranches, ", "))
+	}
+	if !*force {
+		return errorf("%s, use --force to override", reason)
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return errorf("%s, overriding it with --force requires an interactive terminal", reason)
+	}
+	slog.Warn(reason)
+	fmt.Print(msg("Type the env name (%s) to deploy anyway: ", env.Name))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != env.Name {
+		return errorf("deploy not confirmed")
+	}
+	slog.Warn(msg("Branch policy of %s overridden with --force", env.Name))
+	return nil
+}
+
+// allowsBranch 分支是否匹配环境的allowed_branches
+func (e Env) allowsBranch(branch string) bool {
+	for _, pattern := range e.AllowedBranches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// disallowedBranchParam 返回$branch/$remote_branch参数中第一个不符合allowed_branches的分支，都符合时返回空
+func disallowedBranchParam(env Env, params map[string]string) string {
+	for _, param := range env.Params {
+		if param.Value != "$branch" && param.Value != "$remote_branch" {
+			continue
+		}
+		if branch := params[param.Name]; branch != "" && !env.allowsBranch(branch) {
+			return branch
+		}
+	}
+	return ""
+}
+
+// BranchEnvRule 分支到默认环境的映射，没有指定环境时按当前分支选择
+type BranchEnvRule struct {
+	Branch string `yaml:"branch"` // 分支，支持通配符，如release/*
+	Env    string `yaml:"env"`
+}
+
+// defaultEnvForBranch 按branch_envs规则选择当前分支对应的环境，返回环境和分支，没有匹配时环境为空
+func (p Project) defaultEnvForBranch() (string, string) {
+	branch, source := deployBranchName()
+	if source == "commit sha" {
+		return "", branch
+	}
+	for _, rule := range p.BranchEnvs {
+		if matched, _ := path.Match(rule.Branch, branch); matched {
+			return rule.Env, branch
+		}
+	}
+	return "", branch
+}
+
+// GitTagConfig 部署成功后给部署的提交打annotated tag，在git中留下不可变的发布记录
+type GitTagConfig struct {
+	Name   string `yaml:"name,omitempty"`   // tag名称，支持$project、$env、$time(2006-01-02-1504)、$build，默认deploy/$env/$time
+	Push   bool   `yaml:"push,omitempty"`   // 创建后推送到远程仓库
+	Remote string `yaml:"remote,omitempty"` // 推送的远程仓库，默认origin
+}
+
+// tagDeployedCommit 给部署的提交打tag并按配置推送，失败只打印警告，不影响部署结果
+func tagDeployedCommit(config *GitTagConfig, summary *deploySummary) {
+	if config == nil || summary.Commit == "" {
+		return
+	}
+	name := config.Name
+	if name == "" {
+		name = "deploy/$env/$time"
+	}
+	name = strings.NewReplacer(
+		"$project", summary.Project,
+		"$env", summary.Env,
+		"$time", time.Now().Format("2006-01-02-1504"),
+		"$build", strconv.FormatInt(summary.BuildNumber, 10),
+	).Replace(name)
+
+	message := fmt.Sprintf("Deployed %s to %s by %s", summary.Project, summary.Env, summary.Deployer)
+	if summary.BuildURL != "" {
+		message += fmt.Sprintf("\n\nJenkins build #%d: %s", summary.BuildNumber, summary.BuildURL)
+	}
+	if out, err := exec.Command("git", "tag", "-a", name, "-m", message, summary.Commit).CombinedOutput(); err != nil {
+		slog.Warn(msg("failed to create git tag %s: %v: %s", name, err, strings.TrimSpace(string(out))))
+		return
+	}
+	slog.Info(msg("Tagged commit %s as %s", shortCommit(summary.Commit), name))
+
+	if !config.Push {
+		return
+	}
+	remote := config.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	if out, err := exec.Command("git", "push", remote, "refs/tags/"+name).CombinedOutput(); err != nil {
+		slog.Warn(msg("failed to push git tag %s to %s: %v: %s", name, remote, err, strings.TrimSpace(string(out))))
+		return
+	}
+	slog.Info(msg("Pushed tag %s to %s", name, remote))
+}
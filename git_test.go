@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestDisallowedBranchParam(t *testing.T) {
+	env := Env{
+		AllowedBranches: []string{"main", "release/*"},
+		Params:          []Param{{Name: "BRANCH", Value: "$branch"}, {Name: "IMAGE_TAG", Value: "latest"}},
+	}
+	tests := []struct {
+		params map[string]string
+		want   string
+	}{
+		{map[string]string{"BRANCH": "main", "IMAGE_TAG": "latest"}, ""},
+		{map[string]string{"BRANCH": "release/1.2"}, ""},
+		// pre-deploy插件把分支改成了不允许的分支
+		{map[string]string{"BRANCH": "feature/x"}, "feature/x"},
+		{map[string]string{"IMAGE_TAG": "feature/x"}, ""},
+	}
+	for _, tt := range tests {
+		if got := disallowedBranchParam(env, tt.params); got != tt.want {
+			t.Errorf("disallowedBranchParam(%v) = %q, want %q", tt.params, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// githubActions 是否在GitHub Actions中运行，此时按阶段折叠日志、标注失败原因并写入任务摘要
+var githubActions = os.Getenv("GITHUB_ACTIONS") == "true"
+
+// githubGroupOpen 当前是否有未结束的日志分组，GitHub Actions的分组不能嵌套
+var githubGroupOpen bool
+
+// startGitHubGroup 结束上一个分组并开始新的日志分组，每个阶段的日志在Actions中可以折叠
+func startGitHubGroup(title string) {
+	if !githubActions {
+		return
+	}
+	endGitHubGroup()
+	fmt.Fprintf(rawOutput, "::group::%s\n", escapeGitHubCommand(title))
+	githubGroupOpen = true
+}
+
+// endGitHubGroup 结束当前的日志分组，汇总和错误输出在分组之外
+func endGitHubGroup() {
+	if !githubGroupOpen {
+		return
+	}
+	fmt.Fprintln(rawOutput, "::endgroup::")
+	githubGroupOpen = false
+}
+
+// annotateGitHubError 将失败原因输出为error注解，显示在workflow运行的摘要页
+func annotateGitHubError(err error) {
+	if !githubActions {
+		return
+	}
+	endGitHubGroup()
+	fmt.Fprintf(rawOutput, "::error title=Deploy failed::%s\n", escapeGitHubCommand(err.Error()))
+}
+
+// escapeGitHubCommand 转义workflow命令中的%、换行
+func escapeGitHubCommand(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// writeGitHubStepSummary 将部署汇总以Markdown表格追加到GITHUB_STEP_SUMMARY
+func (s *deploySummary) writeGitHubStepSummary() {
+	path := os.Getenv("GITHUB_STEP_SUMMARY")
+	if !githubActions || path == "" {
+		return
+	}
+
+	icon := ":white_check_mark:"
+	if s.Result == stageFailure {
+		icon = ":x:"
+	}
+	rows := [][2]string{{"Result", s.Result}}
+	if s.Deployment != "" {
+		rows = append(rows,
+			[2]string{"Target", s.Namespace + "/" + s.Deployment},
+			[2]string{"Revision", s.OldRevision + " → " + s.NewRevision},
+			[2]string{"Pods", fmt.Sprint(s.Pods)})
+		for _, line := range formatImageDelta(s.OldImages, s.NewImages) {
+			rows = append(rows, [2]string{"Image", "`" + line + "`"})
+		}
+	}
+	if s.BuildNumber > 0 {
+		rows = append(rows, [2]string{"Build", fmt.Sprintf("[#%d](%s)", s.BuildNumber, s.BuildURL)})
+	}
+	if s.Commit != "" {
+		rows = append(rows, [2]string{"Commit", "`" + shortCommit(s.Commit) + "`"})
+	}
+	if s.Ticket != "" {
+		rows = append(rows, [2]string{"Ticket", s.Ticket})
+	}
+	for _, phase := range s.Phases {
+		rows = append(rows, [2]string{"Phase: " + phase.Name, roundSeconds(phase.Seconds).String()})
+	}
+	rows = append(rows, [2]string{"Total", roundSeconds(s.TotalSeconds).String()})
+	if s.Error != "" {
+		rows = append(rows, [2]string{"Error", s.Error})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "### %s Deploy %s to %s\n\n| | |\n|---|---|\n", icon, s.Project, s.Env)
+	for _, row := range rows {
+		value := strings.ReplaceAll(strings.ReplaceAll(row[1], "|", `\|`), "\n", "<br>")
+		fmt.Fprintf(&b, "| %s | %s |\n", row[0], value)
+	}
+	if len(s.Changelog) > 0 {
+		b.WriteString("\n<details><summary>Changes since the last deploy</summary>\n\n")
+		for _, line := range s.Changelog {
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+		b.WriteString("\n</details>\n")
+	}
+	b.WriteString("\n")
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err == nil {
+		_, err = file.WriteString(b.String())
+		file.Close()
+	}
+	if err != nil {
+		slog.Warn(msg("failed to write GitHub step summary: %v", err))
+	}
+}
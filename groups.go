@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ProjectGroup 项目组，deploy run --group一次部署组内所有项目的同一环境
+type ProjectGroup struct {
+	Name        string   `yaml:"name"`
+	Projects    []string `yaml:"projects"`
+	Dir         string   `yaml:"dir,omitempty"`         // 成员项目目录的根目录，项目在<dir>/<项目名>下部署，配置了path的monorepo项目在<dir>/<path>下，默认当前目录
+	Concurrency int      `yaml:"concurrency,omitempty"` // 同时部署的成员数量，默认1
+}
+
+// groupSummary 项目组部署的汇总
+type groupSummary struct {
+	Group     string        `json:"group"`
+	Env       string        `json:"env"`
+	Result    string        `json:"result"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Seconds   float64       `json:"duration_seconds"`
+	Deploys   []batchResult `json:"deploys"`
+}
+
+// findGroup 按名称查找项目组
+func (c *Config) findGroup(name string) *ProjectGroup {
+	for i := range c.Groups {
+		if c.Groups[i].Name == name {
+			return &c.Groups[i]
+		}
+	}
+	return nil
+}
+
+// findProject 按名称查找项目
+func (c *Config) findProject(name string) *Project {
+	for i := range c.Projects {
+		if c.Projects[i].Name == name {
+			return &c.Projects[i]
+		}
+	}
+	return nil
+}
+
+// runGroupDeploy 使用批量部署的流程部署项目组内所有项目的同一环境，输出每个项目的结果和组的汇总
+func runGroupDeploy(groupName, envName string, concurrency int, output string) error {
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	group := config.findGroup(groupName)
+	if group == nil {
+		return errorf("project group %s not found in config", groupName)
+	}
+	if len(group.Projects) == 0 {
+		return errorf("project group %s has no projects", groupName)
+	}
+
+	baseDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if group.Dir != "" {
+		dir, err := expandHomePath(group.Dir)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(baseDir, dir)
+		}
+		baseDir = dir
+	}
+
+	// 开始前检查所有成员，避免部署了一部分才发现配置或权限问题
+	var entries []batchEntry
+	var problems []error
+	for _, name := range group.Projects {
+		project := config.findProject(name)
+		if project == nil {
+			problems = append(problems, errorf("project %s is not in config", name))
+			continue
+		}
+		env, found := config.findEnv(name, envName)
+		if !found {
+			problems = append(problems, errorf("project %s has no env %s", name, envName))
+			continue
+		}
+		if err := checkUserPolicy(name, env, currentOperator()); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+		dir := filepath.Join(baseDir, name)
+		if project.Path != "" {
+			dir = filepath.Join(baseDir, filepath.FromSlash(project.Path))
+		}
+		entries = append(entries, batchEntry{Name: name + "/" + envName, Project: name, Env: envName, Dir: dir})
+	}
+	if len(problems) > 0 {
+		return errorf("cannot deploy group %s to %s: %s", groupName, envName, joinErrors(problems, "; "))
+	}
+
+	if concurrency <= 0 {
+		concurrency = group.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	executable, logDir, err := prepareBatch()
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(msg("Deploying group %s (%s) to %s with concurrency %d, logs in %s\n", groupName, strings.Join(group.Projects, ", "), envName, concurrency, logDir))
+	startTime := time.Now()
+	results := runBatch(entries, concurrency, executable, logDir)
+
+	summary := groupSummary{Group: groupName, Env: envName, Result: stageSuccess, Deploys: results,
+		Seconds: time.Since(startTime).Seconds()}
+	for _, result := range results {
+		switch result.Result {
+		case stageSuccess:
+			summary.Succeeded++
+		case batchSkipped:
+			summary.Skipped++
+		default:
+			summary.Failed++
+		}
+	}
+	if summary.Succeeded < len(results) {
+		summary.Result = stageFailure
+	}
+
+	if output == "json" {
+		data, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+	} else {
+		printBatchReport(results)
+		fmt.Print(msg("\nGroup %s to %s: %d succeeded, %d failed, %d skipped in %v\n",
+			groupName, envName, summary.Succeeded, summary.Failed, summary.Skipped, roundSeconds(summary.Seconds)))
+	}
+	if summary.Result != stageSuccess {
+		return errorf("%d of %d deploys of group %s did not succeed", len(results)-summary.Succeeded, len(results), groupName)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// heartbeatInterval 没有任何输出超过该时长时输出一行心跳，说明当前阶段和已等待的时间
+var heartbeatInterval = flag.Duration("heartbeat", 15*time.Second, "print a progress line when nothing has been printed for this long during queueing, builds and rollouts, 0 to disable")
+
+// lastOutput 最近一次输出到终端的时间（UnixNano）
+var lastOutput atomic.Int64
+
+// activityWriter 记录最近一次输出的时间，用于判断是否需要心跳
+type activityWriter struct {
+	w io.Writer
+}
+
+func (a activityWriter) Write(p []byte) (int, error) {
+	lastOutput.Store(time.Now().UnixNano())
+	return a.w.Write(p)
+}
+
+// heartbeatPhase 当前等待的阶段，为空时不输出心跳
+var heartbeatPhase struct {
+	sync.Mutex
+	name  string
+	since time.Time
+}
+
+// setHeartbeatPhase 设置当前等待的阶段，心跳中显示阶段名称和进入该阶段后经过的时间
+func setHeartbeatPhase(format string, args ...any) {
+	heartbeatPhase.Lock()
+	defer heartbeatPhase.Unlock()
+	heartbeatPhase.name, heartbeatPhase.since = msg(format, args...), time.Now()
+	if heartbeatPhase.name != "" {
+		publishEvent(runEvent{Type: eventPhase, Phase: heartbeatPhase.name})
+	}
+}
+
+// startHeartbeat 开始在长时间没有输出时输出心跳，返回停止心跳的函数
+func startHeartbeat() func() {
+	if *heartbeatInterval <= 0 {
+		return func() {}
+	}
+	lastOutput.Store(time.Now().UnixNano())
+	done := make(chan struct{})
+	go func() {
+		for {
+			wait := time.Until(time.Unix(0, lastOutput.Load()).Add(*heartbeatInterval))
+			select {
+			case <-done:
+				return
+			case <-time.After(max(wait, time.Second)):
+			}
+			if time.Since(time.Unix(0, lastOutput.Load())) < *heartbeatInterval {
+				continue
+			}
+			heartbeatPhase.Lock()
+			name, since := heartbeatPhase.name, heartbeatPhase.since
+			heartbeatPhase.Unlock()
+			if name == "" {
+				lastOutput.Store(time.Now().UnixNano())
+				continue
+			}
+			slog.Info(msg("Still %s, %v elapsed", name, time.Since(since).Round(time.Second)))
+		}
+	}()
+	return func() {
+		close(done)
+		setHeartbeatPhase("")
+	}
+}
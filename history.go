@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// deployRecord 每次部署结束后记录的历史，写入本地历史文件和共享的部署台账
+type deployRecord struct {
+	Project     string            `json:"project"`
+	Env         string            `json:"env"`
+	Result      string            `json:"result"`
+	Deployer    string            `json:"deployer,omitempty"`
+	Ticket      string            `json:"ticket,omitempty"` // 关联的变更单号
+	Branch      string            `json:"branch,omitempty"`
+	Commit      string            `json:"commit,omitempty"`
+	BuildNumber int64             `json:"build_number,omitempty"`
+	BuildURL    string            `json:"build_url,omitempty"`
+	BuildLog    string            `json:"build_log,omitempty"` // 本机保存的构建日志路径
+	Revision    string            `json:"revision,omitempty"`
+	Images      map[string]string `json:"images,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	StartedAt   time.Time         `json:"started_at"`
+	FinishedAt  time.Time         `json:"finished_at"`
+	Signer      string            `json:"signer,omitempty"`    // 签名人，配置了ledger.signing时记录
+	Signature   string            `json:"signature,omitempty"` // 签名人对记录其余内容的SSH签名
+}
+
+// historyFileName 本地部署历史文件，位于用户主目录的.deploy目录下，每行一条JSON记录
+const historyFileName = "history.jsonl"
+
+// historyQuery 查询历史记录的条件，字段为空表示不过滤
+type historyQuery struct {
+	Project string
+	Env     string
+	Limit   int
+}
+
+// matches 记录是否属于查询的项目和环境
+func (q historyQuery) matches(record deployRecord) bool {
+	return (q.Project == "" || record.Project == q.Project) && (q.Env == "" || record.Env == q.Env)
+}
+
+// ledgerBackend 部署记录的存储后端
+type ledgerBackend interface {
+	publish(ctx context.Context, record deployRecord) error
+	list(ctx context.Context, query historyQuery) ([]deployRecord, error)
+}
+
+// newDeployRecord 根据部署汇总生成历史记录
+func newDeployRecord(summary *deploySummary, result, errMessage string) deployRecord {
+	return deployRecord{
+		Project:     summary.Project,
+		Env:         summary.Env,
+		Result:      result,
+		Deployer:    summary.Deployer,
+		Ticket:      summary.Ticket,
+		Branch:      summary.Branch,
+		Commit:      summary.Commit,
+		BuildNumber: summary.BuildNumber,
+		BuildURL:    summary.BuildURL,
+		BuildLog:    summary.BuildLog,
+		Revision:    summary.NewRevision,
+		Images:      summary.NewImages,
+		Error:       maskSecrets(errMessage),
+		StartedAt:   summary.startTime,
+		FinishedAt:  time.Now(),
+	}
+}
+
+// recordDeploy 把部署记录写入本地历史和所有配置的共享台账，失败只打印警告
+func recordDeploy(ctx context.Context, config *LedgerConfig, record deployRecord) {
+	if config != nil {
+		if err := config.Signing.signRecord(&record); err != nil {
+			slog.Warn(msg("failed to sign deploy record: %v", err))
+		}
+	}
+	backends := []ledgerBackend{localHistory{}}
+	backends = append(backends, config.backends()...)
+	for _, backend := range backends {
+		if err := backend.publish(ctx, record); err != nil {
+			slog.Warn(msg("failed to record deploy history: %v", err))
+		}
+	}
+}
+
+// localHistory 用户主目录下的本地历史文件
+type localHistory struct{}
+
+func (localHistory) path() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", historyFileName), nil
+}
+
+func (l localHistory) publish(ctx context.Context, record deployRecord) error {
+	path, err := l.path()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+func (l localHistory) list(ctx context.Context, query historyQuery) ([]deployRecord, error) {
+	path, err := l.path()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []deployRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for scanner.Scan() {
+		var record deployRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return filterRecords(records, query), nil
+}
+
+// filterRecords 按条件过滤，按结束时间倒序排列并截取前limit条
+func filterRecords(records []deployRecord, query historyQuery) []deployRecord {
+	var filtered []deployRecord
+	for _, record := range records {
+		if query.matches(record) {
+			filtered = append(filtered, record)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].FinishedAt.After(filtered[j].FinishedAt)
+	})
+	if query.Limit > 0 && len(filtered) > query.Limit {
+		filtered = filtered[:query.Limit]
+	}
+	return filtered
+}
+
+// runHistoryCommand deploy history子命令：查看本地或共享台账中的部署记录
+func runHistoryCommand(args []string) error {
+	flags := flag.NewFlagSet("history", flag.ExitOnError)
+	remote := flags.Bool("remote", false, "query the shared team ledger instead of the local history")
+	env := flags.String("env", "", "only show deploys of this env")
+	all := flags.Bool("all", false, "show deploys of all projects instead of the current directory's project")
+	limit := flags.Int("limit", 20, "maximum number of records to show")
+	verify := flags.Bool("verify", false, "verify record signatures against ledger.signing.allowed_signers")
+	logs := flags.Bool("logs", false, "show the path of the saved Jenkins build log of each deploy")
+	flags.Parse(args)
+
+	query := historyQuery{Env: *env, Limit: *limit}
+	if !*all {
+		project, err := currentProjectName()
+		if err != nil {
+			return err
+		}
+		query.Project = project
+	}
+
+	backend, err := historyBackend(*remote)
+	if err != nil {
+		return err
+	}
+	records, err := backend.list(context.Background(), query)
+	if err != nil {
+		return errorf("failed to read deploy history: %v", err)
+	}
+	if len(records) == 0 {
+		fmt.Println(tr("No deploys found"))
+		return nil
+	}
+
+	var signing *SigningConfig
+	if *verify {
+		config, err := loadDefaultConfig()
+		if err != nil {
+			return err
+		}
+		if config.Ledger != nil {
+			signing = config.Ledger.Signing
+		}
+		if signing == nil || signing.AllowedSigners == "" {
+			return errorf("--verify requires ledger.signing.allowed_signers in deploy_config.yaml")
+		}
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "FINISHED\tPROJECT\tENV\tRESULT\tDEPLOYER\tBRANCH\tCOMMIT\tBUILD\tDURATION"
+	if *logs {
+		header += "\tLOG"
+	}
+	if *verify {
+		header += "\tSIGNATURE"
+	}
+	fmt.Fprintln(writer, header)
+	invalid := 0
+	for _, record := range records {
+		build := ""
+		if record.BuildNumber > 0 {
+			build = fmt.Sprintf("#%d", record.BuildNumber)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%v",
+			formatTime(record.FinishedAt), record.Project, record.Env,
+			strings.ToUpper(record.Result), record.Deployer, record.Branch, shortCommit(record.Commit), build,
+			record.FinishedAt.Sub(record.StartedAt).Round(time.Second))
+		if *logs {
+			fmt.Fprintf(writer, "\t%s", buildLogPath(record))
+		}
+		if *verify {
+			result, err := signing.verify(record.Signer, record.Signature, record.signedPayload())
+			if err != nil {
+				return err
+			}
+			if result == signatureInvalid {
+				invalid++
+			}
+			if result == signatureValid {
+				result += " (" + record.Signer + ")"
+			}
+			fmt.Fprintf(writer, "\t%s", result)
+		}
+		fmt.Fprintln(writer)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return errorf("%d record(s) have invalid signatures", invalid)
+	}
+	return nil
+}
+
+// historyBackend 返回查询使用的后端，remote为true时使用第一个配置的共享台账
+func historyBackend(remote bool) (ledgerBackend, error) {
+	if !remote {
+		return localHistory{}, nil
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	backends := config.Ledger.backends()
+	if len(backends) == 0 {
+		return nil, errorf("no shared ledger configured, add a ledger section to deploy_config.yaml")
+	}
+	return backends[0], nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"time"
+)
+
+// HTTPConfig HTTP请求的超时设置，所有HTTP客户端都使用HTTP_PROXY/HTTPS_PROXY/NO_PROXY环境变量中的代理
+type HTTPConfig struct {
+	JenkinsTimeout string `yaml:"jenkins_timeout,omitempty"` // 单个Jenkins API请求的超时时间，默认30s，Jenkins无响应时不会一直卡住
+	Timeout        string `yaml:"timeout,omitempty"`         // 通知、webhook、Prometheus、OIDC等其他请求的超时时间，默认10s
+}
+
+// HTTP请求的超时时间，由配置文件中的http覆盖
+var (
+	jenkinsTimeout = 30 * time.Second
+	httpTimeout    = 10 * time.Second
+)
+
+// setHTTPTimeouts 按配置设置HTTP请求的超时时间，未配置的保持默认值
+func setHTTPTimeouts(config *HTTPConfig) error {
+	if config == nil {
+		return nil
+	}
+	for _, setting := range []struct {
+		name   string
+		value  string
+		target *time.Duration
+	}{
+		{"http.jenkins_timeout", config.JenkinsTimeout, &jenkinsTimeout},
+		{"http.timeout", config.Timeout, &httpTimeout},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(setting.value)
+		if err != nil || duration <= 0 {
+			return errorf("invalid %s %q, expected a duration such as 30s", setting.name, setting.value)
+		}
+		*setting.target = duration
+	}
+	return nil
+}
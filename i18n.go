@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// langFlag 控制台输出的语言，优先于DEPLOY_LANG环境变量和配置文件中的lang
+var langFlag = flag.String("lang", "", "language of console output: en (default) or zh-CN, overrides DEPLOY_LANG and lang in the config")
+
+// catalogs 各语言的消息目录，以英文原文（格式字符串）为键，目录中没有的消息输出英文
+var catalogs = map[string]map[string]string{
+	"zh-CN": zhCNMessages,
+}
+
+// messages 当前语言的消息目录，英文时为nil
+var messages map[string]string
+
+// setLocale 按--lang、DEPLOY_LANG、配置文件的顺序选择输出语言
+func setLocale(configLang string) error {
+	lang := configLang
+	if env := os.Getenv("DEPLOY_LANG"); env != "" {
+		lang = env
+	}
+	if *langFlag != "" {
+		lang = *langFlag
+	}
+	locale, ok := normalizeLocale(lang)
+	if !ok {
+		return fmt.Errorf("unsupported language %q: must be en or zh-CN", lang)
+	}
+	messages = catalogs[locale]
+	return nil
+}
+
+// normalizeLocale 将zh、zh_CN.UTF-8等写法统一为消息目录的语言名
+func normalizeLocale(lang string) (string, bool) {
+	lang, _, _ = strings.Cut(lang, ".")
+	switch strings.ToLower(strings.ReplaceAll(lang, "_", "-")) {
+	case "", "en", "en-us", "en-gb", "c", "posix":
+		return "en", true
+	case "zh", "zh-cn", "zh-hans", "zh-sg":
+		return "zh-CN", true
+	}
+	return "", false
+}
+
+// extractLangFlag 取出任意位置的--lang参数，子命令的输出同样使用该语言
+func extractLangFlag(args []string) []string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "lang" {
+			continue
+		}
+		end := i + 1
+		if !hasValue && end < len(args) {
+			value, end = args[end], end+1
+		}
+		flag.Set("lang", value)
+		return append(args[:i:i], args[end:]...)
+	}
+	return args
+}
+
+// tr 翻译消息，当前语言的目录中没有时返回原文
+func tr(message string) string {
+	if translated, ok := messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// msg 按当前语言格式化输出到终端的消息，参数中errorf创建的错误同样翻译
+func msg(format string, args ...any) string {
+	return fmt.Sprintf(tr(format), localizeArgs(args)...)
+}
+
+// localizedError errorf创建的错误：Error()始终是英文，台账、审计日志、通知和--summary-file中的内容不随语言变化，
+// 同时保留格式和参数，输出到终端时由localize翻译
+type localizedError struct {
+	format string
+	args   []any
+	err    error
+}
+
+func (e *localizedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回%w包装的错误，errors.Is和errors.As可以穿过errorf
+func (e *localizedError) Unwrap() []error {
+	switch err := e.err.(type) {
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		return []error{err.Unwrap()}
+	}
+	return nil
+}
+
+// errorf 创建英文的错误，%w包装的错误仍然可以用errors.Is判断
+func errorf(format string, args ...any) error {
+	return &localizedError{format: format, args: args, err: fmt.Errorf(format, args...)}
+}
+
+// localize 按当前语言输出错误，用于终端，参数中errorf创建的错误逐层翻译，其他错误原样输出
+func localize(err error) string {
+	e, ok := err.(*localizedError)
+	if !ok || messages == nil {
+		return err.Error()
+	}
+	return fmt.Sprintf(strings.ReplaceAll(tr(e.format), "%w", "%v"), localizeArgs(e.args)...)
+}
+
+// localizeArgs 把参数中errorf创建的错误替换为翻译后的文本
+func localizeArgs(args []any) []any {
+	if messages == nil {
+		return args
+	}
+	localized := make([]any, len(args))
+	for i, arg := range args {
+		if err, ok := arg.(*localizedError); ok && err != nil {
+			arg = localize(err)
+		}
+		localized[i] = arg
+	}
+	return localized
+}
+
+// joinErrors 用sep连接多个错误，输出到终端时逐个翻译，没有错误时返回nil
+func joinErrors(errs []error, sep string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	args := make([]any, len(errs))
+	for i, err := range errs {
+		args[i] = err
+	}
+	return errorf(strings.Repeat("%v"+strings.ReplaceAll(sep, "%", "%%"), len(errs)-1)+"%v", args...)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestErrorfStaysEnglish(t *testing.T) {
+	t.Cleanup(func() { messages = nil })
+	messages = zhCNMessages
+
+	err := errorf("gitlab: %v", errorf("gitlab: failed to get commit sha"))
+	if got, want := err.Error(), "gitlab: gitlab: failed to get commit sha"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	if got, want := localize(err), "gitlab：gitlab：获取提交 sha 失败"; got != want {
+		t.Errorf("localize = %q, want %q", got, want)
+	}
+	if got, want := msg("%v", err), "gitlab：gitlab：获取提交 sha 失败"; got != want {
+		t.Errorf("msg = %q, want %q", got, want)
+	}
+}
+
+func TestLocalizeEnglish(t *testing.T) {
+	messages = nil
+	err := errorf("lock: %w", errors.New("boom"))
+	if got, want := localize(err), "lock: boom"; got != want {
+		t.Errorf("localize = %q, want %q", got, want)
+	}
+	if got := localize(errors.New("plain")); got != "plain" {
+		t.Errorf("localize(plain) = %q", got)
+	}
+}
+
+func TestErrorfUnwrap(t *testing.T) {
+	err := errorf("read config: %w", fs.ErrNotExist)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("errors.Is(%v, fs.ErrNotExist) = false", err)
+	}
+	err = errorf("%w: %w", fs.ErrPermission, fs.ErrClosed)
+	if !errors.Is(err, fs.ErrPermission) || !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("errors.Is through multiple %%w failed: %v", err)
+	}
+}
+
+func TestErrorfKeepsSentinels(t *testing.T) {
+	err := errorf("Failed to monitor pod rollout: %w", errorf("canary rollout failed: %w", ErrConcurrentRollout))
+	if !errors.Is(err, ErrConcurrentRollout) {
+		t.Errorf("errors.Is(%v, ErrConcurrentRollout) = false", err)
+	}
+	if code := exitCode(err); code != exitCodeConcurrentRollout {
+		t.Errorf("exitCode(%v) = %d, want %d", err, code, exitCodeConcurrentRollout)
+	}
+	err = errorf("%w, or use --queue to wait for it", errorf("%w: %s is being deployed by %s", ErrLockHeld, "prod", "ci"))
+	if !errors.Is(err, ErrLockHeld) {
+		t.Errorf("errors.Is(%v, ErrLockHeld) = false", err)
+	}
+	err = errorf("failed to acquire deploy lock: %w", context.Canceled)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("errors.Is(%v, context.Canceled) = false", err)
+	}
+}
+
+func TestJoinErrors(t *testing.T) {
+	t.Cleanup(func() { messages = nil })
+	if joinErrors(nil, ", ") != nil {
+		t.Error("joinErrors(nil) != nil")
+	}
+	err := joinErrors([]error{errorf("empty reply"), errors.New("100%")}, "; ")
+	if got, want := err.Error(), "empty reply; 100%"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	messages = zhCNMessages
+	if got, want := localize(err), "空回复; 100%"; got != want {
+		t.Errorf("localize = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,747 @@
+package main
+
+// zhCNMessages 简体中文消息目录，按消息所在的文件分组
+var zhCNMessages = map[string]string{
+	// 通用
+	"Error:": "错误：",
+
+	// audit.go
+	"failed to write audit log: %v":                                                   "写入审计日志失败：%v",
+	"failed to ship audit entry to ledger: %v":                                        "发送审计记录到共享账本失败：%v",
+	"failed to get user home directory: %v":                                           "获取用户主目录失败：%v",
+	"failed to sign audit entry: %v":                                                  "签名审计记录失败：%v",
+	"%s line %d: invalid entry: %v":                                                   "%s 第 %d 行：无效的记录：%v",
+	"entry %d does not link to the previous entry, entries were removed or reordered": "第 %d 条记录未链接到上一条记录，有记录被删除或调整了顺序",
+	"entry %d was modified, hash mismatch":                                            "第 %d 条记录被修改，哈希不匹配",
+	"entry %d has an invalid signature for %s":                                        "第 %d 条记录的签名对 %s 无效",
+	"%d of %d entries have valid signatures\n":                                        "%d/%d 条记录的签名有效\n",
+	"audit log %s failed verification: %v":                                            "审计日志 %s 校验失败：%v",
+	"Audit log %s is intact (%d entries)\n":                                           "审计日志 %s 完整（%d 条记录）\n",
+	"No audit entries found":                                                          "没有审计记录",
+
+	// authexpiry.go
+	"Jenkins (%s) rejected the credentials (HTTP 401): the API token has probably expired or been revoked, run `deploy login` to store a new one":                                               "Jenkins（%s）拒绝了凭证（HTTP 401）：API token 可能已过期或被撤销，运行 `deploy login` 保存新的 token",
+	"Kubernetes API server (%s) rejected the credentials (HTTP 401): refresh your kubeconfig credentials (e.g. `aws sso login`, `gcloud auth login`, `az login`, or download a new kubeconfig)": "Kubernetes API server（%s）拒绝了凭证（HTTP 401）：请刷新 kubeconfig 的凭证（如 `aws sso login`、`gcloud auth login`、`az login`，或重新下载 kubeconfig）",
+	"Jenkins rejected the saved credentials, they may have expired. Run deploy login now? [y/N] ":                                                                                               "Jenkins 拒绝了保存的凭证，可能已过期。现在运行 deploy login？[y/N] ",
+	"%w: %w": "%w：%w",
+
+	// batch.go
+	"usage: deploy batch -f manifest.yaml [--concurrency N] [--output json]": "用法：deploy batch -f manifest.yaml [--concurrency N] [--output json]",
+	"failed to locate deploy executable: %v":                                 "找不到 deploy 可执行文件：%v",
+	"failed to create log dir: %v":                                           "创建日志目录失败：%v",
+	"Deploying %d entries with concurrency %d, logs in %s\n":                 "部署 %d 项，并发数 %d，日志在 %s\n",
+	"%d of %d deploys did not succeed":                                       "%d/%d 个部署未成功",
+	"failed to read manifest: %v":                                            "读取部署清单失败：%v",
+	"failed to parse manifest: %v":                                           "解析部署清单失败：%v",
+	"manifest %s has no deploys":                                             "部署清单 %s 中没有部署",
+	"deploy %d: project and env are required":                                "第 %d 个部署：project 和 env 必须填写",
+	"duplicate deploy name %q, set a unique name":                            "部署名称 %q 重复，请设置唯一的名称",
+	"%s depends on unknown deploy %q":                                        "%s 依赖的部署 %q 不存在",
+	"dependency cycle: %s -> %s":                                             "依赖存在循环：%s -> %s",
+	"[%s] skipped: %s":                                                       "[%s] 已跳过：%s",
+	"[%s] started":                                                           "[%s] 已开始",
+	"[%s] succeeded in %v":                                                   "[%s] 成功，耗时 %v",
+	"[%s] failed after %v: %s (see %s)":                                      "[%s] 失败，耗时 %v：%s（详见 %s）",
+	"failed to create log file: %v":                                          "创建日志文件失败：%v",
+	"project dir %s is not recognized as %s, it must be named %s or match the project's path": "无法将项目目录 %s 识别为 %s，目录名必须是 %s 或与项目的 path 匹配",
+	"exit code %d": "退出码 %d",
+	"Progress: %d succeeded, %d failed, %d skipped, %d pending, %d running (%s)": "进度：%d 成功，%d 失败，%d 跳过，%d 等待，%d 运行中（%s）",
+
+	// buildlog.go
+	"invalid build_logs.max_age %q, expected a duration such as 720h": "无效的 build_logs.max_age %q，应为时长，如 720h",
+	"failed to save the log of build #%d: %v":                         "保存构建 #%d 的日志失败：%v",
+	"Log of build #%d saved to %s":                                    "构建 #%d 的日志已保存到 %s",
+
+	// bluegreen.go
+	"blue/green configuration incomplete: service=%s, blue=%s, green=%s": "蓝绿部署配置不完整：service=%s，blue=%s，green=%s",
+	"failed to get service %s: %v":                                       "获取 service %s 失败：%v",
+	"service %s selector label %s=%q is neither blue nor green":          "service %s 的 selector 标签 %s=%q 既不是 blue 也不是 green",
+	"failed to patch service %s selector: %v":                            "修改 service %s 的 selector 失败：%v",
+	"Service %s now routes traffic to %s":                                "Service %s 的流量已切换到 %s",
+
+	// canary.go
+	"failed to get deployment: %v":                             "获取 deployment 失败：%v",
+	"Canary: scaling %s to %d replicas (%d%% of %d)":           "金丝雀：将 %s 扩容到 %d 个副本（%[4]d 个的 %[3]d%%）",
+	"canary rollout failed: %w":                                "金丝雀发布失败：%w",
+	"canary bake failed: %w":                                   "金丝雀观察期未通过：%w",
+	"failed to get deployment status before promotion: %v":     "全量发布前获取 deployment 状态失败：%v",
+	"full rollout after canary failed: %w":                     "金丝雀之后的全量发布失败：%w",
+	"Canary: full rollout completed, scaling %s back to 0":     "金丝雀：全量发布完成，将 %s 缩容到 0",
+	"invalid canary bake_time %q: %v":                          "无效的金丝雀 bake_time %q：%v",
+	"Canary: baking for %v (max restarts=%d)":                  "金丝雀：观察 %v（最多重启 %d 次）",
+	"failed to get canary deployment: %v":                      "获取金丝雀 deployment 失败：%v",
+	"failed to get canary pods: %v":                            "获取金丝雀 pod 失败：%v",
+	"canary pods restarted %d times, more than the allowed %d": "金丝雀 pod 重启了 %d 次，超过允许的 %d 次",
+	"prometheus query failed: %v":                              "prometheus 查询失败：%v",
+	"prometheus query returned %v, above threshold %v":         "prometheus 查询结果为 %v，超过阈值 %v",
+	"Canary: healthy, %d restarts, %v remaining":               "金丝雀：健康，重启 %d 次，还剩 %v",
+	"Canary: promoting container %s image %s to %s":            "金丝雀：将容器 %s 的镜像 %s 推广到 %s",
+	"failed to promote canary images to %s: %v":                "推广金丝雀镜像到 %s 失败：%v",
+	"Canary: rolling back, scaling %s to 0":                    "金丝雀：回滚，将 %s 缩容到 0",
+	"Canary: rollback failed: %v":                              "金丝雀：回滚失败：%v",
+	"failed to scale deployment %s to %d: %v":                  "将 deployment %s 扩缩容到 %d 失败：%v",
+	"failed to decode prometheus response: %v":                 "解析 prometheus 响应失败：%v",
+	"prometheus returned %s: %s":                               "prometheus 返回 %s：%s",
+	"invalid prometheus sample value %q":                       "无效的 prometheus 样本值 %q",
+
+	// changelog.go
+	"%d commit(s) since the last deploy to %s:": "自上次部署到 %[2]s 以来有 %[1]d 个提交：",
+
+	// configcheck.go
+	"failed to get configmap %s: %v":      "获取 configmap %s 失败：%v",
+	"failed to get secret %s: %v":         "获取 secret %s 失败：%v",
+	"Config %s changed (sha256 %s -> %s)": "配置 %s 已变化（sha256 %s -> %s）",
+	"config changed but deployment %s was not restarted (annotation %s unchanged), pods are still running the old config": "配置已变化，但 deployment %s 没有重启（注解 %s 未变化），pod 仍在使用旧配置",
+	"No ConfigMap/Secret content changed and deployment was not restarted, nothing to roll out":                           "ConfigMap/Secret 内容没有变化，deployment 也没有重启，无需滚动更新",
+	"deployment was restarted but no ConfigMap/Secret content changed":                                                    "deployment 已重启，但 ConfigMap/Secret 内容没有变化",
+	"Deployment picked up new %s: %s": "Deployment 已使用新的 %s：%s",
+
+	// credentials.go
+	"failed to look up credential %s for param %s: %v":                                                                                           "查询参数 %[2]s 的凭据 %[1]s 失败：%[3]v",
+	"Cannot verify credential %s for param %s: no permission to view the Jenkins credential stores":                                              "无法校验参数 %[2]s 的凭据 %[1]s：没有查看 Jenkins 凭据存储的权限",
+	"credential %q for param %s does not exist in Jenkins (checked the global domain of the job's folders, the system store and the user store)": "参数 %[2]s 的凭据 %[1]q 在 Jenkins 中不存在（已检查任务所在文件夹、系统和用户凭据存储的全局域）",
+
+	// debug.go
+	"--debug-on-failure requires an interactive terminal, skipping debug container": "--debug-on-failure 需要交互终端，跳过调试容器",
+	"Attach an ephemeral debug container to pod %s (target container %s)? [y/N] ":   "为 pod %s 附加临时调试容器（目标容器 %s）？[y/N] ",
+	"Failed to debug pod %s: %v": "调试 pod %s 失败：%v",
+	"failed to get pod: %v":      "获取 pod 失败：%v",
+	"failed to add ephemeral container (requires Kubernetes 1.25+ and pods/ephemeralcontainers permission): %v": "添加临时容器失败（需要 Kubernetes 1.25+ 和 pods/ephemeralcontainers 权限）：%v",
+	"Started debug container %s (%s) in pod %s, waiting for it to run...":                                       "已在 pod %[3]s 中启动调试容器 %[1]s（%[2]s），等待其运行……",
+	"debug container %s did not start within 60 seconds":                                                        "调试容器 %s 在 60 秒内未启动",
+	"kubectl not found in PATH, attach manually: kubectl %s":                                                    "PATH 中找不到 kubectl，请手动连接：kubectl %s",
+
+	// endpoints.go
+	"failed to get pods: %v":                       "获取 pod 失败：%v",
+	"Service %s endpoints include all %d new pods": "Service %s 的 endpoints 已包含全部 %d 个新 pod",
+	"pods are ready but not receiving traffic: service %s endpoints are missing %s (check the service selector and ports)": "pod 已就绪但没有接收流量：service %s 的 endpoints 缺少 %s（请检查 service 的 selector 和端口）",
+	"failed to list endpoint slices for service %s: %v":                                                                    "列出 service %s 的 endpoint slice 失败：%v",
+	"failed to get ingress %s: %v":                "获取 ingress %s 失败：%v",
+	"ingress %s has no load balancer address yet": "ingress %s 还没有负载均衡地址",
+	"ingress %s (%s) is not answering: %v":        "ingress %s（%s）无响应：%v",
+	"ingress %s answered with status %d":          "ingress %s 返回状态码 %d",
+	"Ingress %s (%s) answered with status %d":     "Ingress %s（%s）返回状态码 %d",
+
+	// git.go
+	"branch %s does not exist on %s, push it first (git push -u %s %s)": "分支 %s 在 %s 上不存在，请先推送（git push -u %s %s）",
+	"failed to check branch %s on %s: %v":                               "检查 %[2]s 上的分支 %[1]s 失败：%[3]v",
+	"%d uncommitted change(s) in the working tree":                      "工作区有 %d 处未提交的修改",
+	"branch %s has no upstream, Jenkins may not be able to build it":    "分支 %s 没有上游分支，Jenkins 可能无法构建",
+	"%d commit(s) not pushed to %s":                                     "有 %d 个提交未推送到 %s",
+	"branch %s is not allowed to deploy to %s (allowed: %s)":            "分支 %s 不允许部署到 %s（允许：%s）",
+	"no branch detected (detached HEAD at %s), %s only allows: %s":      "未检测到分支（detached HEAD 位于 %s），%s 只允许：%s",
+	"%s, use --force to override":                                       "%s，使用 --force 强制部署",
+	"%s, overriding it with --force requires an interactive terminal":   "%s，使用 --force 强制部署需要交互终端",
+	"Type the env name (%s) to deploy anyway: ":                         "输入环境名（%s）以继续部署：",
+	"deploy not confirmed":                                              "部署未确认",
+	"Branch policy of %s overridden with --force":                       "已使用 --force 跳过 %s 的分支限制",
+	"failed to create git tag %s: %v: %s":                               "创建 git 标签 %s 失败：%v：%s",
+	"Tagged commit %s as %s":                                            "已将提交 %s 标记为 %s",
+	"failed to push git tag %s to %s: %v: %s":                           "推送 git 标签 %s 到 %s 失败：%v：%s",
+	"Pushed tag %s to %s":                                               "已推送标签 %s 到 %s",
+	"git %s: %v: %s":                                                    "git %s：%v：%s",
+	"%v: Jenkins builds the remote branch, not your local checkout":     "%v：Jenkins 构建的是远程分支，而不是本地检出的代码",
+
+	// groups.go
+	"project group %s not found in config":                                      "配置中没有项目组 %s",
+	"project group %s has no projects":                                          "项目组 %s 中没有项目",
+	"project %s is not in config":                                               "配置中没有项目 %s",
+	"project %s has no env %s":                                                  "项目 %s 没有环境 %s",
+	"cannot deploy group %s to %s: %s":                                          "无法将项目组 %s 部署到 %s：%s",
+	"Deploying group %s (%s) to %s with concurrency %d, logs in %s\n":           "正在将项目组 %[1]s（%[2]s）部署到 %[3]s，并发数 %[4]d，日志在 %[5]s\n",
+	"\nGroup %s to %s: %d succeeded, %d failed, %d skipped in %v\n":             "\n项目组 %s 部署到 %s：%d 个成功，%d 个失败，%d 个跳过，耗时 %v\n",
+	"%d of %d deploys of group %s did not succeed":                              "项目组 %[3]s 的 %[2]d 个部署中有 %[1]d 个未成功",
+	"usage: deploy run --group <group> <env> [--concurrency N] [--output json]": "用法：deploy run --group <项目组> <环境> [--concurrency N] [--output json]",
+
+	// history.go、metrics.go
+	"failed to sign deploy record: %v":                                        "签名部署记录失败：%v",
+	"failed to record deploy history: %v":                                     "记录部署历史失败：%v",
+	"failed to read deploy history: %v":                                       "读取部署历史失败：%v",
+	"No deploys found":                                                        "没有部署记录",
+	"--verify requires ledger.signing.allowed_signers in deploy_config.yaml":  "--verify 需要在 deploy_config.yaml 中配置 ledger.signing.allowed_signers",
+	"%d record(s) have invalid signatures":                                    "%d 条记录的签名无效",
+	"no shared ledger configured, add a ledger section to deploy_config.yaml": "没有配置共享账本，请在 deploy_config.yaml 中添加 ledger",
+	"invalid --until %q: %v":                                                  "无效的 --until %q：%v",
+	"invalid --since %q: %v":                                                  "无效的 --since %q：%v",
+	"--since must be before --until":                                          "--since 必须早于 --until",
+	"DORA metrics from %s to %s\n\n":                                          "%s 至 %s 的 DORA 指标\n\n",
+
+	// httpclient.go、timefmt.go、i18n.go
+	"invalid %s %q, expected a duration such as 30s":                                                  "无效的 %s %q，应为 30s 这样的时长",
+	"invalid time zone %q: %v":                                                                        "无效的时区 %q：%v",
+	"invalid time format %q: must be default, rfc3339 or relative":                                    "无效的时间格式 %q：必须是 default、rfc3339 或 relative",
+	"time zone of displayed timestamps: local (default), UTC or an IANA name such as Europe/Berlin":   "输出中时间的时区：local（默认）、UTC 或 IANA 时区名，如 Asia/Shanghai",
+	"format of displayed timestamps: default (2006-01-02 15:04:05), rfc3339 or relative":              "输出中时间的格式：default（2006-01-02 15:04:05）、rfc3339 或 relative",
+	"language of console output: en (default) or zh-CN, overrides DEPLOY_LANG and lang in the config": "控制台输出的语言：en（默认）或 zh-CN，覆盖 DEPLOY_LANG 环境变量和配置中的 lang",
+
+	// idempotency.go
+	"failed to record deployed commit on %s: %v": "在 %s 上记录已部署的提交失败：%v",
+
+	// imagepull.go
+	"Image pull failed for container %s of pod %s (%s): %s": "pod %[2]s 的容器 %[1]s 拉取镜像失败（%[3]s）：%[4]s",
+	"  Pull error: %s":                              "  拉取错误：%s",
+	"invalid image reference %q":                    "无效的镜像引用 %q",
+	"failed to get the password of registry %s: %v": "获取镜像仓库 %s 的密码失败：%v",
+	"unexpected HTTP %d from %s":                    "%[2]s 返回了意外的 HTTP %[1]d",
+	"registry %s returned no token realm":           "镜像仓库 %s 没有返回 token 地址",
+	"failed to get a token from %s: %v":             "从 %s 获取 token 失败：%v",
+	"invalid token response from %s: %v":            "%s 返回的 token 无效：%v",
+
+	// jenkinscache.go
+	"invalid jenkins_cache.ttl %q, expected a duration such as 5m": "无效的 jenkins_cache.ttl %q，应为时长，如 5m",
+	"job %s not found (HTTP %d at %s)":                             "找不到任务 %s（%[3]s 返回 HTTP %[2]d）",
+	"could not invoke job %q: %s":                                  "无法触发任务 %q：%s",
+	"no queue item location in the response of job %q":             "任务 %q 的响应中没有队列项地址",
+
+	// jobs.go
+	"k8s.namespace is required for job targets":                                        "Job 类型的环境必须配置 k8s.namespace",
+	"failed to get cronjob %s: %v":                                                     "获取 cronjob %s 失败：%v",
+	"Current cronjob %s images: %s":                                                    "cronjob %s 当前的镜像：%s",
+	"failed to build Jenkins job: %v":                                                  "构建 Jenkins 任务失败：%v",
+	"invalid k8s.job_timeout %q: %v":                                                   "无效的 k8s.job_timeout %q：%v",
+	"cronjob %s image was not updated by the build (still %s)":                         "构建没有更新 cronjob %s 的镜像（仍为 %s）",
+	"CronJob %s updated: %s -> %s":                                                     "CronJob %s 已更新：%s -> %s",
+	"Triggered job %s from cronjob %s":                                                 "已从 cronjob %[2]s 触发 job %[1]s",
+	"failed to create job from cronjob %s: %v":                                         "从 cronjob %s 创建 job 失败：%v",
+	"failed to get job %s: %v":                                                         "获取 job %s 失败：%v",
+	"job %s was created at %s, before this build: the Jenkins job did not recreate it": "job %s 创建于 %s，早于本次构建：Jenkins 任务没有重新创建它",
+	"Job %s: %s": "Job %s：%s",
+	"Job %s completed successfully! Run time: %v": "Job %s 执行成功！运行时间：%v",
+	"job %s failed: %s (%s)":                      "job %s 失败：%s（%s）",
+	"job %s did not complete within %v":           "job %s 在 %v 内未完成",
+	"Job pod: %s, status: %s, message: %s":        "Job pod：%s，状态：%s，信息：%s",
+
+	// k8sauth.go、k8sclient.go、k8sretry.go
+	"kubeconfig user %s uses exec credential plugin %q which was not found in PATH: %s":                                                               "kubeconfig 用户 %s 使用的凭证插件 %q 不在 PATH 中：%s",
+	"kubeconfig user %s uses the removed gcp auth provider: install gke-gcloud-auth-plugin and run `gcloud container clusters get-credentials` again": "kubeconfig 用户 %s 使用了已移除的 gcp 认证方式：请安装 gke-gcloud-auth-plugin 并重新运行 `gcloud container clusters get-credentials`",
+	"kubeconfig user %s uses the removed azure auth provider: install kubelogin and run `kubelogin convert-kubeconfig`":                               "kubeconfig 用户 %s 使用了已移除的 azure 认证方式：请安装 kubelogin 并运行 `kubelogin convert-kubeconfig`",
+	"username to impersonate for Kubernetes operations, overrides k8s.as":                                                                             "以该用户身份访问 Kubernetes，覆盖 k8s.as",
+	"group to impersonate for Kubernetes operations, can be repeated, overrides k8s.as_groups":                                                        "以该用户组身份访问 Kubernetes，可以重复，覆盖 k8s.as_groups",
+	"invalid k8s.timeout %q: %v":             "无效的 k8s.timeout %q：%v",
+	"failed to create kubernetes client: %v": "创建 kubernetes 客户端失败：%v",
+	"in_cluster is enabled but in-cluster config is unavailable (is the tool running in a pod with a mounted service account token?): %v": "已启用 in_cluster，但无法获取集群内配置（是否在挂载了 service account token 的 pod 中运行？）：%v",
+	"failed to build config from flags: %v":                     "创建集群配置失败：%v",
+	"failed to get k8s config: %v":                              "获取 k8s 配置失败：%v",
+	"Transient error on %s (attempt %d/%d), retrying in %v: %v": "%s 临时错误（第 %d/%d 次），%v 后重试：%v",
+
+	// keychain.go
+	"deploy login requires an interactive terminal": "deploy login 需要交互终端",
+	"Jenkins username [%s]: ":                       "Jenkins 用户名 [%s]：",
+	"Jenkins username: ":                            "Jenkins 用户名：",
+	"username is required":                          "用户名不能为空",
+	"API token is required":                         "API token 不能为空",
+	"failed to log in to %s: %v":                    "登录 %s 失败：%v",
+	"Jenkins credentials for %s saved to the %s\n":  "%s 的 Jenkins 凭证已保存到%s\n",
+	"Note: api_token in the config file takes precedence, remove it to use the saved token": "注意：配置文件中的 api_token 优先，删除它才会使用保存的 token",
+	"Saved, reference it in the config as \"keychain:%s\"\n":                                "已保存，在配置中用 \"keychain:%s\" 引用\n",
+	"no api_token in the config and none in the %s, run deploy login: %v":                   "配置中没有 api_token，%s中也没有，请运行 deploy login：%v",
+	"failed to read input: %v":            "读取输入失败：%v",
+	"failed to save %s to the %s: %v: %s": "保存 %s 到%s失败：%v：%s",
+	"failed to save %s to the %s: %s":     "保存 %s 到%s失败：%s",
+	"%s not found in the %s":              "%[2]s中找不到 %[1]s",
+
+	// ledger.go
+	"http ledger: invalid response: %v": "http 账本：无效的响应：%v",
+	"s3 ledger: aws %s: %v: %s":         "s3 账本：aws %s：%v：%s",
+	"postgres ledger: psql: %v: %s":     "postgres 账本：psql：%v：%s",
+	"http ledger: %v":                   "http 账本：%v",
+
+	// lock.go
+	"invalid lock ttl %q, expected a positive duration such as 2h":                           "无效的锁 ttl %q，需要正的时长，如 2h",
+	"failed to renew deploy lock %s: %v":                                                     "部署锁 %s 续期失败：%v",
+	"Deploy lock %s was force released or taken over by another deploy, stopping the deploy": "部署锁 %s 已被强制释放或被其他部署接管，停止部署",
+	"%w, the deploy was stopped so it does not race the new holder (%v)":                     "%w，部署已停止，避免与新的持有者同时部署（%v）",
+	"s3 lock: no ETag in get-object output":                                                  "s3 锁：get-object 输出中没有 ETag",
+	"redis lock: invalid lock value: %v":                                                     "redis 锁：无效的锁内容：%v",
+	"redis lock: lock key keeps being recreated":                                             "redis 锁：锁键被反复重新创建",
+	"Force releasing deploy lock of %s/%s":                                                   "强制释放 %s/%s 的部署锁",
+	"failed to force release deploy lock: %w":                                                "强制释放部署锁失败：%w",
+	"failed to acquire deploy lock: %w":                                                      "获取部署锁失败：%w",
+	"%w: %s is being deployed by %s, use --force-unlock if the lock is stuck":                "%w：%s 正在被 %s 部署，如果锁没有正常释放，使用 --force-unlock",
+	"Acquired deploy lock of %s/%s":                                                          "已获取 %s/%s 的部署锁",
+	"failed to release deploy lock: %v":                                                      "释放部署锁失败：%v",
+	"lease lock requires k8s.namespace":                                                      "lease 锁需要配置 k8s.namespace",
+	"s3 lock requires bucket":                                                                "s3 锁需要配置 bucket",
+	"redis lock requires redis_addr":                                                         "redis 锁需要配置 redis_addr",
+	"unknown lock backend %q: must be lease, file, s3 or redis":                              "未知的锁类型 %q：必须是 lease、file、s3 或 redis",
+	"invalid lock file %s: %v":                                                               "无效的锁文件 %s：%v",
+	"lock file %s keeps being recreated":                                                     "锁文件 %s 不断被重新创建",
+	"s3 lock: aws s3api %s: %v: %s":                                                          "s3 锁：aws s3api %s：%v：%s",
+	"s3 lock: invalid lock object: %v":                                                       "s3 锁：无效的锁对象：%v",
+	"s3 lock: lock object keeps being recreated":                                             "s3 锁：锁对象不断被重新创建",
+	"redis lock: %v":     "redis 锁：%v",
+	"redis lock: %s: %v": "redis 锁：%s：%v",
+	"release a deploy lock held by someone else (e.g. left by a crashed deploy) before deploying": "部署前释放别人持有的部署锁（如崩溃的部署遗留的锁）",
+	"empty reply":          "空回复",
+	"invalid reply %q":     "无效的回复 %q",
+	"unsupported reply %q": "不支持的回复 %q",
+
+	// logging.go
+	"invalid --log-level %q: %v":                                             "无效的 --log-level %q：%v",
+	"invalid --log-format %q: must be text or json":                          "无效的 --log-format %q：必须是 text 或 json",
+	"failed to open log file: %v":                                            "打开日志文件失败：%v",
+	"terminal log format: text or json":                                      "终端日志格式：text 或 json",
+	"terminal log level: debug, info, warn or error":                         "终端日志级别：debug、info、warn 或 error",
+	"also write full debug-level output, including build logs, to this file": "同时将完整的 debug 级别输出（包括构建日志）写入该文件",
+
+	// main.go
+	"failed to load config: %v":                       "加载配置失败：%v",
+	"Deploy did not finish within %v (--timeout): %w": "部署未在 %v 内完成（--timeout）：%w",
+	"project: %s, env: %s":                            "项目：%s，环境：%s",
+	"Deploying is not allowed in read-only mode, only history, audit, metrics and run list are available (use --simulate to try a deploy)": "只读模式下不允许部署，只能使用 history、audit、metrics 和 run list（可以用 --simulate 试运行部署）",
+	"Detected project %s from its path in the repository": "根据仓库中的路径识别为项目 %s",
+	"No env given and no branch_envs rule of %s matches branch %s: usage: deploy <env-name>, available envs: %s (run deploy help for all commands)": "没有指定环境，%s 的 branch_envs 中也没有匹配分支 %s 的规则：用法：deploy <环境名>，可用环境：%s（运行 deploy help 查看全部命令）",
+	"No env given, deploying branch %s to %s":                                                  "没有指定环境，将分支 %s 部署到 %s",
+	"Failed to detect namespace: %v":                                                           "检测 namespace 失败：%v",
+	"Using in-cluster namespace: %s":                                                           "使用集群内的 namespace：%s",
+	"Failed to resolve blue/green target: %v":                                                  "确定蓝绿部署目标失败：%v",
+	"Blue/green: %s (%s) is live, deploying to idle %s (%s)":                                   "蓝绿部署：%s（%s）正在服务，部署到空闲的 %s（%s）",
+	"Env %s cannot use blue_green and canary at the same time":                                 "环境 %s 不能同时使用 blue_green 和 canary",
+	"Canary configuration incomplete: k8s.canary.deployment is required":                       "金丝雀配置不完整：必须配置 k8s.canary.deployment",
+	"No successful deploy of %s found to promote":                                              "没有找到 %s 的成功部署，无法晋级",
+	"Promoting build #%d (commit %s) from %s":                                                  "晋级 %[3]s 的构建 #%[1]d（提交 %[2]s）",
+	"%s/%s already runs commit %s (from %s), skipping deploy, use --force to deploy anyway":    "%s/%s 已经运行提交 %s（来自 %s），跳过部署，使用 --force 强制部署",
+	"%s has no changes since the last deploy to %s (commit %s), the deploy may be unnecessary": "%s 自上次部署到 %s（提交 %s）以来没有变化，可能不需要部署",
+	"Deploy aborted by pre-deploy plugin: %v":                                                  "部署被 pre-deploy 插件中止：%v",
+	"Failed to get Jenkins credentials from plugin: %v":                                        "从插件获取 Jenkins 凭证失败：%v",
+	"Failed to get Jenkins credentials: %v":                                                    "获取 Jenkins 凭证失败：%v",
+	"Failed to connect to Jenkins: %v":                                                         "连接 Jenkins 失败：%v",
+	"Successfully connected to Jenkins":                                                        "已连接 Jenkins",
+	"Failed to verify job deployment: %v":                                                      "校验 Job 部署失败：%v",
+	"Deploy failed by post-rollout plugin: %v":                                                 "部署被 post-rollout 插件判定为失败：%v",
+	"K8s deployment configuration incomplete: namespace=%s, deployment=%s":                     "K8s 部署配置不完整：namespace=%s，deployment=%s",
+	"Preflight check failed: %w":                                                               "预检失败：%w",
+	"Failed to get current deployment status: %v":                                              "获取当前 deployment 状态失败：%v",
+	"Current deployment revision: %s, found %d pods":                                           "当前 deployment 版本：%s，共 %d 个 pod",
+	"Failed to snapshot config: %v":                                                            "记录配置快照失败：%v",
+	"Failed to build Jenkins job: %v":                                                          "构建 Jenkins 任务失败：%v",
+	"Deploy aborted by post-build plugin: %v":                                                  "部署被 post-build 插件中止：%v",
+	"Config verification failed: %v":                                                           "配置校验失败：%v",
+	"Aborted pod rollout monitoring: %w":                                                       "已中止 pod 滚动监控：%w",
+	"Failed to monitor pod rollout: %w":                                                        "pod 滚动监控失败：%w",
+	"Smoke checks failed, traffic stays on %s: %v":                                             "冒烟检查失败，流量保持在 %s：%v",
+	"Smoke checks failed: %v":                                                                  "冒烟检查失败：%v",
+	"Failed to shift traffic: %v":                                                              "切换流量失败：%v",
+	"Failed to switch blue/green traffic: %v":                                                  "切换蓝绿流量失败：%v",
+	"Previous color %s (%s) is kept running for instant rollback: kubectl patch service %s -n %s -p '{\"spec\":{\"selector\":{\"%s\":\"%s\"}}}'": "之前的颜色 %s（%s）保持运行，可以立即回滚：kubectl patch service %s -n %s -p '{\"spec\":{\"selector\":{\"%s\":\"%s\"}}}'",
+	"Traffic readiness check failed: %v":                                                                "流量就绪检查失败：%v",
+	"failed to get branch for param %s: not in a git repository, use --branch to specify it":            "获取参数 %s 的分支失败：不在 git 仓库中，请用 --branch 指定",
+	"failed to resolve %s for param %s: not available in the current git repository":                    "解析参数 %[2]s 的 %[1]s 失败：当前 git 仓库中无法获取",
+	"failed to resolve param %s: %v":                                                                    "解析参数 %s 失败：%v",
+	"Using branch %s from %s":                                                                           "使用来自 %[2]s 的分支 %[1]s",
+	"Starting Jenkins build job: %s":                                                                    "开始构建 Jenkins 任务：%s",
+	"failed to get job: %v":                                                                             "获取任务失败：%v",
+	"failed to trigger build: %v":                                                                       "触发构建失败：%v",
+	"Build triggered with queue ID: %d":                                                                 "已触发构建，队列 ID：%d",
+	"failed to get build: %w":                                                                           "获取构建失败：%w",
+	"Build #%d started after waiting %v in the Jenkins queue":                                           "构建 #%d 在 Jenkins 队列中等待 %v 后开始",
+	"Estimated build duration: %v (from the last successful build)":                                     "预计构建时长：%v（根据最近一次成功的构建）",
+	"Jenkins build completed successfully! Queue wait: %v, total: %v":                                   "Jenkins 构建成功！排队：%v，总计：%v",
+	"Jenkins build failed after %v (queue wait %v)":                                                     "Jenkins 构建失败，耗时 %v（排队 %v）",
+	"build failed: %s":                                                                                  "构建失败：%s",
+	"failed to poll build: %v":                                                                          "查询构建状态失败：%v",
+	"Build is taking longer than 30 seconds. Showing real-time logs:":                                   "构建超过 30 秒，显示实时日志：",
+	"Build #%d succeeded, build execution: %v":                                                          "构建 #%d 成功，执行时间：%v",
+	"=============Build Failed Log=============":                                                        "=============构建失败日志=============",
+	"Build #%d failed, build execution: %v":                                                             "构建 #%d 失败，执行时间：%v",
+	"Starting pod rollout monitoring for deployment %s in namespace %s...":                              "开始监控 namespace %[2]s 中 deployment %[1]s 的 pod 滚动更新……",
+	"Monitoring rollout from revision: %s, found %d initial pods":                                       "从版本 %s 开始监控滚动更新，初始共 %d 个 pod",
+	"Rollout strategy: %s":                                                                              "滚动策略：%s",
+	"failed to check HorizontalPodAutoscalers: %v":                                                      "检查 HorizontalPodAutoscaler 失败：%v",
+	"Deployment is managed by HPA %s (min=%d, max=%d), desired replicas will be tracked on every check": "Deployment 由 HPA %s 管理（min=%d，max=%d），每次检查时跟踪期望副本数",
+	"deployment %s is paused and the rollout will not progress: run `kubectl rollout resume deployment/%s -n %s` or set k8s.resume_paused: true": "deployment %s 已暂停，滚动更新不会进行：请运行 `kubectl rollout resume deployment/%s -n %s` 或配置 k8s.resume_paused: true",
+	"Deployment %s is paused, resuming it (k8s.resume_paused: true)":                                                                             "Deployment %s 已暂停，恢复滚动更新（k8s.resume_paused: true）",
+	"failed to resume paused deployment: %v":                                                                                                     "恢复已暂停的 deployment 失败：%v",
+	"Deployment %s is scaled to 0 replicas, skipping pod rollout monitoring (set k8s.zero_replicas: wait to wait for scale-up)":                  "Deployment %s 的副本数为 0，跳过 pod 滚动监控（配置 k8s.zero_replicas: wait 可以等待扩容）",
+	"Deployment %s is scaled to 0 replicas, waiting for scale-up...":                                                                             "Deployment %s 的副本数为 0，等待扩容……",
+	"rollout timed out after %d attempts, %s":                                                                                                    "滚动更新在 %d 次检查后超时，%s",
+	"rollout timed out after %d attempts":                                                                                                        "滚动更新在 %d 次检查后超时",
+	"Deployment revision advanced to %s":                                                                                                         "Deployment 版本已更新到 %s",
+	"deployment revision advanced from %s to %s while monitoring, the observed rollout is not ours":                                              "监控期间 deployment 版本从 %s 变为 %s，当前的滚动更新不是本次部署触发的",
+	"%w: revision advanced from %s to %s":                                                                                                        "%w：版本从 %s 变为 %s",
+	"Desired replicas changed from %d to %d (%s)":                                                                                                "期望副本数从 %d 变为 %d（%s）",
+	"deployment %s was paused during the rollout, progress will stall until it is resumed":                                                       "deployment %s 在滚动更新期间被暂停，恢复之前不会继续",
+	"Deployment still has 0 desired replicas, waiting for scale-up":                                                                              "Deployment 的期望副本数仍为 0，等待扩容",
+	"Pod status: %d/%d new pods ready (%d available after minReadySeconds=%d), %d old pods remaining":                                            "Pod 状态：%d/%d 个新 pod 就绪（%d 个已过 minReadySeconds=%d 可用），剩余 %d 个旧 pod",
+	"Pod status: %d/%d new pods ready, %d old pods remaining":                                                                                    "Pod 状态：%d/%d 个新 pod 就绪，剩余 %d 个旧 pod",
+	"only %d pods ready, below the %d guaranteed by maxUnavailable=%d":                                                                           "只有 %d 个 pod 就绪，低于 maxUnavailable=%[3]d 保证的 %[2]d 个",
+	"Rollout stalled with %d old pods remaining, %s":                                                                                             "滚动更新停滞，剩余 %d 个旧 pod，%s",
+	"New pod %s not ready: Phase=%s, Ready=%v, ContainerReady=%v":                                                                                "新 pod %s 未就绪：Phase=%s，Ready=%v，ContainerReady=%v",
+	"Pod %s waiting on readiness gates: %s":                                                                                                      "Pod %s 等待 readiness gate：%s",
+	"Waiting: %s (%s)":                                                                                                                           "等待中：%s（%s）",
+	"Terminated: %s (%s)":                                                                                                                        "已终止：%s（%s）",
+	"Container %s not ready: %s, RestartCount=%d":                                                                                                "容器 %s 未就绪：%s，RestartCount=%d",
+	"All pods ready, waiting additional %v to ensure stability...":                                                                               "所有 pod 已就绪，再等待 %v 确认稳定……",
+	"failed to get deployment during final check: %v":                                                                                            "最终检查时获取 deployment 失败：%v",
+	"failed to get pods during final check: %v":                                                                                                  "最终检查时获取 pod 失败：%v",
+	"K8s rollout completed successfully! Rollout time: %v (stability wait %v)":                                                                   "K8s 滚动更新成功！滚动时间：%v（稳定等待 %v）",
+	"Success criteria met with %d/%d new pods available and %d old pods remaining, the rest of the rollout continues in the cluster: kubectl rollout status deployment/%s -n %s": "已满足成功条件：%d/%d 个新 pod 可用，剩余 %d 个旧 pod，其余的滚动更新在集群中继续：kubectl rollout status deployment/%s -n %s",
+	"Pods became unhealthy during stability check, continuing to monitor":                                                                                                        "稳定检查期间 pod 变为不健康，继续监控",
+	"Problem pod: %s on node %s, status: %s, message: %s":                                                                                                                        "异常 pod：%s（节点 %s），状态：%s，信息：%s",
+	"K8s rollout failed after %v - new pods are not becoming ready (failure class: %s)%s":                                                                                        "K8s 滚动更新失败，耗时 %v - 新 pod 无法就绪（失败类型：%s）%s",
+	"K8s rollout failed after %v - new pods are not becoming ready%s":                                                                                                            "K8s 滚动更新失败，耗时 %v - 新 pod 无法就绪%s",
+	"waiting on PDB %s, disruptionsAllowed=0 (currentHealthy=%d, desiredHealthy=%d)":                                                                                             "等待 PDB %s，disruptionsAllowed=0（currentHealthy=%d，desiredHealthy=%d）",
+	"deployment has no selector labels for pod selection":                                                                                                                        "deployment 没有用于选择 pod 的 selector 标签",
+	"Container %s in pod %s was OOMKilled: memory request=%s, memory limit=%s, RestartCount=%d":                                                                                  "pod %[2]s 中的容器 %[1]s 因内存不足被终止（OOMKilled）：内存 request=%[3]s，limit=%[4]s，RestartCount=%[5]d",
+	"k8s.namespace is not configured and the service account namespace is unavailable: %v":                                                                                       "没有配置 k8s.namespace，也无法获取 service account 的 namespace：%v",
+	"%w (make sure you are logged in to your cloud provider, e.g. `aws sso login`, `gcloud auth login` or `az login`)":                                                           "%w（请确认已登录云服务商，如 `aws sso login`、`gcloud auth login` 或 `az login`）",
+	"Kubernetes API server rejected the credentials: %v":                                                                                                                         "Kubernetes API server 拒绝了凭证：%v",
+	"cannot reach Kubernetes API server: %v (check k8s.config_path, current context and network/VPN access)":                                                                     "无法连接 Kubernetes API server：%v（请检查 k8s.config_path、当前 context 以及网络/VPN）",
+	"failed to check permission to %s %s: %v":                                                                                                                                    "检查 %s %s 的权限失败：%v",
+	"current credentials cannot %s %s in namespace %s: ask a cluster admin to grant this permission or use a kubeconfig with access":                                             "当前凭证无法在 namespace %[3]s 中 %[1]s %[2]s：请联系集群管理员授权，或使用有权限的 kubeconfig",
+	"namespace %s does not exist in the cluster: check k8s.namespace in deploy_config.yaml":                                                                                      "集群中不存在 namespace %s：请检查 deploy_config.yaml 中的 k8s.namespace",
+	"failed to get namespace %s: %v": "获取 namespace %s 失败：%v",
+	"deployment %s does not exist in namespace %s: check k8s.deployment in deploy_config.yaml": "namespace %[2]s 中不存在 deployment %[1]s：请检查 deploy_config.yaml 中的 k8s.deployment",
+	"failed to get deployment %s: %v":                                                         "获取 deployment %s 失败：%v",
+	"unable to determine deployment revision":                                                 "无法确定 deployment 版本",
+	"failed to get initial pods: %v":                                                          "获取初始 pod 失败：%v",
+	"offer to attach an ephemeral debug container to crash-looping pods":                      "对反复崩溃的 pod 提供附加临时调试容器的选项",
+	"also write a JSON summary of the run, including the failure reason, to this file for CI": "同时将本次运行的 JSON 汇总（包括失败原因）写入该文件，供 CI 使用",
+	"truncate build log lines in the terminal to this many columns, 0 to use the terminal width (no truncation when not a terminal)":                        "终端中构建日志每行最多显示的列数，超出部分截断，0 表示使用终端宽度（输出不是终端时不截断）",
+	"do not truncate long build log lines in the terminal":                                                                                                  "终端中不截断构建日志的长行",
+	"format of the final deploy summary: text or json":                                                                                                      "部署总结的格式：text 或 json",
+	"ring the terminal bell when the deploy finishes":                                                                                                       "部署结束时终端响铃",
+	"shell command to run when the deploy finishes, with DEPLOY_RESULT, DEPLOY_PROJECT, DEPLOY_ENV, DEPLOY_DURATION, DEPLOY_BUILD_URL and DEPLOY_ERROR set": "部署结束时执行的 shell 命令，可以使用 DEPLOY_RESULT、DEPLOY_PROJECT、DEPLOY_ENV、DEPLOY_DURATION、DEPLOY_BUILD_URL 和 DEPLOY_ERROR 环境变量",
+	"do not show a desktop notification when the deploy finishes":                                                                                           "部署结束时不显示桌面通知",
+	"promote the build last successfully deployed to this env, exposing $promoted_commit, $promoted_build and $promoted_image to params":                    "晋级该环境最近一次成功部署的构建，参数中可以使用 $promoted_commit、$promoted_build 和 $promoted_image",
+	", %v": "，%v",
+
+	// replicasets.go
+	"after a successful rollout, delete old ReplicaSets scaled to 0 beyond revisionHistoryLimit and stale ones not owned by the deployment": "滚动成功后删除超出 revisionHistoryLimit 以及不属于 deployment 的已缩容到 0 的旧 ReplicaSet",
+	"failed to check old ReplicaSets: %v": "检查旧 ReplicaSet 失败：%v",
+	"Deployment %s has %d old ReplicaSets (revisionHistoryLimit=%d) and %d ReplicaSets not owned by any controller":                                              "Deployment %s 有 %d 个旧 ReplicaSet（revisionHistoryLimit=%d），另有 %d 个不属于任何控制器的 ReplicaSet",
+	"%d ReplicaSets of %s are beyond revisionHistoryLimit or not owned by the deployment, use --prune-replicasets or k8s.prune_replicasets: true to delete them": "%[2]s 的 %[1]d 个 ReplicaSet 超出 revisionHistoryLimit 或不属于 deployment，使用 --prune-replicasets 或配置 k8s.prune_replicasets: true 删除",
+	"failed to delete ReplicaSet %s: %v":      "删除 ReplicaSet %s 失败：%v",
+	"Pruned %d of %d stale ReplicaSets of %s": "已清理 %[3]s 的 %[2]d 个遗留 ReplicaSet 中的 %[1]d 个",
+
+	// podtable.go
+	"print pod status line by line instead of a table refreshed on each rollout check": "按行输出 pod 状态，不使用每次滚动检查时刷新的表格",
+	" (old)": "（旧）",
+
+	// events.go
+	"also write deploy events (phase changes, log messages, build output, pod updates, result) as JSON lines to this file": "同时将部署事件（阶段变化、日志、构建输出、pod 状态、结果）以 JSON Lines 写入该文件",
+	"failed to open events file: %v": "打开事件文件失败：%v",
+
+	// followup.go
+	"abort the Jenkins build if the deploy fails while its follow-up stages are still running (requires monitor_after)":                                             "部署失败时中止后续阶段仍在运行的 Jenkins 构建（需要配置 monitor_after）",
+	"Found %q in the log of build #%d, monitoring the rollout while the build continues":                                                                            "构建 #%[2]d 的日志中出现 %[1]q，在构建继续运行的同时监控滚动",
+	"waiting for the remaining stages of Jenkins build #%d":                                                                                                         "等待 Jenkins 构建 #%d 的其余阶段",
+	"Rollout finished, waiting for the remaining stages of Jenkins build #%d...":                                                                                    "滚动已完成，等待 Jenkins 构建 #%d 的其余阶段...",
+	"Jenkins build #%d failed after the rollout: %s (%s)":                                                                                                           "Jenkins 构建 #%d 在滚动之后失败：%s（%s）",
+	"Jenkins build #%d is still running its follow-up stages after the deploy failed, use --abort-build-on-failure or abort_build_on_failure: true to abort it: %s": "部署失败后 Jenkins 构建 #%d 的后续阶段仍在运行，可以使用 --abort-build-on-failure 或配置 abort_build_on_failure: true 中止：%s",
+	"Aborted Jenkins build #%d because the deploy failed while its follow-up stages were running: %s":                                                               "部署失败时 Jenkins 构建 #%d 的后续阶段仍在运行，已中止该构建：%s",
+
+	// imagediff.go
+	"failed to compare images of old and new pods: %v":                                           "对比新旧 pod 的镜像失败：%v",
+	"Images of old pods -> new pods:":                                                            "旧 pod -> 新 pod 的镜像：",
+	"new pods use the same images as the old pods, the build may not have updated the image tag": "新 pod 使用的镜像与旧 pod 相同，构建可能没有更新镜像 tag",
+
+	// nodes.go
+	"Node %s hosts problem pods %s (node conditions unavailable: %v)":                                                               "节点 %s 上有异常 pod %s（无法获取节点状况：%v）",
+	"node %s hosting problem pods %s is unhealthy: %s":                                                                              "异常 pod %[2]s 所在的节点 %[1]s 状况异常：%[3]s",
+	"Node %s hosts problem pods %s, node conditions are healthy":                                                                    "节点 %s 上有异常 pod %s，节点状况正常",
+	"all problem pods are on node %s while new pods on other nodes are ready, the node is a likely cause: kubectl describe node %s": "所有异常 pod 都在节点 %s 上，而其他节点上的新 pod 已就绪，该节点可能是原因：kubectl describe node %s",
+	"unhealthy nodes: %s": "异常节点：%s",
+
+	// notify*.go
+	"failed to send %s notification: %v":                    "发送 %s 通知失败：%v",
+	"Updated Jira issues: %s":                               "已更新 Jira 问题：%s",
+	"Jira issue %s has no transition %q available, skipped": "Jira 问题 %s 没有可用的状态流转 %q，已跳过",
+	"failed to comment: %v":                                 "添加评论失败：%v",
+	"failed to get transitions: %v":                         "获取状态流转失败：%v",
+	"invalid transitions response: %v":                      "无效的状态流转响应：%v",
+	"failed to transition to %q: %v":                        "流转到 %q 失败：%v",
+	"email: host, from and to are required":                 "email：必须配置 host、from 和 to",
+	"slack: webhook_url or token + channel is required":     "slack：必须配置 webhook_url，或 token 和 channel",
+	"github: cannot determine repository from origin remote, set notifications.github.repository": "github：无法从 origin 远程仓库确定仓库，请配置 notifications.github.repository",
+	"status %d: %s":                  "状态码 %d：%s",
+	"newrelic: %v":                   "newrelic：%v",
+	"newrelic: invalid response: %v": "newrelic：无效的响应：%v",
+	"newrelic: %s":                   "newrelic：%s",
+	"datadog: %v":                    "datadog：%v",
+	"on_finish: %v: %s":              "on_finish：%v：%s",
+	"desktop: %v: %s":                "桌面通知：%v：%s",
+	"github: failed to list pull requests: %v":           "github：获取 pull request 列表失败：%v",
+	"github: invalid pull requests response: %v":         "github：无效的 pull request 响应：%v",
+	"github: failed to list comments: %v":                "github：获取评论列表失败：%v",
+	"github: invalid comments response: %v":              "github：无效的评论响应：%v",
+	"github: failed to update comment: %v":               "github：更新评论失败：%v",
+	"github: failed to create comment: %v":               "github：创建评论失败：%v",
+	"gitlab: failed to get commit sha":                   "gitlab：获取提交 sha 失败",
+	"gitlab: invalid response: %v":                       "gitlab：无效的响应：%v",
+	"gitlab: %v":                                         "gitlab：%v",
+	"grafana: %v":                                        "grafana：%v",
+	"grafana: invalid response: %v":                      "grafana：无效的响应：%v",
+	"%s: errcode %d: %s":                                 "%s：错误码 %d：%s",
+	"%s: invalid response: %v":                           "%s：无效的响应：%v",
+	"opsgenie: %v":                                       "opsgenie：%v",
+	"pagerduty: %v":                                      "pagerduty：%v",
+	"jira: %s":                                           "jira：%s",
+	"sentry: failed to get commit sha":                   "sentry：获取提交 sha 失败",
+	"sentry: failed to create release %s: %v":            "sentry：创建 release %s 失败：%v",
+	"sentry: failed to create deploy for release %s: %v": "sentry：为 release %s 创建部署失败：%v",
+	"slack: invalid response: %v":                        "slack：无效的响应：%v",
+	"slack: %s":                                          "slack：%s",
+	"telegram: %v":                                       "telegram：%v",
+	"telegram: invalid response: %v":                     "telegram：无效的响应：%v",
+	"telegram: %s":                                       "telegram：%s",
+
+	// oidc.go
+	"invalid token: %v":                      "无效的 token：%v",
+	"invalid token claims: %v":               "无效的 token 声明：%v",
+	"token has no %s claim":                  "token 没有 %s 声明",
+	"token email %s is not verified":         "token 中的邮箱 %s 未经验证",
+	"identity provider %s is unavailable":    "身份提供方 %s 不可用",
+	"failed to get signing keys from %s: %v": "从 %s 获取签名密钥失败：%v",
+
+	// pipeline.go
+	"run deploy promote from the %s project directory, current directory is %s": "请在 %s 项目目录中运行 deploy promote，当前目录是 %s",
+	"no pipeline configured for project %s":                                     "项目 %s 没有配置流水线",
+	"pipeline of project %s needs at least two stages":                          "项目 %s 的流水线至少需要两个阶段",
+	"All stages of %s are up to date with %s\n":                                 "%s 的所有阶段都已与 %s 一致\n",
+	"Promoting %s from %s to %s: commit %s, build #%d, finished %s":             "将 %s 从 %s 晋级到 %s：提交 %s，构建 #%d，完成于 %s",
+	"gate for %s not passed: %v":                                                "%s 的晋级条件未通过：%v",
+	"deploy to %s failed: %v":                                                   "部署到 %s 失败：%v",
+	"%s has no successful deploy to promote, deploy it first":                   "%s 没有可以晋级的成功部署，请先部署",
+	"env %s is not a promotion stage of the pipeline":                           "环境 %s 不是流水线的晋级阶段",
+	"invalid wait %q: %v":                                                       "无效的等待时间 %q：%v",
+	"Gate: %s must run for %v before promotion, waiting %v...":                  "晋级条件：%s 必须运行 %v 后才能晋级，等待 %v……",
+	"Gate: %s has been running for %v":                                          "晋级条件：%s 已运行 %v",
+	"Gate: running smoke checks against %s...":                                  "晋级条件：对 %s 运行冒烟检查……",
+	"manual confirmation requires an interactive terminal, use --yes to skip":   "人工确认需要交互终端，使用 --yes 跳过确认",
+	"Promote commit %s from %s to %s? [y/N] ":                                   "将提交 %s 从 %s 晋级到 %s？[y/N] ",
+	"promotion not confirmed":                                                   "晋级未确认",
+
+	// plugin.go、policy.go
+	"Skipping plugin %s: %v":                                               "跳过插件 %s：%v",
+	"Plugin %s set build parameter %s":                                     "插件 %s 设置了构建参数 %s",
+	"policy denied: %s is not allowed to deploy %s/%s (allowed_users: %s)": "策略拒绝：%s 不允许部署 %s/%s（allowed_users：%s）",
+	"%s %s: %v":                   "%s %s：%v",
+	"%s %s: invalid response: %v": "%s %s：无效的响应：%v",
+	"%s: %s":                      "%s：%s",
+
+	// queuewait.go
+	"give up when the Jenkins build is still queued after this long and report the queue congestion, 0 to wait indefinitely (overrides jenkins_queue.timeout)": "Jenkins 构建排队超过该时长时放弃等待并报告队列拥堵，0 表示一直等待（覆盖 jenkins_queue.timeout）",
+	"cancel the queued Jenkins build when --queue-timeout expires":                                                                                             "--queue-timeout 到期时取消排队中的 Jenkins 构建",
+	"invalid jenkins_queue.timeout %q, expected a duration such as 15m":                                                                                        "无效的 jenkins_queue.timeout %q，应为时长，如 15m",
+	"%w after %v (queue ID %d), failed to get the queue item: %v":                                                                                              "%[1]w，已等待 %[2]v（队列 ID %[3]d），获取队列项失败：%[4]v",
+	"Jenkins queue is congested: %d items queued, build waiting %v because: %s":                                                                                "Jenkins 队列拥堵：共 %d 项排队，构建已等待 %v，原因：%s",
+	"%w after %v (queue ID %d): %s; the build is left in the queue, use deploy resume to keep waiting or --cancel-on-queue-timeout to cancel it":               "%[1]w，已等待 %[2]v（队列 ID %[3]d）：%[4]s；构建仍保留在队列中，可以使用 deploy resume 继续等待，或使用 --cancel-on-queue-timeout 取消",
+	"%w after %v (queue ID %d): %s": "%[1]w，已等待 %[2]v（队列 ID %[3]d）：%[4]s",
+
+	// queue.go、readonly.go
+	"%s. Queue this deploy to start when it finishes? [y/N] ":                                             "%s。排队等其结束后开始本次部署？[y/N] ",
+	"%s/%s is being deployed by process %d since %s":                                                      "%s/%s 正在被进程 %d 部署，开始于 %s",
+	"%s, use --queue to wait for it":                                                                      "%s，使用 --queue 排队等待",
+	"Queued: waiting for process %d to finish deploying %s/%s...":                                         "排队中：等待进程 %d 完成 %s/%s 的部署……",
+	"Previous deploy finished after %v in queue, starting":                                                "上一个部署已结束，排队 %v，开始部署",
+	"%w, or use --queue to wait for it":                                                                   "%w，或使用 --queue 排队等待",
+	"Queued: waiting for the deploy lock of %s/%s...":                                                     "排队中：等待 %s/%s 的部署锁……",
+	"if the env is being deployed, wait for that deploy to finish and then start, without asking":         "环境正在部署时，不询问直接排队，等其结束后开始",
+	"deploy %s is not allowed in read-only mode, only history, audit, metrics and run list are available": "只读模式下不允许 deploy %s，只能使用 history、audit、metrics 和 run list",
+	"only allow commands that do not change anything (history, audit, metrics, run list)":                 "只允许不会修改任何内容的命令（history、audit、metrics、run list）",
+
+	// resume.go
+	"failed to save deploy state, it cannot be resumed if interrupted: %v": "保存部署状态失败，中断后将无法恢复：%v",
+	"invalid deploy state %s: %v":                                          "无效的部署状态 %s：%v",
+	"usage: deploy resume <env>":                                           "用法：deploy resume <环境>",
+	"no interrupted deploy of %s/%s found":                                 "没有找到 %s/%s 被中断的部署",
+	"env %s not found in config":                                           "配置中找不到环境 %s",
+	"Resuming deploy of %s/%s started at %s (phase: %s)":                   "恢复 %s/%s 开始于 %s 的部署（阶段：%s）",
+	"aborted pod rollout monitoring: %w":                                   "已中止 pod 滚动监控：%w",
+	"failed to monitor pod rollout: %w":                                    "pod 滚动监控失败：%w",
+	"smoke checks failed: %v":                                              "冒烟检查失败：%v",
+	"Canary promotion, blue/green switch and traffic shift are not resumed, run a new deploy to finish them": "金丝雀推广、蓝绿切换和流量切换不会恢复，请重新部署完成",
+	"failed to get Jenkins credentials: %v":                                                  "获取 Jenkins 凭证失败：%v",
+	"failed to connect to Jenkins: %v":                                                       "连接 Jenkins 失败：%v",
+	"Waiting for queued build %d to start...":                                                "等待排队中的构建 %d 开始……",
+	"the interrupted deploy had not triggered a Jenkins build yet, run a new deploy instead": "被中断的部署还没有触发 Jenkins 构建，请重新部署",
+	"failed to find the Jenkins build: %v":                                                   "找不到 Jenkins 构建：%v",
+	"Re-attached to Jenkins build #%d: %s":                                                   "已重新接上 Jenkins 构建 #%d：%s",
+
+	// schedule.go
+	"invalid schedule file %s: %v":    "无效的定时部署文件 %s：%v",
+	"usage: deploy run cancel <id>":   "用法：deploy run cancel <id>",
+	"scheduled deploy %s not found":   "找不到定时部署 %s",
+	"Cancelled scheduled deploy %s\n": "已取消定时部署 %s\n",
+	`usage: deploy run <env> --at "22:00" [--detach] | --cron "0 22 * * 1-5" | --group <group>`:           `用法：deploy run <环境> --at "22:00" [--detach] | --cron "0 22 * * 1-5" | --group <项目组>`,
+	"env %s of project %s not found in config":                                                            "配置中找不到项目 %[2]s 的环境 %[1]s",
+	"failed to save scheduled deploy: %v":                                                                 "保存定时部署失败：%v",
+	"Scheduled deploy %s of %s to %s, next run at %s\n":                                                   "已创建定时部署 %s：将 %s 部署到 %s，下次运行于 %s\n",
+	"It will be executed by deploy serve running on this machine, cancel it with: deploy run cancel %s\n": "将由本机运行的 deploy serve 执行，取消：deploy run cancel %s\n",
+	"Waiting until %s, press Ctrl+C or run deploy run cancel %s to cancel":                                "等待到 %s，按 Ctrl+C 或运行 deploy run cancel %s 取消",
+	"scheduled deploy cancelled":                                                                          "定时部署已取消",
+	"scheduled deploy %s was cancelled":                                                                   "定时部署 %s 已被取消",
+	"failed to remove scheduled deploy: %v":                                                               "删除定时部署失败：%v",
+	"No scheduled deploys":                                                                                "没有定时部署",
+	`invalid time %q: use "22:00", "2006-01-02 22:00" or RFC 3339`:                                        `无效的时间 %q：请使用 "22:00"、"2006-01-02 22:00" 或 RFC 3339 格式`,
+	"failed to check scheduled deploys: %v":                                                               "检查定时部署失败：%v",
+	"Scheduled deploy %s of %s/%s not started: %v":                                                        "定时部署 %s（%s/%s）未能开始：%v",
+	"Scheduled deploy %s started as deploy %s":                                                            "定时部署 %s 已开始，部署 ID %s",
+	"invalid cron expression %q: expected 5 fields":                                                       "无效的 cron 表达式 %q：应为 5 个字段",
+	"invalid cron expression %q: %v":                                                                      "无效的 cron 表达式 %q：%v",
+	"invalid step in %q":                                                                                  "%q 中的步长无效",
+	"invalid value %q":                                                                                    "无效的值 %q",
+	"value %q out of range %d-%d":                                                                         "值 %q 超出范围 %d-%d",
+
+	// secrets.go
+	"failed to resolve %s: %v":                           "解析 %s 失败：%v",
+	"notifications: %v":                                  "通知：%v",
+	"secret %s is not a JSON object, cannot read key %s": "secret %s 不是 JSON 对象，无法读取 %s",
+	"secret %s has no key %s":                            "secret %s 中没有 %s",
+	"%v: %s":                                             "%v：%s",
+
+	// server.go、slack_bot.go、webhook.go
+	"failed to get server token: %v":                              "获取服务 token 失败：%v",
+	"Deploy server listening on %s, project workspaces in %s":     "部署服务监听 %s，项目工作目录在 %s",
+	"Deploy server stopping, waiting for running deploys to exit": "部署服务正在停止，等待执行中的部署退出",
+	"Deploy %s cancelled via API":                                 "部署 %s 已通过 API 取消",
+	"failed to create work dir: %v":                               "创建工作目录失败：%v",
+	"refusing to listen on %s without authentication, configure server.token or server.oidc, or listen on 127.0.0.1": "未配置认证时拒绝监听 %s，请配置 server.token 或 server.oidc，或监听 127.0.0.1",
+	"invalid commit %q, expected a hex commit SHA":                                                                   "无效的提交 %q，需要十六进制的提交 SHA",
+	"commit requires ref":                                        "指定 commit 时需要同时指定 ref",
+	"invalid git ref %q":                                         "无效的 git ref %q",
+	"server.oidc requires issuer and client_id":                  "server.oidc 需要配置 issuer 和 client_id",
+	"Rejected API request to %s: %v":                             "拒绝了对 %s 的 API 请求：%v",
+	"project and env are required":                               "project 和 env 必须填写",
+	"Deploy of %s/%s via %s rejected: %v":                        "通过 %[3]s 部署 %[1]s/%[2]s 被拒绝：%[4]v",
+	"%s/%s is already being deployed by %s":                      "%s/%s 正在被 %s 部署",
+	"Deploy %s started: %s/%s (%s)":                              "部署 %s 已开始：%s/%s（%s）",
+	"Error: %v\n":                                                "错误：%v\n",
+	"Deploy %s finished: %s/%s exit code %d":                     "部署 %s 已结束：%s/%s 退出码 %d",
+	"Slack user %s (%s) is not allowed to deploy %s/%s":          "Slack 用户 %s（%s）不允许部署 %s/%s",
+	"failed to post Slack progress message: %v":                  "发送 Slack 进度消息失败：%v",
+	"failed to update Slack progress message: %v":                "更新 Slack 进度消息失败：%v",
+	"failed to respond to Slack: %v":                             "回复 Slack 失败：%v",
+	"Webhook push to %s %s: failed to start deploy of %s/%s: %v": "Webhook 推送 %s %s：启动 %s/%s 的部署失败：%v",
+	"Webhook push to %s %s triggered deploy %s of %s/%s":         "Webhook 推送 %s %s 触发了部署 %s（%s/%s）",
+
+	// signals.go
+	"Received %s, stopping the deploy (press Ctrl+C again to exit immediately)": "收到 %s，正在停止部署（再次按 Ctrl+C 立即退出）",
+	"Deploy interrupted by %s, run deploy resume %s to re-attach":               "部署被 %s 中断，运行 deploy resume %s 重新接上",
+	"Deploy aborted by %s":                         "部署被 %s 中止",
+	"failed to cancel queued Jenkins build %d: %v": "取消排队中的 Jenkins 构建 %d 失败：%v",
+	"Cancelled queued Jenkins build %d":            "已取消排队中的 Jenkins 构建 %d",
+	"failed to abort Jenkins build #%d: %v":        "中止 Jenkins 构建 #%d 失败：%v",
+	"Aborted Jenkins build #%d":                    "已中止 Jenkins 构建 #%d",
+	"when the deploy is interrupted, also cancel the queued or running Jenkins build":            "部署被中断时同时取消排队中或正在运行的 Jenkins 构建",
+	"maximum duration of the whole deploy including queueing, build and rollout, 0 for no limit": "整个部署（包括排队、构建和滚动更新）的最长时间，0 表示不限制",
+
+	// signing.go
+	"failed to sign with %s: %v: %s":            "使用 %s 签名失败：%v：%s",
+	"signing.allowed_signers is not configured": "没有配置 signing.allowed_signers",
+	"failed to run ssh-keygen: %v":              "运行 ssh-keygen 失败：%v",
+
+	// simulate.go
+	"unknown simulation scenario %q: use success, slow-build, crashloop or timeout":                                                             "未知的模拟场景 %q：请使用 success、slow-build、crashloop 或 timeout",
+	"Simulation %q: mock Jenkins at %s, mock Kubernetes API at %s":                                                                              "模拟场景 %q：模拟 Jenkins 在 %s，模拟 Kubernetes API 在 %s",
+	"failed to start simulation server: %v":                                                                                                     "启动模拟服务失败：%v",
+	"run the deploy against a built-in mock Jenkins and Kubernetes cluster with a scripted scenario: success, slow-build, crashloop or timeout": "使用内置的模拟 Jenkins 和 Kubernetes 集群按场景试运行部署：success、slow-build、crashloop 或 timeout",
+
+	// snapshot.go
+	"usage: deploy snapshot <env> [--list]":                                                            "用法：deploy snapshot <环境> [--list]",
+	"Saved snapshot %s of %s/%s (deployment %s, revision %s, %s replicas, spec %s) to %s\n":            "已保存 %[2]s/%[3]s 的快照 %[1]s（部署 %[4]s，revision %[5]s，%[6]s 个副本，模板 %[7]s）到 %[8]s\n",
+	"usage: deploy restore <env> <snapshot ID|latest>":                                                 "用法：deploy restore <环境> <快照ID|latest>",
+	"Snapshot %s was taken from %s/%s, the env now deploys %s/%s; restoring the snapshot's deployment": "快照 %s 取自 %s/%s，环境现在部署的是 %s/%s；将恢复快照中的部署",
+	"%s changed since snapshot %s, restore only rolls back the workload, not its content":              "%s 在快照 %s 之后已修改，恢复只回滚工作负载，不恢复配置内容",
+	"%s from snapshot %s is no longer referenced or no longer exists":                                  "快照 %[2]s 中的 %[1]s 已不再被引用或已不存在",
+	"%s/%s already matches snapshot %s (spec %s), nothing to restore":                                  "%s/%s 已与快照 %s 一致（模板 %s），无需恢复",
+	"Restoring %s/%s to snapshot %s taken at %s by %s (revision %s, spec %s)":                          "正在将 %[1]s/%[2]s 恢复到 %[5]s 于 %[4]s 创建的快照 %[3]s（revision %[6]s，模板 %[7]s）",
+	"Restored %s/%s to snapshot %s":                                                                    "已将 %s/%s 恢复到快照 %s",
+	"env %s has no k8s.namespace or k8s.deployment":                                                    "环境 %s 没有配置 k8s.namespace 或 k8s.deployment",
+	"Deployment is managed by HPA %s, replicas are left to it":                                         "部署由 HPA %s 管理，副本数交给 HPA",
+	"failed to restore deployment %s: %v":                                                              "恢复部署 %s 失败：%v",
+	"failed to create snapshot directory: %v":                                                          "创建快照目录失败：%v",
+	"failed to save snapshot: %v":                                                                      "保存快照失败：%v",
+	"invalid snapshot %s: %v":                                                                          "无效的快照 %s：%v",
+	"no snapshots of %s/%s found, take one with deploy snapshot %s":                                    "没有找到 %s/%s 的快照，请使用 deploy snapshot %s 创建",
+	"snapshot %s of %s/%s not found, list them with deploy snapshot %s --list":                         "找不到 %[2]s/%[3]s 的快照 %[1]s，请使用 deploy snapshot %[4]s --list 查看",
+	"No snapshots of %s/%s\n":                                                                          "%s/%s 没有快照\n",
+
+	// smoke.go
+	"Smoke check %s failed (attempt %d/%d): %v": "冒烟检查 %s 失败（第 %d/%d 次）：%v",
+	"smoke check %s failed: %v":                 "冒烟检查 %s 失败：%v",
+	"Smoke check %s passed":                     "冒烟检查 %s 通过",
+	"unexpected status %d, expected %d":         "状态码为 %d，应为 %d",
+	"failed to read response body: %v":          "读取响应内容失败：%v",
+	"response body does not contain %q":         "响应内容不包含 %q",
+
+	// summary.go
+	"==================== Deploy Summary ====================": "======================= 部署总结 =======================",
+	"Project:   %s\n":       "项目：    %s\n",
+	"Env:       %s\n":       "环境：    %s\n",
+	"Result:    %s\n":       "结果：    %s\n",
+	"Target:    %s/%s\n":    "目标：    %s/%s\n",
+	"Revision:  %s -> %s\n": "版本：    %s -> %s\n",
+	"Image:     %s\n":       "镜像：    %s\n",
+	"Pods:      %d\n":       "Pod：     %d\n",
+	"Build:     #%d %s\n":   "构建：    #%d %s\n",
+	"Ticket:    %s\n":       "变更单：  %s\n",
+	"Log:       %s\n":       "日志：    %s\n",
+	"Changes:   %d commit(s) since the last deploy\n":    "变更：    自上次部署以来 %d 个提交\n",
+	"Smoke:     %s passed\n":                             "冒烟检查：%s 通过\n",
+	"Phases:    %s\n":                                    "阶段：    %s\n",
+	"Total:     %v\n":                                    "总计：    %v\n",
+	"Still %s, %v elapsed":                               "仍在%s，已过 %v",
+	"waiting in the Jenkins queue":                       "Jenkins 队列中排队",
+	"waiting for Jenkins build #%d":                      "等待 Jenkins 构建 #%d",
+	"monitoring the rollout of %s/%s":                    "监控 %s/%s 的滚动更新",
+	"waiting for job %s":                                 "等待 job %s",
+	"shifting traffic":                                   "切换流量",
+	"running smoke checks":                               "运行冒烟检查",
+	"waiting for the previous deploy of %s/%s to finish": "等待 %s/%s 的上一个部署结束",
+	"waiting for the deploy lock of %s/%s":               "等待 %s/%s 的部署锁",
+	"print a progress line when nothing has been printed for this long during queueing, builds and rollouts, 0 to disable": "排队、构建和滚动更新期间超过该时长没有输出时输出一行进度，0 表示关闭",
+	"failed to write GitHub step summary: %v": "写入 GitHub 任务摘要失败：%v",
+	"failed to write summary file %s: %v":     "写入汇总文件 %s 失败：%v",
+
+	// ticket.go
+	"change ticket ID for this deploy (e.g. CHG0012345 or OPS-123), required by envs with change_ticket, also read from DEPLOY_TICKET": "本次部署关联的变更单号（如 CHG0012345 或 OPS-123），配置了 change_ticket 的环境必须提供，也可以通过 DEPLOY_TICKET 环境变量传入",
+	"policy denied: deploying %s/%s requires a change ticket, pass it with --ticket <id>":                                              "策略拒绝：部署 %s/%s 需要关联变更单，请使用 --ticket <单号> 提供",
+	"invalid change_ticket.pattern %q: %v":              "无效的 change_ticket.pattern %q：%v",
+	"policy denied: change ticket %q does not match %s": "策略拒绝：变更单 %q 不符合 %s",
+	"policy denied: change ticket %s: %v":               "策略拒绝：变更单 %s：%v",
+	"Change ticket %s verified for %s/%s":               "变更单 %s 已通过 %s/%s 的校验",
+	"failed to get change_ticket.token: %v":             "获取 change_ticket.token 失败：%v",
+	"lookup failed: %v":                                 "查询失败：%v",
+	"invalid response: %v":                              "无效的响应：%v",
+	"not found (no %s in the response)":                 "不存在（响应中没有 %s）",
+	"status is %q, deploys need one of: %s":             "状态为 %q，部署需要以下状态之一：%s",
+
+	// traffic.go
+	"unsupported traffic_shift kind %q, expected VirtualService or HTTPRoute": "不支持的 traffic_shift kind %q，应为 VirtualService 或 HTTPRoute",
+	"failed to create dynamic client: %v":                                     "创建 dynamic 客户端失败：%v",
+	"Traffic shift step %d/%d: %d%% to %s, %d%% to %s":                        "流量切换第 %d/%d 步：%d%% 到 %s，%d%% 到 %s",
+	"Traffic shift failed at %d%%, routing all traffic back to %s":            "流量切换在 %d%% 时失败，将全部流量切回 %s",
+	"Traffic rollback failed: %v":                                             "流量回滚失败：%v",
+	"traffic shift failed at %d%%: %v":                                        "流量切换在 %d%% 时失败：%v",
+	"failed to get %s %s: %v":                                                 "获取 %s %s 失败：%v",
+	"%s %s has no routing rules":                                              "%s %s 没有路由规则",
+	"%s %s has no destinations named %s or %s":                                "%s %s 没有名为 %s 或 %s 的目标",
+	"failed to update %s %s: %v":                                              "更新 %s %s 失败：%v",
+
+	// usage.go
+	"Usage:": "用法：",
+	"  deploy [flags] [env] [flags]   trigger the Jenkins build of env and monitor the rollout": "  deploy [参数] [环境] [参数]   触发环境的 Jenkins 构建并监控滚动更新",
+	"  deploy <command> [args]":       "  deploy <命令> [参数]",
+	"Commands:":                       "命令：",
+	"Envs of %s:\n":                   "%s 的环境：\n",
+	"Flags:":                          "参数：",
+	"Env %s not found in project %s":  "项目 %[2]s 中找不到环境 %[1]s",
+	", did you mean %q?":              "，是否是 %q？",
+	" Available envs: %s":             " 可用环境：%s",
+	"Project not found in config: %s": "配置中找不到项目：%s",
+	", did you mean %q? Set name or path of the project in deploy_config.yaml to match this directory": "，是否是 %q？请在 deploy_config.yaml 中设置项目的 name 或 path 以匹配当前目录",
+	"show the local audit log, audit verify checks it has not been tampered with":                      "查看本地审计日志，audit verify 校验日志未被篡改",
+	"deploy several projects and envs from a manifest":                                                 "按部署清单部署多个项目和环境",
+	"show this help and the envs of the current project":                                               "显示本帮助和当前项目的环境",
+	"show deploy records from the local history or the shared ledger":                                  "查看本地历史或共享账本中的部署记录",
+	"save Jenkins and notifier credentials in the system keychain":                                     "将 Jenkins 和通知的凭证保存到系统钥匙串",
+	"show deploy frequency, change failure rate and time to restore":                                   "查看部署频率、变更失败率和恢复时间",
+	"promote the last build of a pipeline stage to the next stage":                                     "将流水线某阶段最近的构建晋级到下一阶段",
+	"re-attach to an interrupted deploy":                                                               "重新接上被中断的部署",
+	"schedule a deploy, or list and cancel scheduled deploys":                                          "创建定时部署，或列出、取消定时部署",
+	"restore an env to a snapshot and monitor the rollout":                                             "将环境恢复到快照并监控滚动更新",
+	"record the current state of an env, or list its snapshots":                                        "记录环境的当前状态，或列出其快照",
+	"start the REST API server":                                                                        "启动 REST API 服务",
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// force 目标已经运行同一提交时仍然部署，也可以在确认后覆盖allowed_branches限制
+var force = flag.Bool("force", false, "deploy even if the target already runs the requested commit, or (after confirmation) from a branch not in allowed_branches")
+
+// deployedCommitAnnotation 部署成功后记录在Deployment上的提交sha
+const deployedCommitAnnotation = "deploy/commit"
+
+// resultAlreadyDeployed 目标已经运行同一提交、跳过部署时的结果
+const resultAlreadyDeployed = "already-deployed"
+
+// runningCommit 目标当前运行的提交，优先读取Deployment注解，没有注解时使用最近一次成功的部署记录，返回提交和来源
+func runningCommit(ctx context.Context, config *Config, project, env, namespace, deployment, configPath string) (string, string) {
+	if namespace != "" && deployment != "" {
+		clientset, err := newKubernetesClient(configPath)
+		if err == nil {
+			d, getErr := getDeployment(ctx, clientset, namespace, deployment)
+			if getErr == nil {
+				if commit := d.GetAnnotations()[deployedCommitAnnotation]; commit != "" {
+					return commit, "deployment " + deployment
+				}
+			}
+			err = getErr
+		}
+		if err != nil {
+			slog.Debug(fmt.Sprintf("failed to read deployed commit annotation: %v", err))
+		}
+	}
+	record, err := latestSuccessfulDeploy(ctx, config, project, env)
+	if err != nil || record == nil {
+		return "", ""
+	}
+	return record.Commit, "deploy history"
+}
+
+// recordDeployedCommit 部署成功后在Deployment上记录提交sha和关联的变更单号，修改metadata不会触发滚动
+func recordDeployedCommit(ctx context.Context, namespace, deployment, configPath, commit, ticket string) {
+	patch := deployedCommitPatch(commit, ticket)
+	if patch == nil {
+		return
+	}
+	clientset, err := newKubernetesClient(configPath)
+	if err == nil {
+		_, err = clientset.AppsV1().Deployments(namespace).Patch(ctx, deployment, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		slog.Warn(msg("failed to record deployed commit on %s: %v", deployment, err))
+	}
+}
+
+// recordJobTargetCommit Job/CronJob类型的环境部署成功后在CronJob（没有CronJob时在Job）上记录提交sha和变更单号
+func recordJobTargetCommit(ctx context.Context, k8s K8sConfig, configPath, commit, ticket string) {
+	patch := deployedCommitPatch(commit, ticket)
+	if patch == nil {
+		return
+	}
+	name := k8s.CronJob
+	clientset, err := newKubernetesClient(configPath)
+	if err == nil && k8s.CronJob != "" {
+		_, err = clientset.BatchV1().CronJobs(k8s.Namespace).Patch(ctx, k8s.CronJob, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	} else if err == nil {
+		name = k8s.Job
+		_, err = clientset.BatchV1().Jobs(k8s.Namespace).Patch(ctx, k8s.Job, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		slog.Warn(msg("failed to record deployed commit on %s: %v", name, err))
+	}
+}
+
+// deployedCommitPatch 记录提交sha和变更单号的metadata补丁，都为空时返回nil
+func deployedCommitPatch(commit, ticket string) []byte {
+	annotations := make(map[string]string)
+	if commit != "" {
+		annotations[deployedCommitAnnotation] = commit
+	}
+	if ticket != "" {
+		annotations[changeTicketAnnotation] = ticket
+	}
+	if len(annotations) == 0 {
+		return nil
+	}
+	patch, _ := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	return patch
+}
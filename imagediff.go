@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// reportImageDiff 监控开始时按容器（包括initContainer和sidecar）对比旧pod运行的镜像和新pod模板的镜像，
+// 所有镜像都没有变化时提示构建可能没有更新镜像tag
+func reportImageDiff(ctx context.Context, clientset *kubernetes.Clientset, namespace string, deployment *appsv1.Deployment, initialPodUIDs map[string]bool) {
+	podList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
+	if err != nil {
+		slog.Warn(msg("failed to compare images of old and new pods: %v", err))
+		return
+	}
+
+	// 旧pod中同一容器可能运行着不同的镜像（上一次滚动未完成），全部列出
+	oldImageSets := make(map[string]map[string]bool)
+	oldPods := 0
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if !initialPodUIDs[string(pod.UID)] {
+			continue
+		}
+		oldPods++
+		for name, image := range podSpecImages(pod.Spec) {
+			if oldImageSets[name] == nil {
+				oldImageSets[name] = make(map[string]bool)
+			}
+			oldImageSets[name][image] = true
+		}
+	}
+	if oldPods == 0 {
+		return
+	}
+	oldImages := make(map[string]string)
+	for name, set := range oldImageSets {
+		images := make([]string, 0, len(set))
+		for image := range set {
+			images = append(images, image)
+		}
+		sort.Strings(images)
+		oldImages[name] = strings.Join(images, " | ")
+	}
+	newImages := podSpecImages(deployment.Spec.Template.Spec)
+
+	slog.Info(tr("Images of old pods -> new pods:"))
+	for _, line := range formatImageDelta(oldImages, newImages) {
+		slog.Info("  " + line)
+	}
+	var removed []string
+	for name, image := range oldImages {
+		if _, ok := newImages[name]; !ok {
+			removed = append(removed, name+": "+image+" (removed)")
+		}
+	}
+	sort.Strings(removed)
+	for _, line := range removed {
+		slog.Info("  " + line)
+	}
+
+	unchanged := true
+	for name, image := range newImages {
+		if oldImages[name] != image {
+			unchanged = false
+		}
+	}
+	if unchanged && len(oldImages) == len(newImages) {
+		slog.Warn(tr("new pods use the same images as the old pods, the build may not have updated the image tag"))
+	}
+}
+
+// podSpecImages 返回pod spec中容器名到镜像的映射，initContainer（包括原生sidecar）以"init:"为前缀
+func podSpecImages(spec corev1.PodSpec) map[string]string {
+	images := getContainerImages(spec.Containers)
+	for name, image := range getContainerImages(spec.InitContainers) {
+		images["init:"+name] = image
+	}
+	return images
+}
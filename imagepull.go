@@ -0,0 +1,419 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// RegistryConfig 镜像仓库的凭证，新pod拉取镜像失败时用于查询tag是否存在
+type RegistryConfig struct {
+	Host     string `yaml:"host"`               // 仓库地址，如registry.example.com、ghcr.io，Docker Hub为docker.io
+	Username string `yaml:"username,omitempty"` // 用户名，为空时password作为Bearer token
+	Password string `yaml:"password,omitempty"` // 密码或token，支持钥匙串和AWS密钥引用
+}
+
+// registries 配置文件中的镜像仓库凭证
+var registries []RegistryConfig
+
+// 镜像拉取失败的原因分类
+const (
+	pullTagNotFound      = "tag not found in registry"
+	pullAuthFailure      = "auth failure"
+	pullNotFoundOrDenied = "repository not found or access denied"
+	pullUnreachable      = "registry unreachable"
+	pullInvalidName      = "invalid image reference"
+	pullTagExists        = "tag exists in registry"
+)
+
+// imagePullFailure 新pod中一个拉取镜像失败的容器
+type imagePullFailure struct {
+	Pod       string
+	Container string
+	Image     string
+	Reason    string // ImagePullBackOff、ErrImagePull等
+	Message   string // 拉取失败事件或容器状态中的原始信息
+}
+
+// imagePullDiagnoser 滚动监控中诊断镜像拉取失败，每个镜像只查询和输出一次
+type imagePullDiagnoser struct {
+	clientset *kubernetes.Clientset
+	namespace string
+	diagnoses map[string]string // 镜像 -> 诊断结果
+}
+
+func newImagePullDiagnoser(clientset *kubernetes.Clientset, namespace string) *imagePullDiagnoser {
+	return &imagePullDiagnoser{clientset: clientset, namespace: namespace, diagnoses: make(map[string]string)}
+}
+
+// report 诊断新pod中尚未诊断过的镜像拉取失败并输出结果
+func (d *imagePullDiagnoser) report(ctx context.Context, pods []*corev1.Pod) {
+	for _, pod := range pods {
+		for _, failure := range findImagePullFailures(pod) {
+			if _, done := d.diagnoses[failure.Image]; done {
+				continue
+			}
+			// 事件中的信息比容器状态完整，同一pod中其他容器的事件不使用
+			if message := latestPullEventMessage(ctx, d.clientset, d.namespace, pod.Name); message != "" {
+				if image := imageFromPullMessage(message); image == "" || image == failure.Image {
+					failure.Message = message
+				}
+			}
+			diagnosis := diagnoseImagePull(ctx, failure)
+			d.diagnoses[failure.Image] = diagnosis
+			slog.Warn(msg("Image pull failed for container %s of pod %s (%s): %s", failure.Container, failure.Pod, failure.Reason, diagnosis))
+			if failure.Message != "" {
+				slog.Info(msg("  Pull error: %s", failure.Message))
+			}
+		}
+	}
+}
+
+// describe 异常pod中拉取失败的镜像及诊断结果，用于失败原因，没有拉取失败时返回空
+func (d *imagePullDiagnoser) describe(pods []*corev1.Pod) string {
+	var images []string
+	seen := make(map[string]bool)
+	for _, pod := range pods {
+		for _, failure := range findImagePullFailures(pod) {
+			if seen[failure.Image] {
+				continue
+			}
+			seen[failure.Image] = true
+			diagnosis, ok := d.diagnoses[failure.Image]
+			if !ok {
+				diagnosis = failure.Reason
+			}
+			images = append(images, diagnosis)
+		}
+	}
+	sort.Strings(images)
+	if len(images) == 0 {
+		return ""
+	}
+	return "ImagePullBackOff - " + strings.Join(images, "; ")
+}
+
+// findImagePullFailures 返回pod中处于ImagePullBackOff、ErrImagePull等状态的容器（包括init容器）
+func findImagePullFailures(pod *corev1.Pod) []imagePullFailure {
+	var failures []imagePullFailure
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting == nil || !isImagePullReason(status.State.Waiting.Reason) {
+			continue
+		}
+		failures = append(failures, imagePullFailure{
+			Pod:       pod.Name,
+			Container: status.Name,
+			Image:     status.Image,
+			Reason:    status.State.Waiting.Reason,
+			Message:   status.State.Waiting.Message,
+		})
+	}
+	return failures
+}
+
+// isImagePullReason 容器等待原因是否为镜像拉取失败
+func isImagePullReason(reason string) bool {
+	switch reason {
+	case "ImagePullBackOff", "ErrImagePull", "InvalidImageName", "ErrImageNeverPull":
+		return true
+	}
+	return false
+}
+
+// hasImagePullBackOff pod是否有容器已经反复拉取镜像失败
+func hasImagePullBackOff(pod *corev1.Pod) bool {
+	for _, failure := range findImagePullFailures(pod) {
+		if failure.Reason != "ErrImagePull" {
+			return true
+		}
+	}
+	return false
+}
+
+// latestPullEventMessage pod最近一次拉取镜像失败事件的信息，没有权限或没有事件时返回空
+func latestPullEventMessage(ctx context.Context, clientset *kubernetes.Clientset, namespace, podName string) string {
+	events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: "involvedObject.kind=Pod,involvedObject.name=" + podName + ",reason=Failed",
+	})
+	if err != nil {
+		slog.Debug(fmt.Sprintf("failed to list events of pod %s: %v", podName, err))
+		return ""
+	}
+	var latest *corev1.Event
+	for i := range events.Items {
+		event := &events.Items[i]
+		if !strings.Contains(event.Message, "pull") {
+			continue
+		}
+		if latest == nil || event.LastTimestamp.After(latest.LastTimestamp.Time) {
+			latest = event
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return latest.Message
+}
+
+// pullMessageImage 拉取失败事件中引号内的镜像引用，如Failed to pull image "registry.example.com/app:1.2.3": ...
+var pullMessageImage = regexp.MustCompile(`(?i)image "([^"]+)"`)
+
+// imageFromPullMessage 从拉取失败信息中取出完整的镜像引用
+func imageFromPullMessage(message string) string {
+	if match := pullMessageImage.FindStringSubmatch(message); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// imageReference 解析后的镜像引用
+type imageReference struct {
+	Registry   string // 仓库地址，Docker Hub为docker.io
+	Repository string // 仓库中的路径，Docker Hub的官方镜像带library/前缀
+	Reference  string // tag或digest
+}
+
+func (r imageReference) String() string {
+	separator := ":"
+	if strings.Contains(r.Reference, ":") {
+		separator = "@"
+	}
+	return r.Registry + "/" + r.Repository + separator + r.Reference
+}
+
+// parseImageReference 按Docker的规则解析镜像引用，没有仓库地址时为docker.io，没有tag时为latest
+func parseImageReference(image string) (imageReference, error) {
+	var ref imageReference
+	name := image
+	if at := strings.Index(name, "@"); at >= 0 {
+		name, ref.Reference = name[:at], name[at+1:]
+	}
+	if slash := strings.LastIndex(name, "/"); ref.Reference == "" {
+		if colon := strings.LastIndex(name, ":"); colon > slash {
+			name, ref.Reference = name[:colon], name[colon+1:]
+		}
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+	if name == "" || strings.ContainsAny(name, " \t") {
+		return ref, errorf("invalid image reference %q", image)
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Registry, ref.Repository = first, rest
+	} else {
+		ref.Registry, ref.Repository = "docker.io", name
+		if !found {
+			ref.Repository = "library/" + name
+		}
+	}
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return ref, errorf("invalid image reference %q", image)
+	}
+	return ref, nil
+}
+
+// diagnoseImagePull 判断拉取失败的原因：配置了仓库凭证时通过仓库API查询tag是否存在，否则按拉取失败信息判断
+func diagnoseImagePull(ctx context.Context, failure imagePullFailure) string {
+	ref, err := parseImageReference(failure.Image)
+	if err != nil || failure.Reason == "InvalidImageName" {
+		return fmt.Sprintf("%s: %s", pullInvalidName, failure.Image)
+	}
+
+	if registry := findRegistry(ref.Registry); registry != nil {
+		result, err := registry.checkManifest(ctx, ref)
+		if err != nil {
+			slog.Debug(fmt.Sprintf("failed to query registry %s for %s: %v", ref.Registry, ref, err))
+		} else {
+			switch result {
+			case pullTagExists:
+				return fmt.Sprintf("%s: %s exists in %s, but the node cannot pull it, check imagePullSecrets of the pod or its service account", pullAuthFailure, ref, ref.Registry)
+			case pullTagNotFound:
+				return fmt.Sprintf("%s: %s (checked with the %s registry API), check that the build pushed the image and the tag in the deployment", pullTagNotFound, ref, ref.Registry)
+			case pullAuthFailure:
+				return fmt.Sprintf("%s: the credentials configured for %s were rejected, and %s could not be checked", pullAuthFailure, ref.Registry, ref)
+			}
+		}
+	}
+
+	switch classifyPullMessage(failure.Message) {
+	case pullTagNotFound:
+		return fmt.Sprintf("%s: %s, check that the build pushed the image and the tag in the deployment", pullTagNotFound, ref)
+	case pullAuthFailure:
+		return fmt.Sprintf("%s: the node is not allowed to pull %s, check imagePullSecrets of the pod or its service account", pullAuthFailure, ref)
+	case pullNotFoundOrDenied:
+		return fmt.Sprintf("%s: %s does not exist in %s or the node is not allowed to pull it, check the image name and imagePullSecrets of the pod or its service account, or configure registries to check the tag", pullNotFoundOrDenied, ref, ref.Registry)
+	case pullUnreachable:
+		return fmt.Sprintf("%s: the node cannot reach %s to pull %s", pullUnreachable, ref.Registry, ref)
+	}
+	return fmt.Sprintf("%s cannot be pulled from %s", ref, ref.Registry)
+}
+
+// classifyPullMessage 按容器运行时返回的拉取失败信息判断原因
+func classifyPullMessage(message string) string {
+	message = strings.ToLower(message)
+	switch {
+	// Docker Hub对不存在的仓库和没有权限的私有仓库返回同样的信息，如
+	// pull access denied for x, repository does not exist or may require 'docker login'
+	case strings.Contains(message, "may require") || (strings.Contains(message, "pull access denied") && strings.Contains(message, "does not exist")):
+		return pullNotFoundOrDenied
+	// 认证失败的信息中也可能包含not found，先于tag不存在判断
+	case strings.Contains(message, "unauthorized") || strings.Contains(message, "authentication required") ||
+		strings.Contains(message, "pull access denied") || strings.Contains(message, "403 forbidden") || strings.Contains(message, "denied"):
+		return pullAuthFailure
+	case strings.Contains(message, "not found") || strings.Contains(message, "manifest unknown") || strings.Contains(message, "does not exist"):
+		return pullTagNotFound
+	case strings.Contains(message, "no such host") || strings.Contains(message, "i/o timeout") ||
+		strings.Contains(message, "connection refused") || strings.Contains(message, "tls handshake timeout"):
+		return pullUnreachable
+	}
+	return ""
+}
+
+// findRegistry 查找仓库地址对应的凭证
+func findRegistry(host string) *RegistryConfig {
+	for i := range registries {
+		configured := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(registries[i].Host, "https://"), "http://"), "/")
+		if configured == host || (host == "docker.io" && (configured == "index.docker.io" || configured == "registry-1.docker.io")) {
+			return &registries[i]
+		}
+	}
+	return nil
+}
+
+// manifestAccept 查询manifest时接受的格式，包括多架构镜像的索引
+var manifestAccept = strings.Join([]string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}, ", ")
+
+// checkManifest 通过Docker Registry HTTP API v2查询镜像的manifest，返回pullTagExists、pullTagNotFound或pullAuthFailure
+func (c *RegistryConfig) checkManifest(ctx context.Context, ref imageReference) (string, error) {
+	password, err := resolveSecret(ctx, c.Password)
+	if err != nil {
+		return "", errorf("failed to get the password of registry %s: %v", c.Host, err)
+	}
+	registerSecret(password)
+
+	host := ref.Registry
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, ref.Repository, ref.Reference)
+	status, challenge, err := c.headManifest(ctx, manifestURL, c.basicAuthorization(password))
+	if err != nil {
+		return "", err
+	}
+	// 需要Bearer token的仓库按WWW-Authenticate中的地址用凭证换取token
+	if status == http.StatusUnauthorized && strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		token, err := c.fetchToken(ctx, challenge, password, ref)
+		if err != nil {
+			return "", err
+		}
+		if status, _, err = c.headManifest(ctx, manifestURL, "Bearer "+token); err != nil {
+			return "", err
+		}
+	}
+
+	switch status {
+	case http.StatusOK:
+		return pullTagExists, nil
+	case http.StatusNotFound:
+		return pullTagNotFound, nil
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return pullAuthFailure, nil
+	}
+	return "", errorf("unexpected HTTP %d from %s", status, manifestURL)
+}
+
+// basicAuthorization 配置了用户名时的Basic认证头，只有password时作为Bearer token
+func (c *RegistryConfig) basicAuthorization(password string) string {
+	switch {
+	case c.Username != "":
+		request := &http.Request{Header: http.Header{}}
+		request.SetBasicAuth(c.Username, password)
+		return request.Header.Get("Authorization")
+	case password != "":
+		return "Bearer " + password
+	}
+	return ""
+}
+
+// headManifest 发送HEAD请求，返回状态码和WWW-Authenticate
+func (c *RegistryConfig) headManifest(ctx context.Context, manifestURL, authorization string) (int, string, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Accept", manifestAccept)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("WWW-Authenticate"), nil
+}
+
+// bearerChallengeParam WWW-Authenticate中的参数，如realm="https://auth.docker.io/token"
+var bearerChallengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// fetchToken 按Bearer认证的challenge用凭证换取拉取该镜像的token
+func (c *RegistryConfig) fetchToken(ctx context.Context, challenge, password string, ref imageReference) (string, error) {
+	params := make(map[string]string)
+	for _, match := range bearerChallengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+	if params["realm"] == "" {
+		return "", errorf("registry %s returned no token realm", ref.Registry)
+	}
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = "repository:" + ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+
+	headers := map[string]string{}
+	if c.Username != "" {
+		headers["Authorization"] = c.basicAuthorization(password)
+	}
+	body, err := sendJSON(ctx, http.MethodGet, params["realm"]+"?"+query.Encode(), nil, headers)
+	if err != nil {
+		return "", errorf("failed to get a token from %s: %v", params["realm"], err)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", errorf("invalid token response from %s: %v", params["realm"], err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	registerSecret(token.Token)
+	return token.Token, nil
+}
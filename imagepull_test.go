@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestClassifyPullMessage(t *testing.T) {
+	tests := []struct {
+		message, want string
+	}{
+		{`Failed to pull image "docker.io/acme/app:1.2.3": rpc error: code = NotFound desc = failed to pull and unpack image "docker.io/acme/app:1.2.3": failed to resolve reference "docker.io/acme/app:1.2.3": docker.io/acme/app:1.2.3: not found`, pullTagNotFound},
+		{`Failed to pull image "ghcr.io/acme/app:1.2.3": manifest unknown`, pullTagNotFound},
+		{`Error response from daemon: pull access denied for acme/app, repository does not exist or may require 'docker login': denied: requested access to the resource is denied`, pullNotFoundOrDenied},
+		{`failed to resolve reference "docker.io/acme/app:1.2.3": pull access denied, repository does not exist or may require authorization: server message: insufficient_scope: authorization failed`, pullNotFoundOrDenied},
+		{`failed to fetch anonymous token: unexpected status from GET request to https://ghcr.io/token: 401 Unauthorized`, pullAuthFailure},
+		{`failed to resolve reference "registry.example.com/app:1.2.3": unexpected status: 403 Forbidden`, pullAuthFailure},
+		{`failed to authorize: failed to fetch oauth token: denied: token not found`, pullAuthFailure},
+		{`dial tcp: lookup registry.example.com: no such host`, pullUnreachable},
+		{`context deadline exceeded`, ""},
+	}
+	for _, tt := range tests {
+		if got := classifyPullMessage(tt.message); got != tt.want {
+			t.Errorf("classifyPullMessage(%q) = %q, want %q", tt.message, got, tt.want)
+		}
+	}
+}
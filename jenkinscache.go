@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// JenkinsCacheConfig 任务元数据（参数定义、文件夹路径、预计构建时长）在磁盘上的缓存，批量部署多个环境时减少重复的Jenkins API请求
+type JenkinsCacheConfig struct {
+	TTL string `yaml:"ttl,omitempty"` // 磁盘缓存的有效期，如5m，默认只在一次运行内缓存
+}
+
+// jenkinsCacheTTL 磁盘缓存的有效期，为0时只在进程内缓存
+var jenkinsCacheTTL time.Duration
+
+// setJenkinsCacheTTL 按配置设置磁盘缓存的有效期
+func setJenkinsCacheTTL(config *JenkinsCacheConfig) error {
+	if config == nil || config.TTL == "" {
+		return nil
+	}
+	ttl, err := time.ParseDuration(config.TTL)
+	if err != nil || ttl < 0 {
+		return errorf("invalid jenkins_cache.ttl %q, expected a duration such as 5m", config.TTL)
+	}
+	jenkinsCacheTTL = ttl
+	return nil
+}
+
+// jobMetadata 缓存的任务元数据
+type jobMetadata struct {
+	Base              string                 `json:"base"` // 任务的路径，文件夹中的任务为/job/<文件夹>/job/<任务>
+	Raw               *gojenkins.JobResponse `json:"raw"`
+	EstimatedDuration time.Duration          `json:"estimated_duration,omitempty"` // 最近一次成功构建给出的预计时长
+	FetchedAt         time.Time              `json:"fetched_at"`
+}
+
+// jobCache 本次运行中已获取的任务元数据，key为Jenkins地址和任务路径
+var jobCache = struct {
+	sync.Mutex
+	jobs map[string]*jobMetadata
+}{jobs: make(map[string]*jobMetadata)}
+
+// jenkinsJobBase 任务名对应的路径，folder/app解析为/job/folder/job/app，已经写成folder/job/app的保持不变
+func jenkinsJobBase(jobName string) string {
+	jobName = strings.Trim(jobName, "/")
+	if strings.HasPrefix(jobName, "job/") || strings.Contains(jobName, "/job/") {
+		return "/" + strings.TrimPrefix(jobName, "job/")
+	}
+	return "/job/" + strings.Join(strings.Split(jobName, "/"), "/job/")
+}
+
+// getJenkinsJob 获取任务，元数据优先使用本次运行或磁盘上未过期的缓存，返回的任务不能用于查询排队、最近构建等实时状态
+func getJenkinsJob(ctx context.Context, jenkins *gojenkins.Jenkins, jobName string) (*gojenkins.Job, *jobMetadata, error) {
+	base := jenkinsJobBase(jobName)
+	key := jenkins.Server + base
+
+	jobCache.Lock()
+	meta, ok := jobCache.jobs[key]
+	jobCache.Unlock()
+	if !ok {
+		meta = loadJobMetadata(key)
+		if meta != nil {
+			slog.Debug(fmt.Sprintf("Using cached metadata of Jenkins job %s (%v old)", jobName, time.Since(meta.FetchedAt).Round(time.Second)))
+		}
+	}
+	if meta == nil {
+		job := &gojenkins.Job{Jenkins: jenkins, Raw: new(gojenkins.JobResponse), Base: base}
+		status, err := job.Poll(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if status != http.StatusOK {
+			return nil, nil, errorf("job %s not found (HTTP %d at %s)", jobName, status, base)
+		}
+		meta = &jobMetadata{Base: base, Raw: job.Raw, FetchedAt: time.Now()}
+		if job.Raw.LastSuccessfulBuild.Number > 0 {
+			var last struct {
+				EstimatedDuration int64 `json:"estimatedDuration"`
+			}
+			if _, err := jenkins.Requester.GetJSON(ctx, base+"/lastSuccessfulBuild", &last, map[string]string{"tree": "estimatedDuration"}); err == nil && last.EstimatedDuration > 0 {
+				meta.EstimatedDuration = time.Duration(last.EstimatedDuration) * time.Millisecond
+			}
+		}
+		saveJobMetadata(key, meta)
+	}
+
+	jobCache.Lock()
+	jobCache.jobs[key] = meta
+	jobCache.Unlock()
+	return &gojenkins.Job{Jenkins: jenkins, Raw: meta.Raw, Base: meta.Base}, meta, nil
+}
+
+// invalidateJenkinsJob 删除任务的缓存，触发失败时缓存的参数定义可能已经过期
+func invalidateJenkinsJob(jenkins *gojenkins.Jenkins, jobName string) {
+	key := jenkins.Server + jenkinsJobBase(jobName)
+	jobCache.Lock()
+	delete(jobCache.jobs, key)
+	jobCache.Unlock()
+	if path, err := jobMetadataPath(key); err == nil {
+		os.Remove(path)
+	}
+}
+
+// jobMetadataPath 任务元数据在磁盘上的缓存文件
+func jobMetadataPath(key string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(homeDir, ".deploy", "cache", "jenkins", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// loadJobMetadata 读取磁盘上未过期的缓存，没有配置jenkins_cache.ttl、缓存不存在或已过期时返回nil
+func loadJobMetadata(key string) *jobMetadata {
+	if jenkinsCacheTTL <= 0 {
+		return nil
+	}
+	path, err := jobMetadataPath(key)
+	if err != nil {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var meta jobMetadata
+	if err := json.Unmarshal(data, &meta); err != nil || meta.Raw == nil || time.Since(meta.FetchedAt) > jenkinsCacheTTL {
+		return nil
+	}
+	return &meta
+}
+
+// saveJobMetadata 配置了jenkins_cache.ttl时把元数据写入磁盘缓存，失败时忽略
+func saveJobMetadata(key string, meta *jobMetadata) {
+	if jenkinsCacheTTL <= 0 {
+		return
+	}
+	path, err := jobMetadataPath(key)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err == nil {
+		os.WriteFile(path, data, 0600)
+	}
+}
+
+// invokeJenkinsJob 按缓存的参数定义选择/build或/buildWithParameters触发构建，不再重复获取任务信息，返回队列ID
+func invokeJenkinsJob(ctx context.Context, job *gojenkins.Job, params map[string]string) (int64, error) {
+	endpoint := "/build"
+	for _, property := range job.Raw.Property {
+		if len(property.ParameterDefinitions) > 0 {
+			endpoint = "/buildWithParameters"
+		}
+	}
+	form := url.Values{}
+	for name, value := range params {
+		form.Set(name, value)
+	}
+	return postJenkinsBuild(ctx, job, endpoint, form)
+}
+
+// postJenkinsBuild 提交触发构建的表单，从响应的Location中读取队列ID
+func postJenkinsBuild(ctx context.Context, job *gojenkins.Job, endpoint string, form url.Values) (int64, error) {
+	response, err := job.Jenkins.Requester.Post(ctx, job.Base+endpoint, bytes.NewBufferString(form.Encode()), nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return 0, errorf("could not invoke job %q: %s", job.GetName(), response.Status)
+	}
+	location, err := url.Parse(response.Header.Get("Location"))
+	if err != nil || location.Path == "" {
+		return 0, errorf("no queue item location in the response of job %q", job.GetName())
+	}
+	return strconv.ParseInt(path.Base(strings.TrimSuffix(location.Path, "/")), 10, 64)
+}
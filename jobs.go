@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bndr/gojenkins"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// runJobTargetDeploy Job/CronJob类型的环境：构建后校验CronJob镜像已更新，按需手动触发Job并等待完成
+func runJobTargetDeploy(ctx context.Context, jenkins *gojenkins.Jenkins, jobName string, params map[string]string, env Env, config *Config, configPath string, summary *deploySummary) error {
+	k8s := env.K8s
+	if k8s.Namespace == "" {
+		return errorf("k8s.namespace is required for job targets")
+	}
+
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	// 记录构建前CronJob的镜像
+	var imagesBefore map[string]string
+	if k8s.CronJob != "" {
+		cronJob, err := clientset.BatchV1().CronJobs(k8s.Namespace).Get(ctx, k8s.CronJob, metav1.GetOptions{})
+		if err != nil {
+			return errorf("failed to get cronjob %s: %v", k8s.CronJob, err)
+		}
+		imagesBefore = getContainerImages(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers)
+		summary.OldImages = imagesBefore
+		slog.Info(msg("Current cronjob %s images: %s", k8s.CronJob, formatImages(imagesBefore)))
+	}
+
+	buildStartTime := time.Now()
+	if err := BuildJenkinsJob(ctx, jenkins, jobName, params, summary); err != nil {
+		return errorf("failed to build Jenkins job: %v", err)
+	}
+
+	timeout := 10 * time.Minute
+	if k8s.JobTimeout != "" {
+		timeout, err = time.ParseDuration(k8s.JobTimeout)
+		if err != nil {
+			return errorf("invalid k8s.job_timeout %q: %v", k8s.JobTimeout, err)
+		}
+	}
+
+	if k8s.CronJob != "" {
+		cronJob, err := clientset.BatchV1().CronJobs(k8s.Namespace).Get(ctx, k8s.CronJob, metav1.GetOptions{})
+		if err != nil {
+			return errorf("failed to get cronjob %s: %v", k8s.CronJob, err)
+		}
+		imagesAfter := getContainerImages(cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers)
+		summary.NewImages = imagesAfter
+		if formatImages(imagesAfter) == formatImages(imagesBefore) {
+			return errorf("cronjob %s image was not updated by the build (still %s)", k8s.CronJob, formatImages(imagesAfter))
+		}
+		slog.Info(msg("CronJob %s updated: %s -> %s", k8s.CronJob, formatImages(imagesBefore), formatImages(imagesAfter)))
+
+		if !k8s.TriggerJob {
+			return nil
+		}
+		job, err := createJobFromCronJob(ctx, clientset, cronJob)
+		if err != nil {
+			return err
+		}
+		slog.Info(msg("Triggered job %s from cronjob %s", job.Name, k8s.CronJob))
+		jobStartTime := time.Now()
+		defer summary.addPhase("job", jobStartTime)
+		return waitForJobCompletion(ctx, clientset, k8s.Namespace, job.Name, buildStartTime, timeout)
+	}
+
+	jobStartTime := time.Now()
+	defer summary.addPhase("job", jobStartTime)
+	return waitForJobCompletion(ctx, clientset, k8s.Namespace, k8s.Job, buildStartTime, timeout)
+}
+
+// createJobFromCronJob 按CronJob模板手动创建Job，等同于kubectl create job --from=cronjob/xxx
+func createJobFromCronJob(ctx context.Context, clientset *kubernetes.Clientset, cronJob *batchv1.CronJob) (*batchv1.Job, error) {
+	// Job名称不能超过63个字符
+	prefix := cronJob.Name
+	if len(prefix) > 40 {
+		prefix = prefix[:40]
+	}
+
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for key, value := range cronJob.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("%s-deploy-%d", prefix, time.Now().Unix()),
+			Namespace:       cronJob.Namespace,
+			Labels:          cronJob.Spec.JobTemplate.Labels,
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+
+	created, err := clientset.BatchV1().Jobs(cronJob.Namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, errorf("failed to create job from cronjob %s: %v", cronJob.Name, err)
+	}
+	return created, nil
+}
+
+// waitForJobCompletion 等待Job执行完成，Job必须是本次构建之后创建的
+func waitForJobCompletion(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string, createdAfter time.Time, timeout time.Duration) error {
+	startTime := time.Now()
+	startGitHubGroup("Job " + namespace + "/" + name)
+	setHeartbeatPhase("waiting for job %s", name)
+	lastStatus := ""
+
+	for time.Since(startTime) < timeout {
+		job, err := retryK8sCall(ctx, "get job "+name, func() (*batchv1.Job, error) {
+			return clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		})
+		if apierrors.IsNotFound(err) {
+			// 构建可能刚删除旧Job，等待新Job被创建
+			if err := sleepContext(ctx, 5*time.Second); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return errorf("failed to get job %s: %v", name, err)
+		}
+		if job.CreationTimestamp.Time.Before(createdAfter.Add(-time.Minute)) {
+			return errorf("job %s was created at %s, before this build: the Jenkins job did not recreate it",
+				name, formatTime(job.CreationTimestamp.Time))
+		}
+
+		status := fmt.Sprintf("active=%d, succeeded=%d, failed=%d", job.Status.Active, job.Status.Succeeded, job.Status.Failed)
+		if status != lastStatus {
+			slog.Info(msg("Job %s: %s", name, status))
+			lastStatus = status
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != corev1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				slog.Info(msg("Job %s completed successfully! Run time: %v", name, time.Since(startTime).Round(time.Second)))
+				return nil
+			case batchv1.JobFailed:
+				printJobPodErrors(ctx, clientset, namespace, name)
+				return errorf("job %s failed: %s (%s)", name, condition.Reason, condition.Message)
+			}
+		}
+
+		if err := sleepContext(ctx, 5*time.Second); err != nil {
+			return err
+		}
+	}
+
+	printJobPodErrors(ctx, clientset, namespace, name)
+	return errorf("job %s did not complete within %v", name, timeout)
+}
+
+// printJobPodErrors 输出Job下异常pod的信息
+func printJobPodErrors(ctx context.Context, clientset *kubernetes.Clientset, namespace, jobName string) {
+	podList, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + jobName})
+	if err != nil {
+		return
+	}
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Status.Phase == corev1.PodSucceeded {
+			continue
+		}
+		slog.Info(msg("Job pod: %s, status: %s, message: %s", pod.Name, getPodStatus(pod), getPodErrorMessage(pod)))
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if isContainerOOMKilled(containerStatus) {
+				printOOMKilledDetails(pod, containerStatus)
+			}
+		}
+	}
+}
+
+// getContainerImages 返回容器名到镜像的映射
+func getContainerImages(containers []corev1.Container) map[string]string {
+	images := make(map[string]string)
+	for _, container := range containers {
+		images[container.Name] = container.Image
+	}
+	return images
+}
+
+// formatImages 按容器名输出镜像列表
+func formatImages(images map[string]string) string {
+	var parts []string
+	for name, image := range images {
+		parts = append(parts, name+"="+image)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
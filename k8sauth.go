@@ -0,0 +1,112 @@
+package main
+
+import (
+	"flag"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	// 注册OIDC等auth provider插件，exec凭证插件（aws eks get-token、gke-gcloud-auth-plugin、kubelogin）由client-go内置支持
+	_ "k8s.io/client-go/plugin/pkg/client/auth"
+)
+
+// 以其他身份访问集群的命令行参数，与kubectl的--as、--as-group相同，优先于配置中的k8s.as、k8s.as_groups
+var (
+	impersonateUser   = flag.String("as", "", "username to impersonate for Kubernetes operations, overrides k8s.as")
+	impersonateGroups stringListFlag
+)
+
+func init() {
+	flag.Var(&impersonateGroups, "as-group", "group to impersonate for Kubernetes operations, can be repeated, overrides k8s.as_groups")
+}
+
+// stringListFlag 可以重复指定的字符串参数
+type stringListFlag []string
+
+func (s *stringListFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringListFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// k8sImpersonation 访问集群时模拟的身份，所有Kubernetes客户端共用，为空时使用kubeconfig中的身份
+var k8sImpersonation rest.ImpersonationConfig
+
+// setK8sImpersonation 按命令行参数、环境配置、全局配置的顺序确定模拟的身份
+func setK8sImpersonation(global GlobalK8sConfig, env K8sConfig) {
+	k8sImpersonation = rest.ImpersonationConfig{UserName: global.As, Groups: global.AsGroups}
+	if env.As != "" {
+		k8sImpersonation = rest.ImpersonationConfig{UserName: env.As, Groups: env.AsGroups}
+	}
+	if *impersonateUser != "" {
+		k8sImpersonation = rest.ImpersonationConfig{UserName: *impersonateUser, Groups: impersonateGroups}
+	}
+}
+
+// impersonationArgs 传给kubectl的模拟身份参数
+func impersonationArgs() []string {
+	if k8sImpersonation.UserName == "" {
+		return nil
+	}
+	args := []string{"--as", k8sImpersonation.UserName}
+	for _, group := range k8sImpersonation.Groups {
+		args = append(args, "--as-group", group)
+	}
+	return args
+}
+
+// execPluginInstallHints 常见云厂商凭证插件的安装提示
+var execPluginInstallHints = map[string]string{
+	"aws":                    "install the AWS CLI v2 (used by `aws eks get-token` for EKS)",
+	"aws-iam-authenticator":  "install aws-iam-authenticator for EKS",
+	"gke-gcloud-auth-plugin": "run `gcloud components install gke-gcloud-auth-plugin` for GKE",
+	"kubelogin":              "install kubelogin with `az aks install-cli` for AKS",
+}
+
+// checkKubeconfigAuth 检查kubeconfig当前上下文使用的exec凭证插件是否已安装，以及是否使用了已移除的auth provider
+func checkKubeconfigAuth(kubeconfigPath string) error {
+	rawConfig, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		// 解析错误交给后续的BuildConfigFromFlags统一报告
+		return nil
+	}
+
+	context, ok := rawConfig.Contexts[rawConfig.CurrentContext]
+	if !ok {
+		return nil
+	}
+	authInfo, ok := rawConfig.AuthInfos[context.AuthInfo]
+	if !ok {
+		return nil
+	}
+
+	if authInfo.Exec != nil {
+		if _, err := exec.LookPath(authInfo.Exec.Command); err != nil {
+			hint := authInfo.Exec.InstallHint
+			if hint == "" {
+				hint = execPluginInstallHints[filepath.Base(authInfo.Exec.Command)]
+			}
+			if hint == "" {
+				hint = "install it or add it to PATH"
+			}
+			return errorf("kubeconfig user %s uses exec credential plugin %q which was not found in PATH: %s",
+				context.AuthInfo, authInfo.Exec.Command, hint)
+		}
+	}
+
+	if authInfo.AuthProvider != nil {
+		switch authInfo.AuthProvider.Name {
+		case "gcp":
+			return errorf("kubeconfig user %s uses the removed gcp auth provider: install gke-gcloud-auth-plugin and run `gcloud container clusters get-credentials` again", context.AuthInfo)
+		case "azure":
+			return errorf("kubeconfig user %s uses the removed azure auth provider: install kubelogin and run `kubelogin convert-kubeconfig`", context.AuthInfo)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// k8sRequestTimeout 单个Kubernetes API请求的默认超时时间，API server无响应时请求失败而不是一直等待
+const k8sRequestTimeout = 30 * time.Second
+
+// k8sClientOptions 创建Kubernetes客户端时的限流和超时设置，未配置QPS和Burst时使用client-go的默认值（QPS 5，Burst 10）
+type k8sClientOptions struct {
+	qps     float32
+	burst   int
+	timeout time.Duration
+}
+
+// k8sOptions 本次运行的客户端设置，所有Kubernetes客户端共用
+var k8sOptions = k8sClientOptions{timeout: k8sRequestTimeout}
+
+// setK8sClientOptions 按环境配置、全局配置的顺序确定客户端设置，需要在创建第一个客户端之前调用
+func setK8sClientOptions(global GlobalK8sConfig, env K8sConfig) error {
+	k8sOptions = k8sClientOptions{qps: global.QPS, burst: global.Burst, timeout: k8sRequestTimeout}
+	if env.QPS > 0 {
+		k8sOptions.qps = env.QPS
+	}
+	if env.Burst > 0 {
+		k8sOptions.burst = env.Burst
+	}
+	timeout := global.Timeout
+	if env.Timeout != "" {
+		timeout = env.Timeout
+	}
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return errorf("invalid k8s.timeout %q: %v", timeout, err)
+		}
+		k8sOptions.timeout = duration
+	}
+	return nil
+}
+
+// k8sClients 按配置路径缓存的rest配置和客户端，一次运行中的各个步骤共用，复用到API server的连接
+var k8sClients = struct {
+	sync.Mutex
+	configs    map[string]*rest.Config
+	clientsets map[string]*kubernetes.Clientset
+}{configs: make(map[string]*rest.Config), clientsets: make(map[string]*kubernetes.Clientset)}
+
+// newKubernetesClient 返回配置文件路径对应的Kubernetes客户端，第一次使用时创建
+func newKubernetesClient(configPath string) (*kubernetes.Clientset, error) {
+	k8sConfig, err := newKubernetesConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	k8sClients.Lock()
+	defer k8sClients.Unlock()
+	if clientset, ok := k8sClients.clientsets[configPath]; ok {
+		return clientset, nil
+	}
+	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	if err != nil {
+		return nil, errorf("failed to create kubernetes client: %v", err)
+	}
+	k8sClients.clientsets[configPath] = clientset
+	return clientset, nil
+}
+
+// newKubernetesConfig 返回配置文件路径对应的rest配置，第一次使用时加载，调用方不能修改返回的配置
+func newKubernetesConfig(configPath string) (*rest.Config, error) {
+	k8sClients.Lock()
+	defer k8sClients.Unlock()
+	if k8sConfig, ok := k8sClients.configs[configPath]; ok {
+		return k8sConfig, nil
+	}
+	k8sConfig, err := loadKubernetesConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	k8sConfig.Impersonate = k8sImpersonation
+	k8sConfig.Wrap(wrapK8sAuthWatch)
+	if k8sOptions.qps > 0 {
+		k8sConfig.QPS = k8sOptions.qps
+	}
+	if k8sOptions.burst > 0 {
+		k8sConfig.Burst = k8sOptions.burst
+	}
+	if k8sOptions.timeout > 0 {
+		k8sConfig.Timeout = k8sOptions.timeout
+	}
+	k8sClients.configs[configPath] = k8sConfig
+	return k8sConfig, nil
+}
+
+// loadKubernetesConfig 根据配置文件路径加载rest配置，未配置时依次尝试集群内配置和默认kubeconfig
+func loadKubernetesConfig(configPath string) (*rest.Config, error) {
+	// 显式配置in_cluster时只使用集群内配置，不回退到kubeconfig
+	// InClusterConfig设置了BearerTokenFile，client-go会定期重新读取投射的token，token轮换后无需重启
+	if configPath == inClusterConfigPath {
+		k8sConfig, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, errorf("in_cluster is enabled but in-cluster config is unavailable (is the tool running in a pod with a mounted service account token?): %v", err)
+		}
+		return k8sConfig, nil
+	}
+
+	// 如果提供了配置文件路径，使用指定的配置文件
+	if configPath != "" {
+		kubeconfig, err := expandHomePath(configPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkKubeconfigAuth(kubeconfig); err != nil {
+			return nil, err
+		}
+		k8sConfig, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+		if err != nil {
+			return nil, errorf("failed to build config from flags: %v", err)
+		}
+		return k8sConfig, nil
+	}
+
+	// 尝试使用集群内配置，失败时使用默认的 kubeconfig
+	if k8sConfig, err := rest.InClusterConfig(); err == nil {
+		return k8sConfig, nil
+	}
+	defaultPath, err := expandHomePath("~/.kube/config")
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKubeconfigAuth(defaultPath); err != nil {
+		return nil, err
+	}
+	k8sConfig, err := clientcmd.BuildConfigFromFlags("", defaultPath)
+	if err != nil {
+		return nil, errorf("failed to get k8s config: %v", err)
+	}
+	return k8sConfig, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/kubernetes"
+)
+
+// k8sMaxAttempts K8s API调用遇到瞬时错误时的最大尝试次数
+const k8sMaxAttempts = 5
+
+// retryK8sCall 对K8s API调用的瞬时错误（超时、429、连接重置等）进行重试
+// 服务端返回Retry-After时按其建议等待，否则按指数退避
+func retryK8sCall[T any](ctx context.Context, description string, call func() (T, error)) (T, error) {
+	var result T
+	var err error
+	backoff := time.Second
+
+	for attempt := 1; attempt <= k8sMaxAttempts; attempt++ {
+		result, err = call()
+		if err == nil || !isTransientK8sError(err) || attempt == k8sMaxAttempts {
+			return result, err
+		}
+
+		delay := backoff
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+		slog.Info(msg("Transient error on %s (attempt %d/%d), retrying in %v: %v", description, attempt, k8sMaxAttempts, delay, err))
+
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+	return result, err
+}
+
+// isTransientK8sError 判断错误是否为可重试的瞬时错误
+func isTransientK8sError(err error) bool {
+	if apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) {
+		return true
+	}
+	if utilnet.IsConnectionReset(err) || utilnet.IsConnectionRefused(err) || utilnet.IsProbableEOF(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// getDeployment 获取部署，瞬时错误时自动重试
+func getDeployment(ctx context.Context, clientset *kubernetes.Clientset, namespace, name string) (*appsv1.Deployment, error) {
+	return retryK8sCall(ctx, "get deployment "+name, func() (*appsv1.Deployment, error) {
+		return clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	})
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/bndr/gojenkins"
+	"golang.org/x/term"
+)
+
+// keychainService 凭证保存在系统钥匙串中使用的服务名
+const keychainService = "deploy"
+
+// 钥匙串中Jenkins凭证的账户名，配置文件没有username/api_token时使用
+const (
+	keychainJenkinsUsername = "jenkins-username"
+	keychainJenkinsToken    = "jenkins-api-token"
+)
+
+// loginNotifierTokens deploy login可以保存的通知渠道token，配置中以keychain:<名称>引用
+var loginNotifierTokens = []string{"slack", "telegram", "github", "gitlab", "jira", "grafana", "sentry"}
+
+// runLoginCommand 交互式输入Jenkins凭证和通知渠道token，保存到系统钥匙串
+func runLoginCommand(args []string) error {
+	flags := flag.NewFlagSet("login", flag.ExitOnError)
+	notifiers := flags.Bool("notifiers", false, "also prompt for notifier tokens, referenced in the config as keychain:<name>")
+	flags.Parse(args)
+
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return errorf("deploy login requires an interactive terminal")
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	username := config.Username
+	if username == "" {
+		username, _ = keychainGet(keychainJenkinsUsername)
+	}
+	if username != "" {
+		fmt.Print(msg("Jenkins username [%s]: ", username))
+	} else {
+		fmt.Print(tr("Jenkins username: "))
+	}
+	if answer, _ := reader.ReadString('\n'); strings.TrimSpace(answer) != "" {
+		username = strings.TrimSpace(answer)
+	}
+	if username == "" {
+		return errorf("username is required")
+	}
+	token, err := readSecretLine(reader, "Jenkins API token: ")
+	if err != nil {
+		return err
+	}
+	if token == "" {
+		return errorf("API token is required")
+	}
+
+	// 保存前确认凭证可用
+	if config.JenkinsURL != "" {
+		jenkins := gojenkins.CreateJenkins(nil, config.JenkinsURL, username, token)
+		if _, err := jenkins.Init(context.Background()); err != nil {
+			return errorf("failed to log in to %s: %v", config.JenkinsURL, err)
+		}
+	}
+	if err := keychainSet(keychainJenkinsUsername, username); err != nil {
+		return err
+	}
+	if err := keychainSet(keychainJenkinsToken, token); err != nil {
+		return err
+	}
+	fmt.Print(msg("Jenkins credentials for %s saved to the %s\n", username, keychainName()))
+	if config.APIToken != "" {
+		fmt.Println(tr("Note: api_token in the config file takes precedence, remove it to use the saved token"))
+	}
+
+	if !*notifiers {
+		return nil
+	}
+	for _, name := range loginNotifierTokens {
+		secret, err := readSecretLine(reader, fmt.Sprintf("%s token (empty to skip): ", name))
+		if err != nil {
+			return err
+		}
+		if secret == "" {
+			continue
+		}
+		if err := keychainSet(name, secret); err != nil {
+			return err
+		}
+		fmt.Print(msg("Saved, reference it in the config as \"keychain:%s\"\n", name))
+	}
+	return nil
+}
+
+// jenkinsCredentials 按配置文件、系统钥匙串的顺序获取Jenkins用户名和API token
+func jenkinsCredentials(ctx context.Context, config *Config) (string, string, error) {
+	username := config.Username
+	token, err := resolveSecret(ctx, config.APIToken)
+	if err != nil {
+		return "", "", err
+	}
+	if token != "" {
+		registerSecret(token)
+		return username, token, nil
+	}
+	token, err = keychainGet(keychainJenkinsToken)
+	if err != nil {
+		return "", "", errorf("no api_token in the config and none in the %s, run deploy login: %v", keychainName(), err)
+	}
+	registerSecret(token)
+	if username == "" {
+		username, _ = keychainGet(keychainJenkinsUsername)
+	}
+	return username, token, nil
+}
+
+// readSecretLine 读取一行输入，标准输入是终端时（包括Windows控制台）不回显，否则从管道读取
+func readSecretLine(reader *bufio.Reader, prompt string) (string, error) {
+	fmt.Print(prompt)
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) && reader.Buffered() == 0 {
+		secret, err := term.ReadPassword(fd)
+		fmt.Println()
+		if err != nil {
+			return "", errorf("failed to read input: %v", err)
+		}
+		return strings.TrimSpace(string(secret)), nil
+	}
+	line, err := reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", errorf("failed to read input: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// keychainName 当前系统的凭证存储名称，用于提示
+func keychainName() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "macOS Keychain"
+	case "windows":
+		return "Windows Credential Manager"
+	default:
+		return "Secret Service keyring"
+	}
+}
+
+// keychainSet 在系统钥匙串中保存凭证，已存在时覆盖
+// macOS使用security，Linux使用Secret Service的secret-tool（需要安装libsecret-tools），Windows使用PasswordVault
+func keychainSet(account, secret string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		// security -i从标准输入读取命令，密钥不出现在进程参数中
+		cmd = exec.Command("security", "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n",
+			securityQuote(keychainService), securityQuote(account), securityQuote(secret)))
+	case "windows":
+		script := fmt.Sprintf(`[Windows.Security.Credentials.PasswordVault, Windows.Security.Credentials, ContentType = WindowsRuntime] > $null
+$vault = New-Object Windows.Security.Credentials.PasswordVault
+try { $vault.Remove($vault.Retrieve('%s', '%s')) } catch {}
+$vault.Add((New-Object Windows.Security.Credentials.PasswordCredential('%s', '%s', [Console]::In.ReadToEnd())))`,
+			keychainService, powerShellEscape(account), keychainService, powerShellEscape(account))
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+		cmd.Stdin = strings.NewReader(secret)
+	default:
+		cmd = exec.Command("secret-tool", "store", "--label", keychainService+" "+account, "service", keychainService, "account", account)
+		cmd.Stdin = strings.NewReader(secret)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errorf("failed to save %s to the %s: %v: %s", account, keychainName(), err, strings.TrimSpace(string(out)))
+	}
+	// security -i中命令失败时退出码仍为0，读回确认已保存
+	if runtime.GOOS == "darwin" {
+		if saved, err := keychainGet(account); err != nil || saved != secret {
+			return errorf("failed to save %s to the %s: %s", account, keychainName(), strings.TrimSpace(string(out)))
+		}
+	}
+	return nil
+}
+
+// securityQuote 把参数写成security -i命令行中的双引号字符串
+func securityQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+// keychainGet 读取系统钥匙串中的凭证
+func keychainGet(account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keychainService, "-a", account, "-w")
+	case "windows":
+		script := fmt.Sprintf(`[Windows.Security.Credentials.PasswordVault, Windows.Security.Credentials, ContentType = WindowsRuntime] > $null
+$credential = (New-Object Windows.Security.Credentials.PasswordVault).Retrieve('%s', '%s')
+$credential.RetrievePassword()
+[Console]::Out.Write($credential.Password)`, keychainService, powerShellEscape(account))
+		cmd = exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	default:
+		cmd = exec.Command("secret-tool", "lookup", "service", keychainService, "account", account)
+	}
+	out, err := cmd.Output()
+	secret := strings.TrimRight(string(out), "\r\n")
+	if err != nil || secret == "" {
+		return "", errorf("%s not found in the %s", account, keychainName())
+	}
+	return secret, nil
+}
@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestSecurityQuote(t *testing.T) {
+	tests := map[string]string{
+		"token":         `"token"`,
+		`a"b`:           `"a\"b"`,
+		`back\slash`:    `"back\\slash"`,
+		"with space -w": `"with space -w"`,
+	}
+	for value, want := range tests {
+		if got := securityQuote(value); got != want {
+			t.Errorf("securityQuote(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
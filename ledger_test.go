@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestSplitDSNPassword(t *testing.T) {
+	tests := []struct {
+		dsn, want, password string
+	}{
+		{"postgres://deploy:s3cret@db:5432/ledger", "postgres://deploy@db:5432/ledger", "s3cret"},
+		{"postgresql://deploy:p%40ss@db/ledger?sslmode=require", "postgresql://deploy@db/ledger?sslmode=require", "p@ss"},
+		{"postgres://db/ledger?password=s3cret&user=deploy", "postgres://db/ledger?user=deploy", "s3cret"},
+		{"postgres://deploy@db/ledger", "postgres://deploy@db/ledger", ""},
+		{"host=db user=deploy password=s3cret dbname=ledger", "host=db user=deploy dbname=ledger", "s3cret"},
+		{`host=db password='with space\'s' dbname=ledger`, "host=db dbname=ledger", "with space's"},
+		{"host=db dbname=ledger", "host=db dbname=ledger", ""},
+	}
+	for _, tt := range tests {
+		dsn, password := splitDSNPassword(tt.dsn)
+		if dsn != tt.want || password != tt.password {
+			t.Errorf("splitDSNPassword(%q) = %q, %q, want %q, %q", tt.dsn, dsn, password, tt.want, tt.password)
+		}
+	}
+}
+
+func TestFilterRecordsBeforeLimit(t *testing.T) {
+	var records []deployRecord
+	for i := 0; i < 5; i++ {
+		records = append(records, deployRecord{Project: "app", Env: "staging"})
+	}
+	records = append(records, deployRecord{Project: "app", Env: "prod"}, deployRecord{Project: "other", Env: "prod"})
+
+	got := filterRecords(records, historyQuery{Project: "app", Env: "prod", Limit: 2})
+	if len(got) != 1 || got[0].Env != "prod" || got[0].Project != "app" {
+		t.Errorf("filterRecords = %+v, want the single app/prod record", got)
+	}
+	if got := filterRecords(records, historyQuery{Env: "staging", Limit: 3}); len(got) != 3 {
+		t.Errorf("filterRecords with limit 3 returned %d records", len(got))
+	}
+}
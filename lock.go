@@ -0,0 +1,792 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// forceUnlock 强制释放其他人持有的部署锁
+var forceUnlock = flag.Bool("force-unlock", false, "release a deploy lock held by someone else (e.g. left by a crashed deploy) before deploying")
+
+// LockConfig 部署锁配置，防止多人同时部署同一个环境，backend为lease、file、s3或redis
+type LockConfig struct {
+	Backend   string `yaml:"backend"`              // lease：目标命名空间中的Lease；file：共享目录中的锁文件；s3：S3对象；redis：Redis键
+	TTL       string `yaml:"ttl,omitempty"`        // 锁的有效期，进程异常退出未释放时到期自动失效，默认2h
+	Path      string `yaml:"path,omitempty"`       // file：锁文件目录（如NFS共享目录），默认~/.deploy/locks
+	Bucket    string `yaml:"bucket,omitempty"`     // s3：存放锁对象的bucket
+	Prefix    string `yaml:"prefix,omitempty"`     // s3：对象key前缀，默认deploy-locks
+	Profile   string `yaml:"profile,omitempty"`    // s3：aws命令行使用的profile
+	RedisAddr string `yaml:"redis_addr,omitempty"` // redis：地址，如localhost:6379
+	Password  string `yaml:"password,omitempty"`   // redis：密码
+}
+
+// lockHolder 锁的持有者信息，冲突时展示给其他部署人
+type lockHolder struct {
+	ID         string    `json:"id"`
+	Deployer   string    `json:"deployer"`
+	Hostname   string    `json:"hostname"`
+	PID        int       `json:"pid"`
+	Project    string    `json:"project"`
+	Env        string    `json:"env"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+func (h lockHolder) String() string {
+	return fmt.Sprintf("%s@%s (pid %d) since %s, expires %s", h.Deployer, h.Hostname, h.PID,
+		formatTime(h.AcquiredAt), formatTime(h.ExpiresAt))
+}
+
+// lockBackend 部署锁的存储后端，acquire在锁被他人持有时返回持有者，接管过期的锁需要是原子的
+type lockBackend interface {
+	acquire(ctx context.Context, key string, holder lockHolder) (*lockHolder, error)
+	renew(ctx context.Context, key string, holder lockHolder) error
+	release(ctx context.Context, key string, holder lockHolder) error
+	forceRelease(ctx context.Context, key string) error
+}
+
+// ErrLockHeld 锁被其他部署持有
+var ErrLockHeld = errors.New("deploy lock is held by another deploy")
+
+// errLockLost 续期时锁已经不属于当前进程（被强制释放或接管）
+var errLockLost = errors.New("deploy lock is no longer held by this deploy")
+
+// deployLock 当前进程持有的部署锁
+type deployLock struct {
+	backend lockBackend
+	key     string
+	holder  lockHolder
+	stop    chan struct{} // 关闭时停止续期
+	renewed chan struct{} // 续期协程退出时关闭
+	lost    chan struct{} // 续期发现锁被强制释放或接管时关闭
+}
+
+// lockLost 部署过程中锁被强制释放或接管，部署因此被取消，退出时用于说明失败原因
+var lockLost atomic.Bool
+
+// lockKeyPattern 锁名中不允许的字符，Lease名称需要符合DNS子域名规则
+var lockKeyPattern = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// acquireDeployLock 获取项目环境的部署锁，未配置锁时返回nil
+func acquireDeployLock(ctx context.Context, config *LockConfig, project, env, namespace, configPath string, force bool) (*deployLock, error) {
+	if config == nil {
+		return nil, nil
+	}
+	backend, err := config.backend(namespace, configPath)
+	if err != nil {
+		return nil, err
+	}
+	ttl := 2 * time.Hour
+	if config.TTL != "" {
+		if ttl, err = time.ParseDuration(config.TTL); err != nil || ttl <= 0 {
+			return nil, errorf("invalid lock ttl %q, expected a positive duration such as 2h", config.TTL)
+		}
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+	now := time.Now()
+	holder := lockHolder{
+		ID:         hex.EncodeToString(id),
+		Deployer:   currentDeployer(),
+		PID:        os.Getpid(),
+		Project:    project,
+		Env:        env,
+		AcquiredAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+	holder.Hostname, _ = os.Hostname()
+	key := strings.Trim(lockKeyPattern.ReplaceAllString(strings.ToLower("deploy-lock-"+project+"-"+env), "-"), "-")
+
+	if force {
+		slog.Warn(msg("Force releasing deploy lock of %s/%s", project, env))
+		if err := backend.forceRelease(ctx, key); err != nil {
+			return nil, errorf("failed to force release deploy lock: %w", err)
+		}
+	}
+	current, err := backend.acquire(ctx, key, holder)
+	if err != nil {
+		return nil, errorf("failed to acquire deploy lock: %w", err)
+	}
+	if current != nil {
+		return nil, errorf("%w: %s is being deployed by %s, use --force-unlock if the lock is stuck", ErrLockHeld, env, current)
+	}
+	slog.Info(msg("Acquired deploy lock of %s/%s", project, env))
+	lock := &deployLock{backend: backend, key: key, holder: holder}
+	lock.startRenewal(ttl)
+	return lock, nil
+}
+
+// startRenewal 每隔三分之一ttl延长锁的有效期，部署时间超过ttl时锁不会过期被其他部署接管
+// 锁已经不属于当前进程时停止续期并关闭lost，guard返回的context随之取消
+func (l *deployLock) startRenewal(ttl time.Duration) {
+	l.stop, l.renewed, l.lost = make(chan struct{}), make(chan struct{}), make(chan struct{})
+	backend, holder := l.backend, l.holder
+	go func() {
+		defer close(l.renewed)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ticker.C:
+			}
+			holder.ExpiresAt = time.Now().Add(ttl)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			err := backend.renew(ctx, l.key, holder)
+			cancel()
+			if errors.Is(err, errLockLost) {
+				slog.Error(msg("Deploy lock %s was force released or taken over by another deploy, stopping the deploy", l.key))
+				lockLost.Store(true)
+				close(l.lost)
+				return
+			}
+			if err != nil {
+				slog.Warn(msg("failed to renew deploy lock %s: %v", l.key, err))
+				continue
+			}
+			slog.Debug(fmt.Sprintf("Renewed deploy lock %s until %s", l.key, formatTime(holder.ExpiresAt)))
+		}
+	}()
+}
+
+// guard 返回锁丢失时取消的context，部署不会在没有锁的情况下继续和新的持有者同时进行，nil安全
+func (l *deployLock) guard(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	if l == nil || l.lost == nil {
+		return ctx, cancel
+	}
+	go func() {
+		select {
+		case <-l.lost:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// lockLostError 部署因锁丢失被取消时，用锁丢失代替各步骤返回的context canceled等错误
+func lockLostError(err error) error {
+	if !lockLost.Load() {
+		return err
+	}
+	return errorf("%w, the deploy was stopped so it does not race the new holder (%v)", errLockLost, err)
+}
+
+// release 释放锁，nil安全，可以重复调用，锁已经丢失时不再删除新持有者的锁
+func (l *deployLock) release(ctx context.Context) {
+	if l == nil || l.backend == nil {
+		return
+	}
+	if l.stop != nil {
+		close(l.stop)
+		<-l.renewed
+		l.stop = nil
+	}
+	select {
+	case <-l.lost:
+		l.backend = nil
+		return
+	default:
+	}
+	if err := l.backend.release(ctx, l.key, l.holder); err != nil {
+		slog.Warn(msg("failed to release deploy lock: %v", err))
+	} else {
+		slog.Debug(fmt.Sprintf("Released deploy lock %s", l.key))
+	}
+	l.backend = nil
+}
+
+// backend 根据配置创建锁后端
+func (c *LockConfig) backend(namespace, configPath string) (lockBackend, error) {
+	switch c.Backend {
+	case "lease":
+		if namespace == "" {
+			return nil, errorf("lease lock requires k8s.namespace")
+		}
+		return &leaseLock{namespace: namespace, configPath: configPath}, nil
+	case "file", "":
+		dir := c.Path
+		if dir == "" {
+			dir = "~/.deploy/locks"
+		}
+		dir, err := expandHomePath(dir)
+		if err != nil {
+			return nil, err
+		}
+		return fileLock{dir: dir}, nil
+	case "s3":
+		if c.Bucket == "" {
+			return nil, errorf("s3 lock requires bucket")
+		}
+		return s3Lock{config: *c}, nil
+	case "redis":
+		if c.RedisAddr == "" {
+			return nil, errorf("redis lock requires redis_addr")
+		}
+		return redisLock{addr: c.RedisAddr, password: c.Password}, nil
+	default:
+		return nil, errorf("unknown lock backend %q: must be lease, file, s3 or redis", c.Backend)
+	}
+}
+
+// leaseLock 目标命名空间中的coordination.k8s.io Lease，持有者信息保存在注解中
+type leaseLock struct {
+	namespace  string
+	configPath string
+}
+
+// leaseHolderAnnotation 保存持有者信息的注解
+const leaseHolderAnnotation = "deploy/holder"
+
+func (l *leaseLock) acquire(ctx context.Context, key string, holder lockHolder) (*lockHolder, error) {
+	clientset, err := newKubernetesClient(l.configPath)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+	duration := int32(holder.ExpiresAt.Sub(holder.AcquiredAt).Seconds())
+	now := metav1.NewMicroTime(holder.AcquiredAt)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        key,
+			Namespace:   l.namespace,
+			Annotations: map[string]string{leaseHolderAnnotation: string(data)},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder.ID,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	}
+
+	leases := clientset.CoordinationV1().Leases(l.namespace)
+	_, err = leases.Create(ctx, lease, metav1.CreateOptions{})
+	if err == nil {
+		return nil, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return nil, err
+	}
+
+	existing, err := leases.Get(ctx, key, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var current lockHolder
+	json.Unmarshal([]byte(existing.Annotations[leaseHolderAnnotation]), &current)
+	if existing.Spec.HolderIdentity != nil && *existing.Spec.HolderIdentity != "" && time.Now().Before(current.ExpiresAt) {
+		return &current, nil
+	}
+	// 锁已释放或过期，基于resourceVersion更新，并发获取时只有一个能成功
+	existing.Annotations = lease.Annotations
+	existing.Spec = lease.Spec
+	if _, err := leases.Update(ctx, existing, metav1.UpdateOptions{}); err != nil {
+		if apierrors.IsConflict(err) {
+			return &current, nil
+		}
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (l *leaseLock) renew(ctx context.Context, key string, holder lockHolder) error {
+	clientset, err := newKubernetesClient(l.configPath)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	leases := clientset.CoordinationV1().Leases(l.namespace)
+	existing, err := leases.Get(ctx, key, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return errLockLost
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Spec.HolderIdentity == nil || *existing.Spec.HolderIdentity != holder.ID {
+		return errLockLost
+	}
+	duration := int32(time.Until(holder.ExpiresAt).Seconds())
+	now := metav1.NewMicroTime(time.Now())
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	existing.Annotations[leaseHolderAnnotation] = string(data)
+	existing.Spec.LeaseDurationSeconds = &duration
+	existing.Spec.RenewTime = &now
+	_, err = leases.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+func (l *leaseLock) release(ctx context.Context, key string, holder lockHolder) error {
+	clientset, err := newKubernetesClient(l.configPath)
+	if err != nil {
+		return err
+	}
+	leases := clientset.CoordinationV1().Leases(l.namespace)
+	existing, err := leases.Get(ctx, key, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if existing.Spec.HolderIdentity == nil || *existing.Spec.HolderIdentity != holder.ID {
+		return nil
+	}
+	return leases.Delete(ctx, key, metav1.DeleteOptions{Preconditions: &metav1.Preconditions{ResourceVersion: &existing.ResourceVersion}})
+}
+
+func (l *leaseLock) forceRelease(ctx context.Context, key string) error {
+	clientset, err := newKubernetesClient(l.configPath)
+	if err != nil {
+		return err
+	}
+	err = clientset.CoordinationV1().Leases(l.namespace).Delete(ctx, key, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// fileLock 目录中的锁文件，以O_EXCL创建保证只有一个进程成功，过期的锁先通过rename原子地移走再创建
+type fileLock struct {
+	dir string
+}
+
+func (f fileLock) path(key string) string {
+	return filepath.Join(f.dir, key+".lock")
+}
+
+func (f fileLock) read(key string) (*lockHolder, error) {
+	data, err := os.ReadFile(f.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var holder lockHolder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, errorf("invalid lock file %s: %v", f.path(key), err)
+	}
+	return &holder, nil
+}
+
+func (f fileLock) acquire(ctx context.Context, key string, holder lockHolder) (*lockHolder, error) {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+	// 先写入同目录的临时文件再硬链接到锁文件，链接是原子的且锁文件存在时失败，其他进程不会读到写了一半的锁文件
+	temp, err := os.CreateTemp(f.dir, key+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(temp.Name())
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		err := os.Link(temp.Name(), f.path(key))
+		if err == nil {
+			return nil, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		current, err := f.read(key)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil && time.Now().Before(current.ExpiresAt) {
+			return current, nil
+		}
+		if current != nil {
+			if holder, err := f.takeOver(key, *current); err != nil || holder != nil {
+				return holder, err
+			}
+		}
+	}
+	return nil, errorf("lock file %s keeps being recreated", f.path(key))
+}
+
+// takeOver 把过期的锁文件rename到当前进程独有的文件名，多个进程同时接管时只有一个能移走同一个文件；
+// 移走的已经是别人新获取的锁时放回原处并返回新的持有者
+func (f fileLock) takeOver(key string, expired lockHolder) (*lockHolder, error) {
+	stale := fmt.Sprintf("%s.%d.stale", f.path(key), os.Getpid())
+	if err := os.Rename(f.path(key), stale); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer os.Remove(stale)
+	data, err := os.ReadFile(stale)
+	if err != nil {
+		return nil, err
+	}
+	var moved lockHolder
+	if json.Unmarshal(data, &moved) == nil && moved.ID != expired.ID && time.Now().Before(moved.ExpiresAt) {
+		if err := os.Link(stale, f.path(key)); err != nil && !os.IsExist(err) {
+			return nil, err
+		}
+		return &moved, nil
+	}
+	return nil, nil
+}
+
+func (f fileLock) renew(ctx context.Context, key string, holder lockHolder) error {
+	current, err := f.read(key)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != holder.ID {
+		return errLockLost
+	}
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	temp := fmt.Sprintf("%s.%d.tmp", f.path(key), os.Getpid())
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, f.path(key))
+}
+
+func (f fileLock) release(ctx context.Context, key string, holder lockHolder) error {
+	current, err := f.read(key)
+	if err != nil || current == nil || current.ID != holder.ID {
+		return err
+	}
+	return os.Remove(f.path(key))
+}
+
+func (f fileLock) forceRelease(ctx context.Context, key string) error {
+	err := os.Remove(f.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// s3Lock 通过aws命令行的条件写入创建S3锁对象：If-None-Match创建，接管过期的锁和续期时以If-Match比较ETag
+type s3Lock struct {
+	config LockConfig
+}
+
+func (s s3Lock) objectKey(key string) string {
+	prefix := s.config.Prefix
+	if prefix == "" {
+		prefix = "deploy-locks"
+	}
+	return strings.Trim(prefix, "/") + "/" + key + ".json"
+}
+
+func (s s3Lock) s3api(ctx context.Context, args ...string) ([]byte, error) {
+	if s.config.Profile != "" {
+		args = append(args, "--profile", s.config.Profile)
+	}
+	cmd := exec.CommandContext(ctx, "aws", append([]string{"s3api"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errorf("s3 lock: aws s3api %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// read 读取锁对象和ETag，对象不存在时返回nil
+func (s s3Lock) read(ctx context.Context, key string) (*lockHolder, string, error) {
+	file, err := os.CreateTemp("", "deploy-lock-*.json")
+	if err != nil {
+		return nil, "", err
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	out, err := s.s3api(ctx, "get-object", "--bucket", s.config.Bucket, "--key", s.objectKey(key), file.Name())
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchKey") {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	var object struct {
+		ETag string `json:"ETag"`
+	}
+	if err := json.Unmarshal(out, &object); err != nil || object.ETag == "" {
+		return nil, "", errorf("s3 lock: no ETag in get-object output")
+	}
+	data, err := os.ReadFile(file.Name())
+	if err != nil {
+		return nil, "", err
+	}
+	var holder lockHolder
+	if err := json.Unmarshal(data, &holder); err != nil {
+		return nil, "", errorf("s3 lock: invalid lock object: %v", err)
+	}
+	return &holder, object.ETag, nil
+}
+
+// put 写入锁对象，condition为--if-none-match *或--if-match <ETag>，条件不满足时返回false
+func (s s3Lock) put(ctx context.Context, key string, holder lockHolder, condition ...string) (bool, error) {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return false, err
+	}
+	file, err := os.CreateTemp("", "deploy-lock-*.json")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file.Name())
+	file.Write(data)
+	file.Close()
+
+	args := append([]string{"put-object", "--bucket", s.config.Bucket, "--key", s.objectKey(key),
+		"--body", file.Name(), "--content-type", "application/json"}, condition...)
+	if _, err := s.s3api(ctx, args...); err != nil {
+		if strings.Contains(err.Error(), "PreconditionFailed") || strings.Contains(err.Error(), "ConditionalRequestConflict") {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s s3Lock) acquire(ctx context.Context, key string, holder lockHolder) (*lockHolder, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		created, err := s.put(ctx, key, holder, "--if-none-match", "*")
+		if err != nil || created {
+			return nil, err
+		}
+		current, etag, err := s.read(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		if current == nil {
+			continue
+		}
+		if time.Now().Before(current.ExpiresAt) {
+			return current, nil
+		}
+		// 锁已过期，只有ETag仍是读到的过期对象时才覆盖，并发接管时只有一个能成功
+		replaced, err := s.put(ctx, key, holder, "--if-match", etag)
+		if err != nil || replaced {
+			return nil, err
+		}
+	}
+	return nil, errorf("s3 lock: lock object keeps being recreated")
+}
+
+func (s s3Lock) renew(ctx context.Context, key string, holder lockHolder) error {
+	current, etag, err := s.read(ctx, key)
+	if err != nil {
+		return err
+	}
+	if current == nil || current.ID != holder.ID {
+		return errLockLost
+	}
+	renewed, err := s.put(ctx, key, holder, "--if-match", etag)
+	if err == nil && !renewed {
+		return errLockLost
+	}
+	return err
+}
+
+func (s s3Lock) release(ctx context.Context, key string, holder lockHolder) error {
+	current, _, err := s.read(ctx, key)
+	if err != nil || current == nil || current.ID != holder.ID {
+		return err
+	}
+	return s.forceRelease(ctx, key)
+}
+
+func (s s3Lock) forceRelease(ctx context.Context, key string) error {
+	_, err := s.s3api(ctx, "delete-object", "--bucket", s.config.Bucket, "--key", s.objectKey(key))
+	return err
+}
+
+// redisLock Redis键，SET NX PX获取，比较持有者后删除
+type redisLock struct {
+	addr     string
+	password string
+}
+
+// redisReleaseScript 只有持有者一致时才删除
+const redisReleaseScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+
+// redisRenewScript 只有持有者ID一致时才更新持有者信息和过期时间，否则返回nil
+const redisRenewScript = `local v = redis.call("get", KEYS[1]) if v and cjson.decode(v).id == ARGV[1] then return redis.call("set", KEYS[1], ARGV[2], "PX", ARGV[3]) else return false end`
+
+// command 建立连接并依次执行命令，返回最后一个命令的回复，nil回复返回nil
+func (r redisLock) command(ctx context.Context, args ...string) (*string, error) {
+	dialer := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, errorf("redis lock: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	reader := bufio.NewReader(conn)
+
+	commands := [][]string{args}
+	if r.password != "" {
+		commands = [][]string{{"AUTH", r.password}, args}
+	}
+	var reply *string
+	for _, command := range commands {
+		var request bytes.Buffer
+		fmt.Fprintf(&request, "*%d\r\n", len(command))
+		for _, arg := range command {
+			fmt.Fprintf(&request, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+		if _, err := conn.Write(request.Bytes()); err != nil {
+			return nil, errorf("redis lock: %v", err)
+		}
+		if reply, err = readRedisReply(reader); err != nil {
+			return nil, errorf("redis lock: %s: %v", command[0], err)
+		}
+	}
+	return reply, nil
+}
+
+// readRedisReply 读取一个RESP回复，支持简单字符串、错误、整数和bulk字符串
+func readRedisReply(reader *bufio.Reader) (*string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errorf("empty reply")
+	}
+	value := line[1:]
+	switch line[0] {
+	case '+', ':':
+		return &value, nil
+	case '-':
+		return nil, errors.New(value)
+	case '$':
+		size, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errorf("invalid reply %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		bulk := string(data[:size])
+		return &bulk, nil
+	default:
+		return nil, errorf("unsupported reply %q", line)
+	}
+}
+
+func (r redisLock) acquire(ctx context.Context, key string, holder lockHolder) (*lockHolder, error) {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return nil, err
+	}
+	ttl := holder.ExpiresAt.Sub(holder.AcquiredAt).Milliseconds()
+	for attempt := 0; attempt < 2; attempt++ {
+		reply, err := r.command(ctx, "SET", key, string(data), "NX", "PX", strconv.FormatInt(ttl, 10))
+		if err != nil {
+			return nil, err
+		}
+		if reply != nil {
+			return nil, nil
+		}
+		// 键已存在，读取持有者，到期由Redis自动删除；读取前刚好过期时重新获取
+		value, err := r.command(ctx, "GET", key)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+		var current lockHolder
+		if err := json.Unmarshal([]byte(*value), &current); err != nil {
+			return nil, errorf("redis lock: invalid lock value: %v", err)
+		}
+		return &current, nil
+	}
+	return nil, errorf("redis lock: lock key keeps being recreated")
+}
+
+func (r redisLock) renew(ctx context.Context, key string, holder lockHolder) error {
+	data, err := json.Marshal(holder)
+	if err != nil {
+		return err
+	}
+	ttl := time.Until(holder.ExpiresAt).Milliseconds()
+	reply, err := r.command(ctx, "EVAL", redisRenewScript, "1", key, holder.ID, string(data), strconv.FormatInt(ttl, 10))
+	if err != nil {
+		return err
+	}
+	if reply == nil {
+		return errLockLost
+	}
+	return nil
+}
+
+func (r redisLock) release(ctx context.Context, key string, holder lockHolder) error {
+	value, err := r.command(ctx, "GET", key)
+	if err != nil || value == nil {
+		return err
+	}
+	var current lockHolder
+	if json.Unmarshal([]byte(*value), &current) != nil || current.ID != holder.ID {
+		return nil
+	}
+	_, err = r.command(ctx, "EVAL", redisReleaseScript, "1", key, *value)
+	return err
+}
+
+func (r redisLock) forceRelease(ctx context.Context, key string) error {
+	_, err := r.command(ctx, "DEL", key)
+	return err
+}
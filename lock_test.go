@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testHolder(id string, expiresIn time.Duration) lockHolder {
+	now := time.Now()
+	return lockHolder{ID: id, Deployer: id, AcquiredAt: now, ExpiresAt: now.Add(expiresIn)}
+}
+
+func TestFileLockAcquireRenewRelease(t *testing.T) {
+	ctx := context.Background()
+	lock := fileLock{dir: t.TempDir()}
+	alice, bob := testHolder("alice", time.Hour), testHolder("bob", time.Hour)
+
+	if current, err := lock.acquire(ctx, "k", alice); err != nil || current != nil {
+		t.Fatalf("first acquire = %v, %v", current, err)
+	}
+	if current, err := lock.acquire(ctx, "k", bob); err != nil || current == nil || current.ID != "alice" {
+		t.Fatalf("second acquire = %v, %v, want held by alice", current, err)
+	}
+
+	alice.ExpiresAt = alice.ExpiresAt.Add(time.Hour)
+	if err := lock.renew(ctx, "k", alice); err != nil {
+		t.Fatalf("renew: %v", err)
+	}
+	if current, _ := lock.read("k"); !current.ExpiresAt.Equal(alice.ExpiresAt) {
+		t.Errorf("expires at %v after renew, want %v", current.ExpiresAt, alice.ExpiresAt)
+	}
+	if err := lock.renew(ctx, "k", bob); !errors.Is(err, errLockLost) {
+		t.Errorf("renew by bob = %v, want errLockLost", err)
+	}
+
+	if err := lock.release(ctx, "k", bob); err != nil {
+		t.Fatalf("release by bob: %v", err)
+	}
+	if current, _ := lock.read("k"); current == nil || current.ID != "alice" {
+		t.Fatalf("release by bob removed alice's lock")
+	}
+	if err := lock.release(ctx, "k", alice); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if current, _ := lock.read("k"); current != nil {
+		t.Errorf("lock still held by %s after release", current.ID)
+	}
+}
+
+func TestFileLockTakeOverExpired(t *testing.T) {
+	ctx := context.Background()
+	lock := fileLock{dir: t.TempDir()}
+	if _, err := lock.acquire(ctx, "k", testHolder("crashed", -time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		owners []string
+	)
+	for _, id := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			current, err := lock.acquire(ctx, "k", testHolder(id, time.Hour))
+			if err != nil {
+				return
+			}
+			if current == nil {
+				mu.Lock()
+				owners = append(owners, id)
+				mu.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+	if len(owners) != 1 {
+		t.Fatalf("expired lock taken over by %v, want exactly one owner", owners)
+	}
+	if current, _ := lock.read("k"); current == nil || current.ID != owners[0] {
+		t.Errorf("lock file held by %v, want %s", current, owners[0])
+	}
+}
+
+func TestDeployLockRenewal(t *testing.T) {
+	ctx := context.Background()
+	backend := fileLock{dir: t.TempDir()}
+	holder := testHolder("alice", 60*time.Millisecond)
+	if _, err := backend.acquire(ctx, "k", holder); err != nil {
+		t.Fatal(err)
+	}
+	lock := &deployLock{backend: backend, key: "k", holder: holder}
+	lock.startRenewal(60 * time.Millisecond)
+	time.Sleep(150 * time.Millisecond)
+
+	current, err := backend.read("k")
+	if err != nil || current == nil {
+		t.Fatalf("read: %v, %v", current, err)
+	}
+	if !current.ExpiresAt.After(holder.ExpiresAt) {
+		t.Errorf("lock not renewed, expires at %v", current.ExpiresAt)
+	}
+	if other, _ := backend.acquire(ctx, "k", testHolder("bob", time.Hour)); other == nil || other.ID != "alice" {
+		t.Errorf("renewed lock was taken over")
+	}
+	lock.release(ctx)
+	if current, _ := backend.read("k"); current != nil {
+		t.Errorf("lock still held by %s after release", current.ID)
+	}
+}
+
+func TestDeployLockLost(t *testing.T) {
+	t.Cleanup(func() { lockLost.Store(false) })
+	ctx := context.Background()
+	backend := fileLock{dir: t.TempDir()}
+	holder := testHolder("alice", time.Hour)
+	if _, err := backend.acquire(ctx, "k", holder); err != nil {
+		t.Fatal(err)
+	}
+	lock := &deployLock{backend: backend, key: "k", holder: holder}
+	lock.startRenewal(60 * time.Millisecond)
+	deployCtx, stop := lock.guard(ctx)
+	defer stop()
+
+	// 其他人强制释放后重新获取了锁
+	if err := backend.forceRelease(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := backend.acquire(ctx, "k", testHolder("bob", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-deployCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("deploy context not cancelled after the lock was lost")
+	}
+	if err := lockLostError(deployCtx.Err()); !errors.Is(err, errLockLost) {
+		t.Errorf("lockLostError = %v, want errLockLost", err)
+	}
+
+	lock.release(ctx)
+	if current, _ := backend.read("k"); current == nil || current.ID != "bob" {
+		t.Errorf("release removed the new holder's lock, held by %v", current)
+	}
+}
+
+func TestFileLockNoPartialFile(t *testing.T) {
+	ctx := context.Background()
+	backend := fileLock{dir: t.TempDir()}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		key := fmt.Sprintf("k%d", i)
+		go func() {
+			defer wg.Done()
+			backend.acquire(ctx, key, testHolder("alice", time.Hour))
+		}()
+		go func() {
+			defer wg.Done()
+			// 锁文件出现时已经是完整的JSON
+			for j := 0; j < 100; j++ {
+				if _, err := backend.read(key); err != nil {
+					t.Errorf("read while acquiring: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if matches, _ := filepath.Glob(filepath.Join(backend.dir, "*.tmp")); len(matches) > 0 {
+		t.Errorf("temporary files left: %v", matches)
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 日志相关的命令行参数
+var (
+	logFormat = flag.String("log-format", "text", "terminal log format: text or json")
+	logLevel  = flag.String("log-level", "info", "terminal log level: debug, info, warn or error")
+	logFile   = flag.String("log-file", "", "also write full debug-level output, including build logs, to this file")
+)
+
+// rawOutput 构建日志等原样输出的内容，使用--log-file时同时写入日志文件，输出前屏蔽密钥
+var rawOutput io.Writer = maskingWriter{activityWriter{os.Stdout}}
+
+// setupLogging 根据命令行参数设置默认的slog logger，返回关闭日志文件的函数
+func setupLogging() (func(), error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		return nil, errorf("invalid --log-level %q: %v", *logLevel, err)
+	}
+
+	stdout := maskingWriter{activityWriter{os.Stdout}}
+	var terminal slog.Handler
+	switch *logFormat {
+	case "text":
+		terminal = newConsoleHandler(stdout, level)
+	case "json":
+		terminal = slog.NewJSONHandler(stdout, &slog.HandlerOptions{Level: level})
+	default:
+		return nil, errorf("invalid --log-format %q: must be text or json", *logFormat)
+	}
+
+	// 构建日志中的长行在终端中截断，日志文件中保留完整内容
+	var console io.Writer = activityWriter{os.Stdout}
+	if width := consoleLineWidth(); width > 0 {
+		console = &truncatingWriter{w: console, width: width}
+	}
+
+	closeEvents, err := openEventsFile()
+	if err != nil {
+		return nil, err
+	}
+	if *logFile == "" {
+		slog.SetDefault(slog.New(teeHandler{terminal, eventHandler{}}))
+		rawOutput = maskingWriter{io.MultiWriter(console, eventWriter{})}
+		return closeEvents, nil
+	}
+
+	path, err := expandHomePath(*logFile)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		closeEvents()
+		return nil, errorf("failed to open log file: %v", err)
+	}
+	var fileHandler slog.Handler = newConsoleHandler(maskingWriter{file}, slog.LevelDebug)
+	if *logFormat == "json" {
+		fileHandler = slog.NewJSONHandler(maskingWriter{file}, &slog.HandlerOptions{Level: slog.LevelDebug})
+	}
+	slog.SetDefault(slog.New(teeHandler{terminal, fileHandler, eventHandler{}}))
+	rawOutput = maskingWriter{io.MultiWriter(console, file, eventWriter{})}
+	return func() {
+		file.Close()
+		closeEvents()
+	}, nil
+}
+
+// statusLog 轮询时每次检查输出的状态行，与上一次检查相同的行按debug级别记录，
+// 终端和CI日志中只显示状态变化，--log-level debug和--log-file中仍保留每次检查的完整输出
+type statusLog struct {
+	previous map[string]bool
+	current  map[string]bool
+}
+
+func newStatusLog() *statusLog {
+	return &statusLog{previous: make(map[string]bool), current: make(map[string]bool)}
+}
+
+// next 开始新的一次检查
+func (l *statusLog) next() {
+	l.previous, l.current = l.current, make(map[string]bool)
+}
+
+// info 输出状态行，上一次检查已经输出过相同的内容时降为debug级别
+func (l *statusLog) info(message string) {
+	l.current[message] = true
+	if l.previous[message] {
+		slog.Debug(message)
+		return
+	}
+	slog.Info(message)
+}
+
+// consoleHandler 以"[时间] 消息 key=value"的格式输出日志，警告和错误加上前缀
+type consoleHandler struct {
+	w     io.Writer
+	level slog.Leveler
+	attrs []slog.Attr
+	mu    *sync.Mutex
+}
+
+func newConsoleHandler(w io.Writer, level slog.Leveler) *consoleHandler {
+	return &consoleHandler{w: w, level: level, mu: &sync.Mutex{}}
+}
+
+func (h *consoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *consoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteString("[" + formatLogTime(r.Time) + "] ")
+	switch {
+	case r.Level >= slog.LevelError:
+		buf.WriteString("Error: ")
+	case r.Level >= slog.LevelWarn:
+		buf.WriteString("Warning: ")
+	}
+	buf.WriteString(r.Message)
+
+	writeAttr := func(attr slog.Attr) bool {
+		value := attr.Value.String()
+		if strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		buf.WriteString(" " + attr.Key + "=" + value)
+		return true
+	}
+	for _, attr := range h.attrs {
+		writeAttr(attr)
+	}
+	r.Attrs(writeAttr)
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+func (h *consoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+func (h *consoleHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// teeHandler 把日志同时交给多个handler，各handler按自己的级别过滤
+type teeHandler []slog.Handler
+
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	handlers := make(teeHandler, len(t))
+	for i, h := range t {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
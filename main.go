@@ -1,67 +1,197 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"math"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bndr/gojenkins"
 	"gopkg.in/yaml.v2"
 	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	k8sversion "k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
 )
 
+// ErrConcurrentRollout 监控过程中部署被其他发布（其他人的部署或kubectl apply）修改
+var ErrConcurrentRollout = errors.New("deployment was changed by a competing rollout")
+
+// exitCodeConcurrentRollout 检测到并发发布时的退出码，与普通失败区分
+const exitCodeConcurrentRollout = 3
+
+// inClusterConfigPath 表示只使用集群内service account配置的特殊配置路径
+const inClusterConfigPath = "in-cluster"
+
+// serviceAccountNamespaceFile pod内service account所在命名空间的文件
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// 命令行参数
+var (
+	debugOnFailure  = flag.Bool("debug-on-failure", false, "offer to attach an ephemeral debug container to crash-looping pods")
+	outputFormat    = flag.String("output", "text", "format of the final deploy summary: text or json")
+	summaryFile     = flag.String("summary-file", "", "also write a JSON summary of the run, including the failure reason, to this file for CI")
+	noDesktopNotify = flag.Bool("no-desktop-notify", false, "do not show a desktop notification when the deploy finishes")
+	promoteFrom     = flag.String("promote-from", "", "promote the build last successfully deployed to this env, exposing $promoted_commit, $promoted_build and $promoted_image to params")
+)
+
+// failureHooks 部署失败退出前依次执行的回调，用于释放锁、发送失败通知、记录历史和审计
+var failureHooks []func(message string)
+
+// runFailureHooks 执行失败回调，每个回调只执行一次
+func runFailureHooks(err error) {
+	hooks := failureHooks
+	failureHooks = nil
+	for _, hook := range hooks {
+		hook(err.Error())
+	}
+}
+
+// exitCode 错误对应的退出码：部署被其他发布修改时为3，其他失败为1
+func exitCode(err error) int {
+	if errors.Is(err, ErrConcurrentRollout) {
+		return exitCodeConcurrentRollout
+	}
+	return 1
+}
+
+// exitWithError 部署失败的统一出口：执行失败回调，记录错误和认证提示，按错误类型退出
+func exitWithError(err error) {
+	err = interruptionError(lockLostError(err))
+	annotateGitHubError(err)
+	runFailureHooks(err)
+	removeSimulationHome()
+	slog.Error(localize(err))
+	for _, hint := range authFailureHints() {
+		slog.Error(hint)
+	}
+	os.Exit(exitCode(err))
+}
+
+// fatalf 无法返回错误的地方（如信号处理）直接以失败退出
+func fatalf(format string, args ...interface{}) {
+	exitWithError(errorf(format, args...))
+}
+
 // Config represents the structure of the YAML configuration file
 type Project struct {
-	Name string `yaml:"name"`
-	Envs []Env  `yaml:"envs"`
+	Name       string          `yaml:"name"`
+	Envs       []Env           `yaml:"envs"`
+	BranchEnvs []BranchEnvRule `yaml:"branch_envs,omitempty"` // 没有指定环境时按当前分支选择的默认环境
+	Path       string          `yaml:"path,omitempty"`        // monorepo中项目相对仓库根目录的子目录，如services/api，在该目录下执行时识别为此项目
 }
 
 type Env struct {
-	Name    string    `yaml:"name"`
-	JobName string    `yaml:"job_name"`
-	Params  []Param   `yaml:"params,omitempty"`
-	K8s     K8sConfig `yaml:"k8s,omitempty"`
+	Name                string               `yaml:"name"`
+	JobName             string               `yaml:"job_name"`
+	Params              []Param              `yaml:"params,omitempty"`
+	K8s                 K8sConfig            `yaml:"k8s,omitempty"`
+	SmokeChecks         []SmokeCheck         `yaml:"smoke_checks,omitempty"`
+	Notifications       *NotificationsConfig `yaml:"notifications,omitempty"`          // 环境单独的通知配置，覆盖全局配置
+	Critical            bool                 `yaml:"critical,omitempty"`               // 部署失败时通过PagerDuty/Opsgenie告警
+	Lock                *LockConfig          `yaml:"lock,omitempty"`                   // 环境单独的部署锁配置，覆盖全局配置
+	AllowedBranches     []string             `yaml:"allowed_branches,omitempty"`       // 允许部署的分支，支持通配符，如main、release/*
+	AllowedUsers        []string             `yaml:"allowed_users,omitempty"`          // 允许部署的人，本机用户名或服务模式下的认证身份，为空时不限制
+	GitTag              *GitTagConfig        `yaml:"git_tag,omitempty"`                // 部署成功后给部署的提交打tag
+	MonitorAfter        string               `yaml:"monitor_after,omitempty"`          // 构建日志中出现该内容时开始监控滚动，之后的阶段（如部署后验证）与监控同时进行
+	AbortBuildOnFailure bool                 `yaml:"abort_build_on_failure,omitempty"` // 部署失败时中止仍在运行的构建，需要配置monitor_after
+	ChangeTicket        *ChangeTicketConfig  `yaml:"change_ticket,omitempty"`          // 部署该环境必须通过--ticket关联变更单
 }
 
 type K8sConfig struct {
-	Namespace  string `yaml:"namespace"`
-	Deployment string `yaml:"deployment"`
-	ConfigPath string `yaml:"config_path,omitempty"`
+	Namespace    string   `yaml:"namespace"`
+	Deployment   string   `yaml:"deployment"`
+	ConfigPath   string   `yaml:"config_path,omitempty"`
+	InCluster    bool     `yaml:"in_cluster,omitempty"`    // 只使用集群内service account配置，未配置namespace时自动检测
+	QPS          float32  `yaml:"qps,omitempty"`           // 访问API server的QPS限制，优先于全局配置
+	Burst        int      `yaml:"burst,omitempty"`         // 访问API server的突发请求数，优先于全局配置
+	Timeout      string   `yaml:"timeout,omitempty"`       // 单个API请求的超时时间，如30s，优先于全局配置
+	As           string   `yaml:"as,omitempty"`            // 以该用户身份访问集群（impersonation），操作受该用户的RBAC限制，审计日志中记录为该用户
+	AsGroups     []string `yaml:"as_groups,omitempty"`     // 模拟的用户组，需要同时配置as
+	ResumePaused bool     `yaml:"resume_paused,omitempty"` // 部署处于暂停状态时自动恢复
+	ZeroReplicas string   `yaml:"zero_replicas,omitempty"` // 副本数为0时的处理方式：skip(默认)或wait
+	DebugImage   string   `yaml:"debug_image,omitempty"`   // --debug-on-failure使用的调试镜像，默认busybox
+	Service      string   `yaml:"service,omitempty"`       // 滚动完成后检查该Service的EndpointSlice包含新pod
+	Ingress      string   `yaml:"ingress,omitempty"`       // 滚动完成后检查该Ingress可以正常响应
+
+	CronJob    string `yaml:"cronjob,omitempty"`     // CronJob类型的目标，构建后校验镜像已更新
+	Job        string `yaml:"job,omitempty"`         // Job类型的目标，构建后等待由构建重新创建的Job完成
+	TriggerJob bool   `yaml:"trigger_job,omitempty"` // CronJob镜像更新后按模板手动触发一次Job并等待完成
+	JobTimeout string `yaml:"job_timeout,omitempty"` // 等待Job完成的超时时间，默认10m
+
+	MinReadyPercent int  `yaml:"min_ready_percent,omitempty"` // 新pod可用比例达到该值即视为成功，默认100
+	AllowOldPods    bool `yaml:"allow_old_pods,omitempty"`    // 达到成功条件时允许仍有旧pod
+
+	PruneReplicaSets bool `yaml:"prune_replicasets,omitempty"` // 滚动成功后删除超出revisionHistoryLimit和不属于deployment的已缩容旧ReplicaSet
+
+	BlueGreen *BlueGreenConfig `yaml:"blue_green,omitempty"` // 蓝绿部署，配置后deployment由颜色决定
+	Canary    *CanaryConfig    `yaml:"canary,omitempty"`     // 金丝雀发布，构建发布到金丝雀部署，观察通过后推广到deployment
+
+	TrafficShift *TrafficShiftConfig `yaml:"traffic_shift,omitempty"` // 滚动完成后通过VirtualService/HTTPRoute逐步切换流量
+	ConfigCheck  *ConfigCheck        `yaml:"config_check,omitempty"`  // 仅发布配置时校验ConfigMap/Secret变化并已重启部署
 }
 
 type GlobalK8sConfig struct {
-	ConfigPath string `yaml:"config_path"`
+	ConfigPath string   `yaml:"config_path"`
+	InCluster  bool     `yaml:"in_cluster,omitempty"`
+	QPS        float32  `yaml:"qps,omitempty"`       // 访问API server的QPS限制，默认5
+	Burst      int      `yaml:"burst,omitempty"`     // 访问API server的突发请求数，默认10
+	Timeout    string   `yaml:"timeout,omitempty"`   // 单个API请求的超时时间，默认30s
+	As         string   `yaml:"as,omitempty"`        // 默认模拟的用户，环境配置优先
+	AsGroups   []string `yaml:"as_groups,omitempty"` // 默认模拟的用户组
 }
 
 type Param struct {
-	Name  string `yaml:"name"`
-	Value string `yaml:"value"`
+	Name   string `yaml:"name"`
+	Value  string `yaml:"value"`
+	Secret bool   `yaml:"secret,omitempty"` // 值为密码等敏感信息，在所有输出中屏蔽
 }
 
 type Config struct {
-	JenkinsURL string          `yaml:"jenkins_url"`
-	Username   string          `yaml:"username"`
-	APIToken   string          `yaml:"api_token"`
-	K8s        GlobalK8sConfig `yaml:"k8s"`
-	Projects   []Project       `yaml:"projects"`
+	JenkinsURL    string               `yaml:"jenkins_url"`
+	Username      string               `yaml:"username"`
+	APIToken      string               `yaml:"api_token"`
+	K8s           GlobalK8sConfig      `yaml:"k8s"`
+	Notifications *NotificationsConfig `yaml:"notifications,omitempty"` // 部署开始、成功、失败时的通知渠道
+	Ledger        *LedgerConfig        `yaml:"ledger,omitempty"`        // 团队共享的部署台账
+	Audit         *AuditConfig         `yaml:"audit,omitempty"`         // 审计日志
+	ReadOnly      bool                 `yaml:"read_only,omitempty"`     // 只读模式，只允许查看历史、审计日志等，不能部署
+	Server        *ServerConfig        `yaml:"server,omitempty"`        // deploy serve的配置
+	Pipelines     []PipelineConfig     `yaml:"pipelines,omitempty"`     // 环境晋级流水线
+	Groups        []ProjectGroup       `yaml:"groups,omitempty"`        // 项目组，deploy run --group部署组内所有项目
+	Lock          *LockConfig          `yaml:"lock,omitempty"`          // 部署锁，防止多人同时部署同一个环境
+	HTTP          *HTTPConfig          `yaml:"http,omitempty"`          // HTTP请求的超时时间
+	TimeZone      string               `yaml:"time_zone,omitempty"`     // 输出中时间的时区：local(默认)、UTC或IANA时区名
+	TimeFormat    string               `yaml:"time_format,omitempty"`   // 输出中时间的格式：default、rfc3339或relative
+	Lang          string               `yaml:"lang,omitempty"`          // 控制台输出的语言：en(默认)或zh-CN
+	Bell          bool                 `yaml:"bell,omitempty"`          // 部署结束时终端响铃
+	OnFinish      string               `yaml:"on_finish,omitempty"`     // 部署结束时执行的命令，结果通过DEPLOY_RESULT等环境变量传入
+	JenkinsQueue  *JenkinsQueueConfig  `yaml:"jenkins_queue,omitempty"` // 等待构建离开Jenkins队列的期限
+	JenkinsCache  *JenkinsCacheConfig  `yaml:"jenkins_cache,omitempty"` // Jenkins任务元数据的磁盘缓存
+	Registries    []RegistryConfig     `yaml:"registries,omitempty"`    // 镜像仓库凭证，拉取镜像失败时查询tag是否存在
+	BuildLogs     *BuildLogsConfig     `yaml:"build_logs,omitempty"`    // 构建日志的保存和保留设置
+	Projects      []Project            `yaml:"projects"`
 }
 
 // LoadConfig loads the configuration from the specified YAML file
 func LoadConfig(filePath string) (*Config, error) {
-	data, err := ioutil.ReadFile(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
@@ -75,32 +205,143 @@ func LoadConfig(filePath string) (*Config, error) {
 	return &config, nil
 }
 
+// loadDefaultConfig 加载用户主目录下的deploy_config.yaml
+func loadDefaultConfig() (*Config, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, errorf("failed to get user home directory: %v", err)
+	}
+	config, err := LoadConfig(filepath.Join(homeDir, "deploy_config.yaml"))
+	if err != nil {
+		return nil, errorf("failed to load config: %v", err)
+	}
+	if err := setHTTPTimeouts(config.HTTP); err != nil {
+		return nil, err
+	}
+	if err := setTimeDisplay(config.TimeZone, config.TimeFormat); err != nil {
+		return nil, err
+	}
+	if err := setJenkinsQueueDeadline(config.JenkinsQueue); err != nil {
+		return nil, err
+	}
+	if err := setBuildLogRetention(config.BuildLogs); err != nil {
+		return nil, err
+	}
+	if err := setJenkinsCacheTTL(config.JenkinsCache); err != nil {
+		return nil, err
+	}
+	registries = config.Registries
+	if err := setLocale(config.Lang); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// subcommands 除部署之外的子命令，deploy <子命令> [参数]
+var subcommands = map[string]func(args []string) error{
+	"audit":    runAuditCommand,
+	"batch":    runBatchCommand,
+	"help":     runHelpCommand,
+	"history":  runHistoryCommand,
+	"login":    runLoginCommand,
+	"metrics":  runMetricsCommand,
+	"promote":  runPromoteCommand,
+	"restore":  runRestoreCommand,
+	"resume":   runResumeCommand,
+	"run":      runRunCommand,
+	"serve":    runServeCommand,
+	"snapshot": runSnapshotCommand,
+}
+
 func main() {
+	os.Args = append(os.Args[:1], extractLangFlag(extractReadOnlyFlag(os.Args[1:]))...)
+	if err := setLocale(""); err != nil {
+		fmt.Fprintln(os.Stderr, tr("Error:"), localize(err))
+		os.Exit(1)
+	}
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			slog.SetDefault(slog.New(newConsoleHandler(maskingWriter{activityWriter{os.Stdout}}, slog.LevelInfo)))
+			err := checkReadOnlySubcommand(os.Args[1], os.Args[2:])
+			if err == nil {
+				err = run(os.Args[2:])
+			}
+			if err != nil {
+				err = interruptionError(lockLostError(err))
+				annotateGitHubError(err)
+				runFailureHooks(err)
+				fmt.Fprintln(os.Stderr, tr("Error:"), localize(err))
+				for _, hint := range authFailureHints() {
+					fmt.Fprintln(os.Stderr, hint)
+				}
+				os.Exit(exitCode(err))
+			}
+			return
+		}
+	}
+
 	execPath, err := os.Getwd()
 	if err != nil {
-		fmt.Println("Error:", err)
-		return
+		fmt.Fprintln(os.Stderr, tr("Error:"), localize(err))
+		os.Exit(1)
+	}
+
+	// 获取环境，环境名之后的参数同样按flag解析
+	flag.Parse()
+	envName := flag.Arg(0)
+	if flag.NArg() > 1 {
+		flag.CommandLine.Parse(flag.Args()[1:])
 	}
 
+	closeLog, err := setupLogging()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, tr("Error:"), localize(err))
+		os.Exit(1)
+	}
+	defer closeLog()
+
+	// 整个部署的期限，集群或Jenkins卡住时不会一直等待
+	ctx, cancel := deployDeadline(context.Background())
+	defer cancel()
+	defer removeSimulationHome()
+	if err := runDeploy(ctx, execPath, envName); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !interrupted.Load() {
+			err = errorf("Deploy did not finish within %v (--timeout): %w", *deployTimeout, err)
+		}
+		exitWithError(err)
+	}
+}
+
+// runDeploy 部署当前目录的项目到环境，失败时返回错误，由main统一执行失败回调并退出
+func runDeploy(ctx context.Context, execPath, envName string) error {
 	// 获取目录的名称作为项目名称
 	projectName := filepath.Base(execPath)
 
-	// 获取环境
-	envName := os.Args[1]
+	slog.Info(msg("project: %s, env: %s", projectName, envName))
+	summary := newDeploySummary(projectName, envName)
+	failureHooks = append(failureHooks, summary.fail)
 
-	fmt.Printf("project: %s, env: %s\n", projectName, envName)
-
-	homeDir, err := os.UserHomeDir()
+	// --simulate时使用内置的模拟Jenkins和Kubernetes
+	var config *Config
+	var err error
+	if *simulate != "" {
+		config, err = startSimulation(*simulate, projectName, envName)
+	} else {
+		config, err = loadDefaultConfig()
+	}
 	if err != nil {
-		fmt.Println("Error getting user home directory:", err)
-		return
+		return err
+	}
+	// 模拟部署不访问真实的Jenkins和集群，只读模式下也可以使用
+	if readOnlyMode(config) && *simulate == "" {
+		return errorf("Deploying is not allowed in read-only mode, only history, audit, metrics and run list are available (use --simulate to try a deploy)")
 	}
 
-	configFilePath := filepath.Join(homeDir, "deploy_config.yaml")
-
-	config, err := LoadConfig(configFilePath)
-	if err != nil {
-		log.Fatalf("Failed to load config: %s", err)
+	// monorepo中按项目配置的path识别项目
+	if name := detectProjectName(config, execPath); name != projectName {
+		projectName = name
+		summary.Project = name
+		slog.Info(msg("Detected project %s from its path in the repository", projectName))
 	}
 
 	// Find the project in the configuration
@@ -112,7 +353,19 @@ func main() {
 		}
 	}
 	if p.Name == "" {
-		log.Fatalf("Project not found in config: %s", projectName)
+		return unknownProjectError(config, projectName)
+	}
+
+	// 没有指定环境时按branch_envs规则选择
+	if envName == "" {
+		var branch string
+		envName, branch = p.defaultEnvForBranch()
+		if envName == "" {
+			return errorf("No env given and no branch_envs rule of %s matches branch %s: usage: deploy <env-name>, available envs: %s (run deploy help for all commands)",
+				projectName, branch, strings.Join(p.envNames(), ", "))
+		}
+		slog.Info(msg("No env given, deploying branch %s to %s", branch, envName))
+		summary.Env = envName
 	}
 
 	var env Env
@@ -123,294 +376,867 @@ func main() {
 		}
 	}
 	if env.Name == "" {
-		log.Fatalf("Env not found in config: %s", envName)
+		return unknownEnvError(p, envName)
+	}
+	// 只有allowed_users中的人可以部署该环境
+	if err := checkUserPolicy(projectName, env, currentOperator()); err != nil {
+		return err
+	}
+	// 受保护的环境需要关联变更单
+	if summary.Ticket, err = requireChangeTicket(ctx, projectName, env); err != nil {
+		return err
+	}
+
+	// 被SIGINT/SIGTERM中断或超过--timeout时ctx被取消，各步骤停止等待并返回；失败回调使用不会被取消的cleanupCtx，中断后仍能释放锁和发送通知
+	ctx = signalContext(ctx)
+	defer startHeartbeat()()
+	cleanupCtx := context.WithoutCancel(ctx)
+
+	// k8s配置文件路径，环境配置优先于全局配置
+	configPath := env.K8s.ConfigPath
+	if configPath == "" {
+		configPath = config.K8s.ConfigPath
+	}
+
+	// 使用共享的kubeconfig时以部署人的身份访问集群
+	setK8sImpersonation(config.K8s, env.K8s)
+	if err := setK8sClientOptions(config.K8s, env.K8s); err != nil {
+		return err
+	}
+
+	// 集群内运行（如作为Jenkins agent pod中的流水线步骤）时只使用service account凭证
+	inCluster := env.K8s.InCluster || config.K8s.InCluster
+	if inCluster {
+		configPath = inClusterConfigPath
+	}
+	if env.K8s.Namespace == "" && (inCluster || os.Getenv("KUBERNETES_SERVICE_HOST") != "") {
+		env.K8s.Namespace, err = detectInClusterNamespace()
+		if err != nil {
+			return errorf("Failed to detect namespace: %v", err)
+		}
+		slog.Info(msg("Using in-cluster namespace: %s", env.K8s.Namespace))
+	}
+
+	// 同一环境正在部署时可以排队，等其结束后再继续，之后再确定蓝绿颜色等目标
+	if err := waitForLocalDeploy(ctx, projectName, envName); err != nil {
+		return err
+	}
+
+	// 触发构建前获取部署锁，部署结束、失败或被中断时释放
+	lockConfig := env.Lock
+	if lockConfig == nil {
+		lockConfig = config.Lock
+	}
+	lock, err := acquireDeployLockQueued(ctx, lockConfig, projectName, envName, env.K8s.Namespace, configPath, *forceUnlock)
+	if err != nil {
+		return err
+	}
+	defer lock.release(cleanupCtx)
+	failureHooks = append(failureHooks, func(string) { lock.release(cleanupCtx) })
+	// 锁被强制释放或接管时停止部署
+	ctx, stopGuard := lock.guard(ctx)
+	defer stopGuard()
+
+	// 蓝绿部署时发布到空闲颜色对应的部署
+	placeholders := make(map[string]string)
+	var blueGreen *blueGreenTarget
+	if env.K8s.BlueGreen != nil {
+		blueGreen, err = resolveBlueGreenTarget(ctx, env.K8s.Namespace, *env.K8s.BlueGreen, configPath)
+		if err != nil {
+			return errorf("Failed to resolve blue/green target: %v", err)
+		}
+		env.K8s.Deployment = blueGreen.IdleDeployment
+		placeholders["$color"] = blueGreen.IdleColor
+		placeholders["$deployment"] = blueGreen.IdleDeployment
+		slog.Info(msg("Blue/green: %s (%s) is live, deploying to idle %s (%s)",
+			blueGreen.ActiveColor, blueGreen.ActiveDeployment, blueGreen.IdleColor, blueGreen.IdleDeployment))
+	}
+
+	// 金丝雀发布时构建先发布到金丝雀部署
+	monitorTarget := env.K8s.Deployment
+	if env.K8s.Canary != nil {
+		if env.K8s.BlueGreen != nil {
+			return errorf("Env %s cannot use blue_green and canary at the same time", env.Name)
+		}
+		if env.K8s.Canary.Deployment == "" {
+			return errorf("Canary configuration incomplete: k8s.canary.deployment is required")
+		}
+		monitorTarget = env.K8s.Canary.Deployment
+		placeholders["$deployment"] = monitorTarget
+	}
+
+	// 晋级部署时使用上一环境最近一次成功部署的构建产物
+	if *promoteFrom != "" {
+		previous, err := latestSuccessfulDeploy(ctx, config, projectName, *promoteFrom)
+		if err != nil {
+			return err
+		}
+		if previous == nil {
+			return errorf("No successful deploy of %s found to promote", *promoteFrom)
+		}
+		for placeholder, value := range promotedPlaceholders(previous) {
+			placeholders[placeholder] = value
+		}
+		summary.Commit = previous.Commit
+		slog.Info(msg("Promoting build #%d (commit %s) from %s", previous.BuildNumber, shortCommit(previous.Commit), *promoteFrom))
 	}
 
 	// build job name
 	jobName := env.JobName
-	params := parseParams(env)
+	if usesGitPlaceholders(env) {
+		if err := checkWorkingTree(); err != nil {
+			return err
+		}
+	}
+	params, err := parseParams(env, placeholders)
+	if err != nil {
+		return err
+	}
+
+	// ~/.deploy/plugins下的插件，pre-deploy插件在分支、远程分支和幂等检查之前修改参数，修改后的参数同样经过这些检查
+	plugins := discoverPlugins(ctx)
+	if err := plugins.preDeploy(ctx, projectName, envName, params); err != nil {
+		return errorf("Deploy aborted by pre-deploy plugin: %v", err)
+	}
+	// 晋级部署使用上一环境的产物，不检查本地分支
+	if *promoteFrom == "" {
+		if err := checkBranchPolicy(env, params); err != nil {
+			return err
+		}
+	}
+	if err := checkRemoteBranches(env, params); err != nil {
+		return err
+	}
+
+	// 目标已经运行同一提交时跳过构建，蓝绿部署检查当前接收流量的颜色
+	if !*force && summary.Commit != "" {
+		runningDeployment := env.K8s.Deployment
+		if blueGreen != nil {
+			runningDeployment = blueGreen.ActiveDeployment
+		}
+		if commit, source := runningCommit(ctx, config, projectName, envName, env.K8s.Namespace, runningDeployment, configPath); commit == summary.Commit {
+			slog.Info(msg("%s/%s already runs commit %s (from %s), skipping deploy, use --force to deploy anyway",
+				projectName, envName, shortCommit(commit), source))
+			summary.Result = resultAlreadyDeployed
+			summary.print(*outputFormat)
+			recordAudit(cleanupCtx, config, newAuditEntry("deploy", projectName, envName, params, resultAlreadyDeployed, ""))
+			return nil
+		}
+	}
+
+	// 输出上次部署以来的提交，同时包含在汇总和通知中
+	var previousCommit string
+	summary.Changelog, previousCommit = deployChangelog(ctx, config, projectName, envName, summary.Commit, p.Path)
+	printChangelog(envName, summary.Changelog)
+	if p.Path != "" && previousCommit != "" && len(summary.Changelog) == 0 {
+		slog.Warn(msg("%s has no changes since the last deploy to %s (commit %s), the deploy may be unnecessary", p.Path, envName, shortCommit(previousCommit)))
+	}
+
+	// 发送部署开始通知，之后的失败都会发送失败通知
+	notifier := newDeployNotifier(resolveNotifications(config.Notifications, env.Notifications), summary, env.Critical)
+	if !*noDesktopNotify && desktopNotificationsAvailable() {
+		notifier.notifiers = append(notifier.notifiers, desktopNotifier{})
+	}
+	notifier.addFinishNotifiers(config)
+	notifier.notifiers = append(notifier.notifiers, plugins.notifiers()...)
+	// 审计日志记录部署的开始和结果，进程被中断时也能看到开始记录
+	audit := func(result, message string) {
+		recordAudit(cleanupCtx, config, newAuditEntry("deploy", projectName, envName, params, result, message))
+	}
+	audit(stageStart, "")
+	notifier.send(ctx, stageStart, "")
+	// 被中断时同样发送失败通知，保留进行中部署的状态以便deploy resume恢复
+	failureHooks = append(failureHooks, func(message string) {
+		notifier.send(cleanupCtx, stageFailure, message)
+		recordDeploy(cleanupCtx, config.Ledger, newDeployRecord(summary, stageFailure, message))
+		audit(stageFailure, message)
+		if !interrupted.Load() {
+			inflight.clear()
+		}
+	})
 
-	ctx := context.Background()
-	jenkins := gojenkins.CreateJenkins(nil, config.JenkinsURL, config.Username, config.APIToken)
+	// 插件提供的Jenkins凭证优先于配置文件，配置文件中没有时使用deploy login保存的凭证
+	username, apiToken, err := plugins.credentials(ctx, projectName, envName, config.JenkinsURL)
+	if err != nil {
+		return errorf("Failed to get Jenkins credentials from plugin: %v", err)
+	}
+	registerSecret(apiToken)
+	if apiToken == "" {
+		if username, apiToken, err = jenkinsCredentials(ctx, config); err != nil {
+			return errorf("Failed to get Jenkins credentials: %v", err)
+		}
+	}
+
+	jenkins := gojenkins.CreateJenkins(jenkinsHTTPClient(), config.JenkinsURL, username, apiToken)
 	_, err = jenkins.Init(ctx)
 	if err != nil {
-		log.Fatalf("Failed to connect to Jenkins: %s", err)
+		// 钥匙串中的token过期时可以在终端中重新登录后继续
+		if reauthenticated, ok := reauthJenkins(ctx, config); ok {
+			jenkins, err = reauthenticated, nil
+		}
+	}
+	if err != nil {
+		return errorf("Failed to connect to Jenkins: %v", err)
 	}
 
-	fmt.Println("Successfully connected to Jenkins")
+	slog.Info(tr("Successfully connected to Jenkins"))
 
-	// 获取当前部署的revision
-	configPath := env.K8s.ConfigPath
-	if configPath == "" {
-		configPath = config.K8s.ConfigPath
+	// Job/CronJob类型的环境走单独的校验流程
+	if env.K8s.CronJob != "" || env.K8s.Job != "" {
+		if err := runJobTargetDeploy(ctx, jenkins, jobName, params, env, config, configPath, summary); err != nil {
+			return errorf("Failed to verify job deployment: %v", err)
+		}
+		if err := plugins.run(ctx, pluginRequest{Hook: hookPostRollout, Project: projectName, Env: envName, Summary: summary}, nil); err != nil {
+			return errorf("Deploy failed by post-rollout plugin: %v", err)
+		}
+		recordJobTargetCommit(ctx, env.K8s, configPath, summary.Commit, summary.Ticket)
+		tagDeployedCommit(env.GitTag, summary)
+		summary.Result = "success"
+		summary.print(*outputFormat)
+		notifier.send(ctx, stageSuccess, "")
+		recordDeploy(ctx, config.Ledger, newDeployRecord(summary, stageSuccess, ""))
+		audit(stageSuccess, "")
+		return nil
 	}
 
 	// 检查部署名称是否为空
 	if env.K8s.Namespace == "" || env.K8s.Deployment == "" {
-		log.Fatalf("K8s deployment configuration incomplete: namespace=%s, deployment=%s",
+		return errorf("K8s deployment configuration incomplete: namespace=%s, deployment=%s",
 			env.K8s.Namespace, env.K8s.Deployment)
 	}
 
-	// 获取当前部署的revision和pod列表
-	initialRevision, initialPodUIDs, err := getCurrentDeploymentStatus(ctx, env.K8s.Namespace, env.K8s.Deployment, configPath)
+	// 构建前检查集群连接和权限，避免构建完成后才发现无法监控
+	if err := runPreflightChecks(ctx, env.K8s.Namespace, env.K8s.Deployment, configPath); err != nil {
+		return errorf("Preflight check failed: %w", err)
+	}
+
+	// 同时获取当前部署的revision和pod列表、汇总用的镜像以及配置哈希
+	baseline, err := collectBaseline(ctx, env.K8s, monitorTarget, configPath)
+	if err != nil {
+		return err
+	}
+	initialRevision, initialPodUIDs := baseline.Revision, baseline.PodUIDs
+	slog.Info(msg("Current deployment revision: %s, found %d pods", initialRevision, len(initialPodUIDs)))
+
+	// 保存进行中部署的状态，进程被中断后可以通过deploy resume恢复监控
+	inflight = &inflightDeploy{
+		Project:         projectName,
+		Env:             envName,
+		JobName:         jobName,
+		Phase:           phaseBuild,
+		Namespace:       env.K8s.Namespace,
+		Deployment:      monitorTarget,
+		ConfigPath:      configPath,
+		InitialRevision: initialRevision,
+		InitialPodUIDs:  initialPodUIDs,
+		Placeholders:    placeholders,
+		Ticket:          summary.Ticket,
+		PID:             os.Getpid(),
+		StartedAt:       time.Now(),
+	}
+	inflight.save()
+
+	// 记录构建前的revision和镜像，用于最终汇总
+	summary.Namespace, summary.Deployment = env.K8s.Namespace, monitorTarget
+	if before := baseline.State; before != nil {
+		summary.OldRevision, summary.OldImages = before.Revision, before.Images
+	}
+	configBefore := baseline.Config
+
+	rolloutMarker = env.MonitorAfter
+	if err := BuildJenkinsJob(ctx, jenkins, jobName, params, summary); err != nil {
+		return errorf("Failed to build Jenkins job: %v", err)
+	}
+	if followUp != nil {
+		abort := *abortBuildOnFailure || env.AbortBuildOnFailure
+		failureHooks = append(failureHooks, func(string) { followUp.abortOnFailure(ctx, abort) })
+	}
+	if err := plugins.run(ctx, pluginRequest{Hook: hookPostBuild, Project: projectName, Env: envName,
+		BuildNumber: summary.BuildNumber, BuildURL: summary.BuildURL}, nil); err != nil {
+		return errorf("Deploy aborted by post-build plugin: %v", err)
+	}
+
+	// 校验配置已变化且部署已重启，没有需要滚动的内容时跳过监控
+	needsRollout := true
+	if configBefore != nil {
+		needsRollout, err = verifyConfigRollout(ctx, env.K8s, configPath, configBefore)
+		if err != nil {
+			return errorf("Config verification failed: %v", err)
+		}
+	}
+
+	// 如果构建成功，监控pod更新，滚动和稳定等待的耗时由监控过程记录
+	if !needsRollout {
+		err = nil
+	} else if env.K8s.Canary != nil {
+		err = runCanaryRollout(ctx, env.K8s, configPath, initialRevision, initialPodUIDs, summary)
+	} else {
+		err = monitorPodRollout(ctx, env.K8s, configPath, initialRevision, initialPodUIDs, summary)
+	}
 	if err != nil {
-		log.Fatalf("Failed to get current deployment status: %s", err)
+		if errors.Is(err, ErrConcurrentRollout) {
+			return errorf("Aborted pod rollout monitoring: %w", err)
+		}
+		return errorf("Failed to monitor pod rollout: %w", err)
+	}
+
+	// 滚动完成后等待构建的其余阶段结束
+	if followUp != nil {
+		if err := followUp.wait(ctx, summary); err != nil {
+			return err
+		}
+	}
+
+	// 滚动完成后执行冒烟检查
+	var phaseStart time.Time
+	if len(env.SmokeChecks) > 0 {
+		phaseStart = time.Now()
+		if err := runSmokeChecks(ctx, env.SmokeChecks, placeholders, summary); err != nil {
+			if blueGreen != nil {
+				return errorf("Smoke checks failed, traffic stays on %s: %v", blueGreen.ActiveColor, err)
+			}
+			return errorf("Smoke checks failed: %v", err)
+		}
+		summary.addPhase("smoke checks", phaseStart)
+	}
+
+	// 服务网格按阶段切换流量权重
+	if env.K8s.TrafficShift != nil {
+		phaseStart = time.Now()
+		if err := runTrafficShift(ctx, env.K8s, configPath); err != nil {
+			return errorf("Failed to shift traffic: %v", err)
+		}
+		summary.addPhase("traffic shift", phaseStart)
+	}
+
+	// 蓝绿部署在新颜色健康后切换流量，旧颜色保留用于快速回滚
+	if blueGreen != nil {
+		if err := switchBlueGreenTraffic(ctx, env.K8s.Namespace, *env.K8s.BlueGreen, configPath, blueGreen.IdleColor); err != nil {
+			return errorf("Failed to switch blue/green traffic: %v", err)
+		}
+		slog.Info(msg("Previous color %s (%s) is kept running for instant rollback: kubectl patch service %s -n %s -p '{\"spec\":{\"selector\":{\"%s\":\"%s\"}}}'",
+			blueGreen.ActiveColor, blueGreen.ActiveDeployment, env.K8s.BlueGreen.Service, env.K8s.Namespace,
+			env.K8s.BlueGreen.selectorLabel(), blueGreen.ActiveColor))
 	}
-	fmt.Printf("Current deployment revision: %s, found %d pods\n", initialRevision, len(initialPodUIDs))
 
-	var success bool
-	success, err = BuildJenkinsJob(jobName, params, err, jenkins, ctx, env, config)
-	if !success {
-		log.Fatalf("Failed to build Jenkins job: %s", err)
+	// 确认新pod已经接收流量
+	if env.K8s.Service != "" || env.K8s.Ingress != "" {
+		phaseStart = time.Now()
+		if err := verifyTrafficReadiness(ctx, env.K8s, configPath, initialPodUIDs); err != nil {
+			return errorf("Traffic readiness check failed: %v", err)
+		}
+		summary.addPhase("traffic readiness", phaseStart)
 	}
 
-	// 如果构建成功，监控pod更新
-	if err := monitorPodRollout(ctx, env.K8s.Namespace, env.K8s.Deployment, configPath, initialRevision, initialPodUIDs); err != nil {
-		log.Fatalf("Failed to monitor pod rollout: %s", err)
+	reportOldReplicaSets(ctx, env.K8s.Namespace, monitorTarget, configPath, *pruneReplicaSets || env.K8s.PruneReplicaSets)
+
+	// 输出部署汇总：revision和镜像变化、pod数量、构建信息和各阶段耗时
+	if after, err := getDeploymentState(ctx, env.K8s.Namespace, monitorTarget, configPath); err == nil {
+		summary.NewRevision, summary.NewImages, summary.Pods = after.Revision, after.Images, after.Pods
 	}
+	if err := plugins.run(ctx, pluginRequest{Hook: hookPostRollout, Project: projectName, Env: envName, Summary: summary}, nil); err != nil {
+		return errorf("Deploy failed by post-rollout plugin: %v", err)
+	}
+	recordDeployedCommit(ctx, env.K8s.Namespace, env.K8s.Deployment, configPath, summary.Commit, summary.Ticket)
+	tagDeployedCommit(env.GitTag, summary)
+	summary.Result = "success"
+	summary.print(*outputFormat)
+	notifier.send(ctx, stageSuccess, "")
+	recordDeploy(ctx, config.Ledger, newDeployRecord(summary, stageSuccess, ""))
+	audit(stageSuccess, "")
+	inflight.clear()
+	return nil
 }
 
-func parseParams(env Env) map[string]string {
+// parseParams 解析构建参数，替换git占位符、部署占位符和密钥引用
+func parseParams(env Env, placeholders map[string]string) (map[string]string, error) {
 	params := make(map[string]string)
 	for _, param := range env.Params {
-		if param.Value == "$branch" {
-			// 读取当前目录的git分支名称
-			params[param.Name] = getBranchName()
+		if resolve, ok := gitPlaceholders[param.Value]; ok {
+			// 读取当前目录的git仓库信息
+			value := resolve()
+			if value == "" && param.Value == "$branch" {
+				return nil, errorf("failed to get branch for param %s: not in a git repository, use --branch to specify it", param.Name)
+			}
+			if value == "" {
+				return nil, errorf("failed to resolve %s for param %s: not available in the current git repository", param.Value, param.Name)
+			}
+			params[param.Name] = value
+		} else if value, ok := placeholders[param.Value]; ok {
+			params[param.Name] = value
 		} else {
-			params[param.Name] = param.Value
+			// 参数值可以引用系统钥匙串或AWS中的密钥
+			value, err := resolveSecret(context.Background(), param.Value)
+			if err != nil {
+				return nil, errorf("failed to resolve param %s: %v", param.Name, err)
+			}
+			if param.Secret {
+				registerSecret(value)
+			}
+			params[param.Name] = value
 		}
 	}
-	return params
+	return params, nil
 }
 
+// getBranchName 读取$branch的值，detached HEAD时按deployBranchName的顺序回退，不在git仓库中时返回空
 func getBranchName() string {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
+	branch, source := deployBranchName()
+	if branch != "" && source != "git" {
+		slog.Info(msg("Using branch %s from %s", branch, source))
+	}
+	return branch
+}
 
-	// 捕获命令的输出
-	var out bytes.Buffer
-	cmd.Stdout = &out
+// BuildJenkinsJob 触发Jenkins构建并等待完成，构建失败或无法跟踪构建时返回错误
+func BuildJenkinsJob(ctx context.Context, jenkins *gojenkins.Jenkins, jobName string, params map[string]string, summary *deploySummary) error {
+	startTime := time.Now()
+	startGitHubGroup("Jenkins build " + jobName)
+	slog.Info(msg("Starting Jenkins build job: %s", jobName))
 
-	// 运行命令
-	err := cmd.Run()
+	job, meta, err := getJenkinsJob(ctx, jenkins, jobName)
 	if err != nil {
-		log.Fatalf("Failed to get branch: %s", err)
+		return errorf("failed to get job: %v", err)
 	}
-	// 获取输出并去掉尾部的换行符
-	branchName := strings.TrimSpace(out.String())
-	return branchName
-}
-
-func BuildJenkinsJob(jobName string, params map[string]string, err error, jenkins *gojenkins.Jenkins, ctx context.Context, env Env, config *Config) (bool, error) {
-	startTime := time.Now().Local()
-	fmt.Printf("[%s] Starting Jenkins build job: %s\n", startTime.Format("2006-01-02 15:04:05"), jobName)
 
+	// Jenkins任务中定义为密码类型的参数同样屏蔽
+	for _, property := range job.Raw.Property {
+		for _, definition := range property.ParameterDefinitions {
+			if definition.Type == "PasswordParameterDefinition" {
+				registerSecret(params[definition.Name])
+			}
+		}
+	}
+	// Credentials参数的值是凭据ID，触发前确认凭据存在，并通过json表单提交
+	credentialParams := jobCredentialParams(job, params)
+	for _, name := range credentialParams {
+		if err := checkJenkinsCredential(ctx, jenkins, job, name, params[name]); err != nil {
+			return err
+		}
+	}
 	paramJSON, _ := json.Marshal(params)
-	fmt.Printf("[%s] Build parameters: %s\n", time.Now().Local().Format("2006-01-02 15:04:05"), paramJSON)
+	slog.Debug(fmt.Sprintf("Build parameters: %s", paramJSON))
 
-	job, err := jenkins.GetJob(ctx, jobName)
+	var queueID int64
+	if len(credentialParams) > 0 {
+		queueID, err = invokeWithCredentials(ctx, job, params)
+	} else {
+		queueID, err = invokeJenkinsJob(ctx, job, params)
+	}
 	if err != nil {
-		log.Fatalf("Failed to get job: %s", err)
+		// 参数定义可能来自过期的缓存，下次重新获取
+		invalidateJenkinsJob(jenkins, jobName)
+		return errorf("failed to trigger build: %v", err)
 	}
 
-	queueID, err := job.InvokeSimple(ctx, params)
+	slog.Info(msg("Build triggered with queue ID: %d", queueID))
+	inflight.update(func(state *inflightDeploy) { state.QueueID = queueID })
+
+	// 等待构建离开Jenkins队列，单独统计排队时间
+	queuedAt := time.Now()
+	setHeartbeatPhase("waiting in the Jenkins queue")
+	build, err := waitForQueuedBuild(ctx, jenkins, queueID)
+	if err != nil && ctx.Err() != nil {
+		cancelJenkinsQueueItem(ctx, jenkins, queueID)
+		return ctx.Err()
+	}
+	if errors.Is(err, ErrJenkinsQueueTimeout) {
+		return err
+	}
 	if err != nil {
-		log.Fatalf("Failed to trigger build: %s", err)
+		return errorf("failed to get build: %w", err)
+	}
+	queueWait := time.Since(queuedAt)
+	summary.addPhaseDuration("jenkins queue", queueWait)
+	slog.Info(msg("Build #%d started after waiting %v in the Jenkins queue", build.GetBuildNumber(), queueWait.Round(time.Second)))
+	if meta.EstimatedDuration > 0 {
+		slog.Info(msg("Estimated build duration: %v (from the last successful build)", meta.EstimatedDuration.Round(time.Second)))
 	}
 
-	fmt.Printf("[%s] Build triggered with queue ID: %d\n", time.Now().Local().Format("2006-01-02 15:04:05"), queueID)
-
-	build, err := jenkins.GetBuildFromQueueID(ctx, queueID)
+	success, err := waitForJenkinsBuild(ctx, build, summary)
 	if err != nil {
-		log.Fatalf("Failed to get build: %s", err)
+		return err
+	}
+	if success {
+		// 构建的其余阶段仍在运行时，滚动完成后再输出构建结果
+		if followUp == nil {
+			slog.Info(msg("Jenkins build completed successfully! Queue wait: %v, total: %v",
+				queueWait.Round(time.Second), time.Since(startTime).Round(time.Second)))
+		}
+		return nil
 	}
+	slog.Info(msg("Jenkins build failed after %v (queue wait %v)", time.Since(startTime).Round(time.Second), queueWait.Round(time.Second)))
+	return errorf("build failed: %s", build.GetResult())
+}
+
+// waitForJenkinsBuild 等待构建结束，超过30秒后实时显示构建日志，失败时输出完整日志，返回构建是否成功
+func waitForJenkinsBuild(ctx context.Context, build *gojenkins.Build, summary *deploySummary) (bool, error) {
+	if summary != nil {
+		summary.BuildNumber, summary.BuildURL = build.GetBuildNumber(), build.GetUrl()
+	}
+	inflight.update(func(state *inflightDeploy) {
+		state.BuildNumber, state.BuildURL = build.GetBuildNumber(), build.GetUrl()
+	})
 
 	buildStartTime := time.Now()
+	setHeartbeatPhase("waiting for Jenkins build #%d", build.GetBuildNumber())
 	lastLogLength := 0
 	shouldShowLogs := false
 
 	// Wait for build to finish
 	for build.IsRunning(ctx) {
-		time.Sleep(300 * time.Millisecond)
+		if sleepContext(ctx, 300*time.Millisecond) != nil {
+			break
+		}
 		_, err := build.Poll(ctx)
-		if err != nil {
-			log.Fatalf("Failed to poll build: %s", err)
+		if err != nil && ctx.Err() == nil {
+			return false, errorf("failed to poll build: %v", err)
 		}
 
 		// Check if 30 seconds have passed
 		if !shouldShowLogs && time.Since(buildStartTime) > 30*time.Second {
 			shouldShowLogs = true
-			fmt.Printf("\n[%s] Build is taking longer than 30 seconds. Showing real-time logs:\n", time.Now().Local().Format("2006-01-02 15:04:05"))
+			slog.Info(tr("Build is taking longer than 30 seconds. Showing real-time logs:"))
 		}
 
 		// If we should show logs, get and display new content
-		if shouldShowLogs {
+		if shouldShowLogs || rolloutMarker != "" {
 			logs := build.GetConsoleOutput(ctx)
-			if len(logs) > lastLogLength {
+			if shouldShowLogs && len(logs) > lastLogLength {
 				newLogs := logs[lastLogLength:]
-				fmt.Print(newLogs)
+				fmt.Fprint(rawOutput, newLogs)
 				lastLogLength = len(logs)
 			}
+
+			// 出现monitor_after标记时开始监控滚动，构建的其余阶段在后台继续跟踪
+			if rolloutMarkerFound(logs) {
+				slog.Info(msg("Found %q in the log of build #%d, monitoring the rollout while the build continues", rolloutMarker, build.GetBuildNumber()))
+				followUp = startFollowUpBuild(ctx, build, buildStartTime)
+				inflight.update(func(state *inflightDeploy) { state.Phase = phaseRollout })
+				return true, nil
+			}
 		}
 	}
 
-	if build.IsGood(ctx) {
-		endTime := time.Now().Local()
-		jenkinsDuration := endTime.Sub(startTime)
-		fmt.Printf("[%s] Jenkins build completed successfully! Jenkins execution time: %v\n",
-			endTime.Format("2006-01-02 15:04:05"), jenkinsDuration)
+	// 被中断时不再等待构建结束，构建继续运行，除非指定了--abort-on-interrupt
+	if ctx.Err() != nil {
+		abortJenkinsBuild(ctx, build)
+		return false, ctx.Err()
+	}
 
+	buildDuration := time.Since(buildStartTime)
+	summary.addPhaseDuration("jenkins build", buildDuration)
+	if build.IsGood(ctx) {
+		slog.Info(msg("Build #%d succeeded, build execution: %v", build.GetBuildNumber(), buildDuration.Round(time.Second)))
+		saveBuildLog(ctx, build, "", summary)
+		inflight.update(func(state *inflightDeploy) { state.Phase = phaseRollout })
 		return true, nil
-	} else {
-		endTime := time.Now().Local()
-		jenkinsDuration := endTime.Sub(startTime)
-		fmt.Printf("\n[%s] =============Build Failed Log=============\n", endTime.Format("2006-01-02 15:04:05"))
-		fmt.Print(build.GetConsoleOutput(ctx))
-		fmt.Printf("\n[%s] =============Build Failed Log=============\n", endTime.Format("2006-01-02 15:04:05"))
-		fmt.Printf("[%s] Jenkins build failed after %v\n", endTime.Format("2006-01-02 15:04:05"), jenkinsDuration)
-		log.Fatalf("Build failed: %s", build.GetResult())
-		return false, nil
 	}
+
+	slog.Info(tr("=============Build Failed Log============="))
+	consoleOutput := build.GetConsoleOutput(ctx)
+	fmt.Fprint(rawOutput, consoleOutput)
+	slog.Info(tr("=============Build Failed Log============="))
+	saveBuildLog(ctx, build, consoleOutput, summary)
+	if summary != nil {
+		summary.failureLog = tailLines(consoleOutput, failureLogLines)
+	}
+	slog.Info(msg("Build #%d failed, build execution: %v", build.GetBuildNumber(), buildDuration.Round(time.Second)))
+	return false, nil
 }
 
-func monitorPodRollout(ctx context.Context, namespace, deploymentName string, configPath string, initialRevision string, initialPodUIDs map[string]bool) error {
-	startTime := time.Now().Local()
-	fmt.Printf("[%s] Starting pod rollout monitoring for deployment %s in namespace %s...\n",
-		startTime.Format("2006-01-02 15:04:05"), deploymentName, namespace)
+// rolloutCheckInterval 监控滚动时两次检查的间隔
+var rolloutCheckInterval = 5 * time.Second
 
-	var k8sConfig *rest.Config
-	var err error
+func monitorPodRollout(ctx context.Context, k8s K8sConfig, configPath string, initialRevision string, initialPodUIDs map[string]bool, summary *deploySummary) error {
+	namespace, deploymentName := k8s.Namespace, k8s.Deployment
+	startTime := time.Now()
+	var stabilityTotal time.Duration // 稳定等待的总时长，单独统计
+	startGitHubGroup("Rollout of " + namespace + "/" + deploymentName)
+	setHeartbeatPhase("monitoring the rollout of %s/%s", namespace, deploymentName)
+	slog.Info(msg("Starting pod rollout monitoring for deployment %s in namespace %s...", deploymentName, namespace))
 
-	// 如果提供了配置文件路径，使用指定的配置文件
-	if configPath != "" {
-		// 展开 ~ 到用户主目录
-		if strings.HasPrefix(configPath, "~/") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("failed to get user home directory: %v", err)
-			}
-			configPath = filepath.Join(homeDir, configPath[2:])
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	// 获取当前部署的版本
+	deployment, err := getDeployment(ctx, clientset, namespace, deploymentName)
+	if err != nil {
+		return errorf("failed to get deployment: %v", err)
+	}
+
+	// 直接使用传入的初始 revision 和 Pod UID 列表
+	slog.Info(msg("Monitoring rollout from revision: %s, found %d initial pods", initialRevision, len(initialPodUIDs)))
+
+	// 输出部署策略，成功判定和稳定等待都会参考这些参数
+	strategy := getRolloutStrategy(deployment)
+	slog.Info(msg("Rollout strategy: %s", strategy))
+	capacityWarned := false
+
+	// 检查是否有HPA管理该部署，HPA扩缩容时期望副本数会在监控过程中变化
+	hpa, err := findDeploymentHPA(ctx, clientset, namespace, deploymentName)
+	if err != nil {
+		slog.Warn(msg("failed to check HorizontalPodAutoscalers: %v", err))
+	} else if hpa != nil {
+		minReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			minReplicas = *hpa.Spec.MinReplicas
 		}
+		slog.Info(msg("Deployment is managed by HPA %s (min=%d, max=%d), desired replicas will be tracked on every check", hpa.Name, minReplicas, hpa.Spec.MaxReplicas))
+	}
+	desiredReplicas := strategy.Replicas
 
-		k8sConfig, err = clientcmd.BuildConfigFromFlags("", configPath)
-		if err != nil {
-			return fmt.Errorf("failed to build config from flags: %v", err)
+	// 按容器对比旧pod和新pod的镜像，尽早发现构建没有更新镜像tag的情况
+	reportImageDiff(ctx, clientset, namespace, deployment, initialPodUIDs)
+
+	// 暂停的部署不会继续滚动，按配置恢复或直接报错
+	if deployment.Spec.Paused {
+		if !k8s.ResumePaused {
+			return errorf("deployment %s is paused and the rollout will not progress: run `kubectl rollout resume deployment/%s -n %s` or set k8s.resume_paused: true",
+				deploymentName, deploymentName, namespace)
 		}
-	} else {
-		// 尝试使用集群内配置
-		k8sConfig, err = rest.InClusterConfig()
-		if err != nil {
-			// 如果集群内配置失败，尝试使用默认的 kubeconfig
-			k8sConfig, err = clientcmd.BuildConfigFromFlags("", filepath.Join(os.Getenv("HOME"), ".kube", "config"))
-			if err != nil {
-				return fmt.Errorf("failed to get k8s config: %v", err)
-			}
+		slog.Info(msg("Deployment %s is paused, resuming it (k8s.resume_paused: true)", deploymentName))
+		patch := []byte(`{"spec":{"paused":false}}`)
+		if _, err := clientset.AppsV1().Deployments(namespace).Patch(ctx, deploymentName, types.StrategicMergePatchType, patch, metav1.PatchOptions{}); err != nil {
+			return errorf("failed to resume paused deployment: %v", err)
 		}
 	}
 
-	clientset, err := kubernetes.NewForConfig(k8sConfig)
-	if err != nil {
-		return fmt.Errorf("failed to create kubernetes client: %v", err)
+	// 副本数为0的部署没有pod可以监控，默认跳过，配置为wait时等待扩容
+	if strategy.Replicas == 0 {
+		if k8s.ZeroReplicas != "wait" {
+			slog.Info(msg("Deployment %s is scaled to 0 replicas, skipping pod rollout monitoring (set k8s.zero_replicas: wait to wait for scale-up)", deploymentName))
+			return nil
+		}
+		slog.Info(msg("Deployment %s is scaled to 0 replicas, waiting for scale-up...", deploymentName))
 	}
+	pausedWarned := false
 
-	// 获取当前部署的版本
-	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to get deployment: %v", err)
-	}
+	// 记录本次构建产生的revision，之后revision再次前进说明有其他人在发布
+	ourRevision := ""
 
-	// 直接使用传入的初始 revision 和 Pod UID 列表
-	fmt.Printf("[%s] Monitoring rollout from revision: %s, found %d initial pods\n",
-		time.Now().Local().Format("2006-01-02 15:04:05"), initialRevision, len(initialPodUIDs))
+	// 记录滚动进度，用于识别旧pod迟迟无法删除的停滞情况
+	lastProgress := ""
+	stalledChecks := 0
+	remainingOldPods := 0
+
+	// 最近一次检查中未就绪的新pod，超时时检查其所在节点
+	var notReadyPods, lastNewPods []*corev1.Pod
 
 	// 存储最大重试次数和超时
 	maxRetries := 120 // 10分钟 (5秒 * 120)
 	retries := 0
 
+	// 重复的状态行只在变化时输出
+	status := newStatusLog()
+
+	// 使用pod状态表格时，每个pod的详细状态只按debug级别记录
+	table := newPodTable()
+	// 新pod拉取镜像失败时诊断原因，每个镜像只诊断一次
+	pulls := newImagePullDiagnoser(clientset, namespace)
+	podDetail := status.info
+	if table != nil {
+		podDetail = func(message string) { slog.Debug(message) }
+	}
+
 	// 等待新的pod准备就绪
 	for {
+		status.next()
 		if retries >= maxRetries {
-			return fmt.Errorf("rollout timed out after %d attempts", maxRetries)
+			// 超时时如果仍有旧pod，检查是否被PDB阻塞
+			if remainingOldPods > 0 {
+				if blocking := describeBlockingPDBs(ctx, clientset, namespace, deployment); blocking != nil {
+					return errorf("rollout timed out after %d attempts, %s", maxRetries, blocking)
+				}
+			}
+			if nodes := describePodNodes(ctx, clientset, notReadyPods, lastNewPods); nodes != nil {
+				return errorf("rollout timed out after %d attempts, %s", maxRetries, nodes)
+			}
+			return errorf("rollout timed out after %d attempts", maxRetries)
 		}
 
-		time.Sleep(5 * time.Second) // 增加等待时间，让健康检查有足够时间执行
+		// 增加等待时间，让健康检查有足够时间执行
+		if err := sleepContext(ctx, rolloutCheckInterval); err != nil {
+			return err
+		}
 		retries++
 
 		// 获取最新的部署状态
-		deployment, err = clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+		deployment, err = getDeployment(ctx, clientset, namespace, deploymentName)
+		if err != nil {
+			return errorf("failed to get deployment: %v", err)
+		}
+
+		// 检查revision变化，识别是否有并发的发布
+		ourRevision, err = trackRevision(initialRevision, ourRevision, getDeploymentRevision(deployment))
 		if err != nil {
-			return fmt.Errorf("failed to get deployment: %v", err)
+			return err
 		}
 
 		// 获取与部署关联的所有pod
 		podList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
 		if err != nil {
-			return fmt.Errorf("failed to get pods: %v", err)
+			return errorf("failed to get pods: %v", err)
+		}
+
+		// 检查新旧pod状态，期望副本数以每次获取到的spec.replicas为准
+		strategy = getRolloutStrategy(deployment)
+		if strategy.Replicas != desiredReplicas {
+			source := "spec change"
+			if hpa != nil {
+				source = "HPA " + hpa.Name
+			}
+			slog.Info(msg("Desired replicas changed from %d to %d (%s)", desiredReplicas, strategy.Replicas, source))
+			desiredReplicas = strategy.Replicas
+		}
+
+		// 监控过程中部署被暂停时提示一次
+		if deployment.Spec.Paused && !pausedWarned {
+			slog.Warn(msg("deployment %s was paused during the rollout, progress will stall until it is resumed", deploymentName))
+			pausedWarned = true
+		} else if !deployment.Spec.Paused {
+			pausedWarned = false
+		}
+
+		// 等待扩容时不做成功判定
+		if strategy.Replicas == 0 {
+			status.info(tr("Deployment still has 0 desired replicas, waiting for scale-up"))
+			continue
 		}
 
-		// 检查新旧pod状态
 		newPods, oldPods := categorizePodsByUID(podList, initialPodUIDs)
 		readyNewPods := countReadyAndHealthyPods(newPods)
+		availableNewPods := countAvailablePods(newPods, strategy.MinReadySeconds)
+		lastNewPods, notReadyPods = newPods, nil
+		for _, pod := range newPods {
+			if !isPodReadyAndHealthy(pod) {
+				notReadyPods = append(notReadyPods, pod)
+			}
+		}
 
 		// 输出当前状态和健康检查详情
-		fmt.Printf("[%s] Pod status: %d/%d new pods ready, %d old pods remaining\n",
-			time.Now().Local().Format("2006-01-02 15:04:05"),
-			readyNewPods, len(newPods), len(oldPods))
+		if strategy.MinReadySeconds > 0 {
+			status.info(msg("Pod status: %d/%d new pods ready (%d available after minReadySeconds=%d), %d old pods remaining", readyNewPods, len(newPods), availableNewPods, strategy.MinReadySeconds, len(oldPods)))
+		} else {
+			status.info(msg("Pod status: %d/%d new pods ready, %d old pods remaining", readyNewPods, len(newPods), len(oldPods)))
+		}
+		if table != nil {
+			table.draw(newPods, oldPods)
+		}
+		publishPods(newPods, oldPods)
+		pulls.report(ctx, newPods)
+
+		// 可用pod数低于策略允许的最小值时提示一次
+		if !capacityWarned && strategy.Type == appsv1.RollingUpdateDeploymentStrategyType {
+			readyPods := readyNewPods + countReadyAndHealthyPods(oldPods)
+			if readyPods < strategy.MinAvailable() {
+				slog.Warn(msg("only %d pods ready, below the %d guaranteed by maxUnavailable=%d", readyPods, strategy.MinAvailable(), strategy.MaxUnavailable))
+				capacityWarned = true
+			}
+		}
+
+		// 旧pod长时间没有减少时，检查是否有PDB阻止驱逐
+		remainingOldPods = len(oldPods)
+		progress := fmt.Sprintf("%d/%d/%d", readyNewPods, len(newPods), len(oldPods))
+		if progress == lastProgress && len(oldPods) > 0 {
+			stalledChecks++
+			if stalledChecks == 6 {
+				if blocking := describeBlockingPDBs(ctx, clientset, namespace, deployment); blocking != nil {
+					slog.Info(msg("Rollout stalled with %d old pods remaining, %s", len(oldPods), blocking))
+				}
+			}
+		} else {
+			stalledChecks = 0
+		}
+		lastProgress = progress
 
 		// 输出任何未就绪新pod的详细状态
 		if readyNewPods < len(newPods) {
 			for _, pod := range newPods {
 				if !isPodReadyAndHealthy(pod) {
-					fmt.Printf("[%s] New pod %s not ready: Phase=%s, Ready=%v, ContainerReady=%v\n",
-						time.Now().Local().Format("2006-01-02 15:04:05"),
-						pod.Name, pod.Status.Phase, isPodReady(pod), areAllContainersReady(pod))
+					podDetail(msg("New pod %s not ready: Phase=%s, Ready=%v, ContainerReady=%v", pod.Name, pod.Status.Phase, isPodReady(pod), areAllContainersReady(pod)))
 
-					// 输出健康检查失败的容器信息
-					for _, containerStatus := range pod.Status.ContainerStatuses {
+					// 输出未满足的readinessGates
+					if unmetGates := getUnmetReadinessGates(pod); len(unmetGates) > 0 {
+						podDetail(msg("Pod %s waiting on readiness gates: %s", pod.Name, strings.Join(unmetGates, ", ")))
+					}
+
+					// 输出健康检查失败的容器信息（包括原生sidecar）
+					for _, containerStatus := range append(getNativeSidecarStatuses(pod), pod.Status.ContainerStatuses...) {
 						if !containerStatus.Ready {
 							state := "Unknown"
 							if containerStatus.State.Waiting != nil {
-								state = fmt.Sprintf("Waiting: %s (%s)",
+								state = msg("Waiting: %s (%s)",
 									containerStatus.State.Waiting.Reason,
 									containerStatus.State.Waiting.Message)
 							} else if containerStatus.State.Terminated != nil {
-								state = fmt.Sprintf("Terminated: %s (%s)",
+								state = msg("Terminated: %s (%s)",
 									containerStatus.State.Terminated.Reason,
 									containerStatus.State.Terminated.Message)
 							}
-							fmt.Printf("[%s] Container %s not ready: %s, RestartCount=%d\n",
-								time.Now().Local().Format("2006-01-02 15:04:05"),
-								containerStatus.Name, state, containerStatus.RestartCount)
+							podDetail(msg("Container %s not ready: %s, RestartCount=%d", containerStatus.Name, state, containerStatus.RestartCount))
+
+							// OOMKilled 单独输出内存配置，方便定位内存限制问题
+							if isContainerOOMKilled(containerStatus) {
+								printOOMKilledDetails(pod, containerStatus)
+							}
 						}
 					}
 				}
 			}
 		}
 
-		// 检查部署是否完成：所有新pod已就绪且没有旧pod
-		if readyNewPods == int(*deployment.Spec.Replicas) && len(oldPods) == 0 {
-			// 成功后额外等待10秒，确保pod真正稳定
-			fmt.Printf("[%s] All pods ready, waiting additional 10 seconds to ensure stability...\n",
-				time.Now().Local().Format("2006-01-02 15:04:05"))
-			time.Sleep(10 * time.Second)
+		// 检查部署是否完成：新pod满足minReadySeconds后可用、没有旧pod，且控制器已确认滚动完成
+		// 配置了min_ready_percent/allow_old_pods时，达到阈值即视为成功
+		requiredReady := getRequiredReadyPods(k8s, strategy.Replicas)
+		partialSuccess := requiredReady < strategy.Replicas || k8s.AllowOldPods
+		if availableNewPods >= requiredReady &&
+			(len(oldPods) == 0 || k8s.AllowOldPods) &&
+			(partialSuccess || isDeploymentRolloutComplete(deployment)) {
+			stabilityWait := strategy.StabilityWait()
+			if stabilityWait > 0 {
+				// 没有配置minReadySeconds时额外等待，确保pod真正稳定
+				slog.Info(msg("All pods ready, waiting additional %v to ensure stability...", stabilityWait))
+				stabilityStart := time.Now()
+				if err := sleepContext(ctx, stabilityWait); err != nil {
+					return err
+				}
 
-			// 再次检查所有pod状态
-			podList, err = getDeploymentPods(ctx, clientset, namespace, deployment)
-			if err != nil {
-				return fmt.Errorf("failed to get pods during final check: %v", err)
+				// 再次检查部署和所有pod状态，等待期间HPA可能已调整副本数
+				deployment, err = getDeployment(ctx, clientset, namespace, deploymentName)
+				if err != nil {
+					return errorf("failed to get deployment during final check: %v", err)
+				}
+				strategy = getRolloutStrategy(deployment)
+
+				podList, err = getDeploymentPods(ctx, clientset, namespace, deployment)
+				if err != nil {
+					return errorf("failed to get pods during final check: %v", err)
+				}
+
+				newPods, oldPods = categorizePodsByUID(podList, initialPodUIDs)
+				availableNewPods = countAvailablePods(newPods, strategy.MinReadySeconds)
+				requiredReady = getRequiredReadyPods(k8s, strategy.Replicas)
+				stabilityTotal += time.Since(stabilityStart)
 			}
 
-			newPods, _ = categorizePodsByUID(podList, initialPodUIDs)
-			readyNewPods = countReadyAndHealthyPods(newPods)
+			if availableNewPods >= requiredReady {
+				rolloutDuration := time.Since(startTime)
+				slog.Info(msg("K8s rollout completed successfully! Rollout time: %v (stability wait %v)", rolloutDuration, stabilityTotal.Round(time.Second)))
+				summary.addPhaseDuration("rollout", rolloutDuration-stabilityTotal)
+				if stabilityTotal > 0 {
+					summary.addPhaseDuration("stability wait", stabilityTotal)
+				}
 
-			if readyNewPods == int(*deployment.Spec.Replicas) {
-				endTime := time.Now().Local()
-				rolloutDuration := endTime.Sub(startTime)
-				fmt.Printf("[%s] K8s rollout completed successfully! Rollout time: %v\n",
-					endTime.Format("2006-01-02 15:04:05"), rolloutDuration)
+				// 部分成功时提示剩余的滚动仍在集群中进行
+				if availableNewPods < strategy.Replicas || len(oldPods) > 0 {
+					slog.Info(msg("Success criteria met with %d/%d new pods available and %d old pods remaining, the rest of the rollout continues in the cluster: kubectl rollout status deployment/%s -n %s", availableNewPods, strategy.Replicas, len(oldPods), deploymentName, namespace))
+				}
 				return nil
 			} else {
-				fmt.Printf("[%s] Pods became unhealthy during stability check, continuing to monitor\n",
-					time.Now().Local().Format("2006-01-02 15:04:05"))
+				slog.Info(tr("Pods became unhealthy during stability check, continuing to monitor"))
 			}
 		}
 
@@ -420,17 +1246,206 @@ func monitorPodRollout(ctx context.Context, namespace, deploymentName string, co
 			errorPods := findErrorPods(newPods)
 			if len(errorPods) > 0 {
 				for _, pod := range errorPods {
-					fmt.Printf("[%s] Problem pod: %s, status: %s, message: %s\n",
-						time.Now().Local().Format("2006-01-02 15:04:05"),
-						pod.Name, getPodStatus(pod), getPodErrorMessage(pod))
+					status.info(msg("Problem pod: %s on node %s, status: %s, message: %s", pod.Name, podNodeName(pod), getPodStatus(pod), getPodErrorMessage(pod)))
+					for _, containerStatus := range pod.Status.ContainerStatuses {
+						if isContainerOOMKilled(containerStatus) {
+							printOOMKilledDetails(pod, containerStatus)
+						}
+					}
+				}
+				// 按需为崩溃的pod挂载调试容器
+				if *debugOnFailure {
+					offerDebugContainer(ctx, clientset, k8s, configPath, errorPods)
+				}
+
+				// 输出异常pod所在节点的状况，识别单个异常节点导致的失败
+				var nodes any = ""
+				if unhealthy := describePodNodes(ctx, clientset, errorPods, newPods); unhealthy != nil {
+					nodes = errorf(", %v", unhealthy)
+				}
+
+				rolloutDuration := time.Since(startTime)
+				failureClass := classifyPodFailure(errorPods)
+				if pull := pulls.describe(errorPods); pull != "" {
+					failureClass = pull
+				}
+				if failureClass != "" {
+					return errorf("K8s rollout failed after %v - new pods are not becoming ready (failure class: %s)%s",
+						rolloutDuration, failureClass, nodes)
 				}
-				endTime := time.Now().Local()
-				rolloutDuration := endTime.Sub(startTime)
-				return fmt.Errorf("[%s] K8s rollout failed after %v - new pods are not becoming ready",
-					endTime.Format("2006-01-02 15:04:05"), rolloutDuration)
+				return errorf("K8s rollout failed after %v - new pods are not becoming ready%s", rolloutDuration, nodes)
+			}
+		}
+	}
+}
+
+// getRequiredReadyPods 根据min_ready_percent计算判定成功所需的可用新pod数量
+func getRequiredReadyPods(k8s K8sConfig, replicas int) int {
+	if k8s.MinReadyPercent <= 0 || k8s.MinReadyPercent >= 100 {
+		return replicas
+	}
+	required := int(math.Ceil(float64(replicas) * float64(k8s.MinReadyPercent) / 100))
+	if required < 1 && replicas > 0 {
+		required = 1
+	}
+	return required
+}
+
+// rolloutStrategy 部署的滚动策略参数，已按副本数换算为pod个数
+type rolloutStrategy struct {
+	Type            appsv1.DeploymentStrategyType
+	Replicas        int
+	MaxSurge        int
+	MaxUnavailable  int
+	MinReadySeconds int32
+}
+
+func (s rolloutStrategy) String() string {
+	if s.Type != appsv1.RollingUpdateDeploymentStrategyType {
+		return fmt.Sprintf("%s, replicas=%d, minReadySeconds=%d", s.Type, s.Replicas, s.MinReadySeconds)
+	}
+	return fmt.Sprintf("%s, replicas=%d, maxSurge=%d, maxUnavailable=%d, minReadySeconds=%d",
+		s.Type, s.Replicas, s.MaxSurge, s.MaxUnavailable, s.MinReadySeconds)
+}
+
+// MinAvailable 滚动过程中策略保证的最少可用pod数
+func (s rolloutStrategy) MinAvailable() int {
+	if s.Replicas-s.MaxUnavailable < 0 {
+		return 0
+	}
+	return s.Replicas - s.MaxUnavailable
+}
+
+// defaultStabilityWait 没有配置minReadySeconds时滚动成功后的额外稳定等待时间
+var defaultStabilityWait = 10 * time.Second
+
+// StabilityWait 成功后的额外稳定等待时间
+// 配置了minReadySeconds时pod已经按该时长判定可用，不再额外等待
+func (s rolloutStrategy) StabilityWait() time.Duration {
+	if s.MinReadySeconds > 0 {
+		return 0
+	}
+	return defaultStabilityWait
+}
+
+// getRolloutStrategy 解析部署的策略类型、maxSurge/maxUnavailable和minReadySeconds
+func getRolloutStrategy(deployment *appsv1.Deployment) rolloutStrategy {
+	strategy := rolloutStrategy{
+		Type:            deployment.Spec.Strategy.Type,
+		Replicas:        1,
+		MinReadySeconds: deployment.Spec.MinReadySeconds,
+	}
+	if deployment.Spec.Replicas != nil {
+		strategy.Replicas = int(*deployment.Spec.Replicas)
+	}
+	if strategy.Type == "" {
+		strategy.Type = appsv1.RollingUpdateDeploymentStrategyType
+	}
+
+	if strategy.Type == appsv1.RollingUpdateDeploymentStrategyType {
+		// 与Kubernetes默认值保持一致：maxSurge=25%向上取整，maxUnavailable=25%向下取整
+		maxSurge := intstr.FromString("25%")
+		maxUnavailable := intstr.FromString("25%")
+		if rollingUpdate := deployment.Spec.Strategy.RollingUpdate; rollingUpdate != nil {
+			if rollingUpdate.MaxSurge != nil {
+				maxSurge = *rollingUpdate.MaxSurge
 			}
+			if rollingUpdate.MaxUnavailable != nil {
+				maxUnavailable = *rollingUpdate.MaxUnavailable
+			}
+		}
+		strategy.MaxSurge, _ = intstr.GetScaledValueFromIntOrPercent(&maxSurge, strategy.Replicas, true)
+		strategy.MaxUnavailable, _ = intstr.GetScaledValueFromIntOrPercent(&maxUnavailable, strategy.Replicas, false)
+	}
+
+	return strategy
+}
+
+// isDeploymentRolloutComplete 按照kubectl rollout status的规则判断控制器是否已完成滚动
+func isDeploymentRolloutComplete(deployment *appsv1.Deployment) bool {
+	if deployment.Status.ObservedGeneration < deployment.Generation {
+		return false
+	}
+	replicas := int32(1)
+	if deployment.Spec.Replicas != nil {
+		replicas = *deployment.Spec.Replicas
+	}
+	return deployment.Status.UpdatedReplicas >= replicas &&
+		deployment.Status.Replicas == deployment.Status.UpdatedReplicas &&
+		deployment.Status.AvailableReplicas >= deployment.Status.UpdatedReplicas
+}
+
+// countAvailablePods 统计已就绪且持续时间满足minReadySeconds的pod数量
+func countAvailablePods(pods []*corev1.Pod, minReadySeconds int32) int {
+	availableCount := 0
+	now := time.Now()
+
+	for _, pod := range pods {
+		if isPodAvailable(pod, minReadySeconds, now) {
+			availableCount++
+		}
+	}
+
+	return availableCount
+}
+
+// isPodAvailable 检查pod是否健康且Ready状态已保持minReadySeconds
+func isPodAvailable(pod *corev1.Pod, minReadySeconds int32, now time.Time) bool {
+	if !isPodReadyAndHealthy(pod) {
+		return false
+	}
+	if minReadySeconds == 0 {
+		return true
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+			minReadyDuration := time.Duration(minReadySeconds) * time.Second
+			return !condition.LastTransitionTime.IsZero() &&
+				condition.LastTransitionTime.Add(minReadyDuration).Before(now)
+		}
+	}
+	return false
+}
+
+// findDeploymentHPA 查找以该部署为扩缩容目标的HPA，不存在时返回nil
+func findDeploymentHPA(ctx context.Context, clientset *kubernetes.Clientset, namespace, deploymentName string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpaList, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range hpaList.Items {
+		hpa := &hpaList.Items[i]
+		if hpa.Spec.ScaleTargetRef.Kind == "Deployment" && hpa.Spec.ScaleTargetRef.Name == deploymentName {
+			return hpa, nil
+		}
+	}
+	return nil, nil
+}
+
+// describeBlockingPDBs 查找匹配该部署pod且不允许中断的PDB，返回描述信息，没有时返回空字符串
+func describeBlockingPDBs(ctx context.Context, clientset *kubernetes.Clientset, namespace string, deployment *appsv1.Deployment) error {
+	pdbList, err := clientset.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil
+	}
+
+	var blocking []error
+	for _, pdb := range pdbList.Items {
+		if pdb.Spec.Selector == nil {
+			continue
+		}
+		selector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			continue
+		}
+		if pdb.Status.DisruptionsAllowed == 0 {
+			blocking = append(blocking, errorf("waiting on PDB %s, disruptionsAllowed=0 (currentHealthy=%d, desiredHealthy=%d)",
+				pdb.Name, pdb.Status.CurrentHealthy, pdb.Status.DesiredHealthy))
 		}
 	}
+	return joinErrors(blocking, "; ")
 }
 
 // 从部署中获取修订版本
@@ -441,12 +1456,44 @@ func getDeploymentRevision(deployment *appsv1.Deployment) string {
 	return ""
 }
 
+// compareRevisions 按数值比较两个revision，a>b返回1，a<b返回-1，相等或无法解析返回0
+func compareRevisions(a, b string) int {
+	revisionA, errA := strconv.ParseInt(a, 10, 64)
+	revisionB, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	switch {
+	case revisionA > revisionB:
+		return 1
+	case revisionA < revisionB:
+		return -1
+	}
+	return 0
+}
+
+// trackRevision 根据部署当前的revision识别本次发布的revision，本次发布的revision确定后再次增加说明有并发的发布
+func trackRevision(initialRevision, ourRevision, currentRevision string) (string, error) {
+	if ourRevision == "" {
+		if compareRevisions(currentRevision, initialRevision) > 0 {
+			slog.Info(msg("Deployment revision advanced to %s", currentRevision))
+			return currentRevision, nil
+		}
+		return "", nil
+	}
+	if compareRevisions(currentRevision, ourRevision) > 0 {
+		slog.Warn(msg("deployment revision advanced from %s to %s while monitoring, the observed rollout is not ours", ourRevision, currentRevision))
+		return ourRevision, errorf("%w: revision advanced from %s to %s", ErrConcurrentRollout, ourRevision, currentRevision)
+	}
+	return ourRevision, nil
+}
+
 // 获取与部署相关联的所有pod
 func getDeploymentPods(ctx context.Context, clientset *kubernetes.Clientset, namespace string, deployment *appsv1.Deployment) (*corev1.PodList, error) {
 	// 从部署中提取选择器
 	deploymentLabels := deployment.Spec.Selector.MatchLabels
 	if len(deploymentLabels) == 0 {
-		return nil, fmt.Errorf("deployment has no selector labels for pod selection")
+		return nil, errorf("deployment has no selector labels for pod selection")
 	}
 
 	// 构建标签选择器
@@ -461,30 +1508,13 @@ func getDeploymentPods(ctx context.Context, clientset *kubernetes.Clientset, nam
 	}
 
 	selector := selectorBuilder.String()
-	return clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
-		LabelSelector: selector,
+	return retryK8sCall(ctx, "list pods", func() (*corev1.PodList, error) {
+		return clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: selector,
+		})
 	})
 }
 
-// 将pod分类为新pod和旧pod
-func categorizePods(podList *corev1.PodList, initialRevision string) ([]*corev1.Pod, []*corev1.Pod) {
-	var newPods, oldPods []*corev1.Pod
-
-	for i := range podList.Items {
-		pod := &podList.Items[i]
-		podRevision := pod.Annotations["deployment.kubernetes.io/revision"]
-
-		// 检查是否是当前修订版本后创建的pod
-		if podRevision > initialRevision {
-			newPods = append(newPods, pod)
-		} else {
-			oldPods = append(oldPods, pod)
-		}
-	}
-
-	return newPods, oldPods
-}
-
 // 新增基于 UID 的分类函数，更准确地标识新旧 Pod
 func categorizePodsByUID(podList *corev1.PodList, initialPodUIDs map[string]bool) ([]*corev1.Pod, []*corev1.Pod) {
 	var newPods, oldPods []*corev1.Pod
@@ -522,32 +1552,86 @@ func isPodReadyAndHealthy(pod *corev1.Pod) bool {
 		return false
 	}
 
-	// 检查所有pod条件
-	for _, condition := range pod.Status.Conditions {
-		if condition.Type == corev1.PodReady && condition.Status != corev1.ConditionTrue {
+	// 检查Ready条件，条件尚未上报时也视为未就绪
+	if !isPodReady(pod) {
+		return false
+	}
+
+	// 检查readinessGates对应的条件（如Istio等外部控制器注入的条件）
+	if len(getUnmetReadinessGates(pod)) > 0 {
+		return false
+	}
+
+	// 检查原生sidecar（restartPolicy为Always的initContainer）和所有业务容器状态
+	for _, containerStatus := range getNativeSidecarStatuses(pod) {
+		if !isContainerHealthy(containerStatus) {
 			return false
 		}
 	}
-
-	// 检查所有容器状态
 	for _, containerStatus := range pod.Status.ContainerStatuses {
-		// 检查容器是否运行中
-		if !containerStatus.Ready {
+		if !isContainerHealthy(containerStatus) {
 			return false
 		}
+	}
 
-		// 检查容器是否频繁重启 (可能是由于liveness probe失败)
-		if containerStatus.RestartCount > 3 && timeFromLastRestart(containerStatus) < 60 {
-			return false
+	return true
+}
+
+// isContainerHealthy 检查单个容器是否就绪且没有频繁重启或处于等待状态
+func isContainerHealthy(containerStatus corev1.ContainerStatus) bool {
+	// 检查容器是否运行中
+	if !containerStatus.Ready {
+		return false
+	}
+
+	// 检查容器是否频繁重启 (可能是由于liveness probe失败)
+	if containerStatus.RestartCount > 3 && timeFromLastRestart(containerStatus) < 60 {
+		return false
+	}
+
+	// 检查容器是否处于等待状态(如CrashLoopBackOff, ImagePullBackOff等)
+	if containerStatus.State.Waiting != nil {
+		return false
+	}
+
+	return true
+}
+
+// getUnmetReadinessGates 返回pod中尚未满足的readinessGates条件
+func getUnmetReadinessGates(pod *corev1.Pod) []string {
+	var unmet []string
+	for _, gate := range pod.Spec.ReadinessGates {
+		satisfied := false
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == gate.ConditionType {
+				satisfied = condition.Status == corev1.ConditionTrue
+				break
+			}
+		}
+		if !satisfied {
+			unmet = append(unmet, string(gate.ConditionType))
 		}
+	}
+	return unmet
+}
 
-		// 检查容器是否处于等待状态(如CrashLoopBackOff, ImagePullBackOff等)
-		if containerStatus.State.Waiting != nil {
-			return false
+// getNativeSidecarStatuses 返回原生sidecar容器的状态
+// 原生sidecar以restartPolicy为Always的initContainer形式声明，状态在InitContainerStatuses中
+func getNativeSidecarStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	sidecars := make(map[string]bool)
+	for _, container := range pod.Spec.InitContainers {
+		if container.RestartPolicy != nil && *container.RestartPolicy == corev1.ContainerRestartPolicyAlways {
+			sidecars[container.Name] = true
 		}
 	}
 
-	return true
+	var statuses []corev1.ContainerStatus
+	for _, containerStatus := range pod.Status.InitContainerStatuses {
+		if sidecars[containerStatus.Name] {
+			statuses = append(statuses, containerStatus)
+		}
+	}
+	return statuses
 }
 
 // 计算从容器最后一次重启到现在的秒数
@@ -568,7 +1652,9 @@ func findErrorPods(pods []*corev1.Pod) []*corev1.Pod {
 	for _, pod := range pods {
 		if pod.Status.Phase == corev1.PodFailed ||
 			pod.Status.Phase == corev1.PodUnknown ||
-			hasCrashLoopBackOff(pod) {
+			hasCrashLoopBackOff(pod) ||
+			hasImagePullBackOff(pod) ||
+			hasOOMKilledContainer(pod) {
 			errorPods = append(errorPods, pod)
 		}
 	}
@@ -587,6 +1673,67 @@ func hasCrashLoopBackOff(pod *corev1.Pod) bool {
 	return false
 }
 
+// 检查pod中是否有容器因OOMKilled被终止
+func hasOOMKilledContainer(pod *corev1.Pod) bool {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if isContainerOOMKilled(containerStatus) {
+			return true
+		}
+	}
+	return false
+}
+
+// isContainerOOMKilled 检查容器当前或上一次终止的原因是否为OOMKilled
+func isContainerOOMKilled(containerStatus corev1.ContainerStatus) bool {
+	if containerStatus.State.Terminated != nil &&
+		containerStatus.State.Terminated.Reason == "OOMKilled" {
+		return true
+	}
+	if containerStatus.LastTerminationState.Terminated != nil &&
+		containerStatus.LastTerminationState.Terminated.Reason == "OOMKilled" {
+		return true
+	}
+	return false
+}
+
+// printOOMKilledDetails 输出OOMKilled容器的内存requests/limits和重启次数
+func printOOMKilledDetails(pod *corev1.Pod, containerStatus corev1.ContainerStatus) {
+	memoryRequest, memoryLimit := getContainerMemoryResources(pod, containerStatus.Name)
+	slog.Info(msg("Container %s in pod %s was OOMKilled: memory request=%s, memory limit=%s, RestartCount=%d", containerStatus.Name, pod.Name, memoryRequest, memoryLimit, containerStatus.RestartCount))
+}
+
+// getContainerMemoryResources 获取容器配置的内存requests和limits
+func getContainerMemoryResources(pod *corev1.Pod, containerName string) (string, string) {
+	formatMemory := func(resources corev1.ResourceList) string {
+		if quantity, ok := resources[corev1.ResourceMemory]; ok {
+			return quantity.String()
+		}
+		return "not set"
+	}
+
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			return formatMemory(container.Resources.Requests), formatMemory(container.Resources.Limits)
+		}
+	}
+	return "unknown", "unknown"
+}
+
+// classifyPodFailure 根据异常pod推断失败类型，用于最终的错误信息
+func classifyPodFailure(pods []*corev1.Pod) string {
+	for _, pod := range pods {
+		if hasOOMKilledContainer(pod) {
+			return "OOMKilled - container exceeded its memory limit, check resources.limits.memory"
+		}
+	}
+	for _, pod := range pods {
+		if hasCrashLoopBackOff(pod) {
+			return "CrashLoopBackOff - container keeps crashing after start, check application logs"
+		}
+	}
+	return ""
+}
+
 // 获取pod状态
 func getPodStatus(pod *corev1.Pod) string {
 	return string(pod.Status.Phase)
@@ -605,69 +1752,131 @@ func getPodErrorMessage(pod *corev1.Pod) string {
 	return "No error message found"
 }
 
-// getCurrentDeploymentStatus 获取当前部署的revision和pod信息
-func getCurrentDeploymentStatus(ctx context.Context, namespace, deploymentName, configPath string) (string, map[string]bool, error) {
-	var k8sConfig *rest.Config
-	var err error
+// detectInClusterNamespace 读取service account挂载的命名空间，POD_NAMESPACE环境变量优先
+func detectInClusterNamespace() (string, error) {
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		return namespace, nil
+	}
+	data, err := os.ReadFile(serviceAccountNamespaceFile)
+	if err != nil {
+		return "", errorf("k8s.namespace is not configured and the service account namespace is unavailable: %v", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
 
-	// 如果提供了配置文件路径，使用指定的配置文件
-	if configPath != "" {
-		// 展开 ~ 到用户主目录
-		if strings.HasPrefix(configPath, "~/") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return "", nil, fmt.Errorf("failed to get user home directory: %v", err)
-			}
-			configPath = filepath.Join(homeDir, configPath[2:])
-		}
+// expandHomePath 将 ~ 和以 ~/ 开头（Windows上也可以是 ~\）的路径展开到用户主目录
+// 使用os.UserHomeDir，Windows上为%USERPROFILE%而不是HOME
+func expandHomePath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !(runtime.GOOS == "windows" && strings.HasPrefix(path, `~\`)) {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
 
-		k8sConfig, err = clientcmd.BuildConfigFromFlags("", configPath)
-		if err != nil {
-			return "", nil, fmt.Errorf("failed to build config from flags: %v", err)
+// runPreflightChecks 在触发Jenkins构建前检查集群连接、命名空间、部署是否存在以及RBAC权限
+func runPreflightChecks(ctx context.Context, namespace, deploymentName, configPath string) error {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+
+	// 检查集群连接
+	versionCtx, credentialPluginError := withCredentialPluginCheck(ctx)
+	var version k8sversion.Info
+	data, err := clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(versionCtx).Raw()
+	if err == nil {
+		err = json.Unmarshal(data, &version)
+	}
+	if err = credentialPluginError(err); errors.Is(err, errCredentialPlugin) {
+		return errorf("%w (make sure you are logged in to your cloud provider, e.g. `aws sso login`, `gcloud auth login` or `az login`)", err)
+	} else if apierrors.IsUnauthorized(err) {
+		// 提示由authFailureHints给出
+		return errorf("Kubernetes API server rejected the credentials: %v", err)
+	} else if err != nil {
+		return errorf("cannot reach Kubernetes API server: %v (check k8s.config_path, current context and network/VPN access)", err)
+	}
+	slog.Debug(fmt.Sprintf("Preflight: connected to Kubernetes %s", version.GitVersion))
+
+	// 检查当前凭证的权限
+	permissions := []authorizationv1.ResourceAttributes{
+		{Namespace: namespace, Verb: "get", Group: "apps", Resource: "deployments"},
+		{Namespace: namespace, Verb: "list", Resource: "pods"},
+	}
+	for _, attributes := range permissions {
+		attributes := attributes
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{ResourceAttributes: &attributes},
 		}
-	} else {
-		// 尝试使用集群内配置
-		k8sConfig, err = rest.InClusterConfig()
+		result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
 		if err != nil {
-			// 如果集群内配置失败，尝试使用默认的 kubeconfig
-			k8sConfig, err = clientcmd.BuildConfigFromFlags("", filepath.Join(os.Getenv("HOME"), ".kube", "config"))
-			if err != nil {
-				return "", nil, fmt.Errorf("failed to get k8s config: %v", err)
-			}
+			return errorf("failed to check permission to %s %s: %v", attributes.Verb, attributes.Resource, err)
 		}
+		if !result.Status.Allowed {
+			return errorf("current credentials cannot %s %s in namespace %s: ask a cluster admin to grant this permission or use a kubeconfig with access",
+				attributes.Verb, attributes.Resource, namespace)
+		}
+	}
+
+	// 检查命名空间是否存在，没有权限读取命名空间时跳过
+	_, err = clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return errorf("namespace %s does not exist in the cluster: check k8s.namespace in deploy_config.yaml", namespace)
+	} else if err != nil && !apierrors.IsForbidden(err) {
+		return errorf("failed to get namespace %s: %v", namespace, err)
 	}
 
-	clientset, err := kubernetes.NewForConfig(k8sConfig)
+	// 检查部署是否存在
+	_, err = clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return errorf("deployment %s does not exist in namespace %s: check k8s.deployment in deploy_config.yaml", deploymentName, namespace)
+	} else if err != nil {
+		return errorf("failed to get deployment %s: %v", deploymentName, err)
+	}
+
+	slog.Debug(fmt.Sprintf("Preflight: deployment %s/%s found, permissions OK", namespace, deploymentName))
+	return nil
+}
+
+// getCurrentDeploymentStatus 获取当前部署的revision和pod信息
+func getCurrentDeploymentStatus(ctx context.Context, namespace, deploymentName, configPath string) (string, map[string]bool, error) {
+	clientset, err := newKubernetesClient(configPath)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to create kubernetes client: %v", err)
+		return "", nil, err
 	}
 
 	// 获取当前部署信息
-	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	deployment, err := getDeployment(ctx, clientset, namespace, deploymentName)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get deployment: %v", err)
+		return "", nil, errorf("failed to get deployment: %v", err)
 	}
 
 	// 获取当前revision
 	initialRevision := getDeploymentRevision(deployment)
 	if initialRevision == "" {
-		return "", nil, fmt.Errorf("unable to determine deployment revision")
+		return "", nil, errorf("unable to determine deployment revision")
 	}
 
 	// 获取与部署关联的所有初始 pod
 	initialPodList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
 	if err != nil {
-		return "", nil, fmt.Errorf("failed to get initial pods: %v", err)
+		return "", nil, errorf("failed to get initial pods: %v", err)
 	}
 
 	// 保存初始 Pod 的 UID 列表作为旧 Pod 标识
-	initialPodUIDs := make(map[string]bool)
-	for i := range initialPodList.Items {
-		pod := &initialPodList.Items[i]
-		initialPodUIDs[string(pod.UID)] = true
-	}
+	return initialRevision, podUIDs(initialPodList), nil
+}
 
-	return initialRevision, initialPodUIDs, nil
+// podUIDs pod列表中所有pod的UID
+func podUIDs(podList *corev1.PodList) map[string]bool {
+	uids := make(map[string]bool)
+	for i := range podList.Items {
+		uids[string(podList.Items[i].UID)] = true
+	}
+	return uids
 }
 
 // isPodReady 检查pod是否处于Ready状态
@@ -680,13 +1889,13 @@ func isPodReady(pod *corev1.Pod) bool {
 	return false
 }
 
-// areAllContainersReady 检查所有容器是否Ready
+// areAllContainersReady 检查所有容器（包括原生sidecar）是否Ready
 func areAllContainersReady(pod *corev1.Pod) bool {
 	if len(pod.Status.ContainerStatuses) == 0 {
 		return false
 	}
 
-	for _, containerStatus := range pod.Status.ContainerStatuses {
+	for _, containerStatus := range append(getNativeSidecarStatuses(pod), pod.Status.ContainerStatuses...) {
 		if !containerStatus.Ready {
 			return false
 		}
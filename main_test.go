@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestCompareRevisions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"10", "9", 1},
+		{"9", "10", -1},
+		{"100", "99", 1},
+		{"7", "7", 0},
+		{"", "3", 0},
+		{"3", "", 0},
+		{"abc", "1", 0},
+		{"2", "v1", 0},
+		{"1.5", "1", 0},
+	}
+	for _, tt := range tests {
+		if got := compareRevisions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareRevisions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestGetDeploymentRevision(t *testing.T) {
+	deployment := &appsv1.Deployment{}
+	if got := getDeploymentRevision(deployment); got != "" {
+		t.Errorf("revision without annotations = %q, want empty", got)
+	}
+	deployment.Annotations = map[string]string{"deployment.kubernetes.io/revision": "12"}
+	if got := getDeploymentRevision(deployment); got != "12" {
+		t.Errorf("revision = %q, want 12", got)
+	}
+}
+
+func TestTrackRevision(t *testing.T) {
+	tests := []struct {
+		name                   string
+		initial, ours, current string
+		want                   string
+		concurrent             bool
+	}{
+		{name: "not advanced yet", initial: "9", current: "9", want: ""},
+		{name: "advanced past 9", initial: "9", current: "10", want: "10"},
+		{name: "initial unknown", initial: "", current: "1", want: ""},
+		{name: "still ours", initial: "9", ours: "10", current: "10", want: "10"},
+		{name: "competing rollout", initial: "9", ours: "10", current: "11", want: "10", concurrent: true},
+		{name: "non-numeric current", initial: "9", ours: "10", current: "x", want: "10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := trackRevision(tt.initial, tt.ours, tt.current)
+			if got != tt.want {
+				t.Errorf("revision = %q, want %q", got, tt.want)
+			}
+			if concurrent := errors.Is(err, ErrConcurrentRollout); concurrent != tt.concurrent {
+				t.Errorf("err = %v, want concurrent rollout %v", err, tt.concurrent)
+			}
+		})
+	}
+}
+
+func TestCategorizePodsByUID(t *testing.T) {
+	podList := &corev1.PodList{Items: []corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "old-1", UID: types.UID("uid-old-1")}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "new-1", UID: types.UID("uid-new-1")}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "old-2", UID: types.UID("uid-old-2")}},
+	}}
+	newPods, oldPods := categorizePodsByUID(podList, map[string]bool{"uid-old-1": true, "uid-old-2": true})
+	if len(newPods) != 1 || newPods[0].Name != "new-1" {
+		t.Errorf("new pods = %v, want [new-1]", podNames(newPods))
+	}
+	if len(oldPods) != 2 || oldPods[0].Name != "old-1" || oldPods[1].Name != "old-2" {
+		t.Errorf("old pods = %v, want [old-1 old-2]", podNames(oldPods))
+	}
+}
+
+func TestExpandHomePath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	tests := []struct {
+		path, want string
+	}{
+		{"~", home},
+		{"~/.kube/config", filepath.Join(home, ".kube", "config")},
+		{"/etc/kube/config", "/etc/kube/config"},
+		{"relative/config", "relative/config"},
+		{"~other/config", "~other/config"},
+	}
+	if runtime.GOOS == "windows" {
+		tests = append(tests, struct{ path, want string }{`~\.kube\config`, filepath.Join(home, ".kube", "config")})
+	}
+	for _, tt := range tests {
+		got, err := expandHomePath(tt.path)
+		if err != nil {
+			t.Fatalf("expandHomePath(%q): %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("expandHomePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func podNames(pods []*corev1.Pod) []string {
+	names := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	return names
+}
+
+func TestCredentialPluginCheck(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	versionError := func(config *rest.Config) error {
+		config.Wrap(wrapK8sAuthWatch)
+		clientset, err := kubernetes.NewForConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, credentialPluginError := withCredentialPluginCheck(context.Background())
+		_, err = clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+		return credentialPluginError(err)
+	}
+
+	// 凭证插件失败时请求没有发送到API server
+	err := versionError(&rest.Config{Host: server.URL, ExecProvider: &clientcmdapi.ExecConfig{
+		Command:         filepath.Join(t.TempDir(), "missing-credential-plugin"),
+		APIVersion:      "client.authentication.k8s.io/v1beta1",
+		InteractiveMode: clientcmdapi.NeverExecInteractiveMode,
+	}})
+	if !errors.Is(err, errCredentialPlugin) {
+		t.Errorf("credential plugin failure: got %v, want errCredentialPlugin", err)
+	}
+
+	// API server返回的错误不是凭证插件失败
+	err = versionError(&rest.Config{Host: server.URL})
+	if err == nil || errors.Is(err, errCredentialPlugin) {
+		t.Errorf("server error: got %v, want a non credential plugin error", err)
+	}
+}
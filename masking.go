@@ -0,0 +1,85 @@
+package main
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// secretMask 输出中替换密钥的内容
+const secretMask = "****"
+
+// minSecretLength 短于该长度的值不作为密钥屏蔽，避免把true、1之类的值从所有输出中替换掉
+const minSecretLength = 4
+
+// secretRegistry 本次运行中出现过的密钥（API token、密码类型的参数等），在终端输出、构建日志、通知和历史记录中屏蔽
+var secretRegistry struct {
+	sync.RWMutex
+	replacer *strings.Replacer
+	values   map[string]bool
+}
+
+// registerSecret 登记需要屏蔽的密钥
+func registerSecret(value string) {
+	if len(value) < minSecretLength {
+		return
+	}
+	secretRegistry.Lock()
+	defer secretRegistry.Unlock()
+	if secretRegistry.values[value] {
+		return
+	}
+	if secretRegistry.values == nil {
+		secretRegistry.values = make(map[string]bool)
+	}
+	secretRegistry.values[value] = true
+
+	// 较长的密钥先替换，一个密钥包含另一个时不会只屏蔽一部分
+	values := make([]string, 0, len(secretRegistry.values))
+	for v := range secretRegistry.values {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		pairs = append(pairs, v, secretMask)
+	}
+	secretRegistry.replacer = strings.NewReplacer(pairs...)
+}
+
+// maskSecrets 把字符串中登记过的密钥替换为****
+func maskSecrets(s string) string {
+	secretRegistry.RLock()
+	replacer := secretRegistry.replacer
+	secretRegistry.RUnlock()
+	if replacer == nil || s == "" {
+		return s
+	}
+	return replacer.Replace(s)
+}
+
+// maskSecretMap 返回屏蔽了密钥的副本
+func maskSecretMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	masked := make(map[string]string, len(m))
+	for k, v := range m {
+		masked[k] = maskSecrets(v)
+	}
+	return masked
+}
+
+// maskingWriter 写入前屏蔽密钥
+// 按每次Write屏蔽，构建日志按增量输出，跨两次输出的密钥无法屏蔽，实际中日志按行输出，很少出现
+type maskingWriter struct {
+	w io.Writer
+}
+
+func (m maskingWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(m.w, maskSecrets(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
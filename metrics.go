@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// doraMetrics 一个项目环境在统计区间内的DORA指标
+type doraMetrics struct {
+	Project           string  `json:"project"`
+	Env               string  `json:"env"`
+	Deploys           int     `json:"deploys"`
+	Failures          int     `json:"failures"`
+	DeploysPerDay     float64 `json:"deploys_per_day"`
+	ChangeFailureRate float64 `json:"change_failure_rate"`
+	MTTRSeconds       float64 `json:"mttr_seconds,omitempty"` // 失败到下一次成功部署的平均时间，没有恢复过的失败时为0
+	Restores          int     `json:"restores"`
+}
+
+// runMetricsCommand deploy metrics子命令：根据部署记录计算部署频率、变更失败率和平均恢复时间
+func runMetricsCommand(args []string) error {
+	flags := flag.NewFlagSet("metrics", flag.ExitOnError)
+	remote := flags.Bool("remote", false, "read records from the shared team ledger instead of the local history")
+	env := flags.String("env", "", "only include deploys of this env")
+	all := flags.Bool("all", false, "include all projects instead of the current directory's project")
+	since := flags.String("since", "", "start date (YYYY-MM-DD), default 30 days ago")
+	until := flags.String("until", "", "end date (YYYY-MM-DD, inclusive), default today")
+	output := flags.String("output", "table", "output format: table or json")
+	flags.Parse(args)
+
+	now := time.Now()
+	end := now
+	if *until != "" {
+		day, err := time.ParseInLocation("2006-01-02", *until, time.Local)
+		if err != nil {
+			return errorf("invalid --until %q: %v", *until, err)
+		}
+		end = day.AddDate(0, 0, 1)
+	}
+	start := end.AddDate(0, 0, -30)
+	if *since != "" {
+		day, err := time.ParseInLocation("2006-01-02", *since, time.Local)
+		if err != nil {
+			return errorf("invalid --since %q: %v", *since, err)
+		}
+		start = day
+	}
+	if !start.Before(end) {
+		return errorf("--since must be before --until")
+	}
+
+	query := historyQuery{Env: *env}
+	if !*all {
+		project, err := currentProjectName()
+		if err != nil {
+			return err
+		}
+		query.Project = project
+	}
+	backend, err := historyBackend(*remote)
+	if err != nil {
+		return err
+	}
+	records, err := backend.list(context.Background(), query)
+	if err != nil {
+		return errorf("failed to read deploy history: %v", err)
+	}
+
+	metrics := computeDoraMetrics(records, start, end)
+	if *output == "json" {
+		data, err := json.MarshalIndent(metrics, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	fmt.Print(msg("DORA metrics from %s to %s\n\n", start.Format("2006-01-02"), end.Add(-time.Second).Format("2006-01-02")))
+	if len(metrics) == 0 {
+		fmt.Println(tr("No deploys found"))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PROJECT\tENV\tDEPLOYS\tPER DAY\tFAILURES\tCHANGE FAILURE RATE\tMTTR")
+	for _, m := range metrics {
+		mttr := "-"
+		if m.Restores > 0 {
+			mttr = (time.Duration(m.MTTRSeconds) * time.Second).String()
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%d\t%.2f\t%d\t%.1f%%\t%s\n",
+			m.Project, m.Env, m.Deploys, m.DeploysPerDay, m.Failures, m.ChangeFailureRate*100, mttr)
+	}
+	return writer.Flush()
+}
+
+// computeDoraMetrics 按项目环境分组计算区间[start, end)内的指标
+func computeDoraMetrics(records []deployRecord, start, end time.Time) []doraMetrics {
+	groups := make(map[[2]string][]deployRecord)
+	for _, record := range records {
+		key := [2]string{record.Project, record.Env}
+		groups[key] = append(groups[key], record)
+	}
+	days := end.Sub(start).Hours() / 24
+
+	var metrics []doraMetrics
+	for key, group := range groups {
+		sort.Slice(group, func(i, j int) bool { return group[i].FinishedAt.Before(group[j].FinishedAt) })
+
+		m := doraMetrics{Project: key[0], Env: key[1]}
+		var restoreTotal time.Duration
+		var failedAt time.Time
+		for _, record := range group {
+			// 区间开始前的失败同样参与恢复时间的计算
+			inRange := !record.FinishedAt.Before(start) && record.FinishedAt.Before(end)
+			if record.FinishedAt.After(end) {
+				break
+			}
+			if record.Result == stageSuccess {
+				if !failedAt.IsZero() && inRange {
+					restoreTotal += record.FinishedAt.Sub(failedAt)
+					m.Restores++
+				}
+				failedAt = time.Time{}
+			} else if failedAt.IsZero() {
+				failedAt = record.FinishedAt
+			}
+			if !inRange {
+				continue
+			}
+			m.Deploys++
+			if record.Result != stageSuccess {
+				m.Failures++
+			}
+		}
+		if m.Deploys == 0 {
+			continue
+		}
+		m.DeploysPerDay = float64(m.Deploys) / days
+		m.ChangeFailureRate = float64(m.Failures) / float64(m.Deploys)
+		if m.Restores > 0 {
+			m.MTTRSeconds = (restoreTotal / time.Duration(m.Restores)).Round(time.Second).Seconds()
+		}
+		metrics = append(metrics, m)
+	}
+
+	sort.Slice(metrics, func(i, j int) bool {
+		if metrics[i].Project != metrics[j].Project {
+			return metrics[i].Project < metrics[j].Project
+		}
+		return metrics[i].Env < metrics[j].Env
+	})
+	return metrics
+}
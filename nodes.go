@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// nodeProblems 返回节点的异常状况：NotReady、各类Pressure、NetworkUnavailable以及被cordon
+func nodeProblems(node *corev1.Node) []string {
+	var problems []string
+	ready := false
+	for _, condition := range node.Status.Conditions {
+		switch condition.Type {
+		case corev1.NodeReady:
+			ready = condition.Status == corev1.ConditionTrue
+		case corev1.NodeDiskPressure, corev1.NodeMemoryPressure, corev1.NodePIDPressure, corev1.NodeNetworkUnavailable:
+			if condition.Status == corev1.ConditionTrue {
+				problems = append(problems, string(condition.Type))
+			}
+		}
+	}
+	if !ready {
+		problems = append([]string{"NotReady"}, problems...)
+	}
+	if node.Spec.Unschedulable {
+		problems = append(problems, "SchedulingDisabled")
+	}
+	return problems
+}
+
+// describePodNodes 输出异常pod所在的节点及节点状况，异常pod都在同一节点而其他节点上有就绪的新pod时提示该节点可能是原因，
+// 返回有异常状况的节点描述，用于最终的错误信息，没有时返回nil
+func describePodNodes(ctx context.Context, clientset *kubernetes.Clientset, problemPods, newPods []*corev1.Pod) error {
+	podsByNode := make(map[string][]string)
+	for _, pod := range problemPods {
+		if pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod.Name)
+		}
+	}
+	if len(podsByNode) == 0 {
+		return nil
+	}
+	nodeNames := make([]string, 0, len(podsByNode))
+	for name := range podsByNode {
+		nodeNames = append(nodeNames, name)
+	}
+	sort.Strings(nodeNames)
+
+	var unhealthy []string
+	for _, name := range nodeNames {
+		node, err := retryK8sCall(ctx, "get node", func() (*corev1.Node, error) {
+			return clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+		})
+		if err != nil {
+			// 没有节点的读取权限时只输出节点名称
+			slog.Info(msg("Node %s hosts problem pods %s (node conditions unavailable: %v)", name, strings.Join(podsByNode[name], ", "), err))
+			continue
+		}
+		if problems := nodeProblems(node); len(problems) > 0 {
+			slog.Warn(msg("node %s hosting problem pods %s is unhealthy: %s", name, strings.Join(podsByNode[name], ", "), strings.Join(problems, ", ")))
+			unhealthy = append(unhealthy, name+" ("+strings.Join(problems, ", ")+")")
+		} else {
+			slog.Info(msg("Node %s hosts problem pods %s, node conditions are healthy", name, strings.Join(podsByNode[name], ", ")))
+		}
+	}
+
+	// 异常pod集中在一个节点，而其他节点上的新pod已就绪
+	if len(nodeNames) == 1 {
+		for _, pod := range newPods {
+			if pod.Spec.NodeName != "" && pod.Spec.NodeName != nodeNames[0] && isPodReadyAndHealthy(pod) {
+				slog.Warn(msg("all problem pods are on node %s while new pods on other nodes are ready, the node is a likely cause: kubectl describe node %s", nodeNames[0], nodeNames[0]))
+				break
+			}
+		}
+	}
+
+	if len(unhealthy) == 0 {
+		return nil
+	}
+	return errorf("unhealthy nodes: %s", strings.Join(unhealthy, "; "))
+}
+
+// podNodeName pod所在的节点，尚未调度时返回"-"
+func podNodeName(pod *corev1.Pod) string {
+	if pod.Spec.NodeName == "" {
+		return "-"
+	}
+	return pod.Spec.NodeName
+}
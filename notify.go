@@ -0,0 +1,294 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os/exec"
+	"os/user"
+	"strings"
+	"time"
+)
+
+// NotificationsConfig 部署开始、成功、失败时发送通知的渠道配置，环境配置优先于全局配置
+type NotificationsConfig struct {
+	Slack    *SlackConfig    `yaml:"slack,omitempty"`
+	DingTalk *DingTalkConfig `yaml:"dingtalk,omitempty"`
+	WeCom    *WeComConfig    `yaml:"wecom,omitempty"`
+	Teams    *TeamsConfig    `yaml:"teams,omitempty"`
+	Telegram *TelegramConfig `yaml:"telegram,omitempty"`
+	Email    *EmailConfig    `yaml:"email,omitempty"`
+
+	// 以下告警渠道只在critical环境部署失败时触发
+	PagerDuty *PagerDutyConfig `yaml:"pagerduty,omitempty"`
+	Opsgenie  *OpsgenieConfig  `yaml:"opsgenie,omitempty"`
+
+	// 在代码托管平台和监控系统中记录部署
+	GitLab   *GitLabConfig   `yaml:"gitlab,omitempty"`
+	Grafana  *GrafanaConfig  `yaml:"grafana,omitempty"`
+	Sentry   *SentryConfig   `yaml:"sentry,omitempty"`
+	Datadog  *DatadogConfig  `yaml:"datadog,omitempty"`
+	NewRelic *NewRelicConfig `yaml:"newrelic,omitempty"`
+	Jira     *JiraConfig     `yaml:"jira,omitempty"`
+	GitHub   *GitHubConfig   `yaml:"github,omitempty"`
+}
+
+// resolveNotifications 环境配置覆盖全局配置，环境的邮件配置未指定SMTP服务器时沿用全局配置
+func resolveNotifications(global, env *NotificationsConfig) *NotificationsConfig {
+	if env == nil {
+		return global
+	}
+	if env.Email == nil || env.Email.Host != "" || global == nil || global.Email == nil {
+		return env
+	}
+
+	resolved := *env
+	email := *global.Email
+	email.To = env.Email.To
+	email.OnStart = env.Email.OnStart
+	resolved.Email = &email
+	return &resolved
+}
+
+// 部署生命周期阶段
+const (
+	stageStart   = "start"
+	stageSuccess = "success"
+	stageFailure = "failure"
+)
+
+// deployEvent 发送给各通知渠道的部署事件
+type deployEvent struct {
+	Stage      string
+	Project    string
+	Env        string
+	Branch     string
+	Commit     string // 当前提交的完整sha
+	Deployer   string // 执行部署的人，取git user.name或系统用户名
+	BuildURL   string
+	Duration   time.Duration
+	Error      string
+	FailureLog string   // 构建失败时的日志末尾
+	Changelog  []string // 上次部署以来的提交
+
+	Images      map[string]string // 部署后的容器镜像，部署成功时才有
+	Phases      []phaseDuration
+	SmokeChecks []smokeCheckResult
+}
+
+// notifier 通知渠道
+type notifier interface {
+	notify(ctx context.Context, event deployEvent) error
+}
+
+// deployNotifier 把部署事件分发到所有配置的通知渠道，发送失败只打印警告，不影响部署
+type deployNotifier struct {
+	notifiers []notifier
+	summary   *deploySummary
+}
+
+func newDeployNotifier(config *NotificationsConfig, summary *deploySummary, critical bool) *deployNotifier {
+	n := &deployNotifier{summary: summary}
+	if config == nil {
+		return n
+	}
+	// token可以引用系统钥匙串或AWS中的密钥
+	config = resolveNotificationSecrets(context.Background(), config)
+	if config.Slack != nil {
+		n.notifiers = append(n.notifiers, &slackNotifier{config: *config.Slack})
+	}
+	if config.DingTalk != nil {
+		n.notifiers = append(n.notifiers, &dingTalkNotifier{config: *config.DingTalk})
+	}
+	if config.WeCom != nil {
+		n.notifiers = append(n.notifiers, &weComNotifier{config: *config.WeCom})
+	}
+	if config.Teams != nil {
+		n.notifiers = append(n.notifiers, &teamsNotifier{config: *config.Teams})
+	}
+	if config.Telegram != nil {
+		n.notifiers = append(n.notifiers, &telegramNotifier{config: *config.Telegram})
+	}
+	if config.Email != nil {
+		n.notifiers = append(n.notifiers, &emailNotifier{config: *config.Email})
+	}
+	if config.GitLab != nil {
+		n.notifiers = append(n.notifiers, &gitLabNotifier{config: *config.GitLab})
+	}
+	if config.Grafana != nil {
+		n.notifiers = append(n.notifiers, &grafanaNotifier{config: *config.Grafana})
+	}
+	if config.Sentry != nil {
+		n.notifiers = append(n.notifiers, &sentryNotifier{config: *config.Sentry})
+	}
+	if config.Datadog != nil {
+		n.notifiers = append(n.notifiers, &datadogNotifier{config: *config.Datadog})
+	}
+	if config.NewRelic != nil {
+		n.notifiers = append(n.notifiers, &newRelicNotifier{config: *config.NewRelic})
+	}
+	if config.Jira != nil {
+		n.notifiers = append(n.notifiers, &jiraNotifier{config: *config.Jira})
+	}
+	if config.GitHub != nil {
+		n.notifiers = append(n.notifiers, &gitHubNotifier{config: *config.GitHub})
+	}
+	if critical && config.PagerDuty != nil {
+		n.notifiers = append(n.notifiers, &pagerDutyNotifier{config: *config.PagerDuty})
+	}
+	if critical && config.Opsgenie != nil {
+		n.notifiers = append(n.notifiers, &opsgenieNotifier{config: *config.Opsgenie})
+	}
+	return n
+}
+
+// send 向所有渠道发送指定阶段的事件
+func (n *deployNotifier) send(ctx context.Context, stage, errMessage string) {
+	if len(n.notifiers) == 0 {
+		return
+	}
+	event := deployEvent{
+		Stage:      stage,
+		Project:    n.summary.Project,
+		Env:        n.summary.Env,
+		Branch:     n.summary.Branch,
+		Commit:     n.summary.Commit,
+		Deployer:   n.summary.Deployer,
+		BuildURL:   n.summary.BuildURL,
+		Duration:   time.Since(n.summary.startTime).Round(time.Second),
+		Error:      maskSecrets(errMessage),
+		FailureLog: maskSecrets(n.summary.failureLog),
+		Changelog:  n.summary.Changelog,
+
+		Images:      n.summary.NewImages,
+		Phases:      n.summary.Phases,
+		SmokeChecks: n.summary.SmokeChecks,
+	}
+	for _, channel := range n.notifiers {
+		if err := channel.notify(ctx, event); err != nil {
+			slog.Warn(msg("failed to send %s notification: %v", stage, err))
+		}
+	}
+}
+
+// title 事件的单行标题
+func (e deployEvent) title() string {
+	switch e.Stage {
+	case stageStart:
+		return fmt.Sprintf("Deploy started: %s/%s", e.Project, e.Env)
+	case stageSuccess:
+		return fmt.Sprintf("Deploy succeeded: %s/%s", e.Project, e.Env)
+	default:
+		return fmt.Sprintf("Deploy failed: %s/%s", e.Project, e.Env)
+	}
+}
+
+// fields 事件的详细信息，按固定顺序输出
+func (e deployEvent) fields() [][2]string {
+	fields := [][2]string{{"Project", e.Project}, {"Env", e.Env}}
+	if e.Branch != "" {
+		fields = append(fields, [2]string{"Branch", e.Branch})
+	}
+	if e.Commit != "" {
+		fields = append(fields, [2]string{"Commit", shortCommit(e.Commit)})
+	}
+	if len(e.Changelog) > 0 {
+		fields = append(fields, [2]string{"Changes", strings.Join(truncateChangelog(e.Changelog), "\n")})
+	}
+	if e.Deployer != "" {
+		fields = append(fields, [2]string{"Deployer", e.Deployer})
+	}
+	if e.BuildURL != "" {
+		fields = append(fields, [2]string{"Build", e.BuildURL})
+	}
+	if e.Stage != stageStart {
+		fields = append(fields, [2]string{"Duration", e.Duration.String()})
+	}
+	if e.Error != "" {
+		fields = append(fields, [2]string{"Error", e.Error})
+	}
+	return fields
+}
+
+// shortCommit 提交sha的前7位
+func shortCommit(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+// postJSON 发送JSON POST请求，非2xx响应视为失败，返回响应内容
+func postJSON(ctx context.Context, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	return sendJSON(ctx, http.MethodPost, url, payload, headers)
+}
+
+// sendJSON 按指定方法发送JSON请求，payload为nil时不带请求体，非2xx响应视为失败，返回响应内容
+func sendJSON(ctx context.Context, method, url string, payload interface{}, headers map[string]string) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, httpTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+	return respBody, nil
+}
+
+// currentBranchName 读取当前git分支，不在git仓库中时返回空字符串
+func currentBranchName() string {
+	return gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// currentDeployer 执行部署的人，优先使用git user.name
+func currentDeployer() string {
+	// 服务模式下为请求的认证身份
+	if operator := serverOperator(); operator != "" {
+		return operator
+	}
+	if name := gitOutput("config", "user.name"); name != "" {
+		return name
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+// gitOutput 执行git命令并返回去掉空白的输出，失败时返回空字符串
+func gitOutput(args ...string) string {
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// DatadogConfig 通过Datadog Events API发送部署事件
+type DatadogConfig struct {
+	APIKey string   `yaml:"api_key"`
+	Site   string   `yaml:"site,omitempty"` // Datadog站点，默认datadoghq.com（欧洲为datadoghq.eu）
+	Tags   []string `yaml:"tags,omitempty"` // 额外的tag，默认已包含project、env、version
+}
+
+// NewRelicConfig 通过New Relic change tracking记录部署标记
+type NewRelicConfig struct {
+	APIKey     string `yaml:"api_key"`          // User API key
+	EntityGUID string `yaml:"entity_guid"`      // 部署的APM应用实体GUID
+	Region     string `yaml:"region,omitempty"` // us或eu，默认us
+}
+
+// datadogNotifier 部署成功或失败时发送Datadog事件
+type datadogNotifier struct {
+	config DatadogConfig
+}
+
+func (d *datadogNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage == stageStart {
+		return nil
+	}
+	site := d.config.Site
+	if site == "" {
+		site = "datadoghq.com"
+	}
+
+	tags := append([]string{"project:" + event.Project, "env:" + event.Env, "source:deploy"}, d.config.Tags...)
+	if event.Commit != "" {
+		tags = append(tags, "version:"+shortCommit(event.Commit))
+	}
+	if event.Deployer != "" {
+		tags = append(tags, "deployer:"+event.Deployer)
+	}
+	alertType := "success"
+	if event.Stage == stageFailure {
+		alertType = "error"
+	}
+
+	var lines []string
+	for _, field := range event.fields() {
+		lines = append(lines, fmt.Sprintf("%s: %s", field[0], field[1]))
+	}
+	payload := map[string]interface{}{
+		"title":           event.title(),
+		"text":            strings.Join(lines, "\n"),
+		"tags":            tags,
+		"alert_type":      alertType,
+		"aggregation_key": incidentDedupKey(event),
+	}
+	if _, err := postJSON(ctx, "https://api."+site+"/api/v1/events", payload, map[string]string{"DD-API-KEY": d.config.APIKey}); err != nil {
+		return errorf("datadog: %v", err)
+	}
+	return nil
+}
+
+// newRelicNotifier 部署成功时通过NerdGraph创建change tracking部署标记
+type newRelicNotifier struct {
+	config NewRelicConfig
+}
+
+func (n *newRelicNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage != stageSuccess {
+		return nil
+	}
+	endpoint := "https://api.newrelic.com/graphql"
+	if n.config.Region == "eu" {
+		endpoint = "https://api.eu.newrelic.com/graphql"
+	}
+
+	version := shortCommit(event.Commit)
+	if version == "" {
+		version = event.Env
+	}
+	query := `mutation($deployment: ChangeTrackingDeploymentInput!) {
+  changeTrackingCreateDeployment(deployment: $deployment) { deploymentId }
+}`
+	deployment := map[string]interface{}{
+		"entityGuid":     n.config.EntityGUID,
+		"version":        version,
+		"user":           event.Deployer,
+		"commit":         event.Commit,
+		"description":    fmt.Sprintf("%s deployed to %s", event.Project, event.Env),
+		"deploymentType": "BASIC",
+	}
+	if event.BuildURL != "" {
+		deployment["deepLink"] = event.BuildURL
+	}
+
+	respBody, err := postJSON(ctx, endpoint, map[string]interface{}{
+		"query":     query,
+		"variables": map[string]interface{}{"deployment": deployment},
+	}, map[string]string{"API-Key": n.config.APIKey})
+	if err != nil {
+		return errorf("newrelic: %v", err)
+	}
+
+	// GraphQL出错时同样返回200，需要检查errors字段
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return errorf("newrelic: invalid response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		return errorf("newrelic: %s", result.Errors[0].Message)
+	}
+	return nil
+}
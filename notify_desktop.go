@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// 部署结束时提醒在后台终端中等待的人，覆盖配置中的bell和on_finish
+var (
+	ringBell        = flag.Bool("bell", false, "ring the terminal bell when the deploy finishes")
+	onFinishCommand = flag.String("on-finish", "", "shell command to run when the deploy finishes, with DEPLOY_RESULT, DEPLOY_PROJECT, DEPLOY_ENV, DEPLOY_DURATION, DEPLOY_BUILD_URL and DEPLOY_ERROR set")
+)
+
+// desktopNotifier 部署结束时发送系统桌面通知，只在终端中运行时启用
+type desktopNotifier struct{}
+
+// desktopNotificationsAvailable 判断是否在交互终端中运行且系统支持桌面通知
+func desktopNotificationsAvailable() bool {
+	if info, err := os.Stdout.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("osascript")
+		return err == nil
+	case "windows":
+		_, err := exec.LookPath("powershell")
+		return err == nil
+	default:
+		_, err := exec.LookPath("notify-send")
+		return err == nil
+	}
+}
+
+func (d desktopNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage == stageStart {
+		return nil
+	}
+
+	title := event.title()
+	message := fmt.Sprintf("Finished in %v", event.Duration)
+	if event.Error != "" {
+		message = event.Error
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title))
+		cmd = exec.CommandContext(ctx, "osascript", "-e", script)
+	case "windows":
+		// 通过Windows Runtime的ToastNotificationManager发送toast通知
+		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$texts = $template.GetElementsByTagName('text')
+$texts.Item(0).AppendChild($template.CreateTextNode('%s')) > $null
+$texts.Item(1).AppendChild($template.CreateTextNode('%s')) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('deploy').Show([Windows.UI.Notifications.ToastNotification]::new($template))`,
+			powerShellEscape(title), powerShellEscape(message))
+		cmd = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", script)
+	default:
+		urgency := "normal"
+		if event.Stage == stageFailure {
+			urgency = "critical"
+		}
+		cmd = exec.CommandContext(ctx, "notify-send", "--app-name=deploy", "--urgency="+urgency, title, message)
+	}
+
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errorf("desktop: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// bellNotifier 部署结束时响铃，终端标签页在后台时大多会高亮提醒
+type bellNotifier struct{}
+
+func (bellNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage != stageStart {
+		fmt.Fprint(os.Stderr, "\a")
+	}
+	return nil
+}
+
+// finishCommandNotifier 部署结束时执行用户配置的命令，部署结果通过环境变量传入
+type finishCommandNotifier struct {
+	command string
+}
+
+// addFinishNotifiers 按--bell、--on-finish和配置添加部署结束时的本地提醒
+func (n *deployNotifier) addFinishNotifiers(config *Config) {
+	if *ringBell || config.Bell {
+		n.notifiers = append(n.notifiers, bellNotifier{})
+	}
+	command := config.OnFinish
+	if *onFinishCommand != "" {
+		command = *onFinishCommand
+	}
+	if command != "" {
+		n.notifiers = append(n.notifiers, finishCommandNotifier{command: command})
+	}
+}
+
+func (f finishCommandNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage == stageStart {
+		return nil
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", f.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", f.command)
+	}
+	cmd.Env = append(os.Environ(),
+		"DEPLOY_RESULT="+event.Stage,
+		"DEPLOY_PROJECT="+event.Project,
+		"DEPLOY_ENV="+event.Env,
+		"DEPLOY_DURATION="+event.Duration.String(),
+		"DEPLOY_BUILD_URL="+event.BuildURL,
+		"DEPLOY_ERROR="+event.Error,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errorf("on_finish: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// appleScriptString 转义为AppleScript字符串字面量
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// powerShellEscape 转义PowerShell单引号字符串中的内容
+func powerShellEscape(s string) string {
+	return strings.ReplaceAll(s, "'", "''")
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"html"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EmailConfig SMTP邮件通知配置，环境下只配置to时沿用全局的SMTP服务器配置
+type EmailConfig struct {
+	Host     string   `yaml:"host,omitempty"`
+	Port     int      `yaml:"port,omitempty"`     // 默认587（STARTTLS），465为隐式TLS
+	Username string   `yaml:"username,omitempty"` // 为空时不进行认证
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+	OnStart  bool     `yaml:"on_start,omitempty"` // 部署开始时是否发送邮件，默认只发送结果
+}
+
+// failureLogLines 邮件中附带的构建失败日志行数
+const failureLogLines = 50
+
+// emailNotifier 通过SMTP发送HTML邮件
+type emailNotifier struct {
+	config EmailConfig
+}
+
+func (e *emailNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage == stageStart && !e.config.OnStart {
+		return nil
+	}
+	if e.config.Host == "" || e.config.From == "" || len(e.config.To) == 0 {
+		return errorf("email: host, from and to are required")
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.config.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.config.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "["+event.Stage+"] "+event.title()))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n\r\n")
+	msg.WriteString(emailHTMLBody(event))
+
+	port := e.config.Port
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(e.config.Host, strconv.Itoa(port))
+	var auth smtp.Auth
+	if e.config.Username != "" {
+		auth = smtp.PlainAuth("", e.config.Username, e.config.Password, e.config.Host)
+	}
+
+	if port != 465 {
+		// smtp.SendMail在服务器支持时自动使用STARTTLS
+		return smtp.SendMail(addr, auth, e.config.From, e.config.To, msg.Bytes())
+	}
+	return sendMailTLS(ctx, addr, e.config.Host, auth, e.config.From, e.config.To, msg.Bytes())
+}
+
+// sendMailTLS 通过隐式TLS连接（465端口）发送邮件
+func sendMailTLS(ctx context.Context, addr, host string, auth smtp.Auth, from string, to []string, msg []byte) error {
+	dialer := &tls.Dialer{NetDialer: &net.Dialer{Timeout: 10 * time.Second}, Config: &tls.Config{ServerName: host}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(msg); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// emailHTMLBody 邮件正文：部署信息表格，失败时附带构建日志末尾
+func emailHTMLBody(event deployEvent) string {
+	color := map[string]string{stageStart: "#1d72b8", stageSuccess: "#2e7d32", stageFailure: "#c62828"}[event.Stage]
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "<h2 style=\"color:%s\">%s</h2>\n", color, html.EscapeString(event.title()))
+	body.WriteString("<table cellpadding=\"4\" style=\"border-collapse:collapse\">\n")
+	for _, field := range event.fields() {
+		value := html.EscapeString(field[1])
+		if field[0] == "Build" {
+			value = fmt.Sprintf("<a href=\"%s\">%s</a>", value, value)
+		}
+		fmt.Fprintf(&body, "<tr><th align=\"left\">%s</th><td>%s</td></tr>\n", field[0], value)
+	}
+	body.WriteString("</table>\n")
+
+	if event.FailureLog != "" {
+		fmt.Fprintf(&body, "<h3>Last %d lines of the build log</h3>\n", failureLogLines)
+		fmt.Fprintf(&body, "<pre style=\"background:#f5f5f5;padding:8px\">%s</pre>\n", html.EscapeString(event.FailureLog))
+	}
+	return body.String()
+}
+
+// tailLines 返回文本最后n行
+func tailLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
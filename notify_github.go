@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// GitHubConfig 部署结束后在分支对应的打开状态PR上发布（或更新）部署结果评论
+type GitHubConfig struct {
+	Token      string `yaml:"token"`                // 需要pull request读写权限的token
+	APIURL     string `yaml:"api_url,omitempty"`    // GitHub Enterprise的API地址，默认https://api.github.com
+	Repository string `yaml:"repository,omitempty"` // owner/repo，默认从origin远程地址解析
+}
+
+// gitHubRemotePattern 从https或ssh形式的远程地址中解析owner/repo
+var gitHubRemotePattern = regexp.MustCompile(`[:/]([^/:]+)/([^/]+?)(\.git)?/?$`)
+
+// gitHubNotifier 同一PR同一环境只保留一条评论，重复部署时更新
+type gitHubNotifier struct {
+	config GitHubConfig
+}
+
+func (g *gitHubNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage == stageStart || event.Branch == "" || event.Branch == "HEAD" {
+		return nil
+	}
+
+	repository := g.config.Repository
+	if repository == "" {
+		match := gitHubRemotePattern.FindStringSubmatch(gitOutput("remote", "get-url", "origin"))
+		if match == nil {
+			return errorf("github: cannot determine repository from origin remote, set notifications.github.repository")
+		}
+		repository = match[1] + "/" + match[2]
+	}
+	owner := strings.SplitN(repository, "/", 2)[0]
+	apiURL := strings.TrimSuffix(g.config.APIURL, "/")
+	if apiURL == "" {
+		apiURL = "https://api.github.com"
+	}
+	repoURL := apiURL + "/repos/" + repository
+	headers := map[string]string{
+		"Authorization":        "Bearer " + g.config.Token,
+		"Accept":               "application/vnd.github+json",
+		"X-GitHub-Api-Version": "2022-11-28",
+	}
+
+	// 查找分支对应的打开状态PR
+	respBody, err := sendJSON(ctx, http.MethodGet,
+		repoURL+"/pulls?state=open&head="+url.QueryEscape(owner+":"+event.Branch), nil, headers)
+	if err != nil {
+		return errorf("github: failed to list pull requests: %v", err)
+	}
+	var pulls []struct {
+		Number int `json:"number"`
+	}
+	if err := json.Unmarshal(respBody, &pulls); err != nil {
+		return errorf("github: invalid pull requests response: %v", err)
+	}
+	if len(pulls) == 0 {
+		return nil
+	}
+	commentsURL := fmt.Sprintf("%s/issues/%d/comments", repoURL, pulls[0].Number)
+
+	// 通过隐藏标记找到该环境之前的评论
+	marker := fmt.Sprintf("<!-- deploy:%s:%s -->", event.Project, event.Env)
+	body := marker + "\n" + gitHubCommentBody(event)
+	respBody, err = sendJSON(ctx, http.MethodGet, commentsURL+"?per_page=100", nil, headers)
+	if err != nil {
+		return errorf("github: failed to list comments: %v", err)
+	}
+	var comments []struct {
+		ID   int64  `json:"id"`
+		Body string `json:"body"`
+	}
+	if err := json.Unmarshal(respBody, &comments); err != nil {
+		return errorf("github: invalid comments response: %v", err)
+	}
+	for _, comment := range comments {
+		if strings.HasPrefix(comment.Body, marker) {
+			_, err = sendJSON(ctx, http.MethodPatch, fmt.Sprintf("%s/issues/comments/%d", repoURL, comment.ID),
+				map[string]string{"body": body}, headers)
+			if err != nil {
+				return errorf("github: failed to update comment: %v", err)
+			}
+			return nil
+		}
+	}
+	if _, err := postJSON(ctx, commentsURL, map[string]string{"body": body}, headers); err != nil {
+		return errorf("github: failed to create comment: %v", err)
+	}
+	return nil
+}
+
+// gitHubCommentBody PR评论内容：结果、镜像、构建链接、滚动耗时和冒烟检查
+func gitHubCommentBody(event deployEvent) string {
+	icon := ":white_check_mark:"
+	if event.Stage == stageFailure {
+		icon = ":x:"
+	}
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("### %s %s", icon, event.title()), "", "| | |", "|---|---|")
+	for _, field := range event.fields() {
+		lines = append(lines, fmt.Sprintf("| %s | %s |", field[0], strings.ReplaceAll(field[1], "|", `\|`)))
+	}
+	if len(event.Images) > 0 {
+		lines = append(lines, fmt.Sprintf("| Images | `%s` |", formatImages(event.Images)))
+	}
+	for _, phase := range event.Phases {
+		if phase.Name == "rollout" {
+			lines = append(lines, fmt.Sprintf("| Rollout | %v |", roundSeconds(phase.Seconds)))
+		}
+	}
+	for _, check := range event.SmokeChecks {
+		result := ":white_check_mark: passed"
+		if !check.Passed {
+			result = ":x: " + strings.ReplaceAll(check.Error, "|", `\|`)
+		}
+		lines = append(lines, fmt.Sprintf("| Smoke check %s | %s |", check.Name, result))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// GitLabConfig 在GitLab环境中记录部署状态（created、running、success、failed）
+type GitLabConfig struct {
+	URL         string `yaml:"url,omitempty"`         // GitLab地址，默认https://gitlab.com
+	Token       string `yaml:"token"`                 // 需要api权限的访问令牌
+	Project     string `yaml:"project"`               // 项目ID或路径（group/project）
+	Environment string `yaml:"environment,omitempty"` // GitLab环境名称，默认使用部署的环境名
+}
+
+// gitLabNotifier 部署开始时创建GitLab部署记录，结束时更新状态
+type gitLabNotifier struct {
+	config       GitLabConfig
+	deploymentID int
+}
+
+func (g *gitLabNotifier) notify(ctx context.Context, event deployEvent) error {
+	baseURL := strings.TrimSuffix(g.config.URL, "/")
+	if baseURL == "" {
+		baseURL = "https://gitlab.com"
+	}
+	endpoint := fmt.Sprintf("%s/api/v4/projects/%s/deployments", baseURL, url.PathEscape(g.config.Project))
+
+	if event.Stage == stageStart {
+		environment := g.config.Environment
+		if environment == "" {
+			environment = event.Env
+		}
+		if event.Commit == "" {
+			return errorf("gitlab: failed to get commit sha")
+		}
+		respBody, err := g.request(ctx, http.MethodPost, endpoint, map[string]interface{}{
+			"environment": environment,
+			"sha":         event.Commit,
+			"ref":         event.Branch,
+			"tag":         false,
+			"status":      "running",
+		})
+		if err != nil {
+			return err
+		}
+		var deployment struct {
+			ID int `json:"id"`
+		}
+		if err := json.Unmarshal(respBody, &deployment); err != nil {
+			return errorf("gitlab: invalid response: %v", err)
+		}
+		g.deploymentID = deployment.ID
+		return nil
+	}
+
+	// 开始时创建失败则没有可更新的记录
+	if g.deploymentID == 0 {
+		return nil
+	}
+	status := "success"
+	if event.Stage == stageFailure {
+		status = "failed"
+	}
+	_, err := g.request(ctx, http.MethodPut, fmt.Sprintf("%s/%d", endpoint, g.deploymentID), map[string]string{"status": status})
+	return err
+}
+
+// request 调用GitLab API，返回响应内容
+func (g *gitLabNotifier) request(ctx context.Context, method, endpoint string, payload interface{}) ([]byte, error) {
+	respBody, err := sendJSON(ctx, method, endpoint, payload, map[string]string{"PRIVATE-TOKEN": g.config.Token})
+	if err != nil {
+		return nil, errorf("gitlab: %v", err)
+	}
+	return respBody, nil
+}
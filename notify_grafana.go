@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GrafanaConfig 部署时在Grafana中添加注解，仪表盘上显示部署标记
+type GrafanaConfig struct {
+	URL          string   `yaml:"url"`                     // Grafana地址
+	Token        string   `yaml:"token"`                   // service account token，需要annotations:write权限
+	DashboardUID string   `yaml:"dashboard_uid,omitempty"` // 为空时添加组织级注解，所有仪表盘可按tag查询
+	Tags         []string `yaml:"tags,omitempty"`          // 额外的tag，默认已包含deploy、项目、环境和分支
+}
+
+// grafanaNotifier 部署开始时添加注解，结束时更新为时间区间并标记结果
+type grafanaNotifier struct {
+	config       GrafanaConfig
+	annotationID int64
+	startTime    time.Time
+}
+
+func (g *grafanaNotifier) notify(ctx context.Context, event deployEvent) error {
+	baseURL := strings.TrimSuffix(g.config.URL, "/") + "/api/annotations"
+	headers := map[string]string{"Authorization": "Bearer " + g.config.Token}
+
+	tags := append([]string{"deploy", "project:" + event.Project, "env:" + event.Env}, g.config.Tags...)
+	if event.Branch != "" {
+		tags = append(tags, "branch:"+event.Branch)
+	}
+	text := event.title()
+	if event.BuildURL != "" {
+		text += fmt.Sprintf(` (<a href="%s">build</a>)`, event.BuildURL)
+	}
+
+	if event.Stage == stageStart {
+		g.startTime = time.Now()
+		payload := map[string]interface{}{
+			"time": g.startTime.UnixMilli(),
+			"tags": tags,
+			"text": text,
+		}
+		if g.config.DashboardUID != "" {
+			payload["dashboardUID"] = g.config.DashboardUID
+		}
+		respBody, err := postJSON(ctx, baseURL, payload, headers)
+		if err != nil {
+			return errorf("grafana: %v", err)
+		}
+		var result struct {
+			ID int64 `json:"id"`
+		}
+		if err := json.Unmarshal(respBody, &result); err != nil {
+			return errorf("grafana: invalid response: %v", err)
+		}
+		g.annotationID = result.ID
+		return nil
+	}
+
+	if g.annotationID == 0 {
+		return nil
+	}
+	payload := map[string]interface{}{
+		"time":    g.startTime.UnixMilli(),
+		"timeEnd": time.Now().UnixMilli(),
+		"tags":    append(tags, "result:"+event.Stage),
+		"text":    text,
+	}
+	if _, err := sendJSON(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", baseURL, g.annotationID), payload, headers); err != nil {
+		return errorf("grafana: %v", err)
+	}
+	return nil
+}
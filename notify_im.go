@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DingTalkConfig 钉钉群机器人通知配置
+type DingTalkConfig struct {
+	WebhookURL string `yaml:"webhook_url"`      // 机器人webhook地址（包含access_token）
+	Secret     string `yaml:"secret,omitempty"` // 安全设置为加签时的密钥
+}
+
+// WeComConfig 企业微信群机器人通知配置
+type WeComConfig struct {
+	WebhookURL string `yaml:"webhook_url"` // 机器人webhook地址（包含key）
+}
+
+// dingTalkNotifier 发送钉钉markdown消息
+type dingTalkNotifier struct {
+	config DingTalkConfig
+}
+
+func (d *dingTalkNotifier) notify(ctx context.Context, event deployEvent) error {
+	endpoint := d.config.WebhookURL
+	if d.config.Secret != "" {
+		// 加签：timestamp + "\n" + secret 做HmacSHA256后base64
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		mac := hmac.New(sha256.New, []byte(d.config.Secret))
+		mac.Write([]byte(timestamp + "\n" + d.config.Secret))
+		sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+		endpoint += "&timestamp=" + timestamp + "&sign=" + url.QueryEscape(sign)
+	}
+
+	payload := map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": event.title(),
+			"text":  markdownMessageText(event),
+		},
+	}
+	respBody, err := postJSON(ctx, endpoint, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrcodeResponse("dingtalk", respBody)
+}
+
+// weComNotifier 发送企业微信markdown消息
+type weComNotifier struct {
+	config WeComConfig
+}
+
+func (w *weComNotifier) notify(ctx context.Context, event deployEvent) error {
+	payload := map[string]interface{}{
+		"msgtype":  "markdown",
+		"markdown": map[string]string{"content": markdownMessageText(event)},
+	}
+	respBody, err := postJSON(ctx, w.config.WebhookURL, payload, nil)
+	if err != nil {
+		return err
+	}
+	return checkErrcodeResponse("wecom", respBody)
+}
+
+// markdownMessageText 钉钉和企业微信通用的markdown卡片内容
+func markdownMessageText(event deployEvent) string {
+	color := map[string]string{stageStart: "comment", stageSuccess: "info", stageFailure: "warning"}[event.Stage]
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("### <font color=\"%s\">%s</font>", color, event.title()))
+	for _, field := range event.fields() {
+		value := field[1]
+		if field[0] == "Build" {
+			value = fmt.Sprintf("[%s](%s)", value, value)
+		}
+		lines = append(lines, fmt.Sprintf("> **%s:** %s", field[0], value))
+	}
+	return strings.Join(lines, "\n\n")
+}
+
+// checkErrcodeResponse 钉钉和企业微信出错时同样返回200，需要检查errcode字段
+func checkErrcodeResponse(channel string, respBody []byte) error {
+	var result struct {
+		Errcode int    `json:"errcode"`
+		Errmsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return errorf("%s: invalid response: %v", channel, err)
+	}
+	if result.Errcode != 0 {
+		return errorf("%s: errcode %d: %s", channel, result.Errcode, result.Errmsg)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// PagerDutyConfig PagerDuty Events API v2配置
+type PagerDutyConfig struct {
+	RoutingKey string `yaml:"routing_key"`        // 服务集成的routing key
+	Severity   string `yaml:"severity,omitempty"` // critical、error、warning、info，默认critical
+}
+
+// OpsgenieConfig Opsgenie Alert API配置
+type OpsgenieConfig struct {
+	APIKey   string `yaml:"api_key"`            // API集成的key
+	Region   string `yaml:"region,omitempty"`   // us或eu，默认us
+	Priority string `yaml:"priority,omitempty"` // P1-P5，默认P1
+}
+
+// incidentDedupKey 同一项目环境的告警使用相同的去重key，部署成功后自动恢复
+func incidentDedupKey(event deployEvent) string {
+	return fmt.Sprintf("deploy:%s:%s", event.Project, event.Env)
+}
+
+// pagerDutyNotifier critical环境部署失败时触发告警，之后部署成功时恢复
+type pagerDutyNotifier struct {
+	config PagerDutyConfig
+}
+
+func (p *pagerDutyNotifier) notify(ctx context.Context, event deployEvent) error {
+	payload := map[string]interface{}{
+		"routing_key": p.config.RoutingKey,
+		"dedup_key":   incidentDedupKey(event),
+	}
+	switch event.Stage {
+	case stageFailure:
+		severity := p.config.Severity
+		if severity == "" {
+			severity = "critical"
+		}
+		details := make(map[string]string)
+		for _, field := range event.fields() {
+			details[field[0]] = field[1]
+		}
+		payload["event_action"] = "trigger"
+		payload["payload"] = map[string]interface{}{
+			"summary":        event.title() + ": " + event.Error,
+			"source":         event.Project,
+			"severity":       severity,
+			"component":      event.Env,
+			"custom_details": details,
+		}
+		if event.BuildURL != "" {
+			payload["links"] = []map[string]string{{"href": event.BuildURL, "text": "Jenkins build"}}
+		}
+	case stageSuccess:
+		payload["event_action"] = "resolve"
+	default:
+		return nil
+	}
+
+	_, err := postJSON(ctx, "https://events.pagerduty.com/v2/enqueue", payload, nil)
+	if err != nil {
+		return errorf("pagerduty: %v", err)
+	}
+	return nil
+}
+
+// opsgenieNotifier critical环境部署失败时创建告警，之后部署成功时关闭
+type opsgenieNotifier struct {
+	config OpsgenieConfig
+}
+
+func (o *opsgenieNotifier) notify(ctx context.Context, event deployEvent) error {
+	baseURL := "https://api.opsgenie.com/v2/alerts"
+	if o.config.Region == "eu" {
+		baseURL = "https://api.eu.opsgenie.com/v2/alerts"
+	}
+	headers := map[string]string{"Authorization": "GenieKey " + o.config.APIKey}
+	alias := incidentDedupKey(event)
+
+	var err error
+	switch event.Stage {
+	case stageFailure:
+		priority := o.config.Priority
+		if priority == "" {
+			priority = "P1"
+		}
+		details := make(map[string]string)
+		for _, field := range event.fields() {
+			details[field[0]] = field[1]
+		}
+		message := event.title()
+		if len(message) > 130 {
+			message = message[:130]
+		}
+		_, err = postJSON(ctx, baseURL, map[string]interface{}{
+			"message":     message,
+			"alias":       alias,
+			"description": event.Error,
+			"priority":    priority,
+			"source":      "deploy",
+			"tags":        []string{"deploy", event.Project, event.Env},
+			"details":     details,
+		}, headers)
+	case stageSuccess:
+		_, err = postJSON(ctx, baseURL+"/"+url.PathEscape(alias)+"/close?identifierType=alias",
+			map[string]string{"source": "deploy", "note": "Deploy succeeded"}, headers)
+	default:
+		return nil
+	}
+	if err != nil {
+		return errorf("opsgenie: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// JiraConfig 部署成功后在分支名和提交信息中提到的Jira issue上添加评论并可选地流转状态
+type JiraConfig struct {
+	URL        string   `yaml:"url"`                  // Jira地址
+	User       string   `yaml:"user,omitempty"`       // Jira Cloud的账号邮箱，为空时使用token作为Bearer PAT（Jira Server/DC）
+	Token      string   `yaml:"token"`                // Jira Cloud的API token或Server/DC的PAT
+	Projects   []string `yaml:"projects,omitempty"`   // 只处理这些项目key的issue，为空时处理所有识别到的issue
+	Comment    *bool    `yaml:"comment,omitempty"`    // 是否添加评论，默认true
+	Transition string   `yaml:"transition,omitempty"` // 要执行的流转名称或目标状态，如"Deployed to staging"
+}
+
+// jiraIssueKeyPattern Jira issue key，如ABC-123
+var jiraIssueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[1-9][0-9]*\b`)
+
+// jiraMaxCommits 扫描issue key时最多读取的提交数
+const jiraMaxCommits = 50
+
+// jiraNotifier 部署成功后处理本次部署涉及的Jira issue
+type jiraNotifier struct {
+	config JiraConfig
+}
+
+func (j *jiraNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage != stageSuccess {
+		return nil
+	}
+
+	keys := j.issueKeys(event.Branch)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	var failed []string
+	for _, key := range keys {
+		if err := j.updateIssue(ctx, key, event); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", key, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errorf("jira: %s", strings.Join(failed, "; "))
+	}
+	slog.Info(msg("Updated Jira issues: %s", strings.Join(keys, ", ")))
+	return nil
+}
+
+// issueKeys 从分支名和分支上的提交信息中提取去重后的issue key
+func (j *jiraNotifier) issueKeys(branch string) []string {
+	text := branch
+	// 只读取当前分支相对默认分支新增的提交，无法确定默认分支时读取最近的提交
+	commitRange := "HEAD"
+	if base := gitOutput("merge-base", "HEAD", "origin/HEAD"); base != "" {
+		commitRange = base + "..HEAD"
+	}
+	text += "\n" + gitOutput("log", fmt.Sprintf("-%d", jiraMaxCommits), "--format=%s%n%b", commitRange)
+
+	allowed := make(map[string]bool)
+	for _, project := range j.config.Projects {
+		allowed[project] = true
+	}
+
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range jiraIssueKeyPattern.FindAllString(text, -1) {
+		project := key[:strings.LastIndex(key, "-")]
+		if seen[key] || (len(allowed) > 0 && !allowed[project]) {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// updateIssue 添加部署评论并执行配置的流转
+func (j *jiraNotifier) updateIssue(ctx context.Context, key string, event deployEvent) error {
+	issueURL := strings.TrimSuffix(j.config.URL, "/") + "/rest/api/2/issue/" + url.PathEscape(key)
+
+	if j.config.Comment == nil || *j.config.Comment {
+		var lines []string
+		lines = append(lines, fmt.Sprintf("Deployed %s to *%s*", event.Project, event.Env))
+		for _, field := range event.fields() {
+			lines = append(lines, fmt.Sprintf("* %s: %s", field[0], field[1]))
+		}
+		if _, err := sendJSON(ctx, http.MethodPost, issueURL+"/comment", map[string]string{"body": strings.Join(lines, "\n")}, j.headers()); err != nil {
+			return errorf("failed to comment: %v", err)
+		}
+	}
+
+	if j.config.Transition == "" {
+		return nil
+	}
+	respBody, err := sendJSON(ctx, http.MethodGet, issueURL+"/transitions", nil, j.headers())
+	if err != nil {
+		return errorf("failed to get transitions: %v", err)
+	}
+	var result struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return errorf("invalid transitions response: %v", err)
+	}
+	for _, transition := range result.Transitions {
+		if strings.EqualFold(transition.Name, j.config.Transition) || strings.EqualFold(transition.To.Name, j.config.Transition) {
+			payload := map[string]interface{}{"transition": map[string]string{"id": transition.ID}}
+			if _, err := sendJSON(ctx, http.MethodPost, issueURL+"/transitions", payload, j.headers()); err != nil {
+				return errorf("failed to transition to %q: %v", j.config.Transition, err)
+			}
+			return nil
+		}
+	}
+	// issue已经处于目标状态或工作流中没有该流转时跳过
+	slog.Info(msg("Jira issue %s has no transition %q available, skipped", key, j.config.Transition))
+	return nil
+}
+
+// headers Jira Cloud使用Basic认证，Server/DC使用Bearer PAT
+func (j *jiraNotifier) headers() map[string]string {
+	if j.config.User == "" {
+		return map[string]string{"Authorization": "Bearer " + j.config.Token}
+	}
+	credentials := base64.StdEncoding.EncodeToString([]byte(j.config.User + ":" + j.config.Token))
+	return map[string]string{"Authorization": "Basic " + credentials}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SentryConfig 部署成功后创建Sentry release并记录部署，用于release health和回归归因
+type SentryConfig struct {
+	URL         string   `yaml:"url,omitempty"`         // Sentry地址，默认https://sentry.io
+	Token       string   `yaml:"token"`                 // 需要project:releases权限的auth token
+	Org         string   `yaml:"org"`                   // 组织slug
+	Projects    []string `yaml:"projects"`              // 项目slug
+	Repository  string   `yaml:"repository,omitempty"`  // Sentry中关联的仓库名（如org/repo），配置后关联提交范围
+	Environment string   `yaml:"environment,omitempty"` // Sentry环境名称，默认使用部署的环境名
+}
+
+// sentryNotifier 部署成功后以当前提交sha为版本创建release，并标记部署到环境
+type sentryNotifier struct {
+	config SentryConfig
+}
+
+func (s *sentryNotifier) notify(ctx context.Context, event deployEvent) error {
+	if event.Stage != stageSuccess {
+		return nil
+	}
+
+	version := event.Commit
+	if version == "" {
+		return errorf("sentry: failed to get commit sha")
+	}
+
+	baseURL := strings.TrimSuffix(s.config.URL, "/")
+	if baseURL == "" {
+		baseURL = "https://sentry.io"
+	}
+	baseURL += fmt.Sprintf("/api/0/organizations/%s/releases/", url.PathEscape(s.config.Org))
+	headers := map[string]string{"Authorization": "Bearer " + s.config.Token}
+
+	// 已存在的release会被更新，重复部署同一提交不会报错
+	release := map[string]interface{}{
+		"version":  version,
+		"projects": s.config.Projects,
+	}
+	if s.config.Repository != "" {
+		// 不指定previousCommit时Sentry使用上一个release的提交作为范围起点
+		release["refs"] = []map[string]string{{"repository": s.config.Repository, "commit": version}}
+	}
+	if _, err := postJSON(ctx, baseURL, release, headers); err != nil {
+		return errorf("sentry: failed to create release %s: %v", version, err)
+	}
+
+	environment := s.config.Environment
+	if environment == "" {
+		environment = event.Env
+	}
+	finished := time.Now()
+	deploy := map[string]interface{}{
+		"environment":  environment,
+		"name":         fmt.Sprintf("%s %s", event.Project, event.Env),
+		"dateStarted":  finished.Add(-event.Duration).UTC().Format(time.RFC3339),
+		"dateFinished": finished.UTC().Format(time.RFC3339),
+	}
+	if event.BuildURL != "" {
+		deploy["url"] = event.BuildURL
+	}
+	if _, err := postJSON(ctx, baseURL+url.PathEscape(version)+"/deploys/", deploy, headers); err != nil {
+		return errorf("sentry: failed to create deploy for release %s: %v", version, err)
+	}
+	return nil
+}
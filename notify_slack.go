@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SlackConfig Slack通知配置，使用incoming webhook或bot token + channel
+type SlackConfig struct {
+	WebhookURL string `yaml:"webhook_url,omitempty"` // incoming webhook地址
+	Token      string `yaml:"token,omitempty"`       // bot token（xoxb-...），需要chat:write权限
+	Channel    string `yaml:"channel,omitempty"`     // 使用bot token时发送的频道
+	Thread     bool   `yaml:"thread,omitempty"`      // 成功/失败消息作为开始消息的回复，仅bot token方式支持
+}
+
+// slackNotifier 发送Slack消息，记录开始消息的ts用于回复
+type slackNotifier struct {
+	config   SlackConfig
+	threadTS string
+}
+
+func (s *slackNotifier) notify(ctx context.Context, event deployEvent) error {
+	text := slackMessageText(event)
+
+	if s.config.Token == "" {
+		if s.config.WebhookURL == "" {
+			return errorf("slack: webhook_url or token + channel is required")
+		}
+		_, err := postJSON(ctx, s.config.WebhookURL, map[string]string{"text": text}, nil)
+		return err
+	}
+
+	payload := map[string]string{"channel": s.config.Channel, "text": text}
+	if s.config.Thread && s.threadTS != "" {
+		payload["thread_ts"] = s.threadTS
+	}
+	ts, err := callSlackAPI(ctx, s.config.Token, "chat.postMessage", payload)
+	if err != nil {
+		return err
+	}
+	if event.Stage == stageStart {
+		s.threadTS = ts
+	}
+	return nil
+}
+
+// callSlackAPI 调用Slack Web API，返回消息的ts
+func callSlackAPI(ctx context.Context, token, method string, payload interface{}) (string, error) {
+	respBody, err := postJSON(ctx, "https://slack.com/api/"+method, payload,
+		map[string]string{"Authorization": "Bearer " + token})
+	if err != nil {
+		return "", err
+	}
+
+	// Web API出错时同样返回200，需要检查ok字段
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", errorf("slack: invalid response: %v", err)
+	}
+	if !result.OK {
+		return "", errorf("slack: %s", result.Error)
+	}
+	return result.TS, nil
+}
+
+// slackMessageText 按Slack mrkdwn格式组织消息内容
+func slackMessageText(event deployEvent) string {
+	icon := map[string]string{stageStart: ":rocket:", stageSuccess: ":white_check_mark:", stageFailure: ":x:"}[event.Stage]
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("%s *%s*", icon, event.title()))
+	for _, field := range event.fields() {
+		lines = append(lines, fmt.Sprintf("*%s:* %s", field[0], field[1]))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+)
+
+// TeamsConfig Microsoft Teams incoming webhook（或Workflows webhook）通知配置
+type TeamsConfig struct {
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+// teamsNotifier 发送Teams adaptive card消息
+type teamsNotifier struct {
+	config TeamsConfig
+}
+
+func (t *teamsNotifier) notify(ctx context.Context, event deployEvent) error {
+	_, err := postJSON(ctx, t.config.WebhookURL, teamsAdaptiveCard(event), nil)
+	return err
+}
+
+// teamsAdaptiveCard 组织adaptive card消息：标题、事实列表和构建链接按钮
+func teamsAdaptiveCard(event deployEvent) map[string]interface{} {
+	color := map[string]string{stageStart: "Accent", stageSuccess: "Good", stageFailure: "Attention"}[event.Stage]
+
+	var facts []map[string]string
+	for _, field := range event.fields() {
+		facts = append(facts, map[string]string{"title": field[0], "value": field[1]})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body": []map[string]interface{}{
+			{"type": "TextBlock", "text": event.title(), "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+			{"type": "FactSet", "facts": facts},
+		},
+	}
+	if event.BuildURL != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "Open build", "url": event.BuildURL}}
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{
+			{"contentType": "application/vnd.microsoft.card.adaptive", "content": card},
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// TelegramConfig Telegram机器人通知配置
+type TelegramConfig struct {
+	BotToken string `yaml:"bot_token"` // 通过@BotFather创建机器人获得的token
+	ChatID   string `yaml:"chat_id"`   // 个人或群组的chat id
+}
+
+// telegramNotifier 通过Bot API发送消息
+type telegramNotifier struct {
+	config TelegramConfig
+}
+
+func (t *telegramNotifier) notify(ctx context.Context, event deployEvent) error {
+	icon := map[string]string{stageStart: "🚀", stageSuccess: "✅", stageFailure: "❌"}[event.Stage]
+
+	var lines []string
+	lines = append(lines, fmt.Sprintf("%s <b>%s</b>", icon, html.EscapeString(event.title())))
+	for _, field := range event.fields() {
+		lines = append(lines, fmt.Sprintf("<b>%s:</b> %s", field[0], html.EscapeString(field[1])))
+	}
+
+	payload := map[string]interface{}{
+		"chat_id":                  t.config.ChatID,
+		"text":                     strings.Join(lines, "\n"),
+		"parse_mode":               "HTML",
+		"disable_web_page_preview": true,
+	}
+	respBody, err := postJSON(ctx, "https://api.telegram.org/bot"+t.config.BotToken+"/sendMessage", payload, nil)
+	if err != nil {
+		// 网络错误中包含带token的地址，只保留底层错误
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return errorf("telegram: %v", err)
+	}
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return errorf("telegram: invalid response: %v", err)
+	}
+	if !result.OK {
+		return errorf("telegram: %s", result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// OIDCConfig 服务模式下通过OIDC/SSO认证API请求，请求携带身份提供方签发的ID token（Authorization: Bearer <token>）
+type OIDCConfig struct {
+	Issuer    string `yaml:"issuer"`               // 身份提供方地址，从<issuer>/.well-known/openid-configuration读取签名密钥
+	ClientID  string `yaml:"client_id"`            // token的aud需要包含该值
+	UserClaim string `yaml:"user_claim,omitempty"` // 作为部署人身份的claim，默认email，使用email时要求email_verified为true
+}
+
+// oidcDiscoveryRetryInterval 读取discovery文档失败后重试的最小间隔，身份提供方不可用时不会每个请求都重新获取
+const oidcDiscoveryRetryInterval = time.Minute
+
+// oidcVerifier 通过go-oidc校验ID token的签名、签发者、受众和有效期，签名密钥按kid自动刷新
+type oidcVerifier struct {
+	config OIDCConfig
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+	failedAt time.Time
+}
+
+func newOIDCVerifier(config OIDCConfig) *oidcVerifier {
+	return &oidcVerifier{config: config}
+}
+
+// identityContextKey 请求context中保存认证身份的key
+type identityContextKey struct{}
+
+// requestIdentity 请求的认证身份，只有静态token时为空
+func requestIdentity(r *http.Request) string {
+	identity, _ := r.Context().Value(identityContextKey{}).(string)
+	return identity
+}
+
+// verify 校验token，返回身份claim的值
+func (v *oidcVerifier) verify(ctx context.Context, token string) (string, error) {
+	verifier, err := v.idTokenVerifier(ctx)
+	if err != nil {
+		return "", err
+	}
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return "", errorf("invalid token: %v", err)
+	}
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return "", errorf("invalid token claims: %v", err)
+	}
+
+	claim := v.config.UserClaim
+	if claim == "" {
+		claim = "email"
+	}
+	identity, _ := claims[claim].(string)
+	if identity == "" {
+		return "", errorf("token has no %s claim", claim)
+	}
+	// 很多身份提供方允许用户自行填写未验证的邮箱，不能用它冒充allowed_users中的人
+	if claim == "email" && !emailVerified(claims["email_verified"]) {
+		return "", errorf("token email %s is not verified", identity)
+	}
+	return identity, nil
+}
+
+// idTokenVerifier 第一次使用时读取身份提供方的discovery文档，失败时在oidcDiscoveryRetryInterval后重试
+func (v *oidcVerifier) idTokenVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.verifier != nil {
+		return v.verifier, nil
+	}
+	if time.Since(v.failedAt) < oidcDiscoveryRetryInterval {
+		return nil, errorf("identity provider %s is unavailable", v.config.Issuer)
+	}
+	// 之后刷新签名密钥的请求使用同样的client，不受本次请求的context影响
+	ctx = oidc.ClientContext(ctx, &http.Client{Timeout: httpTimeout})
+	provider, err := oidc.NewProvider(ctx, v.config.Issuer)
+	if err != nil {
+		v.failedAt = time.Now()
+		return nil, errorf("failed to get signing keys from %s: %v", v.config.Issuer, err)
+	}
+	v.verifier = provider.Verifier(&oidc.Config{ClientID: v.config.ClientID})
+	return v.verifier, nil
+}
+
+// emailVerified email_verified为true，部分身份提供方（如Cognito）以字符串返回
+func emailVerified(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
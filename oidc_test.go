@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// testIdentityProvider 提供discovery文档和JWKS的身份提供方，签发RS256的ID token
+type testIdentityProvider struct {
+	*httptest.Server
+	key *rsa.PrivateKey
+}
+
+func newTestIdentityProvider(t *testing.T) *testIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &testIdentityProvider{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"issuer":                                idp.URL,
+			"jwks_uri":                              idp.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA", "kid": "test", "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	idp.Server = httptest.NewServer(mux)
+	t.Cleanup(idp.Close)
+	return idp
+}
+
+// sign 签发带有默认iss、aud和有效期的token，claims覆盖默认值
+func (idp *testIdentityProvider) sign(t *testing.T, claims map[string]interface{}) string {
+	payload := map[string]interface{}{
+		"iss": idp.URL,
+		"aud": "deploy",
+		"sub": "user-1",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for name, value := range claims {
+		payload[name] = value
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	body, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerify(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	verifier := newOIDCVerifier(OIDCConfig{Issuer: idp.URL, ClientID: "deploy"})
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		claims map[string]interface{}
+		want   string
+	}{
+		{"verified email", map[string]interface{}{"email": "alice@example.com", "email_verified": true}, "alice@example.com"},
+		{"verified email as string", map[string]interface{}{"email": "alice@example.com", "email_verified": "true"}, "alice@example.com"},
+		{"unverified email", map[string]interface{}{"email": "alice@example.com", "email_verified": false}, ""},
+		{"email_verified missing", map[string]interface{}{"email": "alice@example.com"}, ""},
+		{"no email", map[string]interface{}{}, ""},
+		{"wrong audience", map[string]interface{}{"email": "alice@example.com", "email_verified": true, "aud": "other"}, ""},
+		{"expired", map[string]interface{}{"email": "alice@example.com", "email_verified": true, "exp": time.Now().Add(-time.Hour).Unix()}, ""},
+		{"wrong issuer", map[string]interface{}{"email": "alice@example.com", "email_verified": true, "iss": "https://evil.example.com"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.verify(ctx, idp.sign(t, tt.claims))
+			if got != tt.want || (err == nil) != (tt.want != "") {
+				t.Errorf("verify = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := verifier.verify(ctx, idp.sign(t, map[string]interface{}{"email": "alice@example.com", "email_verified": true})+"x"); err == nil {
+		t.Error("token with a tampered signature accepted")
+	}
+}
+
+func TestOIDCVerifySubClaim(t *testing.T) {
+	idp := newTestIdentityProvider(t)
+	verifier := newOIDCVerifier(OIDCConfig{Issuer: idp.URL, ClientID: "deploy", UserClaim: "sub"})
+	// sub不需要email_verified
+	got, err := verifier.verify(context.Background(), idp.sign(t, map[string]interface{}{"email": "mallory@example.com"}))
+	if err != nil || got != "user-1" {
+		t.Errorf("verify = %q, %v, want user-1", got, err)
+	}
+}
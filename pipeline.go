@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PipelineConfig 项目的环境晋级流水线，按顺序定义各阶段，deploy promote把上一阶段的构建产物晋级到下一阶段
+type PipelineConfig struct {
+	Project string          `yaml:"project"`
+	Stages  []PipelineStage `yaml:"stages"`
+}
+
+// PipelineStage 流水线中的一个阶段，gate在晋级到该阶段前检查
+type PipelineStage struct {
+	Env  string        `yaml:"env"`
+	Gate *PipelineGate `yaml:"gate,omitempty"`
+}
+
+// PipelineGate 晋级前的检查，全部通过后才部署
+type PipelineGate struct {
+	Confirm     bool         `yaml:"confirm,omitempty"`      // 需要在终端中手动确认
+	Wait        string       `yaml:"wait,omitempty"`         // 上一阶段部署成功后至少经过该时长，如30m，不足时等待
+	SmokeChecks []SmokeCheck `yaml:"smoke_checks,omitempty"` // 晋级前对上一阶段执行的冒烟检查
+}
+
+// 晋级部署时可以在参数中使用的占位符，值来自上一阶段最近一次成功的部署记录
+const (
+	placeholderPromotedCommit = "$promoted_commit"
+	placeholderPromotedBuild  = "$promoted_build"
+	placeholderPromotedImage  = "$promoted_image"
+)
+
+// findPipeline 查找项目的流水线
+func (c *Config) findPipeline(project string) *PipelineConfig {
+	for i := range c.Pipelines {
+		if c.Pipelines[i].Project == project {
+			return &c.Pipelines[i]
+		}
+	}
+	return nil
+}
+
+// latestSuccessfulDeploy 项目环境最近一次成功的部署记录，配置了共享台账时从台账查询，否则查询本地历史
+func latestSuccessfulDeploy(ctx context.Context, config *Config, project, env string) (*deployRecord, error) {
+	var backend ledgerBackend = localHistory{}
+	if backends := config.Ledger.backends(); len(backends) > 0 {
+		backend = backends[0]
+	}
+	records, err := backend.list(ctx, historyQuery{Project: project, Env: env, Limit: 50})
+	if err != nil {
+		return nil, errorf("failed to read deploy history: %v", err)
+	}
+	for _, record := range records {
+		if record.Result == stageSuccess {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+// promotedPlaceholders 根据上一阶段的部署记录生成晋级占位符，多个容器时$promoted_image为按容器名排序的第一个镜像
+func promotedPlaceholders(record *deployRecord) map[string]string {
+	placeholders := map[string]string{placeholderPromotedCommit: record.Commit}
+	if record.BuildNumber > 0 {
+		placeholders[placeholderPromotedBuild] = strconv.FormatInt(record.BuildNumber, 10)
+	}
+	var containers []string
+	for container := range record.Images {
+		containers = append(containers, container)
+	}
+	sort.Strings(containers)
+	if len(containers) > 0 {
+		placeholders[placeholderPromotedImage] = record.Images[containers[0]]
+	}
+	return placeholders
+}
+
+// runPromoteCommand deploy promote子命令：检查gate后把上一阶段的构建产物晋级到流水线的下一阶段
+func runPromoteCommand(args []string) error {
+	flags := flag.NewFlagSet("promote", flag.ExitOnError)
+	to := flags.String("to", "", "stage env to promote to, default the first stage that is behind its previous stage")
+	yes := flags.Bool("yes", false, "skip manual confirmation gates")
+	var project string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		project, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+
+	// 部署按当前目录识别项目，所以需要在项目目录中执行
+	currentProject, err := currentProjectName()
+	if err != nil {
+		return err
+	}
+	if project == "" {
+		project = currentProject
+	} else if project != currentProject {
+		return errorf("run deploy promote from the %s project directory, current directory is %s", project, currentProject)
+	}
+
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	pipeline := config.findPipeline(project)
+	if pipeline == nil {
+		return errorf("no pipeline configured for project %s", project)
+	}
+	if len(pipeline.Stages) < 2 {
+		return errorf("pipeline of project %s needs at least two stages", project)
+	}
+
+	ctx := context.Background()
+	index, previous, err := nextPromotion(ctx, config, pipeline, *to)
+	if err != nil {
+		return err
+	}
+	if index < 0 {
+		fmt.Print(msg("All stages of %s are up to date with %s\n", project, pipeline.Stages[0].Env))
+		return nil
+	}
+	from, stage := pipeline.Stages[index-1].Env, pipeline.Stages[index]
+	slog.Info(msg("Promoting %s from %s to %s: commit %s, build #%d, finished %s",
+		project, from, stage.Env, shortCommit(previous.Commit), previous.BuildNumber, formatTime(previous.FinishedAt)))
+
+	if err := checkGate(ctx, stage, from, previous, *yes); err != nil {
+		return errorf("gate for %s not passed: %v", stage.Env, err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errorf("failed to locate deploy executable: %v", err)
+	}
+	cmd := exec.Command(executable, stage.Env, "--promote-from", from)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errorf("deploy to %s failed: %v", stage.Env, err)
+	}
+	return nil
+}
+
+// nextPromotion 返回要晋级的阶段序号和上一阶段最近一次成功的部署，所有阶段都已同步时返回-1
+func nextPromotion(ctx context.Context, config *Config, pipeline *PipelineConfig, to string) (int, *deployRecord, error) {
+	for i := 1; i < len(pipeline.Stages); i++ {
+		stage := pipeline.Stages[i]
+		if to != "" && stage.Env != to {
+			continue
+		}
+		from := pipeline.Stages[i-1].Env
+		previous, err := latestSuccessfulDeploy(ctx, config, pipeline.Project, from)
+		if err != nil {
+			return 0, nil, err
+		}
+		if previous == nil {
+			return 0, nil, errorf("%s has no successful deploy to promote, deploy it first", from)
+		}
+		if to != "" {
+			return i, previous, nil
+		}
+		current, err := latestSuccessfulDeploy(ctx, config, pipeline.Project, stage.Env)
+		if err != nil {
+			return 0, nil, err
+		}
+		if current == nil || current.Commit != previous.Commit {
+			return i, previous, nil
+		}
+	}
+	if to != "" {
+		return 0, nil, errorf("env %s is not a promotion stage of the pipeline", to)
+	}
+	return -1, nil, nil
+}
+
+// checkGate 依次检查等待时长、冒烟检查和手动确认
+func checkGate(ctx context.Context, stage PipelineStage, from string, previous *deployRecord, skipConfirm bool) error {
+	gate := stage.Gate
+	if gate == nil {
+		return nil
+	}
+
+	if gate.Wait != "" {
+		wait, err := time.ParseDuration(gate.Wait)
+		if err != nil {
+			return errorf("invalid wait %q: %v", gate.Wait, err)
+		}
+		if remaining := wait - time.Since(previous.FinishedAt); remaining > 0 {
+			slog.Info(msg("Gate: %s must run for %v before promotion, waiting %v...", from, wait, remaining.Round(time.Second)))
+			select {
+			case <-time.After(remaining):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		slog.Info(msg("Gate: %s has been running for %v", from, time.Since(previous.FinishedAt).Round(time.Second)))
+	}
+
+	if len(gate.SmokeChecks) > 0 {
+		slog.Info(msg("Gate: running smoke checks against %s...", from))
+		if err := runSmokeChecks(ctx, gate.SmokeChecks, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	if gate.Confirm && !skipConfirm {
+		if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return errorf("manual confirmation requires an interactive terminal, use --yes to skip")
+		}
+		fmt.Print(msg("Promote commit %s from %s to %s? [y/N] ", shortCommit(previous.Commit), from, stage.Env))
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			return errorf("promotion not confirmed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 插件的扩展点，插件以"<插件> <hook>"方式调用，请求和响应都是通过stdin/stdout传递的JSON
+const (
+	hookDescribe    = "describe"     // 返回插件名称和支持的hook
+	hookPreDeploy   = "pre-deploy"   // 触发构建前，可以修改构建参数或中止部署
+	hookPostBuild   = "post-build"   // Jenkins构建成功后，可以中止部署
+	hookPostRollout = "post-rollout" // 滚动和冒烟检查完成后，可以使部署失败
+	hookNotifier    = "notifier"     // 部署开始、成功、失败时的通知
+	hookCredentials = "credentials"  // 提供Jenkins凭证
+)
+
+// pluginTimeout 单次插件调用的超时时间
+const pluginTimeout = 2 * time.Minute
+
+// pluginRequest 发送给插件的请求
+type pluginRequest struct {
+	Hook        string                 `json:"hook"`
+	Project     string                 `json:"project,omitempty"`
+	Env         string                 `json:"env,omitempty"`
+	Params      map[string]string      `json:"params,omitempty"`
+	BuildNumber int64                  `json:"build_number,omitempty"`
+	BuildURL    string                 `json:"build_url,omitempty"`
+	JenkinsURL  string                 `json:"jenkins_url,omitempty"`
+	Summary     *deploySummary         `json:"summary,omitempty"`
+	Event       map[string]interface{} `json:"event,omitempty"`
+}
+
+// pluginResponse 插件的响应，error不为空时表示中止部署
+type pluginResponse struct {
+	Name     string            `json:"name,omitempty"`
+	Hooks    []string          `json:"hooks,omitempty"`
+	Error    string            `json:"error,omitempty"`
+	Params   map[string]string `json:"params,omitempty"`    // pre-deploy：覆盖或新增的构建参数
+	Username string            `json:"username,omitempty"`  // credentials：Jenkins用户名
+	APIToken string            `json:"api_token,omitempty"` // credentials：Jenkins API token
+}
+
+// plugin ~/.deploy/plugins下的一个可执行文件
+type plugin struct {
+	Name  string
+	Path  string
+	Hooks []string
+}
+
+// pluginSet 已发现的插件
+type pluginSet []plugin
+
+// discoverPlugins 查找~/.deploy/plugins下的可执行文件并询问支持的hook，失败的插件打印警告后跳过
+func discoverPlugins(ctx context.Context) pluginSet {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	dir := filepath.Join(homeDir, ".deploy", "plugins")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var plugins pluginSet
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			continue
+		}
+		p := plugin{Name: entry.Name(), Path: filepath.Join(dir, entry.Name())}
+		response, err := p.call(ctx, pluginRequest{Hook: hookDescribe})
+		if err != nil {
+			slog.Warn(msg("Skipping plugin %s: %v", p.Name, err))
+			continue
+		}
+		if response.Name != "" {
+			p.Name = response.Name
+		}
+		p.Hooks = response.Hooks
+		slog.Debug(fmt.Sprintf("Loaded plugin %s (%s)", p.Name, strings.Join(p.Hooks, ", ")))
+		plugins = append(plugins, p)
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Path < plugins[j].Path })
+	return plugins
+}
+
+// call 执行一次插件调用，插件的stderr直接输出到终端
+func (p plugin) call(ctx context.Context, request pluginRequest) (*pluginResponse, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, pluginTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.Path, request.Hook)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = os.Stderr
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, errorf("%s %s: %v", p.Name, request.Hook, err)
+	}
+	var response pluginResponse
+	if strings.TrimSpace(stdout.String()) != "" {
+		if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+			return nil, errorf("%s %s: invalid response: %v", p.Name, request.Hook, err)
+		}
+	}
+	if response.Error != "" {
+		return &response, errorf("%s: %s", p.Name, response.Error)
+	}
+	return &response, nil
+}
+
+// supports 插件是否实现了该hook
+func (p plugin) supports(hook string) bool {
+	return containsString(p.Hooks, hook)
+}
+
+// run 依次调用实现了该hook的插件，任一插件返回错误时停止
+func (ps pluginSet) run(ctx context.Context, request pluginRequest, handle func(p plugin, response *pluginResponse)) error {
+	for _, p := range ps {
+		if !p.supports(request.Hook) {
+			continue
+		}
+		slog.Debug(fmt.Sprintf("Running %s hook of plugin %s", request.Hook, p.Name))
+		response, err := p.call(ctx, request)
+		if err != nil {
+			return err
+		}
+		if handle != nil {
+			handle(p, response)
+		}
+	}
+	return nil
+}
+
+// preDeploy 调用pre-deploy hook，插件返回的参数合并到构建参数中
+func (ps pluginSet) preDeploy(ctx context.Context, project, env string, params map[string]string) error {
+	return ps.run(ctx, pluginRequest{Hook: hookPreDeploy, Project: project, Env: env, Params: params},
+		func(p plugin, response *pluginResponse) {
+			for name, value := range response.Params {
+				slog.Info(msg("Plugin %s set build parameter %s", p.Name, name))
+				params[name] = value
+			}
+		})
+}
+
+// credentials 调用credentials hook，返回第一个插件提供的Jenkins凭证
+func (ps pluginSet) credentials(ctx context.Context, project, env, jenkinsURL string) (string, string, error) {
+	var username, token string
+	err := ps.run(ctx, pluginRequest{Hook: hookCredentials, Project: project, Env: env, JenkinsURL: jenkinsURL},
+		func(p plugin, response *pluginResponse) {
+			if token == "" && response.APIToken != "" {
+				username, token = response.Username, response.APIToken
+				slog.Debug(fmt.Sprintf("Using Jenkins credentials from plugin %s", p.Name))
+			}
+		})
+	return username, token, err
+}
+
+// notifiers 实现了notifier hook的插件作为通知渠道
+func (ps pluginSet) notifiers() []notifier {
+	var notifiers []notifier
+	for _, p := range ps {
+		if p.supports(hookNotifier) {
+			notifiers = append(notifiers, pluginNotifier{plugin: p})
+		}
+	}
+	return notifiers
+}
+
+// pluginNotifier 通过插件发送通知
+type pluginNotifier struct {
+	plugin plugin
+}
+
+func (n pluginNotifier) notify(ctx context.Context, event deployEvent) error {
+	_, err := n.plugin.call(ctx, pluginRequest{
+		Hook:    hookNotifier,
+		Project: event.Project,
+		Env:     event.Env,
+		Event: map[string]interface{}{
+			"stage":            event.Stage,
+			"project":          event.Project,
+			"env":              event.Env,
+			"branch":           event.Branch,
+			"commit":           event.Commit,
+			"deployer":         event.Deployer,
+			"build_url":        event.BuildURL,
+			"duration_seconds": event.Duration.Seconds(),
+			"error":            event.Error,
+			"images":           event.Images,
+			"changelog":        event.Changelog,
+		},
+	})
+	return err
+}
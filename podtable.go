@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// noPodTable 不使用pod状态表格，按行输出每个未就绪pod的状态
+var noPodTable = flag.Bool("no-pod-table", false, "print pod status line by line instead of a table refreshed on each rollout check")
+
+// podTable 滚动期间每次检查输出的pod状态表格：终端中原地刷新，
+// 输出不是终端时只在pod状态变化时输出一次（不比较AGE）
+type podTable struct {
+	live     bool
+	width    int
+	lines    int    // 上次绘制的行数
+	drawnAt  int64  // 上次绘制后的lastOutput，之后有其他输出时不再覆盖
+	previous string // 上次输出的表格（不含AGE）
+}
+
+// newPodTable 创建pod状态表格，--no-pod-table或输出JSON时返回nil
+func newPodTable() *podTable {
+	if *noPodTable || *outputFormat == "json" || *logFormat == "json" {
+		return nil
+	}
+	return &podTable{
+		live:  term.IsTerminal(int(os.Stdout.Fd())) && !githubActions,
+		width: consoleLineWidth(),
+	}
+}
+
+// draw 输出新旧pod的状态表格，新pod在前
+func (t *podTable) draw(newPods, oldPods []*corev1.Pod) {
+	table, key := renderPodTable(newPods, oldPods)
+	if !t.live {
+		if key == t.previous {
+			return
+		}
+		t.previous = key
+		io.WriteString(rawOutput, table)
+		return
+	}
+
+	var out io.Writer = maskingWriter{activityWriter{os.Stdout}}
+	if t.width > 0 {
+		out = &truncatingWriter{w: out, width: t.width}
+	}
+	// 上次绘制之后没有其他输出时移动光标覆盖上一次的表格
+	if t.lines > 0 && lastOutput.Load() == t.drawnAt {
+		fmt.Fprintf(os.Stdout, "\033[%dA\033[J", t.lines)
+	}
+	io.WriteString(out, table)
+	t.lines = bytes.Count([]byte(table), []byte("\n"))
+	t.drawnAt = lastOutput.Load()
+}
+
+// renderPodTable 生成pod状态表格，同时返回不含AGE列的内容用于判断状态是否变化
+func renderPodTable(newPods, oldPods []*corev1.Pod) (string, string) {
+	type row struct {
+		name, phase, ready, restarts, age, node string
+	}
+	var rows []row
+	add := func(pods []*corev1.Pod, old bool) {
+		sorted := append([]*corev1.Pod(nil), pods...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+		for _, pod := range sorted {
+			name := pod.Name
+			if old {
+				name += tr(" (old)")
+			}
+			ready, restarts := 0, int32(0)
+			for _, status := range pod.Status.ContainerStatuses {
+				if status.Ready {
+					ready++
+				}
+				restarts += status.RestartCount
+			}
+			age := "-"
+			if !pod.CreationTimestamp.IsZero() {
+				age = formatDurationShort(time.Since(pod.CreationTimestamp.Time))
+			}
+			rows = append(rows, row{name, podDisplayPhase(pod), fmt.Sprintf("%d/%d", ready, len(pod.Spec.Containers)), fmt.Sprint(restarts), age, podNodeName(pod)})
+		}
+	}
+	add(newPods, false)
+	add(oldPods, true)
+
+	var table, key bytes.Buffer
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPHASE\tREADY\tRESTARTS\tAGE\tNODE")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.name, r.phase, r.ready, r.restarts, r.age, r.node)
+		fmt.Fprintf(&key, "%s %s %s %s %s\n", r.name, r.phase, r.ready, r.restarts, r.node)
+	}
+	tw.Flush()
+	return table.String(), key.String()
+}
+
+// podDisplayPhase 表格中显示的阶段，容器等待或异常退出时显示原因，与kubectl get pods的STATUS类似
+func podDisplayPhase(pod *corev1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+	for _, status := range append(getNativeSidecarStatuses(pod), pod.Status.ContainerStatuses...) {
+		if status.Ready {
+			continue
+		}
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			return status.State.Waiting.Reason
+		}
+		if status.State.Terminated != nil && status.State.Terminated.Reason != "" {
+			return status.State.Terminated.Reason
+		}
+	}
+	return string(pod.Status.Phase)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// operatorFDEnvVar 服务模式下传给部署子进程的继承管道编号，父进程把请求的认证身份写入管道
+// 本机用户可以自己提供管道伪造身份，只有父进程是同一个deploy可执行文件（服务）时才读取，
+// 命令行部署的allowed_users只是提示性的限制，不能防止本机用户冒充
+const operatorFDEnvVar = "DEPLOY_OPERATOR_FD"
+
+// serverOperator 服务父进程通过继承的管道传入的认证身份，只读取一次，不是服务启动的子进程时返回空
+var serverOperator = sync.OnceValue(func() string {
+	fd := os.Getenv(operatorFDEnvVar)
+	os.Unsetenv(operatorFDEnvVar)
+	if fd == "" || !parentIsDeploy() {
+		return ""
+	}
+	return readOperatorFD(fd)
+})
+
+// parentIsDeploy 父进程是否是当前的deploy可执行文件，服务以自身启动部署子进程
+func parentIsDeploy() bool {
+	self, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	parent, err := processExecutable(os.Getppid())
+	if err != nil {
+		return false
+	}
+	selfInfo, err := os.Stat(self)
+	if err != nil {
+		return false
+	}
+	parentInfo, err := os.Stat(parent)
+	return err == nil && os.SameFile(selfInfo, parentInfo)
+}
+
+// processExecutable 进程的可执行文件路径，Linux读取/proc，其他系统通过ps查询
+func processExecutable(pid int) (string, error) {
+	if runtime.GOOS == "linux" {
+		return os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+	}
+	out, err := exec.Command("ps", "-o", "comm=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// readOperatorFD 从继承的管道读取身份，编号无效或不是管道（如重定向的普通文件）时返回空
+func readOperatorFD(value string) string {
+	fd, err := strconv.Atoi(value)
+	if err != nil || fd < 3 {
+		return ""
+	}
+	file := os.NewFile(uintptr(fd), "operator")
+	if file == nil {
+		return ""
+	}
+	defer file.Close()
+	if info, err := file.Stat(); err != nil || info.Mode()&os.ModeNamedPipe == 0 {
+		return ""
+	}
+	data, err := io.ReadAll(io.LimitReader(file, 1024))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// currentOperator 执行部署的身份：服务模式下为请求的认证身份，否则为本机用户名
+func currentOperator() string {
+	if operator := serverOperator(); operator != "" {
+		return operator
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return ""
+}
+
+// allowsUser 环境是否允许该身份部署，没有配置allowed_users时不限制
+func (e Env) allowsUser(operator string) bool {
+	if len(e.AllowedUsers) == 0 {
+		return true
+	}
+	for _, allowed := range e.AllowedUsers {
+		if operator != "" && allowed == operator {
+			return true
+		}
+	}
+	return false
+}
+
+// checkUserPolicy 检查执行部署的身份是否在环境的allowed_users中
+func checkUserPolicy(project string, env Env, operator string) error {
+	if env.allowsUser(operator) {
+		return nil
+	}
+	if operator == "" {
+		operator = "unknown user"
+	}
+	return errorf("policy denied: %s is not allowed to deploy %s/%s (allowed_users: %s)",
+		operator, project, env.Name, strings.Join(env.AllowedUsers, ", "))
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestCurrentOperatorIgnoresEnv(t *testing.T) {
+	t.Setenv("DEPLOY_OPERATOR", "mallory")
+	if got := currentOperator(); got == "mallory" {
+		t.Errorf("currentOperator trusted DEPLOY_OPERATOR")
+	}
+}
+
+func TestCheckUserPolicy(t *testing.T) {
+	env := Env{AllowedUsers: []string{"alice"}}
+	if err := checkUserPolicy("app", env, "alice"); err != nil {
+		t.Errorf("alice rejected: %v", err)
+	}
+	for _, operator := range []string{"bob", ""} {
+		if err := checkUserPolicy("app", env, operator); err == nil {
+			t.Errorf("%q allowed, want rejected", operator)
+		}
+	}
+	if err := checkUserPolicy("app", Env{}, ""); err != nil {
+		t.Errorf("env without allowed_users rejected: %v", err)
+	}
+}
+
+func TestParentIsDeploy(t *testing.T) {
+	// go test的父进程是go命令，不是测试二进制本身
+	if parentIsDeploy() {
+		t.Errorf("parentIsDeploy() = true for a test binary started by go test")
+	}
+}
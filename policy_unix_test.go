@@ -0,0 +1,53 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// dupFD 复制文件的描述符，readOperatorFD会关闭传入的描述符，不能关闭*os.File仍然持有的描述符
+func dupFD(t *testing.T, file *os.File) string {
+	t.Helper()
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fmt.Sprint(fd)
+}
+
+func TestReadOperatorFD(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	writer.WriteString("alice@example.com\n")
+	writer.Close()
+	if got := readOperatorFD(dupFD(t, reader)); got != "alice@example.com" {
+		t.Errorf("operator from pipe = %q, want alice@example.com", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "operator")
+	if err := os.WriteFile(path, []byte("mallory"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if got := readOperatorFD(dupFD(t, file)); got != "" {
+		t.Errorf("operator from regular file = %q, want empty", got)
+	}
+
+	for _, value := range []string{"", "x", "0", "2"} {
+		if got := readOperatorFD(value); got != "" {
+			t.Errorf("readOperatorFD(%q) = %q, want empty", value, got)
+		}
+	}
+}
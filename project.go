@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// detectProjectName 根据目录识别项目：monorepo中目录位于某个项目配置的path下时为该项目，
+// 否则依次尝试目录名、仓库根目录名、主仓库目录名和远程仓库名，都不匹配配置时为目录名
+func detectProjectName(config *Config, dir string) string {
+	if project := config.projectForPath(dir); project != nil {
+		return project.Name
+	}
+	if config != nil {
+		for _, name := range projectNameCandidates(dir) {
+			if config.hasProject(name) {
+				return name
+			}
+		}
+	}
+	return filepath.Base(dir)
+}
+
+// projectNameCandidates 目录可能对应的项目名
+// 链接worktree的目录名通常与项目不同，使用主仓库的目录名；子模块使用其远程仓库名
+func projectNameCandidates(dir string) []string {
+	candidates := []string{filepath.Base(dir)}
+	root := gitOutput("-C", dir, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return candidates
+	}
+	candidates = append(candidates, filepath.Base(root))
+
+	// 链接worktree的git-dir位于主仓库的.git/worktrees下，与git-common-dir不同
+	gitDir := gitOutput("-C", dir, "rev-parse", "--path-format=absolute", "--git-dir")
+	commonDir := gitOutput("-C", dir, "rev-parse", "--path-format=absolute", "--git-common-dir")
+	if gitDir != "" && commonDir != "" && gitDir != commonDir && filepath.Base(commonDir) == ".git" {
+		candidates = append(candidates, filepath.Base(filepath.Dir(commonDir)))
+	}
+
+	if remote := gitOutput("-C", dir, "remote", "get-url", "origin"); remote != "" {
+		remote = strings.TrimSuffix(strings.TrimSuffix(remote, "/"), ".git")
+		if i := strings.LastIndexAny(remote, "/:"); i >= 0 {
+			remote = remote[i+1:]
+		}
+		candidates = append(candidates, remote)
+	}
+	return candidates
+}
+
+// hasProject 配置中是否有该项目
+func (c *Config) hasProject(name string) bool {
+	for _, p := range c.Projects {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// projectForPath 查找配置了path且包含dir的项目，多个项目匹配时取path最长的
+func (c *Config) projectForPath(dir string) *Project {
+	if c == nil {
+		return nil
+	}
+	rel := repoRelativePath(dir)
+	if rel == "" {
+		return nil
+	}
+	var found *Project
+	for i := range c.Projects {
+		projectPath := strings.Trim(filepath.ToSlash(c.Projects[i].Path), "/")
+		if projectPath == "" || (rel != projectPath && !strings.HasPrefix(rel, projectPath+"/")) {
+			continue
+		}
+		if found == nil || len(projectPath) > len(strings.Trim(found.Path, "/")) {
+			found = &c.Projects[i]
+		}
+	}
+	return found
+}
+
+// repoRelativePath dir相对git仓库根目录的路径，使用/分隔，仓库根目录为"."，不在git仓库中时返回空字符串
+func repoRelativePath(dir string) string {
+	root := gitOutput("-C", dir, "rev-parse", "--show-toplevel")
+	if root == "" {
+		return ""
+	}
+	// 符号链接（如macOS的/tmp）会使两个路径前缀不同，先解析为真实路径
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// currentProjectName 当前目录对应的项目名称
+func currentProjectName() (string, error) {
+	workDir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return projectNameOfDir(workDir), nil
+}
+
+// projectNameOfDir 按默认配置文件识别目录对应的项目，配置文件无法读取时为目录名
+func projectNameOfDir(dir string) string {
+	config, _ := loadDefaultConfig()
+	return detectProjectName(config, dir)
+}
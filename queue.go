@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// queueDeploy 环境正在部署时排队等待，不再询问
+var queueDeploy = flag.Bool("queue", false, "if the env is being deployed, wait for that deploy to finish and then start, without asking")
+
+// queuePollInterval 排队时检查当前部署是否结束的间隔
+const queuePollInterval = 15 * time.Second
+
+// shouldQueue 是否排队等待：指定了--queue时直接排队，交互终端中询问，否则不排队
+func shouldQueue(reason string) bool {
+	if *queueDeploy {
+		return true
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	fmt.Print(msg("%s. Queue this deploy to start when it finishes? [y/N] ", reason))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// processRunning 进程是否存在
+func processRunning(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// Windows不支持信号0，FindProcess需要打开进程，进程不存在时已经返回错误
+	if runtime.GOOS == "windows" {
+		process.Release()
+		return true
+	}
+	return process.Signal(syscall.Signal(0)) == nil
+}
+
+// runningLocalDeploy 本机正在进行的同一环境的部署，进程已退出的状态文件（被中断、可恢复的部署）不算
+func runningLocalDeploy(project, env string) *inflightDeploy {
+	state, err := loadInflightDeploy(project, env)
+	if err != nil || state == nil || state.PID == os.Getpid() || !processRunning(state.PID) {
+		return nil
+	}
+	return state
+}
+
+// waitForLocalDeploy 本机有同一环境的部署在进行时，排队等待其结束，不排队时返回错误
+func waitForLocalDeploy(ctx context.Context, project, env string) error {
+	state := runningLocalDeploy(project, env)
+	if state == nil {
+		return nil
+	}
+	reason := msg("%s/%s is being deployed by process %d since %s", project, env, state.PID, formatTime(state.StartedAt))
+	if !shouldQueue(reason) {
+		return errorf("%s, use --queue to wait for it", reason)
+	}
+	slog.Info(msg("Queued: waiting for process %d to finish deploying %s/%s...", state.PID, project, env))
+	setHeartbeatPhase("waiting for the previous deploy of %s/%s to finish", project, env)
+	queuedAt := time.Now()
+	for runningLocalDeploy(project, env) != nil {
+		select {
+		case <-time.After(queuePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	slog.Info(msg("Previous deploy finished after %v in queue, starting", time.Since(queuedAt).Round(time.Second)))
+	return nil
+}
+
+// acquireDeployLockQueued 获取部署锁，锁被其他部署持有时可以排队，定期重试直到获取
+func acquireDeployLockQueued(ctx context.Context, config *LockConfig, project, env, namespace, configPath string, force bool) (*deployLock, error) {
+	lock, err := acquireDeployLock(ctx, config, project, env, namespace, configPath, force)
+	if !errors.Is(err, ErrLockHeld) {
+		return lock, err
+	}
+	if !shouldQueue(localize(err)) {
+		return nil, errorf("%w, or use --queue to wait for it", err)
+	}
+	slog.Info(msg("Queued: waiting for the deploy lock of %s/%s...", project, env))
+	setHeartbeatPhase("waiting for the deploy lock of %s/%s", project, env)
+	queuedAt := time.Now()
+	for errors.Is(err, ErrLockHeld) {
+		select {
+		case <-time.After(queuePollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		lock, err = acquireDeployLock(ctx, config, project, env, namespace, configPath, false)
+	}
+	if err == nil {
+		slog.Info(msg("Previous deploy finished after %v in queue, starting", time.Since(queuedAt).Round(time.Second)))
+	}
+	return lock, err
+}
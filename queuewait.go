@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// JenkinsQueueConfig 等待构建离开Jenkins队列的期限，执行器全部占用时不会一直等待
+type JenkinsQueueConfig struct {
+	Timeout string `yaml:"timeout,omitempty"` // 排队超过该时长时报告队列拥堵并失败，如15m，默认一直等待
+	Cancel  bool   `yaml:"cancel,omitempty"`  // 超时时取消排队中的构建，默认保留在队列中，可以用deploy resume继续跟踪
+}
+
+// ErrJenkinsQueueTimeout 构建在Jenkins队列中等待超过了期限
+var ErrJenkinsQueueTimeout = errors.New("Jenkins build is still queued")
+
+// 排队期限相关的命令行参数，优先于配置文件中的jenkins_queue
+var (
+	queueTimeout         = flag.Duration("queue-timeout", 0, "give up when the Jenkins build is still queued after this long and report the queue congestion, 0 to wait indefinitely (overrides jenkins_queue.timeout)")
+	cancelOnQueueTimeout = flag.Bool("cancel-on-queue-timeout", false, "cancel the queued Jenkins build when --queue-timeout expires")
+)
+
+// setJenkinsQueueDeadline 按配置设置排队期限，命令行参数优先
+func setJenkinsQueueDeadline(config *JenkinsQueueConfig) error {
+	if config == nil {
+		return nil
+	}
+	if config.Timeout != "" && *queueTimeout == 0 {
+		timeout, err := time.ParseDuration(config.Timeout)
+		if err != nil || timeout <= 0 {
+			return errorf("invalid jenkins_queue.timeout %q, expected a duration such as 15m", config.Timeout)
+		}
+		*queueTimeout = timeout
+	}
+	if config.Cancel {
+		*cancelOnQueueTimeout = true
+	}
+	return nil
+}
+
+// waitForQueuedBuild 等待排队的构建开始，超过--queue-timeout时输出排队原因和队列长度，按需取消构建并返回ErrJenkinsQueueTimeout
+func waitForQueuedBuild(ctx context.Context, jenkins *gojenkins.Jenkins, queueID int64) (*gojenkins.Build, error) {
+	if *queueTimeout <= 0 {
+		return jenkins.GetBuildFromQueueID(ctx, queueID)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, *queueTimeout)
+	defer cancel()
+	build, err := jenkins.GetBuildFromQueueID(waitCtx, queueID)
+	if err == nil || ctx.Err() != nil || !errors.Is(waitCtx.Err(), context.DeadlineExceeded) {
+		return build, err
+	}
+
+	task, err := jenkins.GetQueueItem(ctx, queueID)
+	if err != nil {
+		return nil, errorf("%w after %v (queue ID %d), failed to get the queue item: %v", ErrJenkinsQueueTimeout, *queueTimeout, queueID, err)
+	}
+	// 期限到达的同时构建刚好开始
+	if task.Raw.Executable.Number != 0 {
+		return jenkins.GetBuildFromQueueID(ctx, queueID)
+	}
+	why := task.GetWhy()
+	if why == "" {
+		why = "unknown"
+	}
+	if queue, err := jenkins.GetQueue(ctx); err == nil {
+		slog.Warn(msg("Jenkins queue is congested: %d items queued, build waiting %v because: %s", len(queue.Tasks()), *queueTimeout, why))
+	}
+
+	if !*cancelOnQueueTimeout {
+		return nil, errorf("%w after %v (queue ID %d): %s; the build is left in the queue, use deploy resume to keep waiting or --cancel-on-queue-timeout to cancel it", ErrJenkinsQueueTimeout, *queueTimeout, queueID, why)
+	}
+	if _, err := task.Cancel(ctx); err != nil {
+		slog.Warn(msg("failed to cancel queued Jenkins build %d: %v", queueID, err))
+	} else {
+		slog.Info(msg("Cancelled queued Jenkins build %d", queueID))
+		inflight.clear()
+		inflight = nil
+	}
+	return nil, errorf("%w after %v (queue ID %d): %s", ErrJenkinsQueueTimeout, *queueTimeout, queueID, why)
+}
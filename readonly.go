@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"strconv"
+	"strings"
+)
+
+// readOnly 只允许查看类的命令，不能部署或修改计划、锁等状态，适合把配置分享给审计人员或新成员
+var readOnly = flag.Bool("read-only", false, "only allow commands that do not change anything (history, audit, metrics, run list)")
+
+// readOnlySubcommands 只读模式下允许的子命令，值为允许的第一个参数，为空表示不限制
+var readOnlySubcommands = map[string][]string{
+	"audit":   nil,
+	"help":    nil,
+	"history": nil,
+	"metrics": nil,
+	"login":   nil, // 只修改本机钥匙串中自己的凭证
+	"run":     {"list"},
+}
+
+// extractReadOnlyFlag 从子命令之前的参数中取出--read-only，使deploy --read-only history这样的写法也能识别子命令
+func extractReadOnlyFlag(args []string) []string {
+	for i, arg := range args {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "read-only" {
+			continue
+		}
+		enabled := true
+		if hasValue {
+			enabled, _ = strconv.ParseBool(value)
+		}
+		*readOnly = *readOnly || enabled
+		return append(args[:i:i], args[i+1:]...)
+	}
+	return args
+}
+
+// readOnlyMode 命令行指定了--read-only或配置了read_only
+func readOnlyMode(config *Config) bool {
+	return *readOnly || (config != nil && config.ReadOnly)
+}
+
+// checkReadOnlySubcommand 只读模式下拒绝会修改状态的子命令
+func checkReadOnlySubcommand(name string, args []string) error {
+	allowed, ok := readOnlySubcommands[name]
+	if ok && len(allowed) == 0 {
+		return nil
+	}
+	if ok && len(args) > 0 {
+		for _, arg := range allowed {
+			if args[0] == arg {
+				return nil
+			}
+		}
+	}
+	if !*readOnly {
+		config, _ := loadDefaultConfig()
+		if !readOnlyMode(config) {
+			return nil
+		}
+	}
+	return errorf("deploy %s is not allowed in read-only mode, only history, audit, metrics and run list are available", name)
+}
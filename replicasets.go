@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"sort"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// pruneReplicaSets 滚动成功后删除超出revisionHistoryLimit的旧ReplicaSet，覆盖k8s.prune_replicasets
+var pruneReplicaSets = flag.Bool("prune-replicasets", false, "after a successful rollout, delete old ReplicaSets scaled to 0 beyond revisionHistoryLimit and stale ones not owned by the deployment")
+
+// defaultRevisionHistoryLimit 未设置revisionHistoryLimit时Kubernetes的默认值
+const defaultRevisionHistoryLimit = 10
+
+// reportOldReplicaSets 滚动成功后报告遗留的旧ReplicaSet：属于deployment但超出revisionHistoryLimit的，
+// 以及匹配selector却不属于任何控制器的（如deployment被删除重建后遗留），prune时删除其中已缩容到0的
+func reportOldReplicaSets(ctx context.Context, namespace, deploymentName, configPath string, prune bool) {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return
+	}
+	deployment, err := getDeployment(ctx, clientset, namespace, deploymentName)
+	if err != nil {
+		slog.Warn(msg("failed to check old ReplicaSets: %v", err))
+		return
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		slog.Warn(msg("failed to check old ReplicaSets: %v", err))
+		return
+	}
+	list, err := retryK8sCall(ctx, "list replicasets", func() (*appsv1.ReplicaSetList, error) {
+		return clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	})
+	if err != nil {
+		slog.Warn(msg("failed to check old ReplicaSets: %v", err))
+		return
+	}
+
+	currentRevision := getDeploymentRevision(deployment)
+	var owned, orphaned []*appsv1.ReplicaSet
+	for i := range list.Items {
+		rs := &list.Items[i]
+		owner := metav1.GetControllerOf(rs)
+		switch {
+		case owner == nil:
+			orphaned = append(orphaned, rs)
+		case owner.UID == deployment.UID && rs.Annotations["deployment.kubernetes.io/revision"] != currentRevision:
+			owned = append(owned, rs)
+		}
+	}
+	limit := defaultRevisionHistoryLimit
+	if deployment.Spec.RevisionHistoryLimit != nil {
+		limit = int(*deployment.Spec.RevisionHistoryLimit)
+	}
+	if len(owned) == 0 && len(orphaned) == 0 {
+		return
+	}
+	slog.Info(msg("Deployment %s has %d old ReplicaSets (revisionHistoryLimit=%d) and %d ReplicaSets not owned by any controller",
+		deploymentName, len(owned), limit, len(orphaned)))
+
+	// 超出revisionHistoryLimit的是最旧的那些
+	sort.Slice(owned, func(i, j int) bool {
+		return compareRevisions(owned[i].Annotations["deployment.kubernetes.io/revision"], owned[j].Annotations["deployment.kubernetes.io/revision"]) < 0
+	})
+	var stale []*appsv1.ReplicaSet
+	if len(owned) > limit {
+		stale = append(stale, owned[:len(owned)-limit]...)
+	}
+	stale = append(stale, orphaned...)
+	if len(stale) == 0 {
+		return
+	}
+	if !prune {
+		slog.Warn(msg("%d ReplicaSets of %s are beyond revisionHistoryLimit or not owned by the deployment, use --prune-replicasets or k8s.prune_replicasets: true to delete them", len(stale), deploymentName))
+		return
+	}
+
+	pruned := 0
+	for _, rs := range stale {
+		// 仍有pod的ReplicaSet可能还在使用，不删除
+		if (rs.Spec.Replicas != nil && *rs.Spec.Replicas > 0) || rs.Status.Replicas > 0 {
+			continue
+		}
+		if err := clientset.AppsV1().ReplicaSets(namespace).Delete(ctx, rs.Name, metav1.DeleteOptions{}); err != nil {
+			slog.Warn(msg("failed to delete ReplicaSet %s: %v", rs.Name, err))
+			continue
+		}
+		slog.Debug(fmt.Sprintf("Deleted ReplicaSet %s", rs.Name))
+		pruned++
+	}
+	slog.Info(msg("Pruned %d of %d stale ReplicaSets of %s", pruned, len(stale), deploymentName))
+}
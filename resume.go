@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// 进行中部署的阶段
+const (
+	phaseBuild   = "build"
+	phaseRollout = "rollout"
+)
+
+// inflightDeploy 进行中部署的状态，保存在~/.deploy/state下，进程被中断后deploy resume据此重新接上监控
+type inflightDeploy struct {
+	Project         string            `json:"project"`
+	Env             string            `json:"env"`
+	JobName         string            `json:"job_name"`
+	Phase           string            `json:"phase"` // build：等待Jenkins构建；rollout：构建已成功，监控滚动
+	QueueID         int64             `json:"queue_id,omitempty"`
+	BuildNumber     int64             `json:"build_number,omitempty"`
+	BuildURL        string            `json:"build_url,omitempty"`
+	Namespace       string            `json:"namespace"`
+	Deployment      string            `json:"deployment"` // 监控的部署，金丝雀发布时为金丝雀部署
+	ConfigPath      string            `json:"config_path,omitempty"`
+	InitialRevision string            `json:"initial_revision"`
+	InitialPodUIDs  map[string]bool   `json:"initial_pod_uids"`
+	Placeholders    map[string]string `json:"placeholders,omitempty"`
+	Ticket          string            `json:"ticket,omitempty"` // 关联的变更单号
+	PID             int               `json:"pid"`
+	StartedAt       time.Time         `json:"started_at"`
+}
+
+// inflight 当前进程正在进行的部署，未开始或不可恢复（Job类型目标）时为nil
+var inflight *inflightDeploy
+
+// interrupted 部署是否被信号中断，中断时保留状态文件以便恢复，由信号处理协程设置
+var interrupted atomic.Bool
+
+// inflightStatePath 状态文件路径
+func inflightStatePath(project, env string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", "state", project+"-"+env+".json"), nil
+}
+
+// update 修改状态并保存，nil安全
+func (s *inflightDeploy) update(change func(state *inflightDeploy)) {
+	if s == nil {
+		return
+	}
+	change(s)
+	s.save()
+}
+
+// save 保存状态文件，失败只打印警告
+func (s *inflightDeploy) save() {
+	path, err := inflightStatePath(s.Project, s.Env)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+	}
+	if err == nil {
+		var data []byte
+		if data, err = json.MarshalIndent(s, "", "  "); err == nil {
+			err = os.WriteFile(path, data, 0644)
+		}
+	}
+	if err != nil {
+		slog.Warn(msg("failed to save deploy state, it cannot be resumed if interrupted: %v", err))
+	}
+}
+
+// clear 部署结束后删除状态文件，nil安全
+func (s *inflightDeploy) clear() {
+	if s == nil {
+		return
+	}
+	if path, err := inflightStatePath(s.Project, s.Env); err == nil {
+		os.Remove(path)
+	}
+}
+
+// loadInflightDeploy 读取项目环境的状态文件，不存在时返回nil
+func loadInflightDeploy(project, env string) (*inflightDeploy, error) {
+	path, err := inflightStatePath(project, env)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state inflightDeploy
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errorf("invalid deploy state %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+// runResumeCommand deploy resume子命令：重新接上被中断的部署，等待Jenkins构建完成并监控滚动
+func runResumeCommand(args []string) error {
+	flags := flag.NewFlagSet("resume", flag.ExitOnError)
+	var envName string
+	if len(args) > 0 {
+		envName, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if envName == "" {
+		return errorf("usage: deploy resume <env>")
+	}
+
+	projectName, err := currentProjectName()
+	if err != nil {
+		return err
+	}
+	state, err := loadInflightDeploy(projectName, envName)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return errorf("no interrupted deploy of %s/%s found", projectName, envName)
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	env, ok := config.findEnv(projectName, envName)
+	if !ok {
+		return errorf("env %s not found in config", envName)
+	}
+	slog.Info(msg("Resuming deploy of %s/%s started at %s (phase: %s)",
+		projectName, envName, formatTime(state.StartedAt), state.Phase))
+
+	ctx := signalContext(context.Background())
+	defer startHeartbeat()()
+	cleanupCtx := context.WithoutCancel(ctx)
+	inflight = state
+	summary := newDeploySummary(projectName, envName)
+	summary.startTime = state.StartedAt
+	failureHooks = append(failureHooks, summary.fail)
+	summary.Namespace, summary.Deployment = state.Namespace, state.Deployment
+	summary.BuildNumber, summary.BuildURL = state.BuildNumber, state.BuildURL
+	summary.Ticket = state.Ticket
+
+	notifier := newDeployNotifier(resolveNotifications(config.Notifications, env.Notifications), summary, env.Critical)
+	notifier.addFinishNotifiers(config)
+	failureHooks = append(failureHooks, func(message string) {
+		notifier.send(cleanupCtx, stageFailure, message)
+		recordDeploy(cleanupCtx, config.Ledger, newDeployRecord(summary, stageFailure, message))
+		if !interrupted.Load() {
+			state.clear()
+		}
+	})
+
+	if state.Phase == phaseBuild {
+		if err := resumeJenkinsBuild(ctx, config, state, summary); err != nil {
+			return err
+		}
+	}
+
+	setK8sImpersonation(config.K8s, env.K8s)
+	if err := setK8sClientOptions(config.K8s, env.K8s); err != nil {
+		return err
+	}
+	k8s := env.K8s
+	k8s.Namespace, k8s.Deployment = state.Namespace, state.Deployment
+	if err := monitorPodRollout(ctx, k8s, state.ConfigPath, state.InitialRevision, state.InitialPodUIDs, summary); err != nil {
+		if errors.Is(err, ErrConcurrentRollout) {
+			return errorf("aborted pod rollout monitoring: %w", err)
+		}
+		return errorf("failed to monitor pod rollout: %w", err)
+	}
+	if len(env.SmokeChecks) > 0 {
+		if err := runSmokeChecks(ctx, env.SmokeChecks, state.Placeholders, summary); err != nil {
+			return errorf("smoke checks failed: %v", err)
+		}
+	}
+	if env.K8s.Canary != nil || env.K8s.BlueGreen != nil || env.K8s.TrafficShift != nil {
+		slog.Warn(tr("Canary promotion, blue/green switch and traffic shift are not resumed, run a new deploy to finish them"))
+	}
+
+	if after, err := getDeploymentState(ctx, state.Namespace, state.Deployment, state.ConfigPath); err == nil {
+		summary.NewRevision, summary.NewImages, summary.Pods = after.Revision, after.Images, after.Pods
+	}
+	if env.K8s.Canary == nil && env.K8s.BlueGreen == nil {
+		recordDeployedCommit(ctx, state.Namespace, state.Deployment, state.ConfigPath, summary.Commit, summary.Ticket)
+	}
+	summary.Result = "success"
+	summary.print(*outputFormat)
+	notifier.send(ctx, stageSuccess, "")
+	recordDeploy(ctx, config.Ledger, newDeployRecord(summary, stageSuccess, ""))
+	state.clear()
+	return nil
+}
+
+// resumeJenkinsBuild 根据保存的构建号或队列ID找到构建并等待完成
+func resumeJenkinsBuild(ctx context.Context, config *Config, state *inflightDeploy, summary *deploySummary) error {
+	username, apiToken, err := jenkinsCredentials(ctx, config)
+	if err != nil {
+		return errorf("failed to get Jenkins credentials: %v", err)
+	}
+	jenkins := gojenkins.CreateJenkins(jenkinsHTTPClient(), config.JenkinsURL, username, apiToken)
+	if _, err := jenkins.Init(ctx); err != nil {
+		return errorf("failed to connect to Jenkins: %v", err)
+	}
+
+	startGitHubGroup("Jenkins build")
+	var build *gojenkins.Build
+	switch {
+	case state.BuildNumber > 0:
+		job, _, jobErr := getJenkinsJob(ctx, jenkins, state.JobName)
+		if jobErr != nil {
+			return errorf("failed to get job: %v", jobErr)
+		}
+		build, err = job.GetBuild(ctx, state.BuildNumber)
+	case state.QueueID > 0:
+		slog.Info(msg("Waiting for queued build %d to start...", state.QueueID))
+		build, err = waitForQueuedBuild(ctx, jenkins, state.QueueID)
+	default:
+		return errorf("the interrupted deploy had not triggered a Jenkins build yet, run a new deploy instead")
+	}
+	if errors.Is(err, ErrJenkinsQueueTimeout) {
+		return err
+	}
+	if err != nil {
+		return errorf("failed to find the Jenkins build: %v", err)
+	}
+
+	slog.Info(msg("Re-attached to Jenkins build #%d: %s", build.GetBuildNumber(), build.GetUrl()))
+	success, err := waitForJenkinsBuild(ctx, build, summary)
+	if err != nil {
+		return err
+	}
+	if !success {
+		return errorf("build failed: %s", build.GetResult())
+	}
+	return nil
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// scheduleFileName 计划部署列表，位于用户主目录的.deploy目录下
+const scheduleFileName = "schedules.json"
+
+// scheduleCheckInterval 检查到期计划部署的间隔
+const scheduleCheckInterval = 30 * time.Second
+
+// scheduledDeploy 一个计划部署，at为一次性部署的时间，cron为周期部署的表达式
+type scheduledDeploy struct {
+	ID        string     `json:"id"`
+	Project   string     `json:"project"`
+	Env       string     `json:"env"`
+	Dir       string     `json:"dir"` // 执行部署的项目目录
+	At        *time.Time `json:"at,omitempty"`
+	Cron      string     `json:"cron,omitempty"`
+	Detached  bool       `json:"detached,omitempty"` // 由deploy serve执行，否则由创建它的deploy run进程等待执行
+	CreatedBy string     `json:"created_by"`
+	Operator  string     `json:"operator,omitempty"` // 创建计划的本机用户，执行时用于allowed_users检查
+	CreatedAt time.Time  `json:"created_at"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+}
+
+// next 返回after之后的下一次执行时间，没有时返回零值
+func (s scheduledDeploy) next(after time.Time) time.Time {
+	if s.At != nil {
+		if s.LastRun != nil {
+			return time.Time{}
+		}
+		return *s.At
+	}
+	schedule, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return schedule.next(after)
+}
+
+// schedulePath 计划部署文件路径
+func schedulePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", scheduleFileName), nil
+}
+
+// loadSchedules 读取所有计划部署
+func loadSchedules() ([]scheduledDeploy, error) {
+	path, err := schedulePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var schedules []scheduledDeploy
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, errorf("invalid schedule file %s: %v", path, err)
+	}
+	return schedules, nil
+}
+
+// updateSchedules 读取、修改并写回计划部署列表
+func updateSchedules(change func(schedules []scheduledDeploy) []scheduledDeploy) error {
+	schedules, err := loadSchedules()
+	if err != nil {
+		return err
+	}
+	schedules = change(schedules)
+	path, err := schedulePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	// 先写临时文件再重命名，避免并发读到写了一半的文件
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// findSchedule 按ID查找计划部署
+func findSchedule(id string) (*scheduledDeploy, error) {
+	schedules, err := loadSchedules()
+	if err != nil {
+		return nil, err
+	}
+	for _, schedule := range schedules {
+		if schedule.ID == id {
+			return &schedule, nil
+		}
+	}
+	return nil, nil
+}
+
+// removeSchedule 删除计划部署，返回是否存在
+func removeSchedule(id string) (bool, error) {
+	found := false
+	err := updateSchedules(func(schedules []scheduledDeploy) []scheduledDeploy {
+		var kept []scheduledDeploy
+		for _, schedule := range schedules {
+			if schedule.ID == id {
+				found = true
+				continue
+			}
+			kept = append(kept, schedule)
+		}
+		return kept
+	})
+	return found, err
+}
+
+// runRunCommand deploy run子命令：计划在指定时间部署，或列出、取消计划部署
+func runRunCommand(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "list":
+			return listSchedules()
+		case "cancel":
+			if len(args) < 2 {
+				return errorf("usage: deploy run cancel <id>")
+			}
+			found, err := removeSchedule(args[1])
+			if err != nil {
+				return err
+			}
+			if !found {
+				return errorf("scheduled deploy %s not found", args[1])
+			}
+			fmt.Print(msg("Cancelled scheduled deploy %s\n", args[1]))
+			return nil
+		}
+	}
+
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	at := flags.String("at", "", `time to deploy: "22:00" (today, or tomorrow if already past), "2006-01-02 22:00" or RFC 3339`)
+	cron := flags.String("cron", "", `recurring schedule as a 5-field cron expression, e.g. "0 22 * * 1-5", executed by deploy serve`)
+	detach := flags.Bool("detach", false, "leave a --at deploy to deploy serve instead of waiting in this process")
+	group := flags.String("group", "", "deploy all projects of this project group now, using the batch orchestration")
+	concurrency := flags.Int("concurrency", 0, "maximum number of concurrent deploys with --group, overrides the group's concurrency")
+	output := flags.String("output", "text", "format of the --group report: text or json")
+	var envName string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		envName, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if envName == "" && flags.NArg() > 0 {
+		envName = flags.Arg(0)
+	}
+	if *group != "" {
+		if envName == "" || *at != "" || *cron != "" {
+			return errorf("usage: deploy run --group <group> <env> [--concurrency N] [--output json]")
+		}
+		return runGroupDeploy(*group, envName, *concurrency, *output)
+	}
+	if envName == "" || (*at == "") == (*cron == "") {
+		return errorf(`usage: deploy run <env> --at "22:00" [--detach] | --cron "0 22 * * 1-5" | --group <group>`)
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	project := detectProjectName(config, dir)
+	env, found := config.findEnv(project, envName)
+	if !found {
+		return errorf("env %s of project %s not found in config", envName, project)
+	}
+	// 创建时检查，避免到时间才因为权限失败
+	if err := checkUserPolicy(project, env, currentOperator()); err != nil {
+		return err
+	}
+
+	id := make([]byte, 4)
+	rand.Read(id)
+	schedule := scheduledDeploy{
+		ID:        hex.EncodeToString(id),
+		Project:   project,
+		Env:       envName,
+		Dir:       dir,
+		Detached:  *detach || *cron != "",
+		CreatedBy: currentDeployer(),
+		Operator:  currentOperator(),
+		CreatedAt: time.Now(),
+	}
+	if *cron != "" {
+		if _, err := parseCron(*cron); err != nil {
+			return err
+		}
+		schedule.Cron = *cron
+	} else {
+		when, err := parseDeployTime(*at, time.Now())
+		if err != nil {
+			return err
+		}
+		schedule.At = &when
+	}
+
+	if err := updateSchedules(func(schedules []scheduledDeploy) []scheduledDeploy {
+		return append(schedules, schedule)
+	}); err != nil {
+		return errorf("failed to save scheduled deploy: %v", err)
+	}
+	next := schedule.next(time.Now())
+	fmt.Print(msg("Scheduled deploy %s of %s to %s, next run at %s\n", schedule.ID, project, envName, formatTime(next)))
+	if schedule.Detached {
+		fmt.Print(msg("It will be executed by deploy serve running on this machine, cancel it with: deploy run cancel %s\n", schedule.ID))
+		return nil
+	}
+	return waitAndRunSchedule(schedule)
+}
+
+// waitAndRunSchedule 在当前进程等待到期后执行部署，等待期间被取消或按Ctrl+C时不执行
+func waitAndRunSchedule(schedule scheduledDeploy) error {
+	slog.Info(msg("Waiting until %s, press Ctrl+C or run deploy run cancel %s to cancel", formatTime(*schedule.At), schedule.ID))
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for time.Now().Before(*schedule.At) {
+		select {
+		case <-signals:
+			removeSchedule(schedule.ID)
+			return errorf("scheduled deploy cancelled")
+		case <-ticker.C:
+		}
+		// 定期检查是否已被其他进程取消
+		if time.Now().Second()%10 == 0 {
+			if current, err := findSchedule(schedule.ID); err == nil && current == nil {
+				return errorf("scheduled deploy %s was cancelled", schedule.ID)
+			}
+		}
+	}
+	signal.Stop(signals)
+
+	if _, err := removeSchedule(schedule.ID); err != nil {
+		slog.Warn(msg("failed to remove scheduled deploy: %v", err))
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, schedule.Env)
+	cmd.Dir = schedule.Dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// listSchedules 列出计划部署
+func listSchedules() error {
+	schedules, err := loadSchedules()
+	if err != nil {
+		return err
+	}
+	if len(schedules) == 0 {
+		fmt.Println(tr("No scheduled deploys"))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tPROJECT\tENV\tSCHEDULE\tNEXT RUN\tRUNNER\tCREATED BY")
+	for _, schedule := range schedules {
+		when, runner := schedule.Cron, "deploy serve"
+		if schedule.At != nil {
+			when = "once"
+		}
+		if !schedule.Detached {
+			runner = "deploy run"
+		}
+		next := "-"
+		if t := schedule.next(time.Now()); !t.IsZero() {
+			next = formatTime(t)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", schedule.ID, schedule.Project, schedule.Env, when, next, runner, schedule.CreatedBy)
+	}
+	return writer.Flush()
+}
+
+// parseDeployTime 解析--at时间，只有时分时取今天，已过去则取明天
+func parseDeployTime(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("15:04", value, time.Local)
+	if err != nil {
+		return time.Time{}, errorf(`invalid time %q: use "22:00", "2006-01-02 22:00" or RFC 3339`, value)
+	}
+	when := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+	if !when.After(now) {
+		when = when.AddDate(0, 0, 1)
+	}
+	return when, nil
+}
+
+// runScheduler deploy serve中执行到期的计划部署
+func (s *deployServer) runScheduler() {
+	ticker := time.NewTicker(scheduleCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		var due []scheduledDeploy
+		err := updateSchedules(func(schedules []scheduledDeploy) []scheduledDeploy {
+			var kept []scheduledDeploy
+			for _, schedule := range schedules {
+				last := schedule.CreatedAt
+				if schedule.LastRun != nil {
+					last = *schedule.LastRun
+				}
+				next := schedule.next(last)
+				if !schedule.Detached || next.IsZero() || next.After(now) {
+					kept = append(kept, schedule)
+					continue
+				}
+				due = append(due, schedule)
+				// 一次性部署执行后删除，周期部署记录执行时间
+				if schedule.Cron != "" {
+					schedule.LastRun = &now
+					kept = append(kept, schedule)
+				}
+			}
+			return kept
+		})
+		if err != nil {
+			slog.Warn(msg("failed to check scheduled deploys: %v", err))
+			continue
+		}
+		for _, schedule := range due {
+			run, _, err := s.startDeploy(deployRequest{Project: schedule.Project, Env: schedule.Env, Dir: schedule.Dir, Trigger: "schedule:" + schedule.ID, User: schedule.Operator})
+			if err != nil {
+				slog.Warn(msg("Scheduled deploy %s of %s/%s not started: %v", schedule.ID, schedule.Project, schedule.Env, err))
+				continue
+			}
+			slog.Info(msg("Scheduled deploy %s started as deploy %s", schedule.ID, run.ID))
+		}
+	}
+}
+
+// cronSchedule 解析后的5段cron表达式：分 时 日 月 周
+type cronSchedule struct {
+	minutes, hours, days, months, weekdays map[int]bool
+	anyDay, anyWeekday                     bool
+}
+
+// parseCron 解析cron表达式，支持*、数字、范围a-b、列表a,b和步长*/n、a-b/n
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+	bounds := [][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, errorf("invalid cron expression %q: %v", expr, err)
+		}
+		sets[i] = set
+	}
+	// 周日可以写成0或7
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return &cronSchedule{
+		minutes: sets[0], hours: sets[1], days: sets[2], months: sets[3], weekdays: sets[4],
+		anyDay: fields[2] == "*", anyWeekday: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析cron表达式的一段
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if rangePart, stepPart, ok := strings.Cut(part, "/"); ok {
+			value, err := strconv.Atoi(stepPart)
+			if err != nil || value <= 0 {
+				return nil, errorf("invalid step in %q", part)
+			}
+			part, step = rangePart, value
+		}
+		start, end := min, max
+		if part != "*" {
+			low, high, isRange := strings.Cut(part, "-")
+			var err error
+			if start, err = strconv.Atoi(low); err != nil {
+				return nil, errorf("invalid value %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(high); err != nil {
+					return nil, errorf("invalid value %q", part)
+				}
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, errorf("value %q out of range %d-%d", part, min, max)
+		}
+		for value := start; value <= end; value += step {
+			set[value] = true
+		}
+	}
+	return set, nil
+}
+
+// next 返回after之后第一个匹配的分钟，最多查找一年
+func (c *cronSchedule) next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(1, 0, 0); t.Before(limit); t = t.Add(time.Minute) {
+		if !c.months[int(t.Month())] || !c.hours[t.Hour()] || !c.minutes[t.Minute()] {
+			continue
+		}
+		// 与标准cron一致：日和周都有限制时满足其一即可
+		dayMatch, weekdayMatch := c.days[t.Day()], c.weekdays[int(t.Weekday())]
+		if (c.anyDay || c.anyWeekday) && dayMatch && weekdayMatch ||
+			!c.anyDay && !c.anyWeekday && (dayMatch || weekdayMatch) {
+			return t
+		}
+	}
+	return time.Time{}
+}
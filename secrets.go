@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// secretSchemes 配置中可以引用外部密钥的前缀，值在使用时读取，不需要写入配置文件
+// keychain:<name> 读取deploy login保存在系统钥匙串中的token
+// aws-sm:<secret-id>[#json-key] 读取AWS Secrets Manager，密钥为JSON时可以用#指定字段
+// aws-ssm:<parameter-name> 读取SSM Parameter Store，SecureString自动解密
+// 使用aws命令行，凭证按默认链查找（环境变量、~/.aws配置、SSO、实例角色等）
+var secretSchemes = map[string]func(ctx context.Context, ref string) (string, error){
+	"keychain:": func(ctx context.Context, name string) (string, error) { return keychainGet(name) },
+	"aws-sm:":   awsSecretsManagerValue,
+	"aws-ssm:":  awsParameterStoreValue,
+}
+
+// secretCache 同一进程中已读取的密钥，避免重复调用aws
+var secretCache sync.Map
+
+// resolveSecret 值为密钥引用时读取密钥，否则原样返回
+func resolveSecret(ctx context.Context, value string) (string, error) {
+	for prefix, read := range secretSchemes {
+		ref, ok := strings.CutPrefix(value, prefix)
+		if !ok {
+			continue
+		}
+		if cached, ok := secretCache.Load(value); ok {
+			return cached.(string), nil
+		}
+		secret, err := read(ctx, ref)
+		if err != nil {
+			return "", errorf("failed to resolve %s: %v", value, err)
+		}
+		secretCache.Store(value, secret)
+		registerSecret(secret)
+		return secret, nil
+	}
+	return value, nil
+}
+
+// resolveNotificationSecrets 复制通知配置并读取其中token、密钥字段引用的密钥，读取失败时打印警告并保留原值
+func resolveNotificationSecrets(ctx context.Context, config *NotificationsConfig) *NotificationsConfig {
+	if config == nil {
+		return nil
+	}
+	resolved := *config
+	resolve := func(field *string) {
+		value, err := resolveSecret(ctx, *field)
+		if err != nil {
+			slog.Warn(msg("notifications: %v", err))
+			return
+		}
+		registerSecret(value)
+		*field = value
+	}
+	if c := resolved.Slack; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.Slack = &copied
+	}
+	if c := resolved.DingTalk; c != nil {
+		copied := *c
+		resolve(&copied.Secret)
+		resolved.DingTalk = &copied
+	}
+	if c := resolved.Telegram; c != nil {
+		copied := *c
+		resolve(&copied.BotToken)
+		resolved.Telegram = &copied
+	}
+	if c := resolved.Email; c != nil {
+		copied := *c
+		resolve(&copied.Password)
+		resolved.Email = &copied
+	}
+	if c := resolved.PagerDuty; c != nil {
+		copied := *c
+		resolve(&copied.RoutingKey)
+		resolved.PagerDuty = &copied
+	}
+	if c := resolved.Opsgenie; c != nil {
+		copied := *c
+		resolve(&copied.APIKey)
+		resolved.Opsgenie = &copied
+	}
+	if c := resolved.GitLab; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.GitLab = &copied
+	}
+	if c := resolved.Grafana; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.Grafana = &copied
+	}
+	if c := resolved.Sentry; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.Sentry = &copied
+	}
+	if c := resolved.Datadog; c != nil {
+		copied := *c
+		resolve(&copied.APIKey)
+		resolved.Datadog = &copied
+	}
+	if c := resolved.NewRelic; c != nil {
+		copied := *c
+		resolve(&copied.APIKey)
+		resolved.NewRelic = &copied
+	}
+	if c := resolved.Jira; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.Jira = &copied
+	}
+	if c := resolved.GitHub; c != nil {
+		copied := *c
+		resolve(&copied.Token)
+		resolved.GitHub = &copied
+	}
+	return &resolved
+}
+
+// awsSecretsManagerValue 读取Secrets Manager中的密钥，ref为secret-id，可以带#json-key
+func awsSecretsManagerValue(ctx context.Context, ref string) (string, error) {
+	secretID, key, _ := strings.Cut(ref, "#")
+	value, err := awsCLI(ctx, "secretsmanager", "get-secret-value", "--secret-id", secretID, "--query", "SecretString", "--output", "text")
+	if err != nil || key == "" {
+		return value, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", errorf("secret %s is not a JSON object, cannot read key %s", secretID, key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", errorf("secret %s has no key %s", secretID, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+// awsParameterStoreValue 读取SSM Parameter Store中的参数
+func awsParameterStoreValue(ctx context.Context, name string) (string, error) {
+	return awsCLI(ctx, "ssm", "get-parameter", "--name", name, "--with-decryption", "--query", "Parameter.Value", "--output", "text")
+}
+
+// awsCLI 执行aws命令并返回去掉末尾换行的输出
+func awsCLI(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "aws", args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", errorf("%v: %s", err, message)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
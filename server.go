@@ -0,0 +1,785 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ServerConfig deploy serve的配置
+type ServerConfig struct {
+	Listen   string          `yaml:"listen,omitempty"`   // 监听地址，默认:8080，没有配置token和oidc时默认127.0.0.1:8080
+	Token    string          `yaml:"token,omitempty"`    // API访问需要的Bearer token，为空且没有配置oidc时不校验，只能监听本机地址
+	OIDC     *OIDCConfig     `yaml:"oidc,omitempty"`     // API请求使用OIDC ID token认证，token中的身份用于权限检查和部署记录
+	WorkDir  string          `yaml:"work_dir,omitempty"` // 项目工作目录的根目录，每个项目在<work_dir>/<项目名>下执行部署，默认~/.deploy/workspace
+	Webhooks *WebhookConfig  `yaml:"webhooks,omitempty"` // git推送webhook触发部署
+	Slack    *SlackBotConfig `yaml:"slack,omitempty"`    // Slack slash command
+}
+
+// 部署任务的状态
+const (
+	runPending = "pending"
+	runRunning = "running"
+)
+
+// deployRun 服务端的一次部署任务，保存输出用于状态查询和日志流
+type deployRun struct {
+	ID         string     `json:"id"`
+	Project    string     `json:"project"`
+	Env        string     `json:"env"`
+	Status     string     `json:"status"` // pending、running、success、failure
+	ExitCode   int        `json:"exit_code"`
+	Trigger    string     `json:"trigger,omitempty"` // api、webhook等
+	User       string     `json:"user,omitempty"`    // 触发部署的认证身份，如Slack用户ID、推送代码的用户
+	Ref        string     `json:"ref,omitempty"`     // 部署前在项目工作目录检出的git ref，如refs/heads/main、refs/tags/v1.0.0
+	Commit     string     `json:"commit,omitempty"`  // 检出的提交，为空时使用ref最新的提交
+	Ticket     string     `json:"ticket,omitempty"`  // 关联的变更单号，通过DEPLOY_TICKET传给部署子进程
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	mu      sync.Mutex
+	dir     string
+	cancel  context.CancelFunc // 取消部署，子进程收到中断信号
+	lines   []string
+	events  []serverEvent // 子进程通过--events-file发送的结构化事件
+	updated chan struct{} // 有新输出或结束时关闭并替换，用于唤醒日志订阅者
+}
+
+// serverEvent 子进程发送的一个事件，data为原始JSON
+type serverEvent struct {
+	Type string
+	Data string
+}
+
+// appendLine 追加一行输出并唤醒订阅者
+func (r *deployRun) appendLine(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+	close(r.updated)
+	r.updated = make(chan struct{})
+}
+
+// appendEvent 追加一个JSON事件并唤醒订阅者
+func (r *deployRun) appendEvent(data []byte) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, serverEvent{Type: header.Type, Data: string(data)})
+	close(r.updated)
+	r.updated = make(chan struct{})
+}
+
+// finish 记录结束状态并唤醒订阅者
+func (r *deployRun) finish(exitCode int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	r.ExitCode, r.FinishedAt = exitCode, &now
+	r.Status = stageSuccess
+	if exitCode != 0 {
+		r.Status = stageFailure
+	}
+	close(r.updated)
+	r.updated = make(chan struct{})
+}
+
+// snapshot 返回从offset开始的输出、是否已结束和下次等待的channel
+func (r *deployRun) snapshot(offset int) ([]string, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lines []string
+	if offset < len(r.lines) {
+		lines = append(lines, r.lines[offset:]...)
+	}
+	return lines, r.FinishedAt != nil, r.updated
+}
+
+// snapshotEvents 返回从offset开始的事件、是否已结束和下次等待的channel
+func (r *deployRun) snapshotEvents(offset int) ([]serverEvent, bool, <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []serverEvent
+	if offset < len(r.events) {
+		events = append(events, r.events[offset:]...)
+	}
+	return events, r.FinishedAt != nil, r.updated
+}
+
+// status 加锁读取状态，用于JSON输出
+func (r *deployRun) status() deployRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return deployRun{ID: r.ID, Project: r.Project, Env: r.Env, Status: r.Status, ExitCode: r.ExitCode,
+		Trigger: r.Trigger, User: r.User, Ref: r.Ref, Commit: r.Commit, Ticket: r.Ticket, StartedAt: r.StartedAt, FinishedAt: r.FinishedAt}
+}
+
+// deployServer 集中执行部署的HTTP服务，每次部署以子进程运行本程序，与命令行部署使用同样的流程
+type deployServer struct {
+	config     *Config
+	workDir    string
+	executable string
+	oidc       *oidcVerifier   // 配置了server.oidc时校验ID token
+	ctx        context.Context // 服务停止时取消，正在执行的部署随之中断
+	running    sync.WaitGroup  // 正在执行的部署，服务停止时等待它们结束
+
+	mu     sync.Mutex
+	runs   map[string]*deployRun
+	nextID int
+}
+
+// maxFinishedRuns 内存中保留的已结束部署数量，超出时删除最早的，完整记录在部署历史中
+const maxFinishedRuns = 200
+
+// runOutputLineLimit 子进程输出和事件的单行长度上限，超出部分丢弃
+const runOutputLineLimit = 1024 * 1024
+
+// runCancelGrace 取消部署后等待子进程释放锁、发送通知并退出的时间，超时后强制结束
+const runCancelGrace = time.Minute
+
+// serverShutdownTimeout 服务停止时等待HTTP请求结束的时间
+const serverShutdownTimeout = 10 * time.Second
+
+// runServeCommand deploy serve子命令：启动REST API服务
+func runServeCommand(args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := flags.String("listen", "", "address to listen on, overrides server.listen in the config (default :8080, 127.0.0.1:8080 without server.token or server.oidc)")
+	flags.Parse(args)
+
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	if config.Server != nil {
+		if config.Server.Token, err = resolveSecret(context.Background(), config.Server.Token); err != nil {
+			return errorf("failed to get server token: %v", err)
+		}
+		registerSecret(config.Server.Token)
+	}
+	server, err := newDeployServer(config)
+	if err != nil {
+		return err
+	}
+
+	// 没有配置认证时默认只监听本机，并拒绝监听其他地址，否则能访问端口的人都可以触发部署
+	authenticated := config.Server != nil && (config.Server.Token != "" || config.Server.OIDC != nil)
+	addr := ":8080"
+	if !authenticated {
+		addr = "127.0.0.1:8080"
+	}
+	if config.Server != nil && config.Server.Listen != "" {
+		addr = config.Server.Listen
+	}
+	if *listen != "" {
+		addr = *listen
+	}
+	if !authenticated && !isLoopbackAddr(addr) {
+		return errorf("refusing to listen on %s without authentication, configure server.token or server.oidc, or listen on 127.0.0.1", addr)
+	}
+	// 收到SIGINT/SIGTERM时停止接受请求，中断正在执行的部署并等待子进程退出
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server.ctx = ctx
+	httpServer := &http.Server{Addr: addr, Handler: server.handler()}
+	served := make(chan error, 1)
+	go func() { served <- httpServer.ListenAndServe() }()
+
+	slog.Info(msg("Deploy server listening on %s, project workspaces in %s", addr, server.workDir))
+	go server.runScheduler()
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info(msg("Deploy server stopping, waiting for running deploys to exit"))
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+	defer cancel()
+	httpServer.Shutdown(shutdownCtx)
+	server.running.Wait()
+	return nil
+}
+
+// isLoopbackAddr 监听地址是否只能从本机访问
+func isLoopbackAddr(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// newDeployServer 创建服务，准备项目工作目录
+func newDeployServer(config *Config) (*deployServer, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, errorf("failed to locate deploy executable: %v", err)
+	}
+	workDir := "~/.deploy/workspace"
+	if config.Server != nil && config.Server.WorkDir != "" {
+		workDir = config.Server.WorkDir
+	}
+	workDir, err = expandHomePath(workDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, errorf("failed to create work dir: %v", err)
+	}
+	server := &deployServer{config: config, workDir: workDir, executable: executable, ctx: context.Background(), runs: make(map[string]*deployRun)}
+	if config.Server != nil && config.Server.OIDC != nil {
+		if config.Server.OIDC.Issuer == "" || config.Server.OIDC.ClientID == "" {
+			return nil, errorf("server.oidc requires issuer and client_id")
+		}
+		server.oidc = newOIDCVerifier(*config.Server.OIDC)
+	}
+	return server, nil
+}
+
+// handler 注册API路由
+func (s *deployServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/deploys", s.authorized(s.handleTrigger))
+	mux.HandleFunc("GET /api/deploys", s.authorized(s.handleList))
+	mux.HandleFunc("GET /api/deploys/{id}", s.authorized(s.handleStatus))
+	mux.HandleFunc("POST /api/deploys/{id}/cancel", s.authorized(s.handleCancel))
+	mux.HandleFunc("GET /api/deploys/{id}/logs", s.authorized(s.handleLogs))
+	mux.HandleFunc("GET /api/deploys/{id}/events", s.authorized(s.handleEvents))
+	mux.HandleFunc("GET /api/history", s.authorized(s.handleHistory))
+	// webhook和Slack使用各自的签名校验，不需要API token
+	mux.HandleFunc("POST /webhooks/github", s.handleGitHubWebhook)
+	mux.HandleFunc("POST /webhooks/gitlab", s.handleGitLabWebhook)
+	mux.HandleFunc("POST /slack/commands", s.handleSlackCommand)
+	mux.HandleFunc("POST /slack/interactions", s.handleSlackInteraction)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("ok")) })
+	return mux
+}
+
+// authorized 配置了token或oidc时校验Bearer token，OIDC ID token校验通过时把身份保存在请求context中
+func (s *deployServer) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.Server == nil || (s.config.Server.Token == "" && s.oidc == nil) {
+			next(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if ok && s.config.Server.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Server.Token)) == 1 {
+			next(w, r)
+			return
+		}
+		if ok && s.oidc != nil {
+			identity, err := s.oidc.verify(r.Context(), token)
+			if err == nil {
+				next(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, identity)))
+				return
+			}
+			slog.Warn(msg("Rejected API request to %s: %v", r.URL.Path, err))
+			writeJSONError(w, http.StatusUnauthorized, "unauthorized: "+err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusUnauthorized, "unauthorized")
+	}
+}
+
+// writeJSON 输出JSON响应
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeJSONError 输出{"error": message}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+func (s *deployServer) handleTrigger(w http.ResponseWriter, r *http.Request) {
+	var request deployRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	request.Trigger = "api"
+	request.User = requestIdentity(r)
+	run, status, err := s.startDeploy(request)
+	if err != nil {
+		writeJSONError(w, status, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, run.status())
+}
+
+func (s *deployServer) handleList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	runs := make([]deployRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run.status())
+	}
+	s.mu.Unlock()
+	sort.Slice(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *deployServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	run := s.getRun(r.PathValue("id"))
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "deploy not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, run.status())
+}
+
+// handleCancel 取消正在执行的部署，子进程按中断处理（释放锁、发送中止通知、记录历史）
+func (s *deployServer) handleCancel(w http.ResponseWriter, r *http.Request) {
+	run := s.getRun(r.PathValue("id"))
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "deploy not found")
+		return
+	}
+	if run.status().FinishedAt != nil {
+		writeJSONError(w, http.StatusConflict, "deploy already finished")
+		return
+	}
+	slog.Info(msg("Deploy %s cancelled via API", run.ID))
+	run.cancel()
+	writeJSON(w, http.StatusAccepted, run.status())
+}
+
+// handleLogs 以Server-Sent Events推送部署输出，先回放已有输出，部署结束后发送done事件
+func (s *deployServer) handleLogs(w http.ResponseWriter, r *http.Request) {
+	run := s.getRun(r.PathValue("id"))
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "deploy not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	offset := 0
+	for {
+		lines, finished, updated := run.snapshot(offset)
+		for _, line := range lines {
+			fmt.Fprintf(w, "data: %s\n\n", line)
+		}
+		offset += len(lines)
+		if finished {
+			data, _ := json.Marshal(run.status())
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleEvents 以Server-Sent Events推送部署的结构化事件（阶段、日志、构建输出、pod状态、结果），事件名为事件类型，
+// 先回放已有事件，部署结束后发送done事件
+func (s *deployServer) handleEvents(w http.ResponseWriter, r *http.Request) {
+	run := s.getRun(r.PathValue("id"))
+	if run == nil {
+		writeJSONError(w, http.StatusNotFound, "deploy not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	offset := 0
+	for {
+		events, finished, updated := run.snapshotEvents(offset)
+		for _, event := range events {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+		}
+		offset += len(events)
+		if finished {
+			data, _ := json.Marshal(run.status())
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			flusher.Flush()
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-updated:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// handleHistory 查询服务端的部署历史，参数同deploy history
+func (s *deployServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	query := historyQuery{Project: r.URL.Query().Get("project"), Env: r.URL.Query().Get("env"), Limit: 20}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit: %v", err))
+			return
+		}
+		query.Limit = value
+	}
+	var backend ledgerBackend = localHistory{}
+	if r.URL.Query().Get("remote") == "true" {
+		backends := s.config.Ledger.backends()
+		if len(backends) == 0 {
+			writeJSONError(w, http.StatusBadRequest, "no shared ledger configured")
+			return
+		}
+		backend = backends[0]
+	}
+	records, err := backend.list(r.Context(), query)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("failed to read deploy history: %v", err))
+		return
+	}
+	if records == nil {
+		records = []deployRecord{}
+	}
+	writeJSON(w, http.StatusOK, records)
+}
+
+func (s *deployServer) getRun(id string) *deployRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.runs[id]
+}
+
+// deployRequest 触发部署的请求
+type deployRequest struct {
+	Project string `json:"project"`
+	Env     string `json:"env"`
+	Ref     string `json:"ref,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+	Ticket  string `json:"ticket,omitempty"` // 关联的变更单号，配置了change_ticket的环境必须提供
+	Trigger string `json:"-"`
+	User    string `json:"-"` // 请求的认证身份，用于allowed_users检查
+	Dir     string `json:"-"` // 执行部署的目录，为空时使用项目工作目录，计划部署使用创建时的项目目录
+}
+
+// startDeploy 校验项目和环境后在后台启动部署，同一项目环境正在部署时拒绝，返回错误对应的HTTP状态码
+func (s *deployServer) startDeploy(request deployRequest) (*deployRun, int, error) {
+	project, env := request.Project, request.Env
+	if project == "" || env == "" {
+		return nil, http.StatusBadRequest, errorf("project and env are required")
+	}
+	envConfig, found := s.config.findEnv(project, env)
+	if !found {
+		return nil, http.StatusNotFound, errorf("env %s of project %s not found in config", env, project)
+	}
+	if err := validateCheckout(request.Ref, request.Commit); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := checkUserPolicy(project, envConfig, request.User); err != nil {
+		slog.Warn(msg("Deploy of %s/%s via %s rejected: %v", project, env, request.Trigger, err))
+		return nil, http.StatusForbidden, err
+	}
+
+	s.mu.Lock()
+	for _, run := range s.runs {
+		if status := run.status(); status.Project == project && status.Env == env && status.FinishedAt == nil {
+			s.mu.Unlock()
+			return nil, http.StatusConflict, errorf("%s/%s is already being deployed by %s", project, env, status.ID)
+		}
+	}
+	s.nextID++
+	ctx, cancel := context.WithCancel(s.ctx)
+	run := &deployRun{
+		ID:        strconv.Itoa(s.nextID),
+		Project:   project,
+		Env:       env,
+		Status:    runPending,
+		Trigger:   request.Trigger,
+		User:      request.User,
+		Ref:       request.Ref,
+		Commit:    request.Commit,
+		Ticket:    request.Ticket,
+		StartedAt: time.Now(),
+		dir:       request.Dir,
+		cancel:    cancel,
+		updated:   make(chan struct{}),
+	}
+	s.runs[run.ID] = run
+	s.pruneRuns()
+	s.running.Add(1)
+	s.mu.Unlock()
+
+	go func() {
+		defer s.running.Done()
+		defer cancel()
+		s.execute(ctx, run)
+	}()
+	return run, 0, nil
+}
+
+// pruneRuns 已结束的部署超过maxFinishedRuns时删除最早的，调用时需要持有s.mu
+func (s *deployServer) pruneRuns() {
+	var finished []*deployRun
+	for _, run := range s.runs {
+		if run.status().FinishedAt != nil {
+			finished = append(finished, run)
+		}
+	}
+	if len(finished) <= maxFinishedRuns {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].StartedAt.Before(finished[j].StartedAt) })
+	for _, run := range finished[:len(finished)-maxFinishedRuns] {
+		delete(s.runs, run.ID)
+	}
+}
+
+// execute 在项目工作目录下以子进程执行部署，逐行收集输出，ctx取消时中断子进程
+func (s *deployServer) execute(ctx context.Context, run *deployRun) {
+	projectDir := filepath.Join(s.workDir, run.Project)
+	if run.dir != "" {
+		projectDir = run.dir
+	}
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		run.appendLine(fmt.Sprintf("Error: failed to create project dir: %v", err))
+		run.finish(1)
+		return
+	}
+
+	if run.Ref != "" {
+		if err := checkoutRef(projectDir, run.Ref, run.Commit); err != nil {
+			run.appendLine(fmt.Sprintf("Error: failed to check out %s: %v", run.Ref, err))
+			run.finish(1)
+			return
+		}
+	}
+
+	slog.Info(msg("Deploy %s started: %s/%s (%s)", run.ID, run.Project, run.Env, run.Trigger))
+	cmd := exec.CommandContext(ctx, s.executable, run.Env, "--no-desktop-notify")
+	// 先发送中断信号，子进程像命令行按Ctrl+C一样释放锁、发送中止通知并记录历史，超时后强制结束
+	cmd.Cancel = func() error {
+		if runtime.GOOS == "windows" {
+			return cmd.Process.Kill()
+		}
+		return cmd.Process.Signal(os.Interrupt)
+	}
+	cmd.WaitDelay = runCancelGrace
+	isolateProcessGroup(cmd)
+	cmd.Dir = projectDir
+	cmd.Env = os.Environ()
+	if run.Ticket != "" {
+		cmd.Env = append(cmd.Env, "DEPLOY_TICKET="+run.Ticket)
+	}
+	reader, writer := io.Pipe()
+	cmd.Stdout, cmd.Stderr = writer, writer
+
+	// 子进程的结构化事件通过继承的管道（fd 3）发送，Windows不支持继承额外的文件描述符，只收集输出
+	eventsDone := make(chan struct{})
+	var eventsWriter *os.File
+	if runtime.GOOS != "windows" {
+		if eventsReader, writer, err := os.Pipe(); err == nil {
+			eventsWriter = writer
+			cmd.Args = append(cmd.Args, "--events-file", "/dev/fd/3")
+			cmd.ExtraFiles = []*os.File{writer}
+			go func() {
+				defer close(eventsDone)
+				defer eventsReader.Close()
+				// 截断的事件不是完整的JSON，直接丢弃
+				readLines(eventsReader, runOutputLineLimit, func(line []byte, truncated bool) {
+					if !truncated {
+						run.appendEvent(line)
+					}
+				})
+			}()
+		}
+	}
+	if eventsWriter == nil {
+		close(eventsDone)
+	}
+
+	// 认证身份通过只有子进程继承的管道传递，Windows上无法继承，子进程使用服务账号的身份
+	if run.User != "" && runtime.GOOS != "windows" {
+		if operatorReader, operatorWriter, err := os.Pipe(); err == nil {
+			operatorWriter.WriteString(run.User)
+			operatorWriter.Close()
+			defer operatorReader.Close()
+			cmd.ExtraFiles = append(cmd.ExtraFiles, operatorReader)
+			cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d", operatorFDEnvVar, 2+len(cmd.ExtraFiles)))
+		}
+	}
+
+	run.mu.Lock()
+	run.Status = runRunning
+	run.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		readLines(reader, runOutputLineLimit, func(line []byte, truncated bool) {
+			if truncated {
+				run.appendLine(string(line) + " [truncated]")
+				return
+			}
+			run.appendLine(string(line))
+		})
+	}()
+
+	// 启动后关闭父进程中的写端，子进程退出时事件管道才会结束
+	exitCode := 0
+	err := cmd.Start()
+	if eventsWriter != nil {
+		eventsWriter.Close()
+	}
+	if err == nil {
+		err = cmd.Wait()
+	}
+	if err != nil {
+		exitCode = 1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		} else {
+			fmt.Fprint(writer, msg("Error: %v\n", err))
+		}
+	}
+	writer.Close()
+	<-done
+	<-eventsDone
+	run.finish(exitCode)
+	slog.Info(msg("Deploy %s finished: %s/%s exit code %d", run.ID, run.Project, run.Env, exitCode))
+}
+
+// readLines 逐行读取直到EOF，超过limit的行截断并丢弃剩余部分，过长的行不会使读取停止而让子进程写管道时阻塞
+func readLines(r io.Reader, limit int, handle func(line []byte, truncated bool)) {
+	reader := bufio.NewReader(r)
+	var line []byte
+	truncated := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if room := limit - len(line); len(chunk) > room {
+			line = append(line, chunk[:max(room, 0)]...)
+			truncated = true
+		} else {
+			line = append(line, chunk...)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == nil || len(line) > 0 {
+			handle(bytes.TrimRight(line, "\r\n"), truncated)
+		}
+		if err != nil {
+			return
+		}
+		line, truncated = line[:0], false
+	}
+}
+
+// checkoutRef 在项目工作目录（需要是git仓库）拉取并检出ref，分支检出为同名本地分支，使构建参数中的分支名正确
+func checkoutRef(dir, ref, commit string) error {
+	git := func(args ...string) error {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errorf("git %s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+		}
+		return nil
+	}
+	if err := validateCheckout(ref, commit); err != nil {
+		return err
+	}
+	// ref和commit来自API请求，--end-of-options之后的参数不会被git当作选项
+	if err := git("fetch", "--force", "--tags", "--end-of-options", "origin", ref); err != nil {
+		return err
+	}
+	target := commit
+	if target == "" {
+		target = "FETCH_HEAD"
+	}
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		return git("checkout", "--force", "-B", branch, "--end-of-options", target)
+	}
+	return git("checkout", "--force", "--detach", "--end-of-options", target)
+}
+
+// commitPattern 完整或缩写的提交SHA（SHA-1或SHA-256）
+var commitPattern = regexp.MustCompile(`^[0-9a-fA-F]{7,64}$`)
+
+// validateCheckout 校验请求中的ref和commit：ref需要通过git check-ref-format，commit需要是十六进制的SHA
+func validateCheckout(ref, commit string) error {
+	if commit != "" && !commitPattern.MatchString(commit) {
+		return errorf("invalid commit %q, expected a hex commit SHA", commit)
+	}
+	if commit != "" && ref == "" {
+		return errorf("commit requires ref")
+	}
+	if ref == "" {
+		return nil
+	}
+	if strings.HasPrefix(ref, "-") {
+		return errorf("invalid git ref %q", ref)
+	}
+	if err := exec.Command("git", "check-ref-format", "--allow-onelevel", ref).Run(); err != nil {
+		return errorf("invalid git ref %q", ref)
+	}
+	return nil
+}
+
+// hasEnv 配置中是否存在该项目的环境
+func (c *Config) hasEnv(project, env string) bool {
+	_, ok := c.findEnv(project, env)
+	return ok
+}
+
+// findEnv 查找项目的环境配置
+func (c *Config) findEnv(project, env string) (Env, bool) {
+	for _, p := range c.Projects {
+		if p.Name != project {
+			continue
+		}
+		for _, e := range p.Envs {
+			if e.Name == env {
+				return e, true
+			}
+		}
+	}
+	return Env{}, false
+}
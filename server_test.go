@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateCheckout(t *testing.T) {
+	tests := []struct {
+		ref, commit string
+		valid       bool
+	}{
+		{"", "", true},
+		{"refs/heads/main", "", true},
+		{"refs/tags/v1.0.0", "0123456789abcdef0123456789abcdef01234567", true},
+		{"main", "abc1234", true},
+		{"--upload-pack=touch /tmp/pwned", "", false},
+		{"-b", "", false},
+		{"refs/heads/a..b", "", false},
+		{"refs/heads/main", "--orphan", false},
+		{"refs/heads/main", "HEAD~1", false},
+		{"", "abc1234", false},
+	}
+	for _, tt := range tests {
+		if err := validateCheckout(tt.ref, tt.commit); (err == nil) != tt.valid {
+			t.Errorf("validateCheckout(%q, %q) = %v, want valid %v", tt.ref, tt.commit, err, tt.valid)
+		}
+	}
+}
+
+func TestIsLoopbackAddr(t *testing.T) {
+	tests := map[string]bool{
+		"127.0.0.1:8080": true,
+		"localhost:8080": true,
+		"[::1]:8080":     true,
+		":8080":          false,
+		"0.0.0.0:8080":   false,
+		"10.0.0.5:8080":  false,
+		"8080":           false,
+	}
+	for addr, want := range tests {
+		if got := isLoopbackAddr(addr); got != want {
+			t.Errorf("isLoopbackAddr(%q) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestReadLines(t *testing.T) {
+	input := "first\r\n" + strings.Repeat("x", 100) + "\n\nlast"
+	type line struct {
+		text      string
+		truncated bool
+	}
+	var got []line
+	readLines(strings.NewReader(input), 16, func(text []byte, truncated bool) {
+		got = append(got, line{string(text), truncated})
+	})
+	want := []line{{"first", false}, {strings.Repeat("x", 16), true}, {"", false}, {"last", false}}
+	if len(got) != len(want) {
+		t.Fatalf("lines = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestReadLinesDrainsLongLines(t *testing.T) {
+	// 超过bufio默认缓冲区的行也要读完，否则写端会阻塞
+	input := strings.Repeat("y", 64*1024) + "\nafter\n"
+	var lines []string
+	readLines(strings.NewReader(input), 1024, func(text []byte, truncated bool) {
+		lines = append(lines, string(text))
+	})
+	if len(lines) != 2 || len(lines[0]) != 1024 || lines[1] != "after" {
+		t.Errorf("got %d lines, want the truncated line and \"after\"", len(lines))
+	}
+}
+
+func TestPruneRuns(t *testing.T) {
+	s := &deployServer{runs: make(map[string]*deployRun)}
+	start := time.Now()
+	for i := 0; i < maxFinishedRuns+5; i++ {
+		finished := start.Add(time.Duration(i) * time.Second)
+		id := strconv.Itoa(i)
+		s.runs[id] = &deployRun{ID: id, StartedAt: finished, FinishedAt: &finished}
+	}
+	s.runs["running"] = &deployRun{ID: "running", StartedAt: start.Add(-time.Hour)}
+	s.pruneRuns()
+	if len(s.runs) != maxFinishedRuns+1 {
+		t.Errorf("%d runs kept, want %d", len(s.runs), maxFinishedRuns+1)
+	}
+	for _, id := range []string{"0", "4"} {
+		if s.runs[id] != nil {
+			t.Errorf("oldest finished run %s kept", id)
+		}
+	}
+	if s.runs["5"] == nil || s.runs["running"] == nil {
+		t.Errorf("newer finished run or running run pruned")
+	}
+}
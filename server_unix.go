@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolateProcessGroup 部署子进程使用独立的进程组，终端的Ctrl+C只发给服务，由服务逐个中断部署，子进程不会收到两次信号而跳过清理
+func isolateProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
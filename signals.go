@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bndr/gojenkins"
+)
+
+// abortOnInterrupt 部署被中断时同时取消排队中或正在运行的Jenkins构建
+var abortOnInterrupt = flag.Bool("abort-on-interrupt", false, "when the deploy is interrupted, also cancel the queued or running Jenkins build")
+
+// deployTimeout 整个部署（包括排队、构建和滚动监控）的期限
+var deployTimeout = flag.Duration("timeout", 2*time.Hour, "maximum duration of the whole deploy including queueing, build and rollout, 0 for no limit")
+
+// interruptedBy 中断部署的信号（os.Signal），由信号处理协程在设置interrupted之前写入
+var interruptedBy atomic.Value
+
+// deployDeadline 返回到达--timeout时取消的context
+func deployDeadline(parent context.Context) (context.Context, context.CancelFunc) {
+	if *deployTimeout <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, *deployTimeout)
+}
+
+// signalContext 返回收到SIGINT/SIGTERM时取消的context，排队等待、Jenkins轮询和滚动监控随之停止，
+// 返回后由main执行失败回调（释放锁、发送中止通知、记录历史和审计）；再次收到信号时不再等待，立即退出
+func signalContext(parent context.Context) context.Context {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		interruptedBy.Store(sig)
+		interrupted.Store(true)
+		slog.Warn(msg("Received %s, stopping the deploy (press Ctrl+C again to exit immediately)", sig))
+		cancel()
+		sig = <-signals
+		fatalf("Deploy aborted by %s", sig)
+	}()
+	return ctx
+}
+
+// interruptionError 部署被信号中断时，用中断原因代替各步骤返回的context canceled等错误
+func interruptionError(err error) error {
+	if !interrupted.Load() {
+		return err
+	}
+	sig := interruptedBy.Load()
+	if inflight != nil {
+		return errorf("Deploy interrupted by %s, run deploy resume %s to re-attach", sig, inflight.Env)
+	}
+	return errorf("Deploy aborted by %s", sig)
+}
+
+// sleepContext 等待一段时间，ctx被取消时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+// cancelJenkinsQueueItem 指定了--abort-on-interrupt时取消还在排队的构建，部署不再需要恢复
+func cancelJenkinsQueueItem(ctx context.Context, jenkins *gojenkins.Jenkins, queueID int64) {
+	if !*abortOnInterrupt {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	task, err := jenkins.GetQueueItem(ctx, queueID)
+	if err == nil {
+		_, err = task.Cancel(ctx)
+	}
+	if err != nil {
+		slog.Warn(msg("failed to cancel queued Jenkins build %d: %v", queueID, err))
+		return
+	}
+	slog.Info(msg("Cancelled queued Jenkins build %d", queueID))
+	inflight.clear()
+	inflight = nil
+}
+
+// abortJenkinsBuild 指定了--abort-on-interrupt时中止正在运行的构建，部署不再需要恢复
+func abortJenkinsBuild(ctx context.Context, build *gojenkins.Build) {
+	if !*abortOnInterrupt {
+		return
+	}
+	if _, err := build.Stop(context.WithoutCancel(ctx)); err != nil {
+		slog.Warn(msg("failed to abort Jenkins build #%d: %v", build.GetBuildNumber(), err))
+		return
+	}
+	slog.Info(msg("Aborted Jenkins build #%d", build.GetBuildNumber()))
+	inflight.clear()
+	inflight = nil
+}
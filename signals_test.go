@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignalContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("os.Process.Signal does not support os.Interrupt on Windows")
+	}
+	t.Cleanup(func() { interrupted.Store(false) })
+	ctx := signalContext(context.Background())
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("context not cancelled after SIGINT")
+	}
+	// 在其他协程读取中断状态，go test -race检查与信号处理协程之间的同步
+	done := make(chan string)
+	go func() { done <- interruptionError(ctx.Err()).Error() }()
+	if got := <-done; !strings.HasPrefix(got, "Deploy aborted by interrupt") {
+		t.Errorf("interruptionError = %q, want aborted by interrupt", got)
+	}
+}
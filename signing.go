@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// SigningConfig 用本地SSH密钥给部署记录和审计记录签名，共享台账中的记录可以校验未被篡改
+type SigningConfig struct {
+	Key            string `yaml:"key"`                       // SSH私钥路径，如~/.ssh/id_ed25519，也可以是ssh-agent中密钥的公钥
+	Principal      string `yaml:"principal,omitempty"`       // 记录中的签名人，需要与allowed_signers中的身份一致，默认git user.email
+	AllowedSigners string `yaml:"allowed_signers,omitempty"` // 校验签名使用的allowed_signers文件（ssh-keygen格式），团队共享
+}
+
+// signatureNamespace ssh-keygen -Y使用的签名命名空间，防止签名被用于其他用途
+const signatureNamespace = "deploy-record"
+
+// 签名校验结果
+const (
+	signatureValid    = "valid"
+	signatureInvalid  = "INVALID"
+	signatureUnsigned = "unsigned"
+)
+
+// principal 签名人身份
+func (c *SigningConfig) principal() string {
+	if c.Principal != "" {
+		return c.Principal
+	}
+	if email := gitOutput("config", "user.email"); email != "" {
+		return email
+	}
+	return currentOperator()
+}
+
+// sign 使用ssh-keygen -Y sign对数据签名，返回armored签名
+func (c *SigningConfig) sign(data []byte) (string, error) {
+	key, err := expandHomePath(c.Key)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.Command("ssh-keygen", "-Y", "sign", "-q", "-f", key, "-n", signatureNamespace)
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", errorf("failed to sign with %s: %v: %s", c.Key, err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// verify 使用allowed_signers校验签名人对数据的签名，返回校验结果
+func (c *SigningConfig) verify(principal, signature string, data []byte) (string, error) {
+	if signature == "" {
+		return signatureUnsigned, nil
+	}
+	if c == nil || c.AllowedSigners == "" {
+		return "", errorf("signing.allowed_signers is not configured")
+	}
+	allowedSigners, err := expandHomePath(c.AllowedSigners)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp("", "deploy-signature-*.sig")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(signature)
+	file.Close()
+	if err != nil {
+		return "", err
+	}
+
+	cmd := exec.Command("ssh-keygen", "-Y", "verify", "-f", allowedSigners, "-I", principal, "-n", signatureNamespace, "-s", file.Name())
+	cmd.Stdin = bytes.NewReader(data)
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return signatureInvalid, nil
+		}
+		return "", errorf("failed to run ssh-keygen: %v", err)
+	}
+	return signatureValid, nil
+}
+
+// signedPayload 部署记录中被签名的内容：不含签名的JSON
+func (r deployRecord) signedPayload() []byte {
+	r.Signature = ""
+	data, _ := json.Marshal(r)
+	return data
+}
+
+// signRecord 配置了签名时给部署记录签名，失败只打印警告，记录不带签名发布
+func (c *SigningConfig) signRecord(record *deployRecord) error {
+	if c == nil {
+		return nil
+	}
+	record.Signer = c.principal()
+	signature, err := c.sign(record.signedPayload())
+	if err != nil {
+		record.Signer = ""
+		return err
+	}
+	record.Signature = signature
+	return nil
+}
+
+// signAuditEntry 配置了签名时对审计记录的哈希签名，哈希包含记录内容和上一条记录的哈希
+func (c *SigningConfig) signAuditEntry(entry *auditEntry) error {
+	if c == nil {
+		return nil
+	}
+	signature, err := c.sign([]byte(entry.Hash))
+	if err != nil {
+		return err
+	}
+	entry.Signature = signature
+	return nil
+}
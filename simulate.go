@@ -0,0 +1,486 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// simulate 使用内置的模拟Jenkins和Kubernetes执行完整部署流程，值为场景名称
+var simulate = flag.String("simulate", "", "run the deploy against a built-in mock Jenkins and Kubernetes cluster with a scripted scenario: success, slow-build, crashloop or timeout")
+
+// 模拟部署使用的名称
+const (
+	simulatedNamespace  = "demo"
+	simulatedDeployment = "demo-app"
+	simulatedJob        = "demo-app"
+	simulatedReplicas   = 3
+	simulatedQueueID    = 7
+	simulatedBuild      = 42
+)
+
+// simulatedDeploymentUID 模拟deployment的UID，ReplicaSet的ownerReferences指向它
+const simulatedDeploymentUID = types.UID("uid-" + simulatedDeployment)
+
+// simulationScenario 模拟场景：构建耗时和新pod的表现
+type simulationScenario struct {
+	BuildDuration time.Duration
+	NewPods       string // ready：逐个就绪；crashloop：第一个新pod反复崩溃；pending：新pod一直无法启动
+}
+
+// simulationScenarios 支持的模拟场景
+var simulationScenarios = map[string]simulationScenario{
+	"success":    {BuildDuration: 6 * time.Second, NewPods: "ready"},
+	"slow-build": {BuildDuration: 40 * time.Second, NewPods: "ready"},
+	"crashloop":  {BuildDuration: 6 * time.Second, NewPods: "crashloop"},
+	"timeout":    {BuildDuration: 6 * time.Second, NewPods: "pending"},
+}
+
+// simulationQueueWait 模拟构建在Jenkins队列中的等待时间
+var simulationQueueWait = 2 * time.Second
+
+// simulationPodStep 模拟滚动中每个新pod就绪的间隔
+var simulationPodStep = 3 * time.Second
+
+// simulationCheckInterval 模拟时滚动监控的检查间隔，缩短后超时等场景在几分钟内结束
+var simulationCheckInterval = time.Second
+
+// simulationBuildLog 模拟构建日志，数值为该行出现的时间在构建耗时中的比例
+var simulationBuildLog = []struct {
+	At   float64
+	Line string
+}{
+	{0, "Started by user demo"},
+	{0.05, "Checking out Revision 3f2a9c1 (refs/remotes/origin/main)"},
+	{0.15, "[Pipeline] stage (Build)"},
+	{0.25, "go build -o demo-app ./..."},
+	{0.4, "[Pipeline] stage (Test)"},
+	{0.5, "ok  \tdemo-app\t1.204s"},
+	{0.6, "[Pipeline] stage (Image)"},
+	{0.7, "Successfully built image registry.example.com/demo-app:1.1.0"},
+	{0.8, "Pushed registry.example.com/demo-app:1.1.0"},
+	{0.9, "[Pipeline] stage (Deploy)"},
+	{0.95, "deployment.apps/demo-app image updated"},
+}
+
+// simulation 模拟Jenkins和Kubernetes共享的状态，所有状态由构建触发时间推算
+type simulation struct {
+	scenario simulationScenario
+
+	mu          sync.Mutex
+	triggeredAt time.Time
+	annotations map[string]string
+}
+
+// simulationHome 模拟期间使用的临时主目录，退出时由removeSimulationHome删除
+var simulationHome string
+
+// startSimulation 启动模拟的Jenkins和Kubernetes API，返回指向它们的配置
+// 模拟期间使用临时主目录，部署历史、审计日志、状态文件和插件都与真实的~/.deploy隔离
+func startSimulation(scenarioName, projectName, envName string) (*Config, error) {
+	scenario, ok := simulationScenarios[scenarioName]
+	if !ok {
+		return nil, errorf("unknown simulation scenario %q: use success, slow-build, crashloop or timeout", scenarioName)
+	}
+	home, err := os.MkdirTemp("", "deploy-simulate-")
+	if err != nil {
+		return nil, err
+	}
+	simulationHome = home
+	// Windows上os.UserHomeDir读取USERPROFILE
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+
+	sim := &simulation{scenario: scenario, annotations: map[string]string{"deployment.kubernetes.io/revision": "1"}}
+	jenkinsURL, err := serveSimulation(sim.handleJenkins)
+	if err != nil {
+		return nil, err
+	}
+	k8sURL, err := serveSimulation(sim.kubernetesHandler().ServeHTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfig := filepath.Join(home, "kubeconfig")
+	if err := os.WriteFile(kubeconfig, []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: simulated
+  cluster:
+    server: %s
+users:
+- name: simulated
+  user:
+    token: simulated
+contexts:
+- name: simulated
+  context:
+    cluster: simulated
+    user: simulated
+    namespace: %s
+current-context: simulated
+`, k8sURL, simulatedNamespace)), 0600); err != nil {
+		return nil, err
+	}
+
+	rolloutCheckInterval = simulationCheckInterval
+	slog.Info(msg("Simulation %q: mock Jenkins at %s, mock Kubernetes API at %s", scenarioName, jenkinsURL, k8sURL))
+
+	return &Config{
+		JenkinsURL: jenkinsURL,
+		Username:   "demo",
+		APIToken:   "simulated",
+		K8s:        GlobalK8sConfig{ConfigPath: kubeconfig},
+		Projects: []Project{{
+			Name: projectName,
+			Envs: []Env{{
+				Name:    envName,
+				JobName: simulatedJob,
+				Params:  []Param{{Name: "BRANCH", Value: "main"}},
+				K8s:     K8sConfig{Namespace: simulatedNamespace, Deployment: simulatedDeployment},
+			}},
+		}},
+	}, nil
+}
+
+// removeSimulationHome 删除模拟使用的临时主目录，在失败回调之后调用，回调写入的历史和审计不会重新创建目录
+func removeSimulationHome() {
+	if simulationHome == "" {
+		return
+	}
+	os.RemoveAll(simulationHome)
+	simulationHome = ""
+}
+
+// serveSimulation 在本机随机端口上启动HTTP服务
+func serveSimulation(handler http.HandlerFunc) (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", errorf("failed to start simulation server: %v", err)
+	}
+	go http.Serve(listener, handler)
+	return "http://" + listener.Addr().String(), nil
+}
+
+// elapsed 构建触发后经过的时间，未触发时返回false
+func (s *simulation) elapsed() (time.Duration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.triggeredAt.IsZero() {
+		return 0, false
+	}
+	return time.Since(s.triggeredAt), true
+}
+
+// buildElapsed 构建开始执行后经过的时间，仍在排队时返回false
+func (s *simulation) buildElapsed() (time.Duration, bool) {
+	elapsed, ok := s.elapsed()
+	if !ok || elapsed < simulationQueueWait {
+		return 0, false
+	}
+	return elapsed - simulationQueueWait, true
+}
+
+// rolloutElapsed 构建完成、开始滚动后经过的时间，尚未开始时返回false
+func (s *simulation) rolloutElapsed() (time.Duration, bool) {
+	elapsed, ok := s.buildElapsed()
+	if !ok || elapsed < s.scenario.BuildDuration {
+		return 0, false
+	}
+	return elapsed - s.scenario.BuildDuration, true
+}
+
+// handleJenkins 模拟gojenkins用到的Jenkins接口
+func (s *simulation) handleJenkins(w http.ResponseWriter, r *http.Request) {
+	base := "http://" + r.Host
+	jobPath := "/job/" + simulatedJob
+	buildPath := fmt.Sprintf("%s/%d", jobPath, simulatedBuild)
+	route := strings.TrimSuffix(path.Clean(r.URL.Path), "/api/json")
+
+	switch {
+	case route == "/" || route == "":
+		w.Header().Set("X-Jenkins", "2.440-simulated")
+		writeJSON(w, http.StatusOK, map[string]interface{}{"mode": "NORMAL", "jobs": []map[string]string{{"name": simulatedJob}}})
+	case strings.HasPrefix(route, "/crumbIssuer"):
+		writeJSON(w, http.StatusOK, map[string]string{})
+	case route == jobPath:
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"name":     simulatedJob,
+			"url":      base + jobPath + "/",
+			"inQueue":  false,
+			"property": []map[string]interface{}{{"parameterDefinitions": []map[string]string{{"name": "BRANCH"}}}},
+		})
+	case r.Method == http.MethodPost && (route == jobPath+"/buildWithParameters" || route == jobPath+"/build"):
+		s.mu.Lock()
+		s.triggeredAt = time.Now()
+		s.mu.Unlock()
+		w.Header().Set("Location", fmt.Sprintf("%s/queue/item/%d/", base, simulatedQueueID))
+		w.WriteHeader(http.StatusCreated)
+	case route == fmt.Sprintf("/queue/item/%d", simulatedQueueID):
+		task := map[string]interface{}{
+			"id":   simulatedQueueID,
+			"task": map[string]string{"name": simulatedJob, "url": base + jobPath + "/"},
+			"why":  "Waiting for next available executor",
+		}
+		if _, started := s.buildElapsed(); started {
+			task["executable"] = map[string]interface{}{"number": simulatedBuild, "url": base + buildPath + "/"}
+		}
+		writeJSON(w, http.StatusOK, task)
+	case route == buildPath:
+		elapsed, _ := s.buildElapsed()
+		build := map[string]interface{}{
+			"number":   simulatedBuild,
+			"url":      base + buildPath + "/",
+			"queueId":  simulatedQueueID,
+			"building": elapsed < s.scenario.BuildDuration,
+		}
+		if elapsed >= s.scenario.BuildDuration {
+			build["result"] = "SUCCESS"
+		}
+		writeJSON(w, http.StatusOK, build)
+	case route == buildPath+"/consoleText":
+		elapsed, _ := s.buildElapsed()
+		var log strings.Builder
+		for _, line := range simulationBuildLog {
+			if elapsed >= time.Duration(line.At*float64(s.scenario.BuildDuration)) {
+				log.WriteString(line.Line + "\n")
+			}
+		}
+		if elapsed >= s.scenario.BuildDuration {
+			log.WriteString("Finished: SUCCESS\n")
+		}
+		w.Write([]byte(log.String()))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// kubernetesHandler 模拟部署监控用到的Kubernetes API
+func (s *simulation) kubernetesHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /version", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"major": "1", "minor": "29", "gitVersion": "v1.29.3-simulated"})
+	})
+	mux.HandleFunc("POST /apis/authorization.k8s.io/v1/selfsubjectaccessreviews", func(w http.ResponseWriter, r *http.Request) {
+		var review authorizationv1.SelfSubjectAccessReview
+		json.NewDecoder(r.Body).Decode(&review)
+		review.APIVersion, review.Kind = "authorization.k8s.io/v1", "SelfSubjectAccessReview"
+		review.Status.Allowed = true
+		writeJSON(w, http.StatusCreated, review)
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("namespace") != simulatedNamespace {
+			writeK8sNotFound(w, "namespaces", r.PathValue("namespace"))
+			return
+		}
+		writeJSON(w, http.StatusOK, corev1.Namespace{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Namespace"},
+			ObjectMeta: metav1.ObjectMeta{Name: simulatedNamespace},
+		})
+	})
+	mux.HandleFunc("GET /apis/apps/v1/namespaces/{namespace}/deployments/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("namespace") != simulatedNamespace || r.PathValue("name") != simulatedDeployment {
+			writeK8sNotFound(w, "deployments.apps", r.PathValue("name"))
+			return
+		}
+		writeJSON(w, http.StatusOK, s.deployment())
+	})
+	mux.HandleFunc("PATCH /apis/apps/v1/namespaces/{namespace}/deployments/{name}", func(w http.ResponseWriter, r *http.Request) {
+		var patch struct {
+			Metadata struct {
+				Annotations map[string]string `json:"annotations"`
+			} `json:"metadata"`
+		}
+		json.NewDecoder(r.Body).Decode(&patch)
+		s.mu.Lock()
+		for key, value := range patch.Metadata.Annotations {
+			s.annotations[key] = value
+		}
+		s.mu.Unlock()
+		writeJSON(w, http.StatusOK, s.deployment())
+	})
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/pods", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.pods())
+	})
+	mux.HandleFunc("GET /apis/apps/v1/namespaces/{namespace}/replicasets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.replicaSets())
+	})
+	mux.HandleFunc("GET /apis/autoscaling/v2/namespaces/{namespace}/horizontalpodautoscalers", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apiVersion": "autoscaling/v2", "kind": "HorizontalPodAutoscalerList", "items": []interface{}{}})
+	})
+	mux.HandleFunc("GET /apis/policy/v1/namespaces/{namespace}/poddisruptionbudgets", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"apiVersion": "policy/v1", "kind": "PodDisruptionBudgetList", "items": []interface{}{}})
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeK8sNotFound(w, "resource", r.URL.Path)
+	})
+	return mux
+}
+
+// writeK8sNotFound 以Kubernetes Status格式返回404，使客户端可以用IsNotFound判断
+func writeK8sNotFound(w http.ResponseWriter, resource, name string) {
+	writeJSON(w, http.StatusNotFound, metav1.Status{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Status"},
+		Status:   metav1.StatusFailure,
+		Message:  fmt.Sprintf("%s %q not found", resource, name),
+		Reason:   metav1.StatusReasonNotFound,
+		Code:     http.StatusNotFound,
+	})
+}
+
+// rolloutProgress 滚动进度：已创建和已就绪的新pod数量，以及是否已开始滚动
+func (s *simulation) rolloutProgress() (created, ready int, started bool) {
+	elapsed, started := s.rolloutElapsed()
+	if !started {
+		return 0, 0, false
+	}
+	steps := int(elapsed / simulationPodStep)
+	switch s.scenario.NewPods {
+	case "ready":
+		return min(steps+1, simulatedReplicas), min(steps, simulatedReplicas), true
+	default:
+		return 1, 0, true
+	}
+}
+
+// deployment 按当前进度生成部署对象
+func (s *simulation) deployment() *appsv1.Deployment {
+	created, ready, started := s.rolloutProgress()
+	revision, image := "1", "registry.example.com/demo-app:1.0.0"
+	if started {
+		revision, image = "2", "registry.example.com/demo-app:1.1.0"
+	}
+	oldPods := simulatedReplicas - ready
+
+	s.mu.Lock()
+	annotations := make(map[string]string)
+	for key, value := range s.annotations {
+		annotations[key] = value
+	}
+	s.mu.Unlock()
+	annotations["deployment.kubernetes.io/revision"] = revision
+
+	replicas := int32(simulatedReplicas)
+	labels := map[string]string{"app": simulatedDeployment}
+	generation := int64(1)
+	if started {
+		generation = 2
+	}
+	return &appsv1.Deployment{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{Name: simulatedDeployment, Namespace: simulatedNamespace, UID: simulatedDeploymentUID, Annotations: annotations, Generation: generation},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+			},
+		},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration:  generation,
+			Replicas:            int32(created + oldPods),
+			UpdatedReplicas:     int32(created),
+			ReadyReplicas:       int32(ready + oldPods),
+			AvailableReplicas:   int32(ready + oldPods),
+			UnavailableReplicas: int32(created - ready),
+		},
+	}
+}
+
+// replicaSets 按当前进度生成deployment拥有的ReplicaSet：旧版本的和滚动开始后新版本的
+func (s *simulation) replicaSets() *appsv1.ReplicaSetList {
+	created, ready, started := s.rolloutProgress()
+	list := &appsv1.ReplicaSetList{TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSetList"}}
+	list.Items = append(list.Items, simulatedReplicaSet("7d4b9c", "1", simulatedReplicas-ready))
+	if started {
+		list.Items = append(list.Items, simulatedReplicaSet("5f8e2a", "2", created))
+	}
+	return list
+}
+
+// simulatedReplicaSet 生成属于模拟deployment的ReplicaSet
+func simulatedReplicaSet(hash, revision string, replicas int) appsv1.ReplicaSet {
+	controller := true
+	count := int32(replicas)
+	return appsv1.ReplicaSet{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "ReplicaSet"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            simulatedDeployment + "-" + hash,
+			Namespace:       simulatedNamespace,
+			UID:             types.UID("uid-" + simulatedDeployment + "-" + hash),
+			Labels:          map[string]string{"app": simulatedDeployment},
+			Annotations:     map[string]string{"deployment.kubernetes.io/revision": revision},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: simulatedDeployment, UID: simulatedDeploymentUID, Controller: &controller}},
+		},
+		Spec:   appsv1.ReplicaSetSpec{Replicas: &count},
+		Status: appsv1.ReplicaSetStatus{Replicas: count},
+	}
+}
+
+// pods 按当前进度生成pod列表：剩余的旧pod和已创建的新pod
+func (s *simulation) pods() *corev1.PodList {
+	created, ready, started := s.rolloutProgress()
+	list := &corev1.PodList{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "PodList"}}
+	for i := ready; i < simulatedReplicas; i++ {
+		list.Items = append(list.Items, simulatedPod(fmt.Sprintf("%s-7d4b9c-old%d", simulatedDeployment, i), "registry.example.com/demo-app:1.0.0", "ready", 0))
+	}
+	if !started {
+		return list
+	}
+	elapsed, _ := s.rolloutElapsed()
+	for i := 0; i < created; i++ {
+		state := "ready"
+		if i >= ready {
+			state = "creating"
+			if s.scenario.NewPods == "crashloop" && elapsed > simulationPodStep {
+				state = "crashloop"
+			}
+		}
+		list.Items = append(list.Items, simulatedPod(fmt.Sprintf("%s-5f8e2a-new%d", simulatedDeployment, i), "registry.example.com/demo-app:1.1.0", state, int32(elapsed/(10*time.Second))+1))
+	}
+	return list
+}
+
+// simulatedPod 生成处于指定状态的pod：ready、creating或crashloop
+func simulatedPod(name, image, state string, restarts int32) corev1.Pod {
+	pod := corev1.Pod{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: simulatedNamespace, UID: types.UID("uid-" + name), Labels: map[string]string{"app": simulatedDeployment}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+	}
+	status := corev1.ContainerStatus{Name: "app", Image: image}
+	switch state {
+	case "ready":
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: metav1.NewTime(time.Now().Add(-time.Minute))}}
+		status.Ready = true
+		status.State.Running = &corev1.ContainerStateRunning{StartedAt: metav1.NewTime(time.Now().Add(-time.Minute))}
+	case "creating":
+		pod.Status.Phase = corev1.PodPending
+		status.State.Waiting = &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}
+	case "crashloop":
+		pod.Status.Phase = corev1.PodRunning
+		pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+		status.RestartCount = restarts
+		status.State.Waiting = &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff", Message: "back-off 10s restarting failed container=app"}
+		status.LastTerminationState.Terminated = &corev1.ContainerStateTerminated{ExitCode: 1, Reason: "Error", Message: "panic: missing required env DATABASE_URL"}
+	}
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{status}
+	return pod
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// runSimulatedDeploy 以缩短的时间运行模拟场景的完整部署，返回--summary-file中的汇总和runDeploy的错误
+func runSimulatedDeploy(t *testing.T, scenario string) (deploySummary, error) {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("simulated deploys take a few seconds")
+	}
+	// 模拟会修改主目录，测试结束后恢复
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	summaryPath := filepath.Join(t.TempDir(), "summary.json")
+
+	savedScenario := simulationScenarios[scenario]
+	savedQueueWait, savedPodStep, savedSimulationCheck := simulationQueueWait, simulationPodStep, simulationCheckInterval
+	savedStabilityWait, savedCheckInterval := defaultStabilityWait, rolloutCheckInterval
+	savedSimulate, savedSummaryFile, savedDesktop := *simulate, *summaryFile, *noDesktopNotify
+	t.Cleanup(func() {
+		simulationScenarios[scenario] = savedScenario
+		simulationQueueWait, simulationPodStep, simulationCheckInterval = savedQueueWait, savedPodStep, savedSimulationCheck
+		defaultStabilityWait, rolloutCheckInterval = savedStabilityWait, savedCheckInterval
+		*simulate, *summaryFile, *noDesktopNotify = savedSimulate, savedSummaryFile, savedDesktop
+		failureHooks = nil
+		removeSimulationHome()
+	})
+
+	fast := savedScenario
+	fast.BuildDuration = 500 * time.Millisecond
+	simulationScenarios[scenario] = fast
+	simulationQueueWait, simulationPodStep, simulationCheckInterval = 100*time.Millisecond, 500*time.Millisecond, 200*time.Millisecond
+	defaultStabilityWait = time.Second
+	*simulate, *summaryFile, *noDesktopNotify = scenario, summaryPath, true
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := runDeploy(ctx, filepath.Join(t.TempDir(), "demo"), "staging")
+	if err != nil {
+		runFailureHooks(err)
+	}
+
+	data, readErr := os.ReadFile(summaryPath)
+	if readErr != nil {
+		t.Fatalf("summary file not written: %v", readErr)
+	}
+	var summary deploySummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid summary file: %v", err)
+	}
+	return summary, err
+}
+
+func TestSimulatedDeploySuccess(t *testing.T) {
+	summary, err := runSimulatedDeploy(t, "success")
+	if err != nil {
+		t.Fatalf("runDeploy: %v", err)
+	}
+	if summary.Result != "success" || summary.Error != "" {
+		t.Errorf("result = %q (%q), want success", summary.Result, summary.Error)
+	}
+	if summary.Project != "demo" || summary.Env != "staging" {
+		t.Errorf("project/env = %s/%s, want demo/staging", summary.Project, summary.Env)
+	}
+	if summary.BuildNumber != simulatedBuild {
+		t.Errorf("build number = %d, want %d", summary.BuildNumber, simulatedBuild)
+	}
+	if summary.OldRevision != "1" || summary.NewRevision != "2" {
+		t.Errorf("revision = %s -> %s, want 1 -> 2", summary.OldRevision, summary.NewRevision)
+	}
+	if summary.Pods != simulatedReplicas {
+		t.Errorf("pods = %d, want %d", summary.Pods, simulatedReplicas)
+	}
+	phases := make(map[string]bool)
+	for _, phase := range summary.Phases {
+		phases[phase.Name] = true
+	}
+	for _, name := range []string{"jenkins build", "rollout"} {
+		if !phases[name] {
+			t.Errorf("summary phases %v are missing %q", summary.Phases, name)
+		}
+	}
+}
+
+func TestSimulatedDeployCrashLoop(t *testing.T) {
+	summary, err := runSimulatedDeploy(t, "crashloop")
+	if err == nil {
+		t.Fatal("runDeploy succeeded, want a rollout failure")
+	}
+	if summary.Result != stageFailure || summary.Error == "" {
+		t.Errorf("result = %q (%q), want %s with the failure reason", summary.Result, summary.Error, stageFailure)
+	}
+}
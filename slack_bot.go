@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SlackBotConfig 服务模式下的Slack slash command（/deploy <项目> <环境>）
+type SlackBotConfig struct {
+	SigningSecret string         `yaml:"signing_secret"`      // Slack应用的signing secret，用于校验请求
+	BotToken      string         `yaml:"bot_token,omitempty"` // bot token（xoxb-...），配置后在频道中持续更新部署进度，否则只通过response_url回复开始和结果
+	Users         []SlackBotUser `yaml:"users"`               // 允许部署的用户，未列出的用户无法部署
+}
+
+// SlackBotUser 允许通过Slack部署的用户，projects和envs为空表示不限制
+type SlackBotUser struct {
+	User     string   `yaml:"user"` // Slack用户ID（U...），用户名可以随时修改，不用于授权
+	Projects []string `yaml:"projects,omitempty"`
+	Envs     []string `yaml:"envs,omitempty"`
+}
+
+// slackProgressInterval 更新进度消息的间隔
+const slackProgressInterval = 5 * time.Second
+
+// slackProgressLines 进度消息中显示的最近输出行数
+const slackProgressLines = 15
+
+// slackRequestMaxAge 请求时间戳允许的最大偏差，防止重放
+const slackRequestMaxAge = 5 * time.Minute
+
+// allows 用户是否可以部署该项目环境，只按不可修改的用户ID匹配
+func (c *SlackBotConfig) allows(userID, project, env string) bool {
+	for _, user := range c.Users {
+		if userID == "" || user.User != userID {
+			continue
+		}
+		if (len(user.Projects) == 0 || containsString(user.Projects, project)) &&
+			(len(user.Envs) == 0 || containsString(user.Envs, env)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString 列表中是否包含该字符串
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// slackBotConfig 返回Slack配置，未配置时为nil
+func (s *deployServer) slackBotConfig() *SlackBotConfig {
+	if s.config.Server == nil {
+		return nil
+	}
+	return s.config.Server.Slack
+}
+
+// readSlackRequest 读取请求体并校验Slack签名
+func (s *deployServer) readSlackRequest(w http.ResponseWriter, r *http.Request) (url.Values, bool) {
+	config := s.slackBotConfig()
+	if config == nil || config.SigningSecret == "" {
+		writeJSONError(w, http.StatusNotFound, "slack commands not configured")
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1024*1024))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return nil, false
+	}
+
+	timestamp := r.Header.Get("X-Slack-Request-Timestamp")
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > slackRequestMaxAge {
+		writeJSONError(w, http.StatusUnauthorized, "invalid timestamp")
+		return nil, false
+	}
+	mac := hmac.New(sha256.New, []byte(config.SigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(r.Header.Get("X-Slack-Signature")), []byte(expected)) {
+		writeJSONError(w, http.StatusUnauthorized, "invalid signature")
+		return nil, false
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid form: %v", err))
+		return nil, false
+	}
+	return form, true
+}
+
+// slackReply slash command和交互的即时回复
+func slackReply(w http.ResponseWriter, inChannel bool, text string, blocks ...map[string]interface{}) {
+	reply := map[string]interface{}{"response_type": "ephemeral", "text": text}
+	if inChannel {
+		reply["response_type"] = "in_channel"
+	}
+	if len(blocks) > 0 {
+		reply["blocks"] = blocks
+	}
+	writeJSON(w, http.StatusOK, reply)
+}
+
+// handleSlackCommand 处理/deploy <项目> <环境>，关键环境先回复确认按钮
+func (s *deployServer) handleSlackCommand(w http.ResponseWriter, r *http.Request) {
+	form, ok := s.readSlackRequest(w, r)
+	if !ok {
+		return
+	}
+	args := strings.Fields(form.Get("text"))
+	if len(args) != 2 {
+		slackReply(w, false, fmt.Sprintf("Usage: `%s <project> <env>`", form.Get("command")))
+		return
+	}
+	project, env := args[0], args[1]
+	userID, userName := form.Get("user_id"), form.Get("user_name")
+
+	envConfig, found := s.config.findEnv(project, env)
+	if !found {
+		slackReply(w, false, fmt.Sprintf("Env `%s` of project `%s` not found in config", env, project))
+		return
+	}
+	if !s.slackBotConfig().allows(userID, project, env) {
+		slog.Warn(msg("Slack user %s (%s) is not allowed to deploy %s/%s", userName, userID, project, env))
+		slackReply(w, false, fmt.Sprintf("You are not allowed to deploy `%s` to `%s`", project, env))
+		return
+	}
+
+	if envConfig.Critical {
+		value := project + " " + env
+		slackReply(w, false, fmt.Sprintf("Deploy %s to %s?", project, env),
+			map[string]interface{}{
+				"type": "section",
+				"text": map[string]string{"type": "mrkdwn", "text": fmt.Sprintf(":warning: `%s` is a critical env. Deploy *%s* to *%s*?", env, project, env)},
+			},
+			map[string]interface{}{
+				"type": "actions",
+				"elements": []map[string]interface{}{
+					{"type": "button", "action_id": "deploy_confirm", "style": "danger", "value": value, "text": map[string]string{"type": "plain_text", "text": "Deploy"}},
+					{"type": "button", "action_id": "deploy_cancel", "value": value, "text": map[string]string{"type": "plain_text", "text": "Cancel"}},
+				},
+			})
+		return
+	}
+
+	s.startSlackDeploy(w, project, env, userID, form.Get("channel_id"), form.Get("response_url"))
+}
+
+// handleSlackInteraction 处理确认按钮
+func (s *deployServer) handleSlackInteraction(w http.ResponseWriter, r *http.Request) {
+	form, ok := s.readSlackRequest(w, r)
+	if !ok {
+		return
+	}
+	var payload struct {
+		Type string `json:"type"`
+		User struct {
+			ID string `json:"id"`
+		} `json:"user"`
+		Channel struct {
+			ID string `json:"id"`
+		} `json:"channel"`
+		ResponseURL string `json:"response_url"`
+		Actions     []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(form.Get("payload")), &payload); err != nil || payload.Type != "block_actions" || len(payload.Actions) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	action := payload.Actions[0]
+	args := strings.Fields(action.Value)
+	if len(args) != 2 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	project, env := args[0], args[1]
+	// 立即确认交互，结果通过response_url替换原消息
+	w.WriteHeader(http.StatusOK)
+
+	if action.ActionID == "deploy_cancel" {
+		postSlackResponse(payload.ResponseURL, true, fmt.Sprintf("Deploy of %s to %s cancelled", project, env))
+		return
+	}
+	if action.ActionID != "deploy_confirm" {
+		return
+	}
+	// 按钮可能被其他人点击，重新校验权限
+	if !s.slackBotConfig().allows(payload.User.ID, project, env) {
+		postSlackResponse(payload.ResponseURL, false, fmt.Sprintf("You are not allowed to deploy `%s` to `%s`", project, env))
+		return
+	}
+	run, _, err := s.startDeploy(deployRequest{Project: project, Env: env, Trigger: "slack:" + payload.User.ID, User: payload.User.ID})
+	if err != nil {
+		postSlackResponse(payload.ResponseURL, true, fmt.Sprintf(":x: Failed to start deploy: %v", err))
+		return
+	}
+	postSlackResponse(payload.ResponseURL, true, fmt.Sprintf("Deploy confirmed by <@%s>", payload.User.ID))
+	go s.reportSlackProgress(run, payload.User.ID, payload.Channel.ID, payload.ResponseURL)
+}
+
+// startSlackDeploy 启动部署并回复，之后在后台汇报进度，部署人记录为Slack用户ID
+func (s *deployServer) startSlackDeploy(w http.ResponseWriter, project, env, userID, channelID, responseURL string) {
+	run, _, err := s.startDeploy(deployRequest{Project: project, Env: env, Trigger: "slack:" + userID, User: userID})
+	if err != nil {
+		slackReply(w, false, fmt.Sprintf(":x: Failed to start deploy: %v", err))
+		return
+	}
+	slackReply(w, true, fmt.Sprintf(":rocket: <@%s> started deploy #%s of *%s* to *%s*", userID, run.ID, project, env))
+	go s.reportSlackProgress(run, userID, channelID, responseURL)
+}
+
+// reportSlackProgress 配置了bot token时在频道中发送并持续更新进度消息，否则部署结束后通过response_url回复结果
+func (s *deployServer) reportSlackProgress(run *deployRun, userID, channelID, responseURL string) {
+	ctx := context.Background()
+	token := s.slackBotConfig().BotToken
+	var ts string
+	if token != "" {
+		var err error
+		ts, err = callSlackAPI(ctx, token, "chat.postMessage", map[string]string{"channel": channelID, "text": slackProgressText(run, userID)})
+		if err != nil {
+			slog.Warn(msg("failed to post Slack progress message: %v", err))
+		}
+	}
+
+	for {
+		_, finished, updated := run.snapshot(0)
+		if finished {
+			break
+		}
+		select {
+		case <-updated:
+		case <-time.After(slackProgressInterval):
+		}
+		if ts != "" {
+			if _, err := callSlackAPI(ctx, token, "chat.update", map[string]string{"channel": channelID, "ts": ts, "text": slackProgressText(run, userID)}); err != nil {
+				slog.Warn(msg("failed to update Slack progress message: %v", err))
+			}
+			// 限制更新频率，避免触发Slack的速率限制
+			time.Sleep(slackProgressInterval)
+		}
+	}
+
+	text := slackProgressText(run, userID)
+	if ts != "" {
+		if _, err := callSlackAPI(ctx, token, "chat.update", map[string]string{"channel": channelID, "ts": ts, "text": text}); err == nil {
+			return
+		}
+	}
+	postSlackResponse(responseURL, false, text)
+}
+
+// slackProgressText 部署状态和最近的输出
+func slackProgressText(run *deployRun, userID string) string {
+	lines, _, _ := run.snapshot(0)
+	if len(lines) > slackProgressLines {
+		lines = lines[len(lines)-slackProgressLines:]
+	}
+	status := run.status()
+	icon := map[string]string{stageSuccess: ":white_check_mark:", stageFailure: ":x:"}[status.Status]
+	if icon == "" {
+		icon = ":hourglass_flowing_sand:"
+	}
+	text := fmt.Sprintf("%s Deploy #%s of *%s* to *%s* by <@%s>: %s", icon, status.ID, status.Project, status.Env, userID, status.Status)
+	if len(lines) > 0 {
+		text += "\n```\n" + strings.Join(lines, "\n") + "\n```"
+	}
+	return text
+}
+
+// postSlackResponse 通过response_url回复，replace为true时替换原消息
+func postSlackResponse(responseURL string, replace bool, text string) {
+	if responseURL == "" {
+		return
+	}
+	payload := map[string]interface{}{"text": text, "response_type": "in_channel", "replace_original": replace}
+	if _, err := postJSON(context.Background(), responseURL, payload, nil); err != nil {
+		slog.Warn(msg("failed to respond to Slack: %v", err))
+	}
+}
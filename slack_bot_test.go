@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestSlackBotAllows(t *testing.T) {
+	config := &SlackBotConfig{Users: []SlackBotUser{
+		{User: "U01234567"},
+		{User: "U07654321", Envs: []string{"staging"}},
+	}}
+	tests := []struct {
+		userID, env string
+		want        bool
+	}{
+		{"U01234567", "prod", true},
+		{"U07654321", "staging", true},
+		{"U07654321", "prod", false},
+		{"U99999999", "staging", false},
+		{"", "prod", false},
+	}
+	for _, tt := range tests {
+		if got := config.allows(tt.userID, "app", tt.env); got != tt.want {
+			t.Errorf("allows(%q, app, %s) = %v, want %v", tt.userID, tt.env, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SmokeCheck 滚动完成后执行的HTTP冒烟检查
+type SmokeCheck struct {
+	Name           string `yaml:"name,omitempty"`
+	URL            string `yaml:"url"`
+	ExpectStatus   int    `yaml:"expect_status,omitempty"`   // 期望的HTTP状态码，默认200
+	Contains       string `yaml:"contains,omitempty"`        // 响应体中必须包含的内容
+	Retries        int    `yaml:"retries,omitempty"`         // 失败重试次数，默认3
+	TimeoutSeconds int    `yaml:"timeout_seconds,omitempty"` // 单次请求超时，默认10秒
+}
+
+// smokeCheckResult 单个冒烟检查的结果，记录到部署汇总中
+type smokeCheckResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// runSmokeChecks 依次执行冒烟检查，URL中的占位符会被替换为实际值
+func runSmokeChecks(ctx context.Context, checks []SmokeCheck, placeholders map[string]string, summary *deploySummary) error {
+	startGitHubGroup("Smoke checks")
+	setHeartbeatPhase("running smoke checks")
+	for _, check := range checks {
+		url := check.URL
+		for placeholder, value := range placeholders {
+			url = strings.ReplaceAll(url, placeholder, value)
+		}
+		name := check.Name
+		if name == "" {
+			name = url
+		}
+
+		retries := check.Retries
+		if retries <= 0 {
+			retries = 3
+		}
+
+		var err error
+		for attempt := 1; attempt <= retries; attempt++ {
+			err = runSmokeCheck(ctx, check, url)
+			if err == nil {
+				break
+			}
+			slog.Warn(msg("Smoke check %s failed (attempt %d/%d): %v", name, attempt, retries, err))
+			if attempt < retries {
+				if err := sleepContext(ctx, 5*time.Second); err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			summary.addSmokeCheck(smokeCheckResult{Name: name, Error: err.Error()})
+			return errorf("smoke check %s failed: %v", name, err)
+		}
+		summary.addSmokeCheck(smokeCheckResult{Name: name, Passed: true})
+
+		slog.Info(msg("Smoke check %s passed", name))
+	}
+	return nil
+}
+
+// runSmokeCheck 执行单次HTTP检查
+func runSmokeCheck(ctx context.Context, check SmokeCheck, url string) error {
+	timeout := time.Duration(check.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	expectStatus := check.ExpectStatus
+	if expectStatus == 0 {
+		expectStatus = http.StatusOK
+	}
+
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectStatus {
+		return errorf("unexpected status %d, expected %d", resp.StatusCode, expectStatus)
+	}
+	if check.Contains != "" {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return errorf("failed to read response body: %v", err)
+		}
+		if !strings.Contains(string(body), check.Contains) {
+			return errorf("response body does not contain %q", check.Contains)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,504 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// envSnapshot 环境在某一时刻的完整状态，deploy restore据此恢复，不依赖Deployment的revisionHistoryLimit
+type envSnapshot struct {
+	ID         string                 `json:"id"`
+	Project    string                 `json:"project"`
+	Env        string                 `json:"env"`
+	Namespace  string                 `json:"namespace"`
+	Deployment string                 `json:"deployment"`
+	Revision   string                 `json:"revision"`
+	SpecHash   string                 `json:"spec_hash"` // pod模板的sha256
+	Replicas   *int32                 `json:"replicas,omitempty"`
+	Images     map[string]string      `json:"images"`
+	Configs    map[string]string      `json:"configs,omitempty"` // pod模板引用的ConfigMap和Secret的内容哈希，key为configmap/<名称>或secret/<名称>
+	Commit     string                 `json:"commit,omitempty"`
+	Template   corev1.PodTemplateSpec `json:"template"`
+	CreatedBy  string                 `json:"created_by"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// snapshotDir 项目环境的快照目录
+func snapshotDir(project, env string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", errorf("failed to get user home directory: %v", err)
+	}
+	return filepath.Join(homeDir, ".deploy", "snapshots", project+"-"+env), nil
+}
+
+// runSnapshotCommand deploy snapshot子命令：记录环境当前的pod模板、镜像、副本数和配置校验和，--list列出已有快照
+func runSnapshotCommand(args []string) error {
+	flags := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	list := flags.Bool("list", false, "list the saved snapshots of env instead of taking one")
+	var envName string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		envName, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+	if envName == "" {
+		return errorf("usage: deploy snapshot <env> [--list]")
+	}
+
+	projectName, err := currentProjectName()
+	if err != nil {
+		return err
+	}
+	if *list {
+		return listSnapshots(projectName, envName)
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	env, ok := config.findEnv(projectName, envName)
+	if !ok {
+		return errorf("env %s not found in config", envName)
+	}
+	k8s, configPath, err := snapshotTarget(config, env)
+	if err != nil {
+		return err
+	}
+
+	ctx := signalContext(context.Background())
+	snapshot, err := takeEnvSnapshot(ctx, k8s, configPath)
+	if err != nil {
+		return err
+	}
+	snapshot.Project, snapshot.Env = projectName, envName
+	path, err := snapshot.save()
+	if err != nil {
+		return err
+	}
+
+	replicas := "-"
+	if snapshot.Replicas != nil {
+		replicas = fmt.Sprint(*snapshot.Replicas)
+	}
+	fmt.Print(msg("Saved snapshot %s of %s/%s (deployment %s, revision %s, %s replicas, spec %s) to %s\n",
+		snapshot.ID, projectName, envName, snapshot.Deployment, snapshot.Revision, replicas, snapshot.SpecHash, path))
+	for _, name := range sortedMapKeys(snapshot.Images) {
+		fmt.Printf("  %s: %s\n", name, snapshot.Images[name])
+	}
+	for _, name := range sortedMapKeys(snapshot.Configs) {
+		fmt.Printf("  %s: %s\n", name, snapshot.Configs[name])
+	}
+	return nil
+}
+
+// runRestoreCommand deploy restore子命令：把部署的pod模板和副本数恢复为快照中的状态并监控滚动
+func runRestoreCommand(args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	var positional []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") && len(positional) < 2 {
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	flags.Parse(args)
+	if len(positional) < 2 {
+		return errorf("usage: deploy restore <env> <snapshot ID|latest>")
+	}
+	envName, snapshotID := positional[0], positional[1]
+
+	projectName, err := currentProjectName()
+	if err != nil {
+		return err
+	}
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return err
+	}
+	env, ok := config.findEnv(projectName, envName)
+	if !ok {
+		return errorf("env %s not found in config", envName)
+	}
+	if err := checkUserPolicy(projectName, env, currentOperator()); err != nil {
+		return err
+	}
+	snapshot, err := loadSnapshot(projectName, envName, snapshotID)
+	if err != nil {
+		return err
+	}
+	k8s, configPath, err := snapshotTarget(config, env)
+	if err != nil {
+		return err
+	}
+	if k8s.Namespace != snapshot.Namespace || k8s.Deployment != snapshot.Deployment {
+		slog.Warn(msg("Snapshot %s was taken from %s/%s, the env now deploys %s/%s; restoring the snapshot's deployment",
+			snapshot.ID, snapshot.Namespace, snapshot.Deployment, k8s.Namespace, k8s.Deployment))
+	}
+	k8s.Namespace, k8s.Deployment = snapshot.Namespace, snapshot.Deployment
+
+	ctx := signalContext(context.Background())
+	defer startHeartbeat()()
+	cleanupCtx := context.WithoutCancel(ctx)
+	summary := newDeploySummary(projectName, envName)
+	summary.Namespace, summary.Deployment = k8s.Namespace, k8s.Deployment
+	summary.Commit = snapshot.Commit
+	failureHooks = append(failureHooks, summary.fail)
+	failureHooks = append(failureHooks, func(message string) {
+		recordDeploy(cleanupCtx, config.Ledger, newDeployRecord(summary, stageFailure, message))
+	})
+
+	lockConfig := env.Lock
+	if lockConfig == nil {
+		lockConfig = config.Lock
+	}
+	lock, err := acquireDeployLockQueued(ctx, lockConfig, projectName, envName, k8s.Namespace, configPath, false)
+	if err != nil {
+		return err
+	}
+	defer lock.release(cleanupCtx)
+	failureHooks = append(failureHooks, func(string) { lock.release(cleanupCtx) })
+	// 锁被强制释放或接管时停止部署
+	ctx, stopGuard := lock.guard(ctx)
+	defer stopGuard()
+
+	current, err := takeEnvSnapshot(ctx, k8s, configPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range sortedMapKeys(snapshot.Configs) {
+		if hash, ok := current.Configs[name]; ok && hash != snapshot.Configs[name] {
+			slog.Warn(msg("%s changed since snapshot %s, restore only rolls back the workload, not its content", name, snapshot.ID))
+		} else if !ok {
+			slog.Warn(msg("%s from snapshot %s is no longer referenced or no longer exists", name, snapshot.ID))
+		}
+	}
+
+	templateChanged := current.SpecHash != snapshot.SpecHash
+	replicasChanged := snapshot.Replicas != nil && (current.Replicas == nil || *current.Replicas != *snapshot.Replicas)
+	if !templateChanged && !replicasChanged {
+		slog.Info(msg("%s/%s already matches snapshot %s (spec %s), nothing to restore", projectName, envName, snapshot.ID, snapshot.SpecHash))
+		return nil
+	}
+
+	initialRevision, initialPodUIDs, err := getCurrentDeploymentStatus(ctx, k8s.Namespace, k8s.Deployment, configPath)
+	if err != nil {
+		return err
+	}
+	slog.Info(msg("Restoring %s/%s to snapshot %s taken at %s by %s (revision %s, spec %s)",
+		projectName, envName, snapshot.ID, formatTime(snapshot.CreatedAt), snapshot.CreatedBy, snapshot.Revision, snapshot.SpecHash))
+	for _, line := range formatImageDelta(current.Images, snapshot.Images) {
+		slog.Info("  " + line)
+	}
+	if before, err := getDeploymentState(ctx, k8s.Namespace, k8s.Deployment, configPath); err == nil {
+		summary.OldRevision, summary.OldImages = before.Revision, before.Images
+	}
+	if err := snapshot.apply(ctx, configPath, replicasChanged); err != nil {
+		return err
+	}
+
+	if templateChanged {
+		if err := monitorPodRollout(ctx, k8s, configPath, initialRevision, initialPodUIDs, summary); err != nil {
+			if errors.Is(err, ErrConcurrentRollout) {
+				return errorf("aborted pod rollout monitoring: %w", err)
+			}
+			return errorf("failed to monitor pod rollout: %w", err)
+		}
+	}
+	recordDeployedCommit(ctx, k8s.Namespace, k8s.Deployment, configPath, snapshot.Commit, "")
+
+	if after, err := getDeploymentState(ctx, k8s.Namespace, k8s.Deployment, configPath); err == nil {
+		summary.NewRevision, summary.NewImages, summary.Pods = after.Revision, after.Images, after.Pods
+	}
+	slog.Info(msg("Restored %s/%s to snapshot %s", projectName, envName, snapshot.ID))
+	summary.Result = "success"
+	summary.print(*outputFormat)
+	recordDeploy(ctx, config.Ledger, newDeployRecord(summary, stageSuccess, ""))
+	return nil
+}
+
+// snapshotTarget 环境的部署目标和k8s配置文件路径，与部署时的解析方式一致
+func snapshotTarget(config *Config, env Env) (K8sConfig, string, error) {
+	configPath := env.K8s.ConfigPath
+	if configPath == "" {
+		configPath = config.K8s.ConfigPath
+	}
+	setK8sImpersonation(config.K8s, env.K8s)
+	if err := setK8sClientOptions(config.K8s, env.K8s); err != nil {
+		return K8sConfig{}, "", err
+	}
+	k8s := env.K8s
+	inCluster := env.K8s.InCluster || config.K8s.InCluster
+	if inCluster {
+		configPath = inClusterConfigPath
+	}
+	if k8s.Namespace == "" && (inCluster || os.Getenv("KUBERNETES_SERVICE_HOST") != "") {
+		namespace, err := detectInClusterNamespace()
+		if err != nil {
+			return K8sConfig{}, "", errorf("Failed to detect namespace: %v", err)
+		}
+		k8s.Namespace = namespace
+	}
+	if k8s.Namespace == "" || k8s.Deployment == "" {
+		return K8sConfig{}, "", errorf("env %s has no k8s.namespace or k8s.deployment", env.Name)
+	}
+	return k8s, configPath, nil
+}
+
+// takeEnvSnapshot 读取部署当前的pod模板、副本数、镜像和引用配置的内容哈希
+func takeEnvSnapshot(ctx context.Context, k8s K8sConfig, configPath string) (*envSnapshot, error) {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := getDeployment(ctx, clientset, k8s.Namespace, k8s.Deployment)
+	if err != nil {
+		return nil, errorf("failed to get deployment: %v", err)
+	}
+
+	template := deployment.Spec.Template
+	now := time.Now()
+	snapshot := &envSnapshot{
+		ID:         now.Format("20060102-150405"),
+		Namespace:  k8s.Namespace,
+		Deployment: k8s.Deployment,
+		Revision:   getDeploymentRevision(deployment),
+		SpecHash:   hashPodTemplate(template),
+		Replicas:   deployment.Spec.Replicas,
+		Images:     podSpecImages(template.Spec),
+		Configs:    hashTemplateConfigs(ctx, clientset, k8s.Namespace, template.Spec),
+		Commit:     deployment.Annotations[deployedCommitAnnotation],
+		Template:   template,
+		CreatedBy:  currentOperator(),
+		CreatedAt:  now,
+	}
+	return snapshot, nil
+}
+
+// hashPodTemplate pod模板JSON的sha256，取前16位
+func hashPodTemplate(template corev1.PodTemplateSpec) string {
+	data, _ := json.Marshal(template)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// hashTemplateConfigs 计算pod模板通过卷、envFrom和env引用的ConfigMap和Secret的内容哈希，读取失败的跳过并警告
+func hashTemplateConfigs(ctx context.Context, clientset *kubernetes.Clientset, namespace string, spec corev1.PodSpec) map[string]string {
+	configMaps, secrets := make(map[string]bool), make(map[string]bool)
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			configMaps[volume.ConfigMap.Name] = true
+		}
+		if volume.Secret != nil {
+			secrets[volume.Secret.SecretName] = true
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					configMaps[source.ConfigMap.Name] = true
+				}
+				if source.Secret != nil {
+					secrets[source.Secret.Name] = true
+				}
+			}
+		}
+	}
+	for _, container := range append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...) {
+		for _, from := range container.EnvFrom {
+			if from.ConfigMapRef != nil {
+				configMaps[from.ConfigMapRef.Name] = true
+			}
+			if from.SecretRef != nil {
+				secrets[from.SecretRef.Name] = true
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				configMaps[env.ValueFrom.ConfigMapKeyRef.Name] = true
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				secrets[env.ValueFrom.SecretKeyRef.Name] = true
+			}
+		}
+	}
+
+	hashes := make(map[string]string)
+	for name := range configMaps {
+		configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				slog.Warn(msg("failed to get configmap %s: %v", name, err))
+			}
+			continue
+		}
+		data := make(map[string][]byte)
+		for key, value := range configMap.Data {
+			data[key] = []byte(value)
+		}
+		for key, value := range configMap.BinaryData {
+			data[key] = value
+		}
+		hashes["configmap/"+name] = hashConfigData(data)
+	}
+	for name := range secrets {
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				slog.Warn(msg("failed to get secret %s: %v", name, err))
+			}
+			continue
+		}
+		hashes["secret/"+name] = hashConfigData(secret.Data)
+	}
+	return hashes
+}
+
+// apply 把部署的pod模板替换为快照中的模板，restoreReplicas为true时同时恢复副本数，有HPA管理时副本数交给HPA
+func (s *envSnapshot) apply(ctx context.Context, configPath string, restoreReplicas bool) error {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+	if restoreReplicas {
+		if hpa, err := findDeploymentHPA(ctx, clientset, s.Namespace, s.Deployment); err == nil && hpa != nil {
+			slog.Info(msg("Deployment is managed by HPA %s, replicas are left to it", hpa.Name))
+			restoreReplicas = false
+		}
+	}
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := getDeployment(ctx, clientset, s.Namespace, s.Deployment)
+		if err != nil {
+			return err
+		}
+		deployment.Spec.Template = s.Template
+		if restoreReplicas {
+			deployment.Spec.Replicas = s.Replicas
+		}
+		_, err = clientset.AppsV1().Deployments(s.Namespace).Update(ctx, deployment, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return errorf("failed to restore deployment %s: %v", s.Deployment, err)
+	}
+	return nil
+}
+
+// save 保存快照，返回文件路径
+func (s *envSnapshot) save() (string, error) {
+	dir, err := snapshotDir(s.Project, s.Env)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", errorf("failed to create snapshot directory: %v", err)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, s.ID+".json")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return "", errorf("failed to save snapshot: %v", err)
+	}
+	return path, nil
+}
+
+// loadSnapshots 读取项目环境的所有快照，按时间从旧到新排列
+func loadSnapshots(project, env string) ([]*envSnapshot, error) {
+	dir, err := snapshotDir(project, env)
+	if err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []*envSnapshot
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var snapshot envSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, errorf("invalid snapshot %s: %v", file, err)
+		}
+		snapshots = append(snapshots, &snapshot)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+// loadSnapshot 按ID读取快照，latest表示最新的快照
+func loadSnapshot(project, env, id string) (*envSnapshot, error) {
+	snapshots, err := loadSnapshots(project, env)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, errorf("no snapshots of %s/%s found, take one with deploy snapshot %s", project, env, env)
+	}
+	if id == "latest" {
+		return snapshots[len(snapshots)-1], nil
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.ID == id {
+			return snapshot, nil
+		}
+	}
+	return nil, errorf("snapshot %s of %s/%s not found, list them with deploy snapshot %s --list", id, project, env, env)
+}
+
+// listSnapshots 列出项目环境的快照
+func listSnapshots(project, env string) error {
+	snapshots, err := loadSnapshots(project, env)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Print(msg("No snapshots of %s/%s\n", project, env))
+		return nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCREATED\tREVISION\tREPLICAS\tSPEC\tIMAGES\tCREATED BY")
+	for _, snapshot := range snapshots {
+		replicas := "-"
+		if snapshot.Replicas != nil {
+			replicas = fmt.Sprint(*snapshot.Replicas)
+		}
+		var images []string
+		for _, name := range sortedMapKeys(snapshot.Images) {
+			images = append(images, snapshot.Images[name])
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", snapshot.ID, formatTime(snapshot.CreatedAt), snapshot.Revision,
+			replicas, snapshot.SpecHash, strings.Join(images, ","), snapshot.CreatedBy)
+	}
+	return writer.Flush()
+}
+
+// sortedMapKeys 按字母顺序返回map的key
+func sortedMapKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// deploySummary 一次部署的汇总信息，运行结束时输出给人或机器阅读
+type deploySummary struct {
+	Project      string             `json:"project"`
+	Env          string             `json:"env"`
+	Result       string             `json:"result"`
+	Branch       string             `json:"branch,omitempty"`
+	Commit       string             `json:"commit,omitempty"`
+	Deployer     string             `json:"deployer,omitempty"`
+	Ticket       string             `json:"ticket,omitempty"` // 关联的变更单号
+	Namespace    string             `json:"namespace,omitempty"`
+	Deployment   string             `json:"deployment,omitempty"`
+	OldRevision  string             `json:"old_revision,omitempty"`
+	NewRevision  string             `json:"new_revision,omitempty"`
+	OldImages    map[string]string  `json:"old_images,omitempty"`
+	NewImages    map[string]string  `json:"new_images,omitempty"`
+	Pods         int                `json:"pods"`
+	BuildNumber  int64              `json:"build_number,omitempty"`
+	BuildURL     string             `json:"build_url,omitempty"`
+	BuildLog     string             `json:"build_log,omitempty"` // 保存的构建日志路径
+	Phases       []phaseDuration    `json:"phases"`
+	SmokeChecks  []smokeCheckResult `json:"smoke_checks,omitempty"`
+	Changelog    []string           `json:"changelog,omitempty"` // 上次部署以来的提交
+	TotalSeconds float64            `json:"total_seconds"`
+	Error        string             `json:"error,omitempty"` // 失败原因
+
+	startTime  time.Time
+	failureLog string // 构建失败时的日志末尾，用于失败通知
+}
+
+// phaseDuration 部署中单个阶段的耗时
+type phaseDuration struct {
+	Name    string  `json:"name"`
+	Seconds float64 `json:"seconds"`
+}
+
+func newDeploySummary(project, env string) *deploySummary {
+	// 没有分支信息时汇总中不填分支，提交sha单独记录
+	branch, source := deployBranchName()
+	if source == "commit sha" {
+		branch = ""
+	}
+	return &deploySummary{
+		Project:   project,
+		Env:       env,
+		Branch:    branch,
+		Commit:    gitOutput("rev-parse", "HEAD"),
+		Deployer:  currentDeployer(),
+		startTime: time.Now(),
+	}
+}
+
+// addPhase 记录从start到现在的阶段耗时
+func (s *deploySummary) addPhase(name string, start time.Time) {
+	s.addPhaseDuration(name, time.Since(start))
+}
+
+// addSmokeCheck 记录冒烟检查结果
+func (s *deploySummary) addSmokeCheck(result smokeCheckResult) {
+	if s == nil {
+		return
+	}
+	s.SmokeChecks = append(s.SmokeChecks, result)
+}
+
+// addPhaseDuration 记录阶段耗时
+func (s *deploySummary) addPhaseDuration(name string, duration time.Duration) {
+	if s == nil {
+		return
+	}
+	s.Phases = append(s.Phases, phaseDuration{Name: name, Seconds: duration.Seconds()})
+}
+
+// deploymentState 部署在某一时刻的revision、镜像和pod数量
+type deploymentState struct {
+	Revision string
+	Images   map[string]string
+	Pods     int
+}
+
+// getDeploymentState 获取部署当前的revision、容器镜像（包括init容器）和pod数量
+func getDeploymentState(ctx context.Context, namespace, deploymentName, configPath string) (*deploymentState, error) {
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return nil, err
+	}
+	deployment, err := getDeployment(ctx, clientset, namespace, deploymentName)
+	if err != nil {
+		return nil, errorf("failed to get deployment: %v", err)
+	}
+	podList, err := getDeploymentPods(ctx, clientset, namespace, deployment)
+	if err != nil {
+		return nil, errorf("failed to get pods: %v", err)
+	}
+	return newDeploymentState(deployment, podList), nil
+}
+
+// newDeploymentState 由已获取的部署和pod列表生成状态
+func newDeploymentState(deployment *appsv1.Deployment, podList *corev1.PodList) *deploymentState {
+	images := getContainerImages(deployment.Spec.Template.Spec.Containers)
+	for name, image := range getContainerImages(deployment.Spec.Template.Spec.InitContainers) {
+		images[name] = image
+	}
+	return &deploymentState{
+		Revision: getDeploymentRevision(deployment),
+		Images:   images,
+		Pods:     len(podList.Items),
+	}
+}
+
+// print 输出汇总，output为json时输出JSON，否则输出文本块
+func (s *deploySummary) print(output string) {
+	s.TotalSeconds = time.Since(s.startTime).Seconds()
+	s.writeFile()
+	s.writeGitHubStepSummary()
+	s.publish()
+	endGitHubGroup()
+
+	if output == "json" {
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode summary: %v\n", err)
+			return
+		}
+		fmt.Fprintln(rawOutput, string(data))
+		return
+	}
+
+	var phases []string
+	for _, phase := range s.Phases {
+		phases = append(phases, fmt.Sprintf("%s %v", phase.Name, roundSeconds(phase.Seconds)))
+	}
+
+	fmt.Fprintln(rawOutput, tr("==================== Deploy Summary ===================="))
+	fmt.Fprint(rawOutput, msg("Project:   %s\n", s.Project))
+	fmt.Fprint(rawOutput, msg("Env:       %s\n", s.Env))
+	fmt.Fprint(rawOutput, msg("Result:    %s\n", s.Result))
+	if s.Deployment != "" {
+		fmt.Fprint(rawOutput, msg("Target:    %s/%s\n", s.Namespace, s.Deployment))
+		fmt.Fprint(rawOutput, msg("Revision:  %s -> %s\n", s.OldRevision, s.NewRevision))
+		for _, line := range formatImageDelta(s.OldImages, s.NewImages) {
+			fmt.Fprint(rawOutput, msg("Image:     %s\n", line))
+		}
+		fmt.Fprint(rawOutput, msg("Pods:      %d\n", s.Pods))
+	}
+	if s.BuildNumber > 0 {
+		fmt.Fprint(rawOutput, msg("Build:     #%d %s\n", s.BuildNumber, s.BuildURL))
+	}
+	if s.BuildLog != "" {
+		fmt.Fprint(rawOutput, msg("Log:       %s\n", s.BuildLog))
+	}
+	if s.Ticket != "" {
+		fmt.Fprint(rawOutput, msg("Ticket:    %s\n", s.Ticket))
+	}
+	if len(s.Changelog) > 0 {
+		fmt.Fprint(rawOutput, msg("Changes:   %d commit(s) since the last deploy\n", len(s.Changelog)))
+	}
+	for _, check := range s.SmokeChecks {
+		fmt.Fprint(rawOutput, msg("Smoke:     %s passed\n", check.Name))
+	}
+	fmt.Fprint(rawOutput, msg("Phases:    %s\n", strings.Join(phases, ", ")))
+	fmt.Fprint(rawOutput, msg("Total:     %v\n", roundSeconds(s.TotalSeconds)))
+	fmt.Fprintln(rawOutput, "========================================================")
+}
+
+// fail 部署失败时记录失败原因，写入--summary-file
+func (s *deploySummary) fail(message string) {
+	s.Result, s.Error = stageFailure, message
+	s.TotalSeconds = time.Since(s.startTime).Seconds()
+	s.writeFile()
+	s.writeGitHubStepSummary()
+	s.publish()
+}
+
+// publish 发布部署结束的事件
+func (s *deploySummary) publish() {
+	publishEvent(runEvent{Type: eventFinish, Result: s.Result, Text: s.Error, Summary: s})
+}
+
+// writeFile 指定了--summary-file时写入JSON汇总，供CI发布任务摘要，写入失败只警告
+func (s *deploySummary) writeFile() {
+	if *summaryFile == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = os.WriteFile(*summaryFile, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		slog.Warn(msg("failed to write summary file %s: %v", *summaryFile, err))
+	}
+}
+
+// formatImageDelta 按容器输出镜像变化
+func formatImageDelta(oldImages, newImages map[string]string) []string {
+	names := make([]string, 0, len(newImages))
+	for name := range newImages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		oldImage, newImage := oldImages[name], newImages[name]
+		if oldImage == newImage {
+			lines = append(lines, fmt.Sprintf("%s: %s (unchanged)", name, newImage))
+		} else if oldImage == "" {
+			lines = append(lines, fmt.Sprintf("%s: %s (added)", name, newImage))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s -> %s", name, oldImage, newImage))
+		}
+	}
+	return lines
+}
+
+func roundSeconds(seconds float64) time.Duration {
+	return (time.Duration(seconds * float64(time.Second))).Round(time.Second)
+}
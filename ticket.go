@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ticketFlag 部署关联的变更单号，配置了change_ticket的环境必须提供，也可以通过DEPLOY_TICKET环境变量传入
+var ticketFlag = flag.String("ticket", "", "change ticket ID for this deploy (e.g. CHG0012345 or OPS-123), required by envs with change_ticket, also read from DEPLOY_TICKET")
+
+// changeTicketAnnotation 部署成功后记录在Deployment上的变更单号
+const changeTicketAnnotation = "deploy/change-ticket"
+
+// ChangeTicketConfig 部署受保护环境前必须关联变更单，按正则和（可选）工单系统API校验
+type ChangeTicketConfig struct {
+	Pattern     string   `yaml:"pattern,omitempty"`      // 变更单号的正则，如^CHG[0-9]{7}$（ServiceNow）或^OPS-[0-9]+$（Jira），默认只要求非空
+	URL         string   `yaml:"url,omitempty"`          // 查询变更单的API地址，$ticket替换为单号，返回2xx表示变更单存在
+	User        string   `yaml:"user,omitempty"`         // Basic认证的用户名，为空时token作为Bearer token
+	Token       string   `yaml:"token,omitempty"`        // API token，支持钥匙串和AWS密钥引用
+	StatusField string   `yaml:"status_field,omitempty"` // 响应JSON中状态字段的路径，以.分隔，如fields.status.name（Jira）或result.0.state（ServiceNow）
+	Statuses    []string `yaml:"statuses,omitempty"`     // 允许部署的状态，如Approved、Implement，配置了status_field时必须匹配其中之一
+}
+
+// requireChangeTicket 检查环境要求的变更单：提供了单号、符合正则并在工单系统中处于允许的状态，返回单号，环境不要求时返回提供的单号
+func requireChangeTicket(ctx context.Context, project string, env Env) (string, error) {
+	ticket := strings.TrimSpace(*ticketFlag)
+	if ticket == "" {
+		ticket = strings.TrimSpace(os.Getenv("DEPLOY_TICKET"))
+	}
+	config := env.ChangeTicket
+	if config == nil {
+		return ticket, nil
+	}
+	if ticket == "" {
+		return "", errorf("policy denied: deploying %s/%s requires a change ticket, pass it with --ticket <id>", project, env.Name)
+	}
+	if config.Pattern != "" {
+		pattern, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return "", errorf("invalid change_ticket.pattern %q: %v", config.Pattern, err)
+		}
+		if !pattern.MatchString(ticket) {
+			return "", errorf("policy denied: change ticket %q does not match %s", ticket, config.Pattern)
+		}
+	}
+	if config.URL != "" {
+		if err := config.verify(ctx, ticket); err != nil {
+			return "", errorf("policy denied: change ticket %s: %v", ticket, err)
+		}
+	}
+	slog.Info(msg("Change ticket %s verified for %s/%s", ticket, project, env.Name))
+	return ticket, nil
+}
+
+// verify 在工单系统中查询变更单，配置了status_field时检查状态
+func (c *ChangeTicketConfig) verify(ctx context.Context, ticket string) error {
+	token, err := resolveSecret(ctx, c.Token)
+	if err != nil {
+		return errorf("failed to get change_ticket.token: %v", err)
+	}
+	registerSecret(token)
+	headers := map[string]string{}
+	switch {
+	case c.User != "":
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(c.User+":"+token))
+	case token != "":
+		headers["Authorization"] = "Bearer " + token
+	}
+
+	body, err := sendJSON(ctx, http.MethodGet, strings.ReplaceAll(c.URL, "$ticket", url.PathEscape(ticket)), nil, headers)
+	if err != nil {
+		return errorf("lookup failed: %v", err)
+	}
+	if c.StatusField == "" {
+		return nil
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return errorf("invalid response: %v", err)
+	}
+	status, ok := jsonField(data, c.StatusField)
+	if !ok {
+		return errorf("not found (no %s in the response)", c.StatusField)
+	}
+	if len(c.Statuses) > 0 && !slices.ContainsFunc(c.Statuses, func(allowed string) bool { return strings.EqualFold(allowed, status) }) {
+		return errorf("status is %q, deploys need one of: %s", status, strings.Join(c.Statuses, ", "))
+	}
+	return nil
+}
+
+// jsonField 按.分隔的路径读取JSON中的值，数字段表示数组下标
+func jsonField(data any, path string) (string, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch value := data.(type) {
+		case map[string]any:
+			data = value[key]
+		case []any:
+			var index int
+			if _, err := fmt.Sscan(key, &index); err != nil || index < 0 || index >= len(value) {
+				return "", false
+			}
+			data = value[index]
+		default:
+			return "", false
+		}
+	}
+	if data == nil {
+		return "", false
+	}
+	return fmt.Sprint(data), true
+}
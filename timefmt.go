@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 时间显示相关的命令行参数，优先于配置文件中的time_zone和time_format
+var (
+	timeZoneFlag   = flag.String("time-zone", "", "time zone of displayed timestamps: local (default), UTC or an IANA name such as Europe/Berlin")
+	timeFormatFlag = flag.String("time-format", "", "format of displayed timestamps: default (2006-01-02 15:04:05), rfc3339 or relative")
+)
+
+// 时间显示格式
+const (
+	timeFormatDefault  = "default"
+	timeFormatRFC3339  = "rfc3339"
+	timeFormatRelative = "relative"
+)
+
+// 日志、历史、审计日志等输出中时间的时区和格式
+var (
+	displayLocation = time.Local
+	displayFormat   = timeFormatDefault
+)
+
+// processStart 进程启动时间，relative格式下日志时间显示为距此的时长
+var processStart = time.Now()
+
+// setTimeDisplay 按命令行参数、配置文件的顺序设置时间的时区和格式
+func setTimeDisplay(zone, format string) error {
+	if *timeZoneFlag != "" {
+		zone = *timeZoneFlag
+	}
+	if *timeFormatFlag != "" {
+		format = *timeFormatFlag
+	}
+
+	switch strings.ToLower(zone) {
+	case "", "local":
+		displayLocation = time.Local
+	case "utc":
+		displayLocation = time.UTC
+	default:
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return errorf("invalid time zone %q: %v", zone, err)
+		}
+		displayLocation = location
+	}
+
+	switch format = strings.ToLower(format); format {
+	case "":
+		displayFormat = timeFormatDefault
+	case timeFormatDefault, timeFormatRFC3339, timeFormatRelative:
+		displayFormat = format
+	default:
+		return errorf("invalid time format %q: must be default, rfc3339 or relative", format)
+	}
+	return nil
+}
+
+// formatTime 按配置的时区和格式显示时间，relative格式显示为多久以前或多久以后
+func formatTime(t time.Time) string {
+	switch displayFormat {
+	case timeFormatRFC3339:
+		return t.In(displayLocation).Format(time.RFC3339)
+	case timeFormatRelative:
+		if d := time.Since(t); d >= 0 {
+			return formatDurationShort(d) + " ago"
+		}
+		return "in " + formatDurationShort(time.Until(t))
+	}
+	return t.In(displayLocation).Format("2006-01-02 15:04:05")
+}
+
+// formatLogTime 日志行的时间，relative格式显示为进程启动后经过的时长
+func formatLogTime(t time.Time) string {
+	if displayFormat == timeFormatRelative {
+		return "+" + formatDurationShort(t.Sub(processStart))
+	}
+	return formatTime(t)
+}
+
+// formatDurationShort 按秒取整的时长，超过一天时显示天数
+func formatDurationShort(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= 24*time.Hour {
+		return fmt.Sprintf("%dd%dh", d/(24*time.Hour), d%(24*time.Hour)/time.Hour)
+	}
+	return d.String()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// TrafficShiftConfig 通过Istio VirtualService或Gateway API HTTPRoute逐步切换流量权重
+type TrafficShiftConfig struct {
+	Kind              string             `yaml:"kind"`               // VirtualService 或 HTTPRoute
+	Name              string             `yaml:"name"`               // 路由资源名称，与部署在同一命名空间
+	StableDestination string             `yaml:"stable_destination"` // 旧版本的destination host（VirtualService）或backendRef名称（HTTPRoute）
+	CanaryDestination string             `yaml:"canary_destination"` // 新版本的destination host或backendRef名称
+	Steps             []TrafficShiftStep `yaml:"steps"`
+	MaxRestarts       int32              `yaml:"max_restarts,omitempty"` // 每个阶段观察期间允许的容器重启次数
+	Prometheus        *PrometheusCheck   `yaml:"prometheus,omitempty"`
+}
+
+// TrafficShiftStep 流量切换的一个阶段
+type TrafficShiftStep struct {
+	Weight   int    `yaml:"weight"`              // 新版本的流量百分比
+	BakeTime string `yaml:"bake_time,omitempty"` // 该阶段的观察时长，为空时不观察
+}
+
+var (
+	virtualServiceResource = schema.GroupVersionResource{Group: "networking.istio.io", Version: "v1beta1", Resource: "virtualservices"}
+	httpRouteResource      = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "httproutes"}
+)
+
+// runTrafficShift 按配置的阶段逐步提高新版本的流量权重，任一阶段观察失败时将流量切回旧版本
+func runTrafficShift(ctx context.Context, k8s K8sConfig, configPath string) error {
+	startGitHubGroup("Traffic shift")
+	setHeartbeatPhase("shifting traffic")
+	shift := k8s.TrafficShift
+	var resource schema.GroupVersionResource
+	switch shift.Kind {
+	case "VirtualService":
+		resource = virtualServiceResource
+	case "HTTPRoute":
+		resource = httpRouteResource
+	default:
+		return errorf("unsupported traffic_shift kind %q, expected VirtualService or HTTPRoute", shift.Kind)
+	}
+
+	k8sConfig, err := newKubernetesConfig(configPath)
+	if err != nil {
+		return err
+	}
+	dynamicClient, err := dynamic.NewForConfig(k8sConfig)
+	if err != nil {
+		return errorf("failed to create dynamic client: %v", err)
+	}
+	clientset, err := newKubernetesClient(configPath)
+	if err != nil {
+		return err
+	}
+	routes := dynamicClient.Resource(resource).Namespace(k8s.Namespace)
+
+	for i, step := range shift.Steps {
+		if err := setTrafficWeight(ctx, routes, shift, step.Weight); err != nil {
+			return err
+		}
+		slog.Info(msg("Traffic shift step %d/%d: %d%% to %s, %d%% to %s", i+1, len(shift.Steps), step.Weight, shift.CanaryDestination, 100-step.Weight, shift.StableDestination))
+
+		if step.BakeTime == "" {
+			continue
+		}
+		bake := &CanaryConfig{
+			Deployment:  k8s.Deployment,
+			BakeTime:    step.BakeTime,
+			MaxRestarts: shift.MaxRestarts,
+			Prometheus:  shift.Prometheus,
+		}
+		if err := bakeCanary(ctx, clientset, k8s.Namespace, bake); err != nil {
+			slog.Info(msg("Traffic shift failed at %d%%, routing all traffic back to %s", step.Weight, shift.StableDestination))
+			if rollbackErr := setTrafficWeight(ctx, routes, shift, 0); rollbackErr != nil {
+				slog.Error(msg("Traffic rollback failed: %v", rollbackErr))
+			}
+			return errorf("traffic shift failed at %d%%: %v", step.Weight, err)
+		}
+	}
+	return nil
+}
+
+// setTrafficWeight 更新路由资源中新旧版本的权重
+func setTrafficWeight(ctx context.Context, routes dynamic.ResourceInterface, shift *TrafficShiftConfig, weight int) error {
+	route, err := routes.Get(ctx, shift.Name, metav1.GetOptions{})
+	if err != nil {
+		return errorf("failed to get %s %s: %v", shift.Kind, shift.Name, err)
+	}
+
+	// VirtualService: spec.http[].route[].destination.host
+	// HTTPRoute:      spec.rules[].backendRefs[].name
+	rulesField, destinationsField := []string{"spec", "http"}, "route"
+	if shift.Kind == "HTTPRoute" {
+		rulesField, destinationsField = []string{"spec", "rules"}, "backendRefs"
+	}
+
+	rules, found, err := unstructured.NestedSlice(route.Object, rulesField...)
+	if err != nil || !found {
+		return errorf("%s %s has no routing rules", shift.Kind, shift.Name)
+	}
+
+	matched := 0
+	for _, rule := range rules {
+		ruleMap, ok := rule.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		destinations, ok := ruleMap[destinationsField].([]interface{})
+		if !ok {
+			continue
+		}
+		for _, destination := range destinations {
+			destinationMap, ok := destination.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var name string
+			if shift.Kind == "HTTPRoute" {
+				name, _, _ = unstructured.NestedString(destinationMap, "name")
+			} else {
+				name, _, _ = unstructured.NestedString(destinationMap, "destination", "host")
+			}
+			switch name {
+			case shift.CanaryDestination:
+				destinationMap["weight"] = int64(weight)
+				matched++
+			case shift.StableDestination:
+				destinationMap["weight"] = int64(100 - weight)
+				matched++
+			}
+		}
+	}
+	if matched == 0 {
+		return errorf("%s %s has no destinations named %s or %s",
+			shift.Kind, shift.Name, shift.StableDestination, shift.CanaryDestination)
+	}
+
+	if err := unstructured.SetNestedSlice(route.Object, rules, rulesField...); err != nil {
+		return err
+	}
+	if _, err := routes.Update(ctx, route, metav1.UpdateOptions{}); err != nil {
+		return errorf("failed to update %s %s: %v", shift.Kind, shift.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// subcommandUsage 子命令及说明，按帮助信息中的顺序排列
+var subcommandUsage = [][2]string{
+	{"audit", "show the local audit log, audit verify checks it has not been tampered with"},
+	{"batch", "deploy several projects and envs from a manifest"},
+	{"help", "show this help and the envs of the current project"},
+	{"history", "show deploy records from the local history or the shared ledger"},
+	{"login", "save Jenkins and notifier credentials in the system keychain"},
+	{"metrics", "show deploy frequency, change failure rate and time to restore"},
+	{"promote", "promote the last build of a pipeline stage to the next stage"},
+	{"restore", "restore an env to a snapshot and monitor the rollout"},
+	{"resume", "re-attach to an interrupted deploy"},
+	{"run", "schedule a deploy, or list and cancel scheduled deploys"},
+	{"serve", "start the REST API server"},
+	{"snapshot", "record the current state of an env, or list its snapshots"},
+}
+
+func init() {
+	flag.Usage = func() {
+		printUsage(flag.CommandLine.Output())
+	}
+}
+
+// runHelpCommand deploy help子命令：输出帮助信息
+func runHelpCommand(args []string) error {
+	printUsage(os.Stdout)
+	return nil
+}
+
+// printUsage 输出用法、子命令、当前项目的环境和全部参数
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, tr("Usage:"))
+	fmt.Fprintln(w, tr("  deploy [flags] [env] [flags]   trigger the Jenkins build of env and monitor the rollout"))
+	fmt.Fprintln(w, tr("  deploy <command> [args]"))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Commands:"))
+	for _, command := range subcommandUsage {
+		fmt.Fprintf(w, "  %-9s %s\n", command[0], tr(command[1]))
+	}
+	if project, ok := currentProject(); ok {
+		fmt.Fprintln(w)
+		fmt.Fprint(w, msg("Envs of %s:\n", project.Name))
+		for _, env := range project.Envs {
+			fmt.Fprintf(w, "  %s\n", env.Name)
+		}
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, tr("Flags:"))
+	flag.VisitAll(func(f *flag.Flag) {
+		f.Usage = tr(f.Usage)
+	})
+	flag.CommandLine.SetOutput(w)
+	flag.PrintDefaults()
+}
+
+// currentProject 当前目录对应的项目，没有配置文件或无法识别项目时返回false
+func currentProject() (Project, bool) {
+	config, err := loadDefaultConfig()
+	if err != nil {
+		return Project{}, false
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return Project{}, false
+	}
+	name := detectProjectName(config, dir)
+	for _, project := range config.Projects {
+		if project.Name == name {
+			return project, true
+		}
+	}
+	return Project{}, false
+}
+
+// envNames 项目的环境名称列表
+func (p Project) envNames() []string {
+	var names []string
+	for _, env := range p.Envs {
+		names = append(names, env.Name)
+	}
+	return names
+}
+
+// projectNames 配置中的项目名称，按名称排序
+func (c *Config) projectNames() []string {
+	var names []string
+	for _, project := range c.Projects {
+		names = append(names, project.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// unknownEnvError 环境不存在时的错误，列出可用环境，拼写接近时给出建议，也可能是拼错的子命令
+func unknownEnvError(p Project, envName string) error {
+	candidates := p.envNames()
+	for _, command := range subcommandUsage {
+		candidates = append(candidates, command[0])
+	}
+	message := msg("Env %s not found in project %s", envName, p.Name)
+	if suggestion := suggestName(envName, candidates); suggestion != "" {
+		message += msg(", did you mean %q?", suggestion)
+	} else {
+		message += "."
+	}
+	if len(p.Envs) > 0 {
+		message += msg(" Available envs: %s", strings.Join(p.envNames(), ", "))
+	}
+	return errorf("%s", message)
+}
+
+// unknownProjectError 项目不存在时的错误，拼写接近时给出建议
+func unknownProjectError(config *Config, projectName string) error {
+	message := msg("Project not found in config: %s", projectName)
+	if suggestion := suggestName(projectName, config.projectNames()); suggestion != "" {
+		message += msg(", did you mean %q? Set name or path of the project in deploy_config.yaml to match this directory", suggestion)
+	}
+	return errorf("%s", message)
+}
+
+// suggestName 在候选中查找与name拼写最接近的名称，编辑距离超过名称长度的三分之一（至少允许1）时不建议
+func suggestName(name string, candidates []string) string {
+	best, bestDistance := "", max(1, len(name)/3)+1
+	for _, candidate := range candidates {
+		if distance := editDistance(strings.ToLower(name), strings.ToLower(candidate)); distance < bestDistance {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance 两个字符串之间的编辑距离（Levenshtein）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
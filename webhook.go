@@ -0,0 +1,194 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// WebhookConfig 服务模式下接收git推送webhook自动触发部署
+type WebhookConfig struct {
+	GitHubSecret string        `yaml:"github_secret,omitempty"` // GitHub webhook的secret，用于校验X-Hub-Signature-256
+	GitLabToken  string        `yaml:"gitlab_token,omitempty"`  // GitLab webhook的secret token，与X-Gitlab-Token比较
+	Rules        []WebhookRule `yaml:"rules"`
+}
+
+// WebhookRule 推送到匹配的分支或tag时部署项目的环境，branch和tag二选一，支持通配符如release/*、v*
+type WebhookRule struct {
+	Repository string `yaml:"repository,omitempty"` // 仓库全名（org/repo），为空时匹配所有仓库
+	Branch     string `yaml:"branch,omitempty"`
+	Tag        string `yaml:"tag,omitempty"`
+	Project    string `yaml:"project"`
+	Env        string `yaml:"env"`
+}
+
+// pushEvent GitHub和GitLab推送事件中用到的字段
+type pushEvent struct {
+	Repository string
+	Ref        string
+	Commit     string
+	Deleted    bool
+	User       string // 推送代码的用户，用于allowed_users检查
+}
+
+// matches 推送事件是否匹配规则
+func (r WebhookRule) matches(event pushEvent) bool {
+	if r.Repository != "" && !strings.EqualFold(r.Repository, event.Repository) {
+		return false
+	}
+	if branch, ok := strings.CutPrefix(event.Ref, "refs/heads/"); ok && r.Branch != "" {
+		matched, _ := path.Match(r.Branch, branch)
+		return matched
+	}
+	if tag, ok := strings.CutPrefix(event.Ref, "refs/tags/"); ok && r.Tag != "" {
+		matched, _ := path.Match(r.Tag, tag)
+		return matched
+	}
+	return false
+}
+
+// handleGitHubWebhook 接收GitHub push事件，校验HMAC签名
+func (s *deployServer) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
+	config := s.webhookConfig()
+	if config == nil || config.GitHubSecret == "" {
+		writeJSONError(w, http.StatusNotFound, "github webhook not configured")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10*1024*1024))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("failed to read body: %v", err))
+		return
+	}
+	mac := hmac.New(sha256.New, []byte(config.GitHubSecret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(expected)) {
+		writeJSONError(w, http.StatusUnauthorized, "invalid signature")
+		return
+	}
+
+	switch r.Header.Get("X-GitHub-Event") {
+	case "ping":
+		writeJSON(w, http.StatusOK, map[string]string{"status": "pong"})
+		return
+	case "push":
+	default:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	var payload struct {
+		Ref        string `json:"ref"`
+		After      string `json:"after"`
+		Deleted    bool   `json:"deleted"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Sender struct {
+			Login string `json:"login"`
+		} `json:"sender"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	s.handlePushEvent(w, "github", pushEvent{
+		Repository: payload.Repository.FullName,
+		Ref:        payload.Ref,
+		Commit:     payload.After,
+		Deleted:    payload.Deleted,
+		User:       payload.Sender.Login,
+	})
+}
+
+// handleGitLabWebhook 接收GitLab Push Hook和Tag Push Hook事件，校验secret token
+func (s *deployServer) handleGitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	config := s.webhookConfig()
+	if config == nil || config.GitLabToken == "" {
+		writeJSONError(w, http.StatusNotFound, "gitlab webhook not configured")
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Gitlab-Token")), []byte(config.GitLabToken)) != 1 {
+		writeJSONError(w, http.StatusUnauthorized, "invalid token")
+		return
+	}
+	if event := r.Header.Get("X-Gitlab-Event"); event != "Push Hook" && event != "Tag Push Hook" {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	var payload struct {
+		Ref         string `json:"ref"`
+		After       string `json:"after"`
+		CheckoutSHA string `json:"checkout_sha"`
+		UserName    string `json:"user_username"`
+		Project     struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 10*1024*1024)).Decode(&payload); err != nil {
+		writeJSONError(w, http.StatusBadRequest, fmt.Sprintf("invalid payload: %v", err))
+		return
+	}
+	s.handlePushEvent(w, "gitlab", pushEvent{
+		Repository: payload.Project.PathWithNamespace,
+		Ref:        payload.Ref,
+		Commit:     payload.CheckoutSHA,
+		// 删除分支或tag时after为全0，checkout_sha为空
+		Deleted: payload.CheckoutSHA == "" || strings.Trim(payload.After, "0") == "",
+		User:    payload.UserName,
+	})
+}
+
+// handlePushEvent 按规则触发部署，返回每条匹配规则的处理结果
+func (s *deployServer) handlePushEvent(w http.ResponseWriter, source string, event pushEvent) {
+	if event.Deleted {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "reason": "ref deleted"})
+		return
+	}
+
+	var results []map[string]string
+	for _, rule := range s.webhookConfig().Rules {
+		if !rule.matches(event) {
+			continue
+		}
+		result := map[string]string{"project": rule.Project, "env": rule.Env}
+		run, _, err := s.startDeploy(deployRequest{
+			Project: rule.Project,
+			Env:     rule.Env,
+			Ref:     event.Ref,
+			Commit:  event.Commit,
+			Trigger: source + " webhook",
+			User:    event.User,
+		})
+		if err != nil {
+			slog.Warn(msg("Webhook push to %s %s: failed to start deploy of %s/%s: %v", event.Repository, event.Ref, rule.Project, rule.Env, err))
+			result["error"] = err.Error()
+		} else {
+			slog.Info(msg("Webhook push to %s %s triggered deploy %s of %s/%s", event.Repository, event.Ref, run.ID, rule.Project, rule.Env))
+			result["id"] = run.ID
+		}
+		results = append(results, result)
+	}
+	if len(results) == 0 {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ignored", "reason": "no matching rule"})
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"status": "triggered", "deploys": results})
+}
+
+// webhookConfig 返回webhook配置，未配置时为nil
+func (s *deployServer) webhookConfig() *WebhookConfig {
+	if s.config.Server == nil {
+		return nil
+	}
+	return s.config.Server.Webhooks
+}